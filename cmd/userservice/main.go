@@ -11,7 +11,7 @@ import (
 )
 
 func main() {
-	userHandlers, cleanup, err := config.SetupDependencies()
+	dependencies, cleanup, err := config.SetupDependencies()
 
 	if err != nil {
 		log.Fatalf("%v: %v", constants.ErrSetUpDependencies, err)
@@ -20,8 +20,12 @@ func main() {
 	defer cleanup()
 
 	r := gin.Default()
+	if err := r.SetTrustedProxies(dependencies.TrustedProxies); err != nil {
+		cleanup()
+		log.Fatalf("%v: %v", constants.ErrSetUpDependencies, err)
+	}
 
-	SetupRoutes(r, userHandlers)
+	SetupRoutes(r, dependencies)
 
 	fmt.Println("Starting my microservice")
 
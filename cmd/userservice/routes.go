@@ -1,16 +1,82 @@
 package main
 
 import (
-	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
+	"expvar"
+
+	"github.com/CNMoreno/cnm-proyect-go/config"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
 	"github.com/gin-gonic/gin"
 )
 
 // SetupRoutes endpoints for user.
-func SetupRoutes(r *gin.Engine, userHandlers *handlers.UserHandlers) {
+func SetupRoutes(r *gin.Engine, dependencies *config.Dependencies) {
+	userHandlers := dependencies.UserHandlers
+	authHandlers := dependencies.AuthHandlers
+	mfaHandlers := dependencies.MFAHandlers
+	webAuthnHandlers := dependencies.WebAuthnHandlers
+	sessionHandlers := dependencies.SessionHandlers
+	oauthHandlers := dependencies.OAuthHandlers
+	openIDHandlers := dependencies.OpenIDHandlers
+	apiKeyHandlers := dependencies.APIKeyHandlers
+	webhookHandlers := dependencies.WebhookHandlers
+
+	r.Use(sessionHandlers.IdentifyRequest)
+
 	route := "/users/:id"
 	r.POST("/users", userHandlers.CreateUser)
 	r.GET(route, userHandlers.GetUserByID)
-	r.PATCH(route, userHandlers.UpdateUser)
-	r.DELETE(route, userHandlers.DeleteUser)
-	r.POST("/users/batch", userHandlers.CreateBatchUser)
+	r.POST(route+"/password", sessionHandlers.DenyImpersonation, userHandlers.ChangePassword)
+	r.GET("/verify-email", userHandlers.VerifyEmail)
+
+	r.POST("/auth/login", authHandlers.Login)
+	r.POST("/auth/login/mfa", authHandlers.LoginMFA)
+	r.POST("/auth/webauthn/login/begin", webAuthnHandlers.BeginLogin)
+	r.POST("/auth/webauthn/login/finish", webAuthnHandlers.FinishLogin)
+	r.POST("/auth/passwordless", authHandlers.RequestPasswordless)
+	r.POST("/auth/passwordless/verify", authHandlers.LoginPasswordless)
+	r.POST("/auth/password/forgot", userHandlers.ForgotPassword)
+	r.POST("/auth/password/reset", userHandlers.ResetPassword)
+
+	r.POST("/oauth/token", oauthHandlers.Token)
+	r.POST("/oauth/introspect", oauthHandlers.Introspect)
+	r.POST("/oauth/revoke", oauthHandlers.Revoke)
+	r.GET("/userinfo", oauthHandlers.RequireAccessToken(""), openIDHandlers.UserInfo)
+	r.POST("/userinfo", oauthHandlers.RequireAccessToken(""), openIDHandlers.UserInfo)
+	r.GET("/.well-known/openid-configuration", openIDHandlers.Discovery)
+	r.GET("/.well-known/jwks.json", openIDHandlers.JWKS)
+
+	authenticated := r.Group("", sessionHandlers.RequireSession)
+	authenticated.GET(route+"/sessions", sessionHandlers.RequireSameUser, sessionHandlers.RequirePermission(domain.PermissionSessionsRead), sessionHandlers.ListSessions)
+	authenticated.DELETE(route+"/sessions", sessionHandlers.RequireSameUser, sessionHandlers.RequirePermission(domain.PermissionSessionsWrite), sessionHandlers.RevokeAllSessions)
+	authenticated.DELETE(route+"/sessions/:sid", sessionHandlers.RequireSameUser, sessionHandlers.RequirePermission(domain.PermissionSessionsWrite), sessionHandlers.RevokeSession)
+	authenticated.GET(route+"/api-keys", sessionHandlers.RequireSameUser, sessionHandlers.RequirePermission(domain.PermissionAPIKeysRead), apiKeyHandlers.ListAPIKeys)
+	authenticated.GET(route+"/audit", sessionHandlers.RequireSameUser, sessionHandlers.RequirePermission(domain.PermissionAuditRead), userHandlers.ListAuditEvents)
+	authenticated.GET("/users/events", sessionHandlers.RequireAdmin, sessionHandlers.RequirePermission(domain.PermissionUserEventsRead), userHandlers.StreamUserEvents)
+
+	interactive := authenticated.Group("", sessionHandlers.DenyAPIKey)
+	interactive.PATCH(route, sessionHandlers.RequireSameUser, sessionHandlers.DenyImpersonation, userHandlers.UpdateUser)
+	interactive.DELETE(route, sessionHandlers.RequireSameUser, sessionHandlers.DenyImpersonation, userHandlers.DeleteUser)
+	interactive.POST(route+"/verify-email/send", sessionHandlers.RequireSameUser, sessionHandlers.DenyImpersonation, userHandlers.SendEmailVerification)
+	interactive.POST(route+"/api-keys", sessionHandlers.RequireSameUser, sessionHandlers.DenyImpersonation, apiKeyHandlers.CreateAPIKey)
+	interactive.DELETE(route+"/api-keys/:kid", sessionHandlers.RequireSameUser, apiKeyHandlers.RevokeAPIKey)
+	interactive.POST(route+"/mfa/totp", sessionHandlers.RequireSameUser, sessionHandlers.DenyImpersonation, mfaHandlers.EnrollTOTP)
+	interactive.GET(route+"/mfa/totp/qr", sessionHandlers.RequireSameUser, sessionHandlers.DenyImpersonation, mfaHandlers.TOTPQRCode)
+	interactive.POST(route+"/mfa/totp/confirm", sessionHandlers.RequireSameUser, sessionHandlers.DenyImpersonation, mfaHandlers.ConfirmTOTP)
+	interactive.POST(route+"/webauthn/register/begin", sessionHandlers.RequireSameUser, sessionHandlers.DenyImpersonation, webAuthnHandlers.BeginRegistration)
+	interactive.POST(route+"/webauthn/register/finish", sessionHandlers.RequireSameUser, sessionHandlers.DenyImpersonation, webAuthnHandlers.FinishRegistration)
+	interactive.POST("/auth/logout", sessionHandlers.Logout)
+	interactive.POST("/admin/users/:id/impersonate", sessionHandlers.Impersonate)
+	interactive.GET("/oauth/authorize", oauthHandlers.Authorize)
+	interactive.POST("/oauth/authorize", oauthHandlers.Consent)
+
+	admin := interactive.Group("", sessionHandlers.RequireAdmin)
+	admin.POST("/users/batch", userHandlers.CreateBatchUser)
+	admin.POST("/admin/users/:id/unlock", authHandlers.UnlockUser)
+	admin.POST("/admin/oauth/clients", oauthHandlers.RegisterClient)
+	admin.POST("/admin/webhooks", webhookHandlers.CreateWebhook)
+	admin.GET("/admin/webhooks", webhookHandlers.ListWebhooks)
+	admin.DELETE("/admin/webhooks/:id", webhookHandlers.DeleteWebhook)
+	admin.GET("/admin/webhooks/:id/deliveries", webhookHandlers.ListDeliveries)
+	admin.POST("/admin/webhooks/:id/deliveries/:did/redeliver", webhookHandlers.Redeliver)
+	admin.GET("/debug/vars", gin.WrapH(expvar.Handler()))
 }
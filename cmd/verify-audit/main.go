@@ -0,0 +1,48 @@
+// Command verify-audit walks the audit log hash chain and its signed checkpoints and reports
+// the first broken link. It exits with status 1 when the audit log was edited.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/CNMoreno/cnm-proyect-go/config"
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+)
+
+func main() {
+	keyPath := flag.String("key", os.Getenv("AUDIT_VERIFICATION_KEY_FILE"), "PEM file of the Ed25519 key that verifies the checkpoints")
+	flag.Parse()
+
+	if *keyPath == "" {
+		log.Fatalf("%v: the -key flag or AUDIT_VERIFICATION_KEY_FILE is required", constants.ErrVerifyAudit)
+	}
+
+	publicKey, err := utils.LoadAuditVerificationKey(*keyPath)
+	if err != nil {
+		log.Fatalf("%v: %v", constants.ErrVerifyAudit, err)
+	}
+
+	auditChain, cleanup, err := config.SetupAuditVerification()
+	if err != nil {
+		log.Fatalf("%v: %v", constants.ErrSetUpDependencies, err)
+	}
+
+	result, err := auditChain.Verify(context.Background(), publicKey)
+	cleanup()
+	if err != nil {
+		log.Fatalf("%v: %v", constants.ErrVerifyAudit, err)
+	}
+
+	if !result.Valid {
+		fmt.Printf("audit log broken at sequence %d (entry %q): %v\n", result.BrokenSequence, result.BrokenEventID, result.Reason)
+		fmt.Printf("%d entries verified before the broken link, %d checkpoints\n", result.Entries, result.Checkpoints)
+		os.Exit(1)
+	}
+
+	fmt.Printf("audit log verified: %d entries, %d checkpoints\n", result.Entries, result.Checkpoints)
+}
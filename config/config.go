@@ -4,65 +4,590 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/netip"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
 	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
 	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
 	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
 	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
 	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"golang.org/x/crypto/bcrypt"
 )
 
+// devEnvironments are the values of APP_ENV where development defaults are allowed.
+var devEnvironments = []string{"development", "test"}
+
+const (
+	defaultBloomFalsePositiveRate = 0.001
+	defaultPasswordHistorySize    = 5
+	defaultPasswordResetTTL       = time.Hour
+	defaultPasswordResetLimit     = 5
+	defaultPasswordResetIPLimit   = 20
+	defaultPasswordResetWindow    = 15 * time.Minute
+	defaultEmailVerificationTTL   = 24 * time.Hour
+	defaultLockoutThreshold       = 5
+	defaultLockoutIPThreshold     = 20
+	defaultLockoutDuration        = time.Minute
+	defaultLockoutMaxDuration     = 24 * time.Hour
+	defaultLockoutWindow          = 15 * time.Minute
+	defaultMFAIssuer              = "cnm-proyect-go"
+	defaultMFAChallengeTTL        = 5 * time.Minute
+	defaultMFAReauthWindow        = 5 * time.Minute
+	defaultWebAuthnRPID           = "localhost"
+	defaultWebAuthnRPName         = "cnm-proyect-go"
+	defaultWebAuthnOrigins        = "http://localhost:8080"
+	defaultWebAuthnChallengeTTL   = 5 * time.Minute
+	defaultPasswordlessTTL        = 10 * time.Minute
+	defaultPasswordlessLimit      = 5
+	defaultPasswordlessIPLimit    = 20
+	defaultPasswordlessWindow     = 15 * time.Minute
+	defaultSessionTTL             = 7 * 24 * time.Hour
+	defaultImpersonationTTL       = 15 * time.Minute
+	defaultOAuthCodeTTL           = time.Minute
+	defaultOAuthAccessTokenTTL    = time.Hour
+	defaultOIDCIssuer             = "http://localhost:8080"
+	defaultOIDCIDTokenTTL         = time.Hour
+	defaultOIDCKeyRotation        = 30 * 24 * time.Hour
+	defaultAuditCheckpoint        = time.Hour
+	defaultOutboxPollInterval     = time.Second
+	defaultOutboxLease            = 30 * time.Second
+	defaultOutboxRetention        = 7 * 24 * time.Hour
+	defaultCloudEventsSource      = "/cnm-proyect-go/users"
+	defaultWebhookMaxAttempts     = 8
+	defaultWebhookPollInterval    = 5 * time.Second
+	defaultWebhookLease           = time.Minute
+	defaultWebhookTimeout         = 10 * time.Second
+	defaultUserEventsHistory      = 1000
+	defaultEventPublisherTimeout  = 10 * time.Second
+	defaultNATSSubjectPrefix      = "users"
+	defaultKafkaBrokers           = "localhost:9092"
+	defaultKafkaTopic             = "user-events"
+	defaultMemoryBrokerSize       = 1000
+	changeStreamProbeTimeout      = 5 * time.Second
+	signingKeyRefreshInterval     = time.Minute
+)
+
+// Dependencies groups the HTTP handlers exposed by the application.
+type Dependencies struct {
+	UserHandlers     *handlers.UserHandlers
+	AuthHandlers     *handlers.AuthHandlers
+	MFAHandlers      *handlers.MFAHandlers
+	WebAuthnHandlers *handlers.WebAuthnHandlers
+	SessionHandlers  *handlers.SessionHandlers
+	OAuthHandlers    *handlers.OAuthHandlers
+	OpenIDHandlers   *handlers.OpenIDHandlers
+	APIKeyHandlers   *handlers.APIKeyHandlers
+	WebhookHandlers  *handlers.WebhookHandlers
+	TrustedProxies   []string
+}
+
 // SetupDependencies initializes all the dependencies required by the application.
 // It returns the HTTP handlers, a cleanup function to close resources, and an error if any occurred during initialization.
-func SetupDependencies() (*handlers.UserHandlers, func(), error) {
-	mongoURI := os.Getenv("MONGO_URL")
+// When a step fails the workers already started are stopped and the resources already opened are closed.
+func SetupDependencies() (_ *Dependencies, _ func(), err error) {
+	var closers []func()
+	cleanup := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+	defer func() {
+		if err != nil {
+			cleanup()
+		}
+	}()
 
-	if mongoURI == "" {
-		return nil, nil, fmt.Errorf(constants.ErrMongoUrlIsNotSet)
+	appCrypto, err := newAppCrypto()
+	if err != nil {
+		return nil, nil, err
 	}
 
-	mongoDBName := os.Getenv("MONGO_DATABASE")
-	if mongoDBName == "" {
-		return nil, nil, fmt.Errorf(constants.ErrMongoDatabaseIsNotSet)
+	passwordPolicy, err := newPasswordPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	trustedProxies, err := newTrustedProxies()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	breachedPasswords, closeBreachedPasswords, err := newBreachedPasswords()
+	if err != nil {
+		return nil, nil, err
 	}
+	closers = append(closers, closeBreachedPasswords)
 
-	mongoClient, err := adapters.NewMongoClient(mongoURI, mongoDBName)
+	mongoClient, err := newMongoClient()
 	if err != nil {
 		return nil, nil, err
 	}
+	closers = append(closers, func() {
+		if err := mongoClient.Close(); err != nil {
+			log.Printf("%v: %v", constants.ErrCloseMongoConnection, err)
+		}
+	})
 
 	userCollection := mongoClient.GetDatabase().Collection("users")
 
 	err = createUniqueIndexes(userCollection)
 
 	if err != nil {
-		log.Fatalf("%v: %v", constants.ErrCreateMongoIndex, err)
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
 	}
 
-	bcryptCrypto := repository.BcryptCrypto{}
+	passwordHistorySize, err := newPasswordHistorySize()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	userRepo := repository.NewUserRepository(userCollection, appCrypto.HashPassword).WithPasswordHistory(passwordHistorySize)
+
+	webhookService, stopWebhooks, err := setupWebhooks(mongoClient)
+	if err != nil {
+		return nil, nil, err
+	}
+	closers = append(closers, stopWebhooks)
+
+	var publishers []adapters.EventPublisher
+	if webhookService.Enabled() {
+		publishers = append(publishers, webhookService)
+	}
+
+	stopOutboxRelay, err := setupOutbox(userRepo, mongoClient, publishers...)
+	if err != nil {
+		return nil, nil, err
+	}
+	closers = append(closers, stopOutboxRelay)
+
+	auditCollection := mongoClient.GetDatabase().Collection("audit")
+
+	err = createAuditIndex(auditCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	auditRepo := repository.NewAuditRepository(auditCollection)
+
+	stopAuditCheckpoints, err := setupAuditCheckpoints(auditRepo, mongoClient.GetDatabase().Collection("audit_checkpoints"))
+	if err != nil {
+		return nil, nil, err
+	}
+	closers = append(closers, stopAuditCheckpoints)
+
+	notifier, closeNotifier, err := newNotifier()
+	if err != nil {
+		return nil, nil, err
+	}
+	closers = append(closers, closeNotifier)
+
+	asyncNotifier := adapters.NewAsyncNotifier(notifier)
+	closers = append(closers, asyncNotifier.Close)
+
+	resetURL, resetTTL, err := newTokenLinkSettings("PASSWORD_RESET_URL", "PASSWORD_RESET_TTL", defaultPasswordResetTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resetPolicy, err := newPasswordResetPolicy(resetTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	verificationURL, verificationTTL, err := newTokenLinkSettings("EMAIL_VERIFICATION_URL", "EMAIL_VERIFICATION_TTL", defaultEmailVerificationTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	resetCollection := mongoClient.GetDatabase().Collection("password_resets")
+
+	err = createExpirationIndex(resetCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	resetRepo := repository.NewPasswordResetRepository(resetCollection)
+
+	rateLimitCollection := mongoClient.GetDatabase().Collection("rate_limits")
+
+	err = createExpirationIndex(rateLimitCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	rateLimitRepo := repository.NewRateLimitRepository(rateLimitCollection)
+
+	verificationCollection := mongoClient.GetDatabase().Collection("email_verifications")
+
+	err = createExpirationIndex(verificationCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	verificationRepo := repository.NewEmailVerificationRepository(verificationCollection)
+
+	sessionTTL, err := newDuration("SESSION_TTL", defaultSessionTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sessionCollection := mongoClient.GetDatabase().Collection("sessions")
+
+	err = createExpirationIndex(sessionCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	err = createUniqueIndex(sessionCollection, "tokenHash")
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	sessionService := usecase.NewSessionService(repository.NewSessionRepository(sessionCollection), sessionTTL).WithAdmins(newAdmins())
+
+	impersonationTTL, err := newDuration("IMPERSONATION_TTL", defaultImpersonationTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if adminIDs := newImpersonationAdmins(); len(adminIDs) > 0 {
+		sessionService.WithImpersonation(userRepo, auditRepo, impersonationTTL, adminIDs)
+	}
+
+	apiKeyCollection := mongoClient.GetDatabase().Collection("api_keys")
+
+	err = createExpirationIndex(apiKeyCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	err = createUniqueIndex(apiKeyCollection, "keyHash")
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	apiKeyService := usecase.NewAPIKeyService(repository.NewAPIKeyRepository(apiKeyCollection))
+
+	lockoutPolicy, err := newLockoutPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	attemptCollection := mongoClient.GetDatabase().Collection("login_attempts")
+
+	err = createExpirationIndex(attemptCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	attemptRepo := repository.NewLoginAttemptRepository(attemptCollection)
+
+	userService := usecase.NewUserService(userRepo, auditRepo, appCrypto.CheckPasswordHash).
+		WithSessionRevoker(sessionService).
+		WithAPIKeyRevoker(apiKeyService).
+		WithLockout(attemptRepo, lockoutPolicy).
+		WithPasswordReset(resetRepo, rateLimitRepo, asyncNotifier, resetURL, resetPolicy).
+		WithEmailVerification(verificationRepo, notifier, verificationURL, verificationTTL)
+
+	userEvents, err := setupUserEvents(userCollection, userService)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	secretBox, err := newSecretBox("MFA")
+	if err != nil {
+		return nil, nil, err
+	}
 
-	appCrypto := utils.NewHashPassword(bcryptCrypto)
+	mfaChallengeTTL, err := newDuration("MFA_CHALLENGE_TTL", defaultMFAChallengeTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mfaReauthWindow, err := newDuration("MFA_REAUTH_WINDOW", defaultMFAReauthWindow)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mfaIssuer := os.Getenv("MFA_ISSUER")
+	if mfaIssuer == "" {
+		mfaIssuer = defaultMFAIssuer
+	}
+
+	challengeCollection := mongoClient.GetDatabase().Collection("mfa_challenges")
+
+	err = createExpirationIndex(challengeCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	mfaService := usecase.NewMFAService(
+		userRepo,
+		repository.NewMFARepository(userCollection),
+		repository.NewMFAChallengeRepository(challengeCollection),
+		secretBox,
+		mfaIssuer,
+		mfaChallengeTTL,
+	).WithReauthentication(appCrypto.CheckPasswordHash, mfaReauthWindow)
+
+	webAuthnConfig, webAuthnAttestation, err := newWebAuthnSettings()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	webAuthnChallengeTTL, err := newDuration("WEBAUTHN_CHALLENGE_TTL", defaultWebAuthnChallengeTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	webAuthnRPName := os.Getenv("WEBAUTHN_RP_NAME")
+	if webAuthnRPName == "" {
+		webAuthnRPName = defaultWebAuthnRPName
+	}
+
+	webAuthnChallengeCollection := mongoClient.GetDatabase().Collection("webauthn_challenges")
+
+	err = createExpirationIndex(webAuthnChallengeCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	webAuthnService := usecase.NewWebAuthnService(
+		userRepo,
+		repository.NewWebAuthnRepository(mongoClient.GetDatabase().Collection("webauthn_credentials")),
+		repository.NewWebAuthnChallengeRepository(webAuthnChallengeCollection),
+		webAuthnConfig,
+		webAuthnRPName,
+		webAuthnAttestation,
+		webAuthnChallengeTTL,
+	)
+
+	passwordlessURL, passwordlessTTL, err := newTokenLinkSettings("PASSWORDLESS_URL", "PASSWORDLESS_TTL", defaultPasswordlessTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	passwordlessPolicy, err := newPasswordlessPolicy(passwordlessTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	passwordlessCollection := mongoClient.GetDatabase().Collection("passwordless_logins")
+
+	err = createExpirationIndex(passwordlessCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	secureCookies, err := newSecureCookies()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	oauthCodeTTL, err := newDuration("OAUTH_CODE_TTL", defaultOAuthCodeTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	oauthAccessTokenTTL, err := newDuration("OAUTH_ACCESS_TOKEN_TTL", defaultOAuthAccessTokenTTL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	oauthCodeCollection := mongoClient.GetDatabase().Collection("oauth_codes")
+
+	err = createExpirationIndex(oauthCodeCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	oauthTokenCollection := mongoClient.GetDatabase().Collection("oauth_tokens")
+
+	err = createExpirationIndex(oauthTokenCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	revokedTokenCollection := mongoClient.GetDatabase().Collection("revoked_tokens")
+
+	err = createExpirationIndex(revokedTokenCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	oauthService := usecase.NewOAuthService(
+		userRepo,
+		repository.NewOAuthClientRepository(mongoClient.GetDatabase().Collection("oauth_clients")),
+		repository.NewOAuthConsentRepository(mongoClient.GetDatabase().Collection("oauth_consents")),
+		repository.NewOAuthCodeRepository(oauthCodeCollection),
+		repository.NewOAuthTokenRepository(oauthTokenCollection),
+		repository.NewRevokedTokenRepository(revokedTokenCollection),
+		oauthCodeTTL,
+		oauthAccessTokenTTL,
+	)
+	userService.WithOAuthTokenRevoker(oauthService)
 
-	userRepo := repository.NewUserRepository(userCollection, appCrypto.HashPassword)
+	stopKeyRotation, err := setupOpenID(oauthService, mongoClient.GetDatabase().Collection("signing_keys"))
+	if err != nil {
+		return nil, nil, err
+	}
+	closers = append(closers, stopKeyRotation)
 
-	userService := usecase.NewUserService(userRepo)
+	authService := usecase.NewAuthService(userRepo, appCrypto.VerifyPassword).
+		WithLockout(attemptRepo, lockoutPolicy).
+		WithMFA(mfaService).
+		WithWebAuthn(webAuthnService).
+		WithSessions(sessionService).
+		WithPasswordless(repository.NewPasswordlessRepository(passwordlessCollection), rateLimitRepo, asyncNotifier, passwordlessURL, passwordlessPolicy)
+	utils.SetPasswordPolicy(passwordPolicy)
+	utils.SetBreachedPasswords(breachedPasswords)
 	utils.NewValidator()
 	userHandlers := &handlers.UserHandlers{
 		UserService: userService,
+		UserEvents:  userEvents,
+	}
+	authHandlers := &handlers.AuthHandlers{
+		AuthService:   authService,
+		SecureCookies: secureCookies,
+	}
+	mfaHandlers := &handlers.MFAHandlers{
+		MFAService: mfaService,
+	}
+	webAuthnHandlers := &handlers.WebAuthnHandlers{
+		WebAuthnService: webAuthnService,
+		AuthService:     authService,
+	}
+	sessionHandlers := &handlers.SessionHandlers{
+		SessionService: sessionService,
+		APIKeyService:  apiKeyService,
+	}
+	apiKeyHandlers := &handlers.APIKeyHandlers{
+		APIKeyService: apiKeyService,
+	}
+	oauthHandlers := &handlers.OAuthHandlers{
+		OAuthService: oauthService,
+	}
+	openIDHandlers := &handlers.OpenIDHandlers{
+		OAuthService: oauthService,
+	}
+	webhookHandlers := &handlers.WebhookHandlers{
+		WebhookService: webhookService,
+	}
+
+	return &Dependencies{
+		UserHandlers:     userHandlers,
+		AuthHandlers:     authHandlers,
+		MFAHandlers:      mfaHandlers,
+		WebAuthnHandlers: webAuthnHandlers,
+		SessionHandlers:  sessionHandlers,
+		OAuthHandlers:    oauthHandlers,
+		OpenIDHandlers:   openIDHandlers,
+		APIKeyHandlers:   apiKeyHandlers,
+		WebhookHandlers:  webhookHandlers,
+		TrustedProxies:   trustedProxies,
+	}, cleanup, nil
+}
+
+// SetupAuditVerification connects to the database of the audit log to verify its hash chain.
+// It returns the audit chain service and a cleanup function to close the connection.
+func SetupAuditVerification() (*usecase.AuditChainService, func(), error) {
+	mongoClient, err := newMongoClient()
+	if err != nil {
+		return nil, nil, err
 	}
 
+	auditRepo := repository.NewAuditRepository(mongoClient.GetDatabase().Collection("audit"))
+	checkpointRepo := repository.NewAuditCheckpointRepository(mongoClient.GetDatabase().Collection("audit_checkpoints"))
+
 	cleanup := func() {
 		if err := mongoClient.Close(); err != nil {
 			log.Printf("%v: %v", constants.ErrCloseMongoConnection, err)
 		}
 	}
 
-	return userHandlers, cleanup, nil
+	return usecase.NewAuditChainService(auditRepo, checkpointRepo, nil), cleanup, nil
+}
+
+// newMongoClient connects to the MONGO_DATABASE database of the MONGO_URL server.
+func newMongoClient() (*adapters.MongoClient, error) {
+	mongoURI := os.Getenv("MONGO_URL")
+
+	if mongoURI == "" {
+		return nil, fmt.Errorf(constants.ErrMongoUrlIsNotSet)
+	}
+
+	mongoDBName := os.Getenv("MONGO_DATABASE")
+	if mongoDBName == "" {
+		return nil, fmt.Errorf(constants.ErrMongoDatabaseIsNotSet)
+	}
+
+	return adapters.NewMongoClient(mongoURI, mongoDBName)
+}
+
+// newAppCrypto selects the password hashing algorithm from PASSWORD_HASH_ALGORITHM,
+// hashes generated by the other algorithm are still accepted and rehashed on login.
+// Peppers are loaded from PASSWORD_PEPPER_FILE, PASSWORD_PEPPER_ID selects the current one.
+func newAppCrypto() (*utils.DefaultAppCrypto, error) {
+	bcryptCrypto := repository.BcryptCrypto{}
+	argon2Crypto := repository.NewArgon2Crypto()
+
+	var appCrypto *utils.DefaultAppCrypto
+	var minCost, maxCost int
+
+	switch os.Getenv("PASSWORD_HASH_ALGORITHM") {
+	case "", "bcrypt":
+		appCrypto = utils.NewHashPassword(bcryptCrypto, argon2Crypto)
+		minCost, maxCost = bcrypt.MinCost, bcrypt.MaxCost
+	case "argon2id":
+		appCrypto = utils.NewHashPassword(argon2Crypto, bcryptCrypto).WithCost(repository.Argon2DefaultTime)
+		minCost, maxCost = repository.Argon2MinTime, repository.Argon2MaxTime
+	default:
+		return nil, fmt.Errorf(constants.ErrUnknownHashAlgorithm)
+	}
+
+	// A cost out of range is rejected, bcrypt would hash with its default cost and every login
+	// would rehash the password.
+	if cost := os.Getenv("PASSWORD_HASH_COST"); cost != "" {
+		value, err := strconv.Atoi(cost)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", constants.ErrInvalidHashCost, err)
+		}
+		if value < minCost || value > maxCost {
+			return nil, fmt.Errorf("%v: must be between %d and %d", constants.ErrInvalidHashCost, minCost, maxCost)
+		}
+		appCrypto.WithCost(value)
+	}
+
+	if pepperFile := os.Getenv("PASSWORD_PEPPER_FILE"); pepperFile != "" {
+		peppers, pepperID, err := utils.LoadPeppers(pepperFile)
+		if err != nil {
+			return nil, err
+		}
+
+		if id := os.Getenv("PASSWORD_PEPPER_ID"); id != "" {
+			pepperID = id
+		}
+
+		if _, ok := peppers[pepperID]; !ok {
+			return nil, fmt.Errorf("%v: %v", constants.ErrUnknownPepper, pepperID)
+		}
+
+		appCrypto.WithPepper(pepperID, peppers)
+	}
+
+	return appCrypto, nil
 }
 
 func createUniqueIndexes(collection *mongo.Collection) error {
@@ -86,10 +611,956 @@ func createUniqueIndexes(collection *mongo.Collection) error {
 		Options: options.Index().SetUnique(true),
 	}
 
-	_, err := collection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{emailIndexModel, userNameIndexModel})
+	emailLookupIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key:   "email",
+				Value: 1,
+			},
+		},
+		Options: options.Index().SetName("email_lookup").SetCollation(repository.EmailCollation),
+	}
+
+	_, err := collection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{emailIndexModel, userNameIndexModel, emailLookupIndexModel})
 
 	if err != nil {
 		return err
 	}
 	return nil
 }
+
+// createUniqueIndex prevents two documents with the same value of key.
+func createUniqueIndex(collection *mongo.Collection, key string) error {
+	uniqueIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key:   key,
+				Value: 1,
+			},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	_, err := collection.Indexes().CreateOne(context.TODO(), uniqueIndexModel)
+
+	return err
+}
+
+// createAuditIndex lists the audit log of a target from newest to oldest and prevents two entries
+// with the same sequence, which would fork the hash chain. Entries recorded before the chain have no sequence.
+func createAuditIndex(collection *mongo.Collection) error {
+	auditIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key:   "targetId",
+				Value: 1,
+			},
+			{
+				Key:   "_id",
+				Value: -1,
+			},
+		},
+	}
+
+	sequenceIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key:   "sequence",
+				Value: 1,
+			},
+		},
+		Options: options.Index().SetUnique(true).SetPartialFilterExpression(bson.M{"sequence": bson.M{"$exists": true}}),
+	}
+
+	_, err := collection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{auditIndexModel, sequenceIndexModel})
+
+	return err
+}
+
+// createWebhookDeliveryIndexes prevents two deliveries of an event to the same webhook, lists the
+// delivery log of a webhook from newest to oldest and finds the pending deliveries that are due.
+func createWebhookDeliveryIndexes(collection *mongo.Collection) error {
+	eventIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key:   "subscriptionId",
+				Value: 1,
+			},
+			{
+				Key:   "eventId",
+				Value: 1,
+			},
+		},
+		Options: options.Index().SetUnique(true),
+	}
+
+	logIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key:   "subscriptionId",
+				Value: 1,
+			},
+			{
+				Key:   "_id",
+				Value: -1,
+			},
+		},
+	}
+
+	dueIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key:   "status",
+				Value: 1,
+			},
+			{
+				Key:   "nextAttemptAt",
+				Value: 1,
+			},
+		},
+	}
+
+	_, err := collection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{eventIndexModel, logIndexModel, dueIndexModel})
+
+	return err
+}
+
+// createOutboxIndexes removes the published events once their expiresAt date is reached and
+// finds the pending events to claim, published events are left out of it because the relay
+// removes their lockedUntil.
+func createOutboxIndexes(collection *mongo.Collection) error {
+	expirationIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key:   "expiresAt",
+				Value: 1,
+			},
+		},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	pendingIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key:   "lockedUntil",
+				Value: 1,
+			},
+			{
+				Key:   "_id",
+				Value: 1,
+			},
+		},
+		Options: options.Index().SetPartialFilterExpression(bson.M{"lockedUntil": bson.M{"$exists": true}}),
+	}
+
+	_, err := collection.Indexes().CreateMany(context.TODO(), []mongo.IndexModel{expirationIndexModel, pendingIndexModel})
+
+	return err
+}
+
+// createExpirationIndex removes documents once their expiresAt date is reached.
+func createExpirationIndex(collection *mongo.Collection) error {
+	expirationIndexModel := mongo.IndexModel{
+		Keys: bson.D{
+			{
+				Key:   "expiresAt",
+				Value: 1,
+			},
+		},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	}
+
+	_, err := collection.Indexes().CreateOne(context.TODO(), expirationIndexModel)
+
+	return err
+}
+
+// newPasswordPolicy overrides the default password policy with the PASSWORD_* variables.
+func newPasswordPolicy() (utils.PasswordPolicy, error) {
+	policy := utils.DefaultPasswordPolicy()
+
+	ints := map[string]*int{
+		"PASSWORD_MIN_LENGTH":   &policy.MinLength,
+		"PASSWORD_MAX_LENGTH":   &policy.MaxLength,
+		"PASSWORD_MAX_REPEATED": &policy.MaxRepeated,
+	}
+
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil || number < 0 {
+				return policy, fmt.Errorf("%v: %v", constants.ErrInvalidPasswordPolicy, name)
+			}
+			*target = number
+		}
+	}
+
+	bools := map[string]*bool{
+		"PASSWORD_REQUIRE_LOWER":     &policy.RequireLower,
+		"PASSWORD_REQUIRE_UPPER":     &policy.RequireUpper,
+		"PASSWORD_REQUIRE_DIGIT":     &policy.RequireDigit,
+		"PASSWORD_REQUIRE_SYMBOL":    &policy.RequireSymbol,
+		"PASSWORD_DISALLOW_IDENTITY": &policy.DisallowIdentity,
+	}
+
+	for name, target := range bools {
+		if value := os.Getenv(name); value != "" {
+			enabled, err := strconv.ParseBool(value)
+			if err != nil {
+				return policy, fmt.Errorf("%v: %v", constants.ErrInvalidPasswordPolicy, name)
+			}
+			*target = enabled
+		}
+	}
+
+	if symbols := os.Getenv("PASSWORD_SYMBOLS"); symbols != "" {
+		policy.Symbols = symbols
+	}
+
+	if policy.MaxLength > 0 && policy.MaxLength < policy.MinLength {
+		return policy, fmt.Errorf("%v: PASSWORD_MAX_LENGTH", constants.ErrInvalidPasswordPolicy)
+	}
+
+	return policy, nil
+}
+
+// newBreachedPasswords loads the breach corpus from BREACHED_PASSWORDS_FILE, with
+// BREACHED_PASSWORDS_MODE=bloom the list is loaded in memory as a Bloom filter.
+func newBreachedPasswords() (utils.BreachedPasswordChecker, func(), error) {
+	path := os.Getenv("BREACHED_PASSWORDS_FILE")
+	if path == "" {
+		return nil, func() {}, nil
+	}
+
+	switch os.Getenv("BREACHED_PASSWORDS_MODE") {
+	case "", "file":
+		hashFile, err := utils.OpenSortedHashFile(path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return hashFile, func() {
+			if err := hashFile.Close(); err != nil {
+				log.Printf("%v: %v", constants.ErrClosingFile, err)
+			}
+		}, nil
+	case "bloom":
+		falsePositiveRate := defaultBloomFalsePositiveRate
+		if value := os.Getenv("BREACHED_PASSWORDS_FALSE_POSITIVE_RATE"); value != "" {
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil || rate <= 0 || rate >= 1 {
+				return nil, nil, fmt.Errorf("%v: BREACHED_PASSWORDS_FALSE_POSITIVE_RATE", constants.ErrInvalidPasswordPolicy)
+			}
+			falsePositiveRate = rate
+		}
+
+		filter, err := utils.BuildBloomFilter(path, falsePositiveRate)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return filter, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf(constants.ErrUnknownBreachedMode)
+	}
+}
+
+// newPasswordHistorySize reads how many previous passwords can not be reused from PASSWORD_HISTORY_SIZE.
+func newPasswordHistorySize() (int, error) {
+	value := os.Getenv("PASSWORD_HISTORY_SIZE")
+	if value == "" {
+		return defaultPasswordHistorySize, nil
+	}
+
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf(constants.ErrInvalidHistorySize)
+	}
+
+	return size, nil
+}
+
+// newNotifier selects how messages are delivered with NOTIFIER. The log notifier writes
+// messages to NOTIFIER_FILE or the standard output with their links and codes redacted, the
+// smtp notifier uses the SMTP_* variables. NOTIFIER must be set unless APP_ENV is development
+// or test, where the log notifier is used by default.
+func newNotifier() (adapters.Notifier, func(), error) {
+	kind := os.Getenv("NOTIFIER")
+	if kind == "" {
+		if !slices.Contains(devEnvironments, os.Getenv("APP_ENV")) {
+			return nil, nil, fmt.Errorf(constants.ErrNotifierIsNotSet)
+		}
+		kind = "log"
+	}
+
+	switch kind {
+	case "log":
+		path := os.Getenv("NOTIFIER_FILE")
+		if path == "" {
+			return adapters.NewLogNotifier(os.Stdout), func() {}, nil
+		}
+
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v: %w", constants.ErrOpenFile, err)
+		}
+
+		return adapters.NewLogNotifier(file), func() {
+			if err := file.Close(); err != nil {
+				log.Printf("%v: %v", constants.ErrClosingFile, err)
+			}
+		}, nil
+	case "smtp":
+		notifier, err := adapters.NewSMTPNotifier(
+			os.Getenv("SMTP_ADDR"),
+			os.Getenv("SMTP_FROM"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return notifier, func() {}, nil
+	default:
+		return nil, nil, fmt.Errorf(constants.ErrUnknownNotifier)
+	}
+}
+
+// newTokenLinkSettings reads the page of emailed links from urlName and the token
+// lifetime from ttlName, the lifetime is optional.
+func newTokenLinkSettings(urlName, ttlName string, defaultTTL time.Duration) (string, time.Duration, error) {
+	linkURL := os.Getenv(urlName)
+	if linkURL == "" {
+		return "", 0, fmt.Errorf("%v: %v", constants.ErrTokenLinkURLIsNotSet, urlName)
+	}
+
+	ttl, err := newDuration(ttlName, defaultTTL)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return linkURL, ttl, nil
+}
+
+// newLockoutPolicy reads the login lockout thresholds from LOGIN_LOCKOUT_THRESHOLD and
+// LOGIN_LOCKOUT_IP_THRESHOLD, zero disables a threshold, and the lock durations from
+// LOGIN_LOCKOUT_DURATION, LOGIN_LOCKOUT_MAX_DURATION and LOGIN_LOCKOUT_WINDOW.
+func newLockoutPolicy() (usecase.LockoutPolicy, error) {
+	policy := usecase.LockoutPolicy{
+		Threshold:    defaultLockoutThreshold,
+		IPThreshold:  defaultLockoutIPThreshold,
+		BaseDuration: defaultLockoutDuration,
+		MaxDuration:  defaultLockoutMaxDuration,
+		Window:       defaultLockoutWindow,
+	}
+
+	ints := map[string]*int{
+		"LOGIN_LOCKOUT_THRESHOLD":    &policy.Threshold,
+		"LOGIN_LOCKOUT_IP_THRESHOLD": &policy.IPThreshold,
+	}
+
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil || number < 0 {
+				return policy, fmt.Errorf("%v: %v", constants.ErrInvalidLockoutPolicy, name)
+			}
+			*target = number
+		}
+	}
+
+	durations := map[string]*time.Duration{
+		"LOGIN_LOCKOUT_DURATION":     &policy.BaseDuration,
+		"LOGIN_LOCKOUT_MAX_DURATION": &policy.MaxDuration,
+		"LOGIN_LOCKOUT_WINDOW":       &policy.Window,
+	}
+
+	for name, target := range durations {
+		if value := os.Getenv(name); value != "" {
+			duration, err := time.ParseDuration(value)
+			if err != nil || duration <= 0 {
+				return policy, fmt.Errorf("%v: %v", constants.ErrInvalidLockoutPolicy, name)
+			}
+			*target = duration
+		}
+	}
+
+	if policy.MaxDuration < policy.BaseDuration {
+		return policy, fmt.Errorf("%v: LOGIN_LOCKOUT_MAX_DURATION", constants.ErrInvalidLockoutPolicy)
+	}
+
+	return policy, nil
+}
+
+// newTrustedProxies reads the comma separated addresses or CIDR ranges of the proxies allowed to
+// set the client IP with X-Forwarded-For from TRUSTED_PROXIES. No proxy is trusted when it is
+// empty, so the client IP used by the lockout and rate limits is the address of the peer.
+func newTrustedProxies() ([]string, error) {
+	var proxies []string
+
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy == "" {
+			continue
+		}
+
+		if _, err := netip.ParsePrefix(proxy); err != nil {
+			if _, err := netip.ParseAddr(proxy); err != nil {
+				return nil, fmt.Errorf("%v: %v", constants.ErrInvalidTrustedProxies, proxy)
+			}
+		}
+		proxies = append(proxies, proxy)
+	}
+
+	return proxies, nil
+}
+
+// newSecretBox loads encryption keys from the <prefix>_ENCRYPTION_KEY_FILE variable, with the
+// same id=secret format of peppers, <prefix>_ENCRYPTION_KEY_ID selects the current key.
+// MFA keys encrypt TOTP secrets, OIDC keys the ID token signing keys and WEBHOOK keys the
+// secrets signing webhook deliveries, without the file the feature is disabled.
+func newSecretBox(prefix string) (*utils.SecretBox, error) {
+	path := os.Getenv(prefix + "_ENCRYPTION_KEY_FILE")
+	if path == "" {
+		return nil, nil
+	}
+
+	keys, keyID, err := utils.LoadPeppers(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if id := os.Getenv(prefix + "_ENCRYPTION_KEY_ID"); id != "" {
+		keyID = id
+	}
+
+	return utils.NewSecretBox(keyID, keys)
+}
+
+// newDuration reads a positive duration from the variable name.
+func newDuration(name string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration <= 0 {
+		return 0, fmt.Errorf("%v: %v", constants.ErrInvalidTokenTTL, name)
+	}
+
+	return duration, nil
+}
+
+// newImpersonationAdmins reads the comma separated IDs of the support staff allowed to
+// impersonate users from IMPERSONATION_ADMIN_IDS, impersonation is disabled when it is empty.
+func newImpersonationAdmins() []string {
+	return newUserIDs("IMPERSONATION_ADMIN_IDS")
+}
+
+// newAdmins reads the comma separated IDs of the administrators from ADMIN_USER_IDS, the admin
+// endpoints are forbidden to everyone when it is empty.
+func newAdmins() []string {
+	return newUserIDs("ADMIN_USER_IDS")
+}
+
+// newUserIDs reads comma separated user IDs from the variable name.
+func newUserIDs(name string) []string {
+	var userIDs []string
+
+	for _, id := range strings.Split(os.Getenv(name), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			userIDs = append(userIDs, id)
+		}
+	}
+
+	return userIDs
+}
+
+// newWebAuthnSettings reads the relying party of passkeys from WEBAUTHN_RP_ID and the
+// comma separated origins allowed to use it from WEBAUTHN_ORIGINS. WEBAUTHN_USER_VERIFICATION
+// requires a PIN or biometric check and WEBAUTHN_ATTESTATION selects none or direct attestation.
+func newWebAuthnSettings() (utils.WebAuthnConfig, string, error) {
+	config := utils.WebAuthnConfig{RPID: os.Getenv("WEBAUTHN_RP_ID")}
+	if config.RPID == "" {
+		config.RPID = defaultWebAuthnRPID
+	}
+
+	origins := os.Getenv("WEBAUTHN_ORIGINS")
+	if origins == "" {
+		origins = defaultWebAuthnOrigins
+	}
+
+	for _, origin := range strings.Split(origins, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			config.Origins = append(config.Origins, origin)
+		}
+	}
+
+	if value := os.Getenv("WEBAUTHN_USER_VERIFICATION"); value != "" {
+		required, err := strconv.ParseBool(value)
+		if err != nil {
+			return config, "", fmt.Errorf("%v: WEBAUTHN_USER_VERIFICATION", constants.ErrInvalidWebAuthnSettings)
+		}
+		config.RequireUserVerification = required
+	}
+
+	attestation := os.Getenv("WEBAUTHN_ATTESTATION")
+	switch attestation {
+	case "":
+		attestation = "none"
+	case "none", "direct":
+	default:
+		return config, "", fmt.Errorf("%v: WEBAUTHN_ATTESTATION", constants.ErrInvalidWebAuthnSettings)
+	}
+
+	return config, attestation, nil
+}
+
+// newPasswordResetPolicy reads how many reset links each email address and source IP can
+// request per PASSWORD_RESET_WINDOW from PASSWORD_RESET_LIMIT and PASSWORD_RESET_IP_LIMIT,
+// zero disables a limit.
+func newPasswordResetPolicy(ttl time.Duration) (usecase.PasswordResetPolicy, error) {
+	policy := usecase.PasswordResetPolicy{
+		TTL:          ttl,
+		AddressLimit: defaultPasswordResetLimit,
+		IPLimit:      defaultPasswordResetIPLimit,
+	}
+
+	ints := map[string]*int{
+		"PASSWORD_RESET_LIMIT":    &policy.AddressLimit,
+		"PASSWORD_RESET_IP_LIMIT": &policy.IPLimit,
+	}
+
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil || number < 0 {
+				return policy, fmt.Errorf("%v: %v", constants.ErrInvalidResetLimit, name)
+			}
+			*target = number
+		}
+	}
+
+	window, err := newDuration("PASSWORD_RESET_WINDOW", defaultPasswordResetWindow)
+	if err != nil {
+		return policy, err
+	}
+	policy.Window = window
+
+	return policy, nil
+}
+
+// newPasswordlessPolicy reads how many passwordless logins each email address and source IP
+// can request per PASSWORDLESS_WINDOW from PASSWORDLESS_LIMIT and PASSWORDLESS_IP_LIMIT,
+// zero disables a limit.
+func newPasswordlessPolicy(ttl time.Duration) (usecase.PasswordlessPolicy, error) {
+	policy := usecase.PasswordlessPolicy{
+		TTL:          ttl,
+		AddressLimit: defaultPasswordlessLimit,
+		IPLimit:      defaultPasswordlessIPLimit,
+	}
+
+	ints := map[string]*int{
+		"PASSWORDLESS_LIMIT":    &policy.AddressLimit,
+		"PASSWORDLESS_IP_LIMIT": &policy.IPLimit,
+	}
+
+	for name, target := range ints {
+		if value := os.Getenv(name); value != "" {
+			number, err := strconv.Atoi(value)
+			if err != nil || number < 0 {
+				return policy, fmt.Errorf("%v: %v", constants.ErrInvalidPasswordlessLimit, name)
+			}
+			*target = number
+		}
+	}
+
+	window, err := newDuration("PASSWORDLESS_WINDOW", defaultPasswordlessWindow)
+	if err != nil {
+		return policy, err
+	}
+	policy.Window = window
+
+	return policy, nil
+}
+
+// setupOpenID enables OpenID Connect on the authorization server when OIDC_ENCRYPTION_KEY_FILE
+// is set. OIDC_ISSUER is the base URL of the endpoints, OIDC_AUTHORIZATION_URL the consent page,
+// OIDC_ID_TOKEN_TTL the lifetime of ID tokens and OIDC_KEY_ROTATION how often signing keys rotate.
+// It returns the function that stops the rotation.
+func setupOpenID(oauthService *usecase.OAuthService, keyCollection *mongo.Collection) (func(), error) {
+	secretBox, err := newSecretBox("OIDC")
+	if err != nil || secretBox == nil {
+		return func() {}, err
+	}
+
+	issuer := strings.TrimSuffix(os.Getenv("OIDC_ISSUER"), "/")
+	if issuer == "" {
+		issuer = defaultOIDCIssuer
+	}
+
+	authorizationURL := os.Getenv("OIDC_AUTHORIZATION_URL")
+	if authorizationURL == "" {
+		authorizationURL = issuer + "/oauth/authorize"
+	}
+
+	idTokenTTL, err := newDuration("OIDC_ID_TOKEN_TTL", defaultOIDCIDTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	rotation, err := newDuration("OIDC_KEY_ROTATION", defaultOIDCKeyRotation)
+	if err != nil {
+		return nil, err
+	}
+
+	if idTokenTTL >= rotation {
+		return nil, fmt.Errorf("%v: OIDC_ID_TOKEN_TTL must be shorter than OIDC_KEY_ROTATION", constants.ErrInvalidOpenIDSettings)
+	}
+
+	err = createExpirationIndex(keyCollection)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	signingKeys := usecase.NewSigningKeyService(repository.NewSigningKeyRepository(keyCollection), secretBox, rotation)
+
+	if err := signingKeys.Refresh(context.Background()); err != nil {
+		return nil, fmt.Errorf("%v: %v", constants.ErrRotateSigningKeys, err)
+	}
+
+	oauthService.WithOpenID(signingKeys, usecase.OpenIDConfig{
+		Issuer:           issuer,
+		AuthorizationURL: authorizationURL,
+		IDTokenTTL:       idTokenTTL,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go signingKeys.Run(ctx, signingKeyRefreshInterval)
+
+	return cancel, nil
+}
+
+// setupAuditCheckpoints signs checkpoints of the audit log hash chain when AUDIT_SIGNING_KEY_FILE
+// is set, every AUDIT_CHECKPOINT_INTERVAL. It returns the function that stops the checkpoints.
+func setupAuditCheckpoints(auditRepo *repository.AuditService, checkpointCollection *mongo.Collection) (func(), error) {
+	path := os.Getenv("AUDIT_SIGNING_KEY_FILE")
+	if path == "" {
+		return func() {}, nil
+	}
+
+	signingKey, err := utils.LoadAuditSigningKey(path)
+	if err != nil {
+		return nil, err
+	}
+
+	interval, err := newDuration("AUDIT_CHECKPOINT_INTERVAL", defaultAuditCheckpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	err = createUniqueIndex(checkpointCollection, "sequence")
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	auditChain := usecase.NewAuditChainService(auditRepo, repository.NewAuditCheckpointRepository(checkpointCollection), signingKey)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go auditChain.Run(ctx, interval)
+
+	return cancel, nil
+}
+
+// setupOutbox writes the domain events of users into the outbox collection and relays them as
+// CloudEvents when EVENT_PUBLISHER is set or there are other publishers, like webhooks.
+// CLOUDEVENTS_SOURCE is the source of the events, OUTBOX_POLL_INTERVAL how often pending events
+// are relayed, OUTBOX_LEASE how long an event is claimed while it is published and
+// OUTBOX_RETENTION how long published events are kept. It returns the function that stops the
+// relay, the relay finishes the event it publishes.
+func setupOutbox(userRepo *repository.UserService, mongoClient *adapters.MongoClient, publishers ...adapters.EventPublisher) (func(), error) {
+	pollInterval, err := newDuration("OUTBOX_POLL_INTERVAL", defaultOutboxPollInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	lease, err := newDuration("OUTBOX_LEASE", defaultOutboxLease)
+	if err != nil {
+		return nil, err
+	}
+
+	retention, err := newDuration("OUTBOX_RETENTION", defaultOutboxRetention)
+	if err != nil {
+		return nil, err
+	}
+
+	publisher, closePublisher, err := newEventPublisher()
+	if err != nil {
+		return nil, err
+	}
+
+	if publisher != nil {
+		publishers = append([]adapters.EventPublisher{publisher}, publishers...)
+	}
+	if len(publishers) == 0 {
+		return func() {}, nil
+	}
+
+	outboxCollection := mongoClient.GetDatabase().Collection("outbox")
+
+	err = createOutboxIndexes(outboxCollection)
+	if err != nil {
+		closePublisher()
+		return nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	userRepo.WithOutbox(outboxCollection, mongoClient)
+
+	relay := usecase.NewOutboxRelay(repository.NewOutboxRepository(outboxCollection), adapters.NewMultiEventPublisher(publishers...), cloudEventsSource(), lease, retention)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		relay.Run(ctx, pollInterval)
+	}()
+
+	return func() {
+		cancel()
+		<-done
+		closePublisher()
+	}, nil
+}
+
+// cloudEventsSource returns the source of the published CloudEvents from CLOUDEVENTS_SOURCE.
+func cloudEventsSource() string {
+	if source := os.Getenv("CLOUDEVENTS_SOURCE"); source != "" {
+		return source
+	}
+
+	return defaultCloudEventsSource
+}
+
+// setupUserEvents returns the stream of user events served to dashboards, it is backed by the
+// change streams of Mongo when the deployment supports them. Otherwise the user service publishes
+// its changes to an in-process broadcaster keeping the last USER_EVENTS_HISTORY events to resume.
+func setupUserEvents(userCollection *mongo.Collection, userService *usecase.UserService) (usecase.UserEventStream, error) {
+	if supportsChangeStreams(userCollection) {
+		return usecase.NewChangeStreamUserEvents(repository.NewUserEventStreamRepository(userCollection), cloudEventsSource()), nil
+	}
+
+	historySize := defaultUserEventsHistory
+	if value := os.Getenv("USER_EVENTS_HISTORY"); value != "" {
+		var err error
+		historySize, err = strconv.Atoi(value)
+		if err != nil || historySize < 1 {
+			return nil, fmt.Errorf("%v: USER_EVENTS_HISTORY", constants.ErrInvalidUserEventSetting)
+		}
+	}
+
+	broadcaster := usecase.NewUserEventBroadcaster(historySize)
+	userService.WithEventPublisher(broadcaster, cloudEventsSource())
+
+	return broadcaster, nil
+}
+
+// supportsChangeStreams reports whether a change stream can be opened on collection, Mongo only
+// supports them on replica sets and sharded clusters.
+func supportsChangeStreams(collection *mongo.Collection) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), changeStreamProbeTimeout)
+	defer cancel()
+
+	stream, err := collection.Watch(ctx, mongo.Pipeline{})
+	if err != nil {
+		return false
+	}
+
+	_ = stream.Close(ctx)
+
+	return true
+}
+
+// setupWebhooks enables webhook subscriptions when WEBHOOK_ENCRYPTION_KEY_FILE is set, the key
+// encrypts the secrets signing the deliveries. WEBHOOK_MAX_ATTEMPTS is how many times a delivery
+// is attempted before it is moved to the dead letter state, WEBHOOK_POLL_INTERVAL how often due
+// deliveries are sent, WEBHOOK_LEASE how long a delivery is claimed while it is sent and
+// WEBHOOK_TIMEOUT how long an endpoint is waited for. It returns the function that stops the deliveries.
+func setupWebhooks(mongoClient *adapters.MongoClient) (*usecase.WebhookService, func(), error) {
+	secretBox, err := newSecretBox("WEBHOOK")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	maxAttempts := defaultWebhookMaxAttempts
+	if value := os.Getenv("WEBHOOK_MAX_ATTEMPTS"); value != "" {
+		maxAttempts, err = strconv.Atoi(value)
+		if err != nil || maxAttempts < 1 {
+			return nil, nil, fmt.Errorf("%v: WEBHOOK_MAX_ATTEMPTS", constants.ErrInvalidWebhookSetting)
+		}
+	}
+
+	pollInterval, err := newDuration("WEBHOOK_POLL_INTERVAL", defaultWebhookPollInterval)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lease, err := newDuration("WEBHOOK_LEASE", defaultWebhookLease)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	timeout, err := newDuration("WEBHOOK_TIMEOUT", defaultWebhookTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if timeout >= lease {
+		return nil, nil, fmt.Errorf("%v: WEBHOOK_TIMEOUT must be shorter than WEBHOOK_LEASE", constants.ErrInvalidWebhookSetting)
+	}
+
+	subscriptionCollection := mongoClient.GetDatabase().Collection("webhooks")
+	deliveryCollection := mongoClient.GetDatabase().Collection("webhook_deliveries")
+
+	webhookService := usecase.NewWebhookService(
+		repository.NewWebhookSubscriptionRepository(subscriptionCollection),
+		repository.NewWebhookDeliveryRepository(deliveryCollection),
+		adapters.NewHTTPWebhookSender(timeout),
+		secretBox,
+		maxAttempts,
+		lease,
+	)
+	if secretBox == nil {
+		return webhookService, func() {}, nil
+	}
+
+	err = createWebhookDeliveryIndexes(deliveryCollection)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%v: %w", constants.ErrCreateMongoIndex, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		webhookService.Run(ctx, pollInterval)
+	}()
+
+	return webhookService, func() {
+		cancel()
+		<-done
+	}, nil
+}
+
+// newEventPublisher selects the publisher of domain events from EVENT_PUBLISHER, events are
+// not published when it is empty. The log publisher writes to EVENT_PUBLISHER_FILE or stdout,
+// the other publishers send the events through a message broker.
+func newEventPublisher() (adapters.EventPublisher, func(), error) {
+	switch os.Getenv("EVENT_PUBLISHER") {
+	case "":
+		return nil, func() {}, nil
+	case "memory", "nats", "kafka":
+		return newBrokerEventPublisher(os.Getenv("EVENT_PUBLISHER"))
+	case "log":
+		path := os.Getenv("EVENT_PUBLISHER_FILE")
+		if path == "" {
+			return adapters.NewLogEventPublisher(os.Stdout), func() {}, nil
+		}
+
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v: %w", constants.ErrOpenFile, err)
+		}
+
+		return adapters.NewLogEventPublisher(file), func() {
+			if err := file.Close(); err != nil {
+				log.Printf("%v: %v", constants.ErrClosingFile, err)
+			}
+		}, nil
+	default:
+		return nil, nil, fmt.Errorf(constants.ErrUnknownEventPublisher)
+	}
+}
+
+// newBrokerEventPublisher creates the publisher sending events through the broker of kind as
+// CloudEvents in EVENT_PUBLISHER_MODE, structured or binary. EVENT_PUBLISHER_TIMEOUT is how long
+// the broker is waited for. The NATS broker connects to NATS_URL and publishes under
+// NATS_SUBJECT_PREFIX, the Kafka broker writes to KAFKA_TOPIC of the comma separated
+// KAFKA_BROKERS and the memory broker keeps the last events in process.
+func newBrokerEventPublisher(kind string) (adapters.EventPublisher, func(), error) {
+	mode := os.Getenv("EVENT_PUBLISHER_MODE")
+	if mode == "" {
+		mode = domain.CloudEventsStructured
+	}
+	if mode != domain.CloudEventsStructured && mode != domain.CloudEventsBinary {
+		return nil, nil, fmt.Errorf("%v: EVENT_PUBLISHER_MODE", constants.ErrInvalidPublisherSetting)
+	}
+
+	timeout, err := newDuration("EVENT_PUBLISHER_TIMEOUT", defaultEventPublisherTimeout)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var broker adapters.MessageBroker
+	switch kind {
+	case "nats":
+		url := os.Getenv("NATS_URL")
+		if url == "" {
+			url = nats.DefaultURL
+		}
+
+		subjectPrefix := os.Getenv("NATS_SUBJECT_PREFIX")
+		if subjectPrefix == "" {
+			subjectPrefix = defaultNATSSubjectPrefix
+		}
+
+		conn, err := nats.Connect(url, nats.Name("cnm-proyect-go"), nats.Timeout(timeout))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%v: %w", constants.ErrConnectEventBroker, err)
+		}
+
+		broker = adapters.NewNATSBroker(conn, subjectPrefix, timeout)
+	case "kafka":
+		brokers := os.Getenv("KAFKA_BROKERS")
+		if brokers == "" {
+			brokers = defaultKafkaBrokers
+		}
+
+		topic := os.Getenv("KAFKA_TOPIC")
+		if topic == "" {
+			topic = defaultKafkaTopic
+		}
+
+		broker = adapters.NewKafkaBroker(&kafka.Writer{
+			Addr:         kafka.TCP(strings.Split(brokers, ",")...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			BatchSize:    1,
+			WriteTimeout: timeout,
+		})
+	default:
+		broker = adapters.NewMemoryBroker(defaultMemoryBrokerSize)
+	}
+
+	return adapters.NewBrokerEventPublisher(broker, mode), func() {
+		if err := broker.Close(); err != nil {
+			log.Printf("%v: %v", constants.ErrCloseEventBroker, err)
+		}
+	}, nil
+}
+
+// newSecureCookies reads from COOKIE_SECURE whether cookies are only sent over HTTPS, it is enabled by default.
+func newSecureCookies() (bool, error) {
+	value := os.Getenv("COOKIE_SECURE")
+	if value == "" {
+		return true, nil
+	}
+
+	secure, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%v: COOKIE_SECURE", constants.ErrInvalidCookieSetting)
+	}
+
+	return secure, nil
+}
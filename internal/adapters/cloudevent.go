@@ -0,0 +1,109 @@
+package adapters
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+)
+
+// CloudEventsContentType is the content type of a CloudEvent sent in the structured JSON mode.
+const CloudEventsContentType = "application/cloudevents+json"
+
+// Headers carrying the attributes of a CloudEvent read in the binary HTTP mode.
+const (
+	cloudEventSpecVersionHeader = "ce-specversion"
+	cloudEventIDHeader          = "ce-id"
+	cloudEventSourceHeader      = "ce-source"
+	cloudEventTypeHeader        = "ce-type"
+	cloudEventSubjectHeader     = "ce-subject"
+	cloudEventTimeHeader        = "ce-time"
+)
+
+// EncodeCloudEventHTTP returns the body and headers of an HTTP message carrying the event in
+// mode, the structured mode is used unless mode is binary.
+func EncodeCloudEventHTTP(event *domain.CloudEvent, mode string) ([]byte, http.Header, error) {
+	header := http.Header{}
+
+	if mode != domain.CloudEventsBinary {
+		body, err := json.Marshal(event)
+		if err != nil {
+			return nil, nil, err
+		}
+		header.Set("Content-Type", CloudEventsContentType)
+
+		return body, header, nil
+	}
+
+	body, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	header.Set("Content-Type", event.DataContentType)
+	for name, value := range cloudEventAttributes(event) {
+		header.Set("ce-"+name, value)
+	}
+
+	return body, header, nil
+}
+
+// cloudEventAttributes returns the attributes of the event sent as headers in the binary mode by
+// their name in the CloudEvents specification, the subject is omitted when it is empty.
+func cloudEventAttributes(event *domain.CloudEvent) map[string]string {
+	attributes := map[string]string{
+		"specversion": event.SpecVersion,
+		"id":          event.ID,
+		"source":      event.Source,
+		"type":        event.Type,
+		"time":        event.Time.Format(time.RFC3339Nano),
+	}
+	if event.Subject != "" {
+		attributes["subject"] = event.Subject
+	}
+
+	return attributes
+}
+
+// DecodeCloudEventHTTP reads a CloudEvent from the body and headers of an HTTP message in
+// either content mode, it is how consumers parse the events they receive.
+func DecodeCloudEventHTTP(header http.Header, body []byte) (*domain.CloudEvent, error) {
+	var event domain.CloudEvent
+
+	if strings.HasPrefix(header.Get("Content-Type"), CloudEventsContentType) {
+		if err := json.Unmarshal(body, &event); err != nil {
+			return nil, fmt.Errorf("%v: %w", constants.ErrInvalidCloudEvent, err)
+		}
+
+		return &event, nil
+	}
+
+	event.SpecVersion = header.Get(cloudEventSpecVersionHeader)
+	if event.SpecVersion == "" {
+		return nil, fmt.Errorf("%v: missing %v", constants.ErrInvalidCloudEvent, cloudEventSpecVersionHeader)
+	}
+
+	event.ID = header.Get(cloudEventIDHeader)
+	event.Source = header.Get(cloudEventSourceHeader)
+	event.Type = header.Get(cloudEventTypeHeader)
+	event.Subject = header.Get(cloudEventSubjectHeader)
+	event.DataContentType = header.Get("Content-Type")
+
+	if value := header.Get(cloudEventTimeHeader); value != "" {
+		eventTime, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", constants.ErrInvalidCloudEvent, err)
+		}
+		event.Time = eventTime
+	}
+
+	if err := json.Unmarshal(body, &event.Data); err != nil {
+		return nil, fmt.Errorf("%v: %w", constants.ErrInvalidCloudEvent, err)
+	}
+
+	return &event, nil
+}
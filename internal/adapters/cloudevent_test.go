@@ -0,0 +1,74 @@
+package adapters_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeCloudEventHTTP(t *testing.T) {
+	t.Run("should encode the whole event as body in structured mode", func(t *testing.T) {
+		body, header, err := adapters.EncodeCloudEventHTTP(userCreatedEvent, domain.CloudEventsStructured)
+		assert.NoError(t, err)
+		assert.Equal(t, "application/cloudevents+json", header.Get("Content-Type"))
+		assert.Empty(t, header.Get("ce-id"))
+
+		var event map[string]interface{}
+		assert.NoError(t, json.Unmarshal(body, &event))
+		assert.Equal(t, "1.0", event["specversion"])
+		assert.Equal(t, "com.cnmoreno.user.created", event["type"])
+		assert.Equal(t, "2024-01-01T12:30:00Z", event["time"])
+		assert.Equal(t, "12345", event["data"].(map[string]interface{})["id"])
+	})
+
+	t.Run("should encode the data as body and attributes as headers in binary mode", func(t *testing.T) {
+		body, header, err := adapters.EncodeCloudEventHTTP(userCreatedEvent, domain.CloudEventsBinary)
+		assert.NoError(t, err)
+		assert.Equal(t, "application/json", header.Get("Content-Type"))
+		assert.Equal(t, "1.0", header.Get("ce-specversion"))
+		assert.Equal(t, "event-1", header.Get("ce-id"))
+		assert.Equal(t, "/cnm-proyect-go/users", header.Get("ce-source"))
+		assert.Equal(t, "com.cnmoreno.user.created", header.Get("ce-type"))
+		assert.Equal(t, "12345", header.Get("ce-subject"))
+		assert.Equal(t, "2024-01-01T12:30:00Z", header.Get("ce-time"))
+
+		var data domain.UserEventData
+		assert.NoError(t, json.Unmarshal(body, &data))
+		assert.Equal(t, userCreatedEvent.Data, data)
+	})
+}
+
+func TestDecodeCloudEventHTTP(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header http.Header
+		body   string
+	}{
+		{
+			name:   "should return an error when binary message has no spec version",
+			header: http.Header{"Content-Type": {"application/json"}},
+			body:   `{"id":"12345"}`,
+		},
+		{
+			name:   "should return an error when structured message is not JSON",
+			header: http.Header{"Content-Type": {"application/cloudevents+json"}},
+			body:   `event`,
+		},
+		{
+			name:   "should return an error when binary message has invalid time",
+			header: http.Header{"Content-Type": {"application/json"}, "Ce-Specversion": {"1.0"}, "Ce-Time": {"yesterday"}},
+			body:   `{"id":"12345"}`,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := adapters.DecodeCloudEventHTTP(test.header, []byte(test.body))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"sync"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+)
+
+// BrokerMessage struct of a CloudEvent encoded for a message broker. Key is the subject of the
+// event, so brokers partitioning by key keep the order of the events of a user. Attributes are
+// the CloudEvent attributes sent as headers in the binary mode, each broker names them after its
+// protocol binding.
+type BrokerMessage struct {
+	ID          string
+	Type        string
+	Key         string
+	ContentType string
+	Attributes  map[string]string
+	Value       []byte
+}
+
+// MessageBroker sends messages to a message broker such as NATS or Kafka.
+type MessageBroker interface {
+	Send(ctx context.Context, message *BrokerMessage) error
+	Close() error
+}
+
+// BrokerEventPublisher publishes events as CloudEvents in a content mode through a message broker.
+type BrokerEventPublisher struct {
+	broker MessageBroker
+	mode   string
+}
+
+// NewBrokerEventPublisher creates a publisher sending events through broker in mode, the
+// structured mode is used unless mode is binary.
+func NewBrokerEventPublisher(broker MessageBroker, mode string) *BrokerEventPublisher {
+	return &BrokerEventPublisher{
+		broker: broker,
+		mode:   mode,
+	}
+}
+
+// Publish encodes the event and sends it through the broker.
+func (p *BrokerEventPublisher) Publish(ctx context.Context, event *domain.CloudEvent) error {
+	message, err := EncodeCloudEventMessage(event, p.mode)
+	if err != nil {
+		return err
+	}
+
+	return p.broker.Send(ctx, message)
+}
+
+// EncodeCloudEventMessage returns the message of a broker carrying the event in mode, the
+// structured mode is used unless mode is binary.
+func EncodeCloudEventMessage(event *domain.CloudEvent, mode string) (*BrokerMessage, error) {
+	message := &BrokerMessage{
+		ID:   event.ID,
+		Type: event.Type,
+		Key:  event.Subject,
+	}
+
+	if mode != domain.CloudEventsBinary {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return nil, err
+		}
+		message.ContentType = CloudEventsContentType
+		message.Value = value
+
+		return message, nil
+	}
+
+	value, err := json.Marshal(event.Data)
+	if err != nil {
+		return nil, err
+	}
+	message.ContentType = event.DataContentType
+	message.Attributes = cloudEventAttributes(event)
+	message.Value = value
+
+	return message, nil
+}
+
+// memoryBrokerBufferSize is how many messages wait for a subscriber of the memory broker, later
+// messages are dropped for it until it catches up.
+const memoryBrokerBufferSize = 64
+
+// MemoryBroker is an in-process message broker keeping the last messages, it is used in
+// development and tests instead of NATS or Kafka.
+type MemoryBroker struct {
+	mu          sync.Mutex
+	messages    []*BrokerMessage
+	size        int
+	subscribers map[chan *BrokerMessage]string
+}
+
+// NewMemoryBroker creates a broker keeping the last size messages.
+func NewMemoryBroker(size int) *MemoryBroker {
+	return &MemoryBroker{
+		size:        size,
+		subscribers: make(map[chan *BrokerMessage]string),
+	}
+}
+
+// Send keeps the message and sends it to the subscribers of its type.
+func (b *MemoryBroker) Send(_ context.Context, message *BrokerMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.messages = append(b.messages, message)
+	if len(b.messages) > b.size {
+		b.messages = slices.Delete(b.messages, 0, len(b.messages)-b.size)
+	}
+
+	for subscriber, eventType := range b.subscribers {
+		if eventType != "" && eventType != message.Type {
+			continue
+		}
+
+		select {
+		case subscriber <- message:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Messages returns the kept messages from oldest to newest.
+func (b *MemoryBroker) Messages() []*BrokerMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return slices.Clone(b.messages)
+}
+
+// Subscribe receives the messages of eventType sent until ctx is done, or every message when
+// eventType is empty.
+func (b *MemoryBroker) Subscribe(ctx context.Context, eventType string) <-chan *BrokerMessage {
+	subscriber := make(chan *BrokerMessage, memoryBrokerBufferSize)
+
+	b.mu.Lock()
+	b.subscribers[subscriber] = eventType
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		if _, found := b.subscribers[subscriber]; found {
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}()
+
+	return subscriber
+}
+
+// Close stops sending messages to the subscribers.
+func (b *MemoryBroker) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for subscriber := range b.subscribers {
+		delete(b.subscribers, subscriber)
+		close(subscriber)
+	}
+
+	return nil
+}
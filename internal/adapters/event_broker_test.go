@@ -0,0 +1,86 @@
+package adapters_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBrokerEventPublisher(t *testing.T) {
+	t.Run("should send the whole event as value in structured mode", func(t *testing.T) {
+		broker := adapters.NewMemoryBroker(10)
+		publisher := adapters.NewBrokerEventPublisher(broker, domain.CloudEventsStructured)
+
+		assert.NoError(t, publisher.Publish(context.Background(), userCreatedEvent))
+
+		messages := broker.Messages()
+		assert.Len(t, messages, 1)
+		assert.Equal(t, "event-1", messages[0].ID)
+		assert.Equal(t, "12345", messages[0].Key)
+		assert.Equal(t, domain.CloudEventUserCreated, messages[0].Type)
+		assert.Equal(t, adapters.CloudEventsContentType, messages[0].ContentType)
+		assert.Empty(t, messages[0].Attributes)
+
+		var event domain.CloudEvent
+		assert.NoError(t, json.Unmarshal(messages[0].Value, &event))
+		assert.Equal(t, *userCreatedEvent, event)
+	})
+
+	t.Run("should send the data as value and attributes apart in binary mode", func(t *testing.T) {
+		broker := adapters.NewMemoryBroker(10)
+		publisher := adapters.NewBrokerEventPublisher(broker, domain.CloudEventsBinary)
+
+		assert.NoError(t, publisher.Publish(context.Background(), userCreatedEvent))
+
+		message := broker.Messages()[0]
+		assert.Equal(t, "application/json", message.ContentType)
+		assert.Equal(t, map[string]string{
+			"specversion": "1.0",
+			"id":          "event-1",
+			"source":      "/cnm-proyect-go/users",
+			"type":        domain.CloudEventUserCreated,
+			"subject":     "12345",
+			"time":        "2024-01-01T12:30:00Z",
+		}, message.Attributes)
+
+		var data domain.UserEventData
+		assert.NoError(t, json.Unmarshal(message.Value, &data))
+		assert.Equal(t, userCreatedEvent.Data, data)
+	})
+}
+
+func TestMemoryBroker(t *testing.T) {
+	broker := adapters.NewMemoryBroker(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	created := broker.Subscribe(ctx, domain.CloudEventUserCreated)
+	all := broker.Subscribe(ctx, "")
+
+	for _, message := range []*adapters.BrokerMessage{
+		{ID: "event-1", Type: domain.CloudEventUserCreated},
+		{ID: "event-2", Type: domain.CloudEventUserUpdated},
+		{ID: "event-3", Type: domain.CloudEventUserDeleted},
+	} {
+		assert.NoError(t, broker.Send(ctx, message))
+	}
+
+	assert.Equal(t, "event-1", (<-created).ID)
+	assert.Equal(t, "event-1", (<-all).ID)
+	assert.Equal(t, "event-2", (<-all).ID)
+	assert.Equal(t, "event-3", (<-all).ID)
+
+	var kept []string
+	for _, message := range broker.Messages() {
+		kept = append(kept, message.ID)
+	}
+	assert.Equal(t, []string{"event-2", "event-3"}, kept)
+
+	assert.NoError(t, broker.Close())
+	_, open := <-created
+	assert.False(t, open)
+}
@@ -0,0 +1,72 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+)
+
+// EventPublisher delivers domain events as CloudEvents to downstream services. The outbox relay
+// publishes an event again when it is not acknowledged, so publishers must tolerate duplicates by event ID.
+type EventPublisher interface {
+	Publish(ctx context.Context, event *domain.CloudEvent) error
+}
+
+// LogEventPublisher writes events as structured CloudEvents JSON lines instead of delivering
+// them, it is used in development and tests.
+type LogEventPublisher struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewLogEventPublisher creates a publisher writing to writer.
+func NewLogEventPublisher(writer io.Writer) *LogEventPublisher {
+	return &LogEventPublisher{
+		writer: writer,
+	}
+}
+
+// Publish writes the event to the log.
+func (p *LogEventPublisher) Publish(_ context.Context, event *domain.CloudEvent) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = fmt.Fprintln(p.writer, string(line))
+
+	return err
+}
+
+// MultiEventPublisher publishes events through each of its publishers in order. An event is
+// published again to every publisher when one fails, which publishers tolerate as a duplicate.
+type MultiEventPublisher struct {
+	publishers []EventPublisher
+}
+
+// NewMultiEventPublisher creates a publisher publishing through publishers.
+func NewMultiEventPublisher(publishers ...EventPublisher) *MultiEventPublisher {
+	return &MultiEventPublisher{
+		publishers: publishers,
+	}
+}
+
+// Publish publishes the event through every publisher and joins their errors.
+func (p *MultiEventPublisher) Publish(ctx context.Context, event *domain.CloudEvent) error {
+	var errs []error
+	for _, publisher := range p.publishers {
+		if err := publisher.Publish(ctx, event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,62 @@
+package adapters_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogEventPublisher(t *testing.T) {
+	output := &bytes.Buffer{}
+	publisher := adapters.NewLogEventPublisher(output)
+
+	event := &domain.CloudEvent{
+		SpecVersion:     domain.CloudEventsSpecVersion,
+		ID:              "event1",
+		Source:          "/cnm-proyect-go/users",
+		Type:            domain.CloudEventUserCreated,
+		Subject:         "12345",
+		Time:            time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            domain.UserEventData{ID: "12345", Email: "cristian@gmail.com", Enabled: true},
+	}
+
+	err := publisher.Publish(context.Background(), event)
+	assert.NoError(t, err)
+
+	var written domain.CloudEvent
+	err = json.Unmarshal(output.Bytes(), &written)
+	assert.NoError(t, err)
+	assert.Equal(t, *event, written)
+	assert.Contains(t, output.String(), `"specversion":"1.0"`)
+	assert.Contains(t, output.String(), `"type":"com.cnmoreno.user.created"`)
+}
+
+type failingEventPublisher struct{}
+
+func (failingEventPublisher) Publish(context.Context, *domain.CloudEvent) error {
+	return errors.New("broker unavailable")
+}
+
+func TestMultiEventPublisher(t *testing.T) {
+	first := &bytes.Buffer{}
+	second := &bytes.Buffer{}
+	event := &domain.CloudEvent{ID: "event1", Type: domain.CloudEventUserDeleted, Subject: "12345"}
+
+	publisher := adapters.NewMultiEventPublisher(adapters.NewLogEventPublisher(first), adapters.NewLogEventPublisher(second))
+	assert.NoError(t, publisher.Publish(context.Background(), event))
+	assert.Contains(t, first.String(), "event1")
+	assert.Contains(t, second.String(), "event1")
+
+	third := &bytes.Buffer{}
+	publisher = adapters.NewMultiEventPublisher(failingEventPublisher{}, adapters.NewLogEventPublisher(third))
+	assert.EqualError(t, publisher.Publish(context.Background(), event), "broker unavailable")
+	assert.Contains(t, third.String(), "event1")
+}
@@ -0,0 +1,45 @@
+package adapters
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// KafkaWriter writes messages to a Kafka topic, it is implemented by *kafka.Writer.
+type KafkaWriter interface {
+	WriteMessages(ctx context.Context, messages ...kafka.Message) error
+	Close() error
+}
+
+// KafkaBroker writes messages to the topic of its writer keyed by the subject of the event, so the
+// events of a user land in the same partition in order. The attributes are sent as ce_ headers.
+type KafkaBroker struct {
+	writer KafkaWriter
+}
+
+// NewKafkaBroker creates a broker writing through writer.
+func NewKafkaBroker(writer KafkaWriter) *KafkaBroker {
+	return &KafkaBroker{
+		writer: writer,
+	}
+}
+
+// Send writes the message and waits until the brokers acknowledge it.
+func (b *KafkaBroker) Send(ctx context.Context, message *BrokerMessage) error {
+	headers := []kafka.Header{{Key: "content-type", Value: []byte(message.ContentType)}}
+	for name, value := range message.Attributes {
+		headers = append(headers, kafka.Header{Key: "ce_" + name, Value: []byte(value)})
+	}
+
+	return b.writer.WriteMessages(ctx, kafka.Message{
+		Key:     []byte(message.Key),
+		Value:   message.Value,
+		Headers: headers,
+	})
+}
+
+// Close writes the pending messages and closes the writer.
+func (b *KafkaBroker) Close() error {
+	return b.writer.Close()
+}
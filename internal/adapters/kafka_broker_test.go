@@ -0,0 +1,63 @@
+package adapters_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+)
+
+// kafkaWriter records the written messages instead of sending them to Kafka.
+type kafkaWriter struct {
+	messages []kafka.Message
+	err      error
+	closed   bool
+}
+
+func (w *kafkaWriter) WriteMessages(_ context.Context, messages ...kafka.Message) error {
+	w.messages = append(w.messages, messages...)
+	return w.err
+}
+
+func (w *kafkaWriter) Close() error {
+	w.closed = true
+	return nil
+}
+
+func TestKafkaBroker(t *testing.T) {
+	t.Run("should write binary CloudEvent keyed by subject with ce_ headers", func(t *testing.T) {
+		writer := &kafkaWriter{}
+		broker := adapters.NewKafkaBroker(writer)
+		publisher := adapters.NewBrokerEventPublisher(broker, domain.CloudEventsBinary)
+
+		assert.NoError(t, publisher.Publish(context.Background(), userCreatedEvent))
+		assert.Len(t, writer.messages, 1)
+
+		message := writer.messages[0]
+		assert.Equal(t, "12345", string(message.Key))
+
+		headers := map[string]string{}
+		for _, header := range message.Headers {
+			headers[header.Key] = string(header.Value)
+		}
+		assert.Equal(t, "application/json", headers["content-type"])
+		assert.Equal(t, "1.0", headers["ce_specversion"])
+		assert.Equal(t, "event-1", headers["ce_id"])
+		assert.Equal(t, domain.CloudEventUserCreated, headers["ce_type"])
+		assert.Equal(t, "12345", headers["ce_subject"])
+
+		assert.NoError(t, broker.Close())
+		assert.True(t, writer.closed)
+	})
+
+	t.Run("should return an error when Kafka rejects the message", func(t *testing.T) {
+		broker := adapters.NewKafkaBroker(&kafkaWriter{err: errors.New("leader not available")})
+		publisher := adapters.NewBrokerEventPublisher(broker, domain.CloudEventsStructured)
+
+		assert.EqualError(t, publisher.Publish(context.Background(), userCreatedEvent), "leader not available")
+	})
+}
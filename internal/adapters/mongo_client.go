@@ -57,3 +57,19 @@ func (mc *MongoClient) Close() error {
 func (mc *MongoClient) GetDatabase() *mongo.Database {
 	return mc.db
 }
+
+// WithTransaction runs fn in a transaction of a new session, it is retried on transient errors.
+// Transactions require MongoDB to run as a replica set.
+func (mc *MongoClient) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := mc.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sessionCtx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sessionCtx)
+	})
+
+	return err
+}
@@ -0,0 +1,53 @@
+package adapters
+
+import (
+	"context"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSBroker publishes messages on the subjects of a NATS server. A message is published on the
+// subject of the prefix and its type, with the attributes as ce- headers and its ID as the
+// Nats-Msg-Id header so JetStream streams skip duplicates.
+type NATSBroker struct {
+	conn          *nats.Conn
+	subjectPrefix string
+	timeout       time.Duration
+}
+
+// NewNATSBroker creates a broker publishing through conn under subjectPrefix, a message is sent
+// once the server acknowledges it or fails after timeout.
+func NewNATSBroker(conn *nats.Conn, subjectPrefix string, timeout time.Duration) *NATSBroker {
+	return &NATSBroker{
+		conn:          conn,
+		subjectPrefix: subjectPrefix,
+		timeout:       timeout,
+	}
+}
+
+// Send publishes the message and waits until the server has processed it.
+func (b *NATSBroker) Send(ctx context.Context, message *BrokerMessage) error {
+	msg := nats.NewMsg(b.subjectPrefix + "." + message.Type)
+	msg.Data = message.Value
+	msg.Header.Set("content-type", message.ContentType)
+	msg.Header.Set(nats.MsgIdHdr, message.ID)
+	for name, value := range message.Attributes {
+		msg.Header.Set("ce-"+name, value)
+	}
+
+	if err := b.conn.PublishMsg(msg); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, b.timeout)
+	defer cancel()
+
+	return b.conn.FlushWithContext(ctx)
+}
+
+// Close closes the connection, every sent message was already flushed.
+func (b *NATSBroker) Close() error {
+	b.conn.Close()
+	return nil
+}
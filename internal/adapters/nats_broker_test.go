@@ -0,0 +1,104 @@
+package adapters_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+// runNATSServer starts an embedded NATS server on a random port.
+func runNATSServer(t *testing.T) *server.Server {
+	t.Helper()
+
+	natsServer, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: server.RANDOM_PORT, NoLog: true, NoSigs: true})
+	assert.NoError(t, err)
+
+	go natsServer.Start()
+	if !natsServer.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server is not ready")
+	}
+	t.Cleanup(natsServer.Shutdown)
+
+	return natsServer
+}
+
+func TestNATSBroker(t *testing.T) {
+	natsServer := runNATSServer(t)
+
+	testCases := []struct {
+		name        string
+		mode        string
+		contentType string
+		ceID        string
+	}{
+		{
+			name:        "should publish structured CloudEvent on the subject of its type",
+			mode:        domain.CloudEventsStructured,
+			contentType: adapters.CloudEventsContentType,
+		},
+		{
+			name:        "should publish binary CloudEvent with ce- headers",
+			mode:        domain.CloudEventsBinary,
+			contentType: "application/json",
+			ceID:        "event-1",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			subscriber, err := nats.Connect(natsServer.ClientURL())
+			assert.NoError(t, err)
+			defer subscriber.Close()
+
+			subscription, err := subscriber.SubscribeSync("users.>")
+			assert.NoError(t, err)
+			assert.NoError(t, subscriber.Flush())
+
+			conn, err := nats.Connect(natsServer.ClientURL())
+			assert.NoError(t, err)
+
+			broker := adapters.NewNATSBroker(conn, "users", time.Second)
+			defer broker.Close()
+
+			publisher := adapters.NewBrokerEventPublisher(broker, test.mode)
+			assert.NoError(t, publisher.Publish(context.Background(), userCreatedEvent))
+
+			msg, err := subscription.NextMsg(time.Second)
+			assert.NoError(t, err)
+			assert.Equal(t, "users.com.cnmoreno.user.created", msg.Subject)
+			assert.Equal(t, test.contentType, msg.Header.Get("content-type"))
+			assert.Equal(t, "event-1", msg.Header.Get(nats.MsgIdHdr))
+			assert.Equal(t, test.ceID, msg.Header.Get("ce-id"))
+
+			var data domain.UserEventData
+			if test.mode == domain.CloudEventsBinary {
+				assert.NoError(t, json.Unmarshal(msg.Data, &data))
+			} else {
+				var event domain.CloudEvent
+				assert.NoError(t, json.Unmarshal(msg.Data, &event))
+				data = event.Data
+			}
+			assert.Equal(t, userCreatedEvent.Data, data)
+		})
+	}
+}
+
+func TestNATSBrokerClosed(t *testing.T) {
+	natsServer := runNATSServer(t)
+
+	conn, err := nats.Connect(natsServer.ClientURL())
+	assert.NoError(t, err)
+
+	broker := adapters.NewNATSBroker(conn, "users", time.Second)
+	assert.NoError(t, broker.Close())
+
+	err = broker.Send(context.Background(), &adapters.BrokerMessage{ID: "event-1", Type: domain.CloudEventUserCreated})
+	assert.Error(t, err)
+}
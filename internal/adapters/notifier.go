@@ -0,0 +1,134 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+)
+
+// Notifier delivers messages to users.
+type Notifier interface {
+	Send(ctx context.Context, message *domain.Message) error
+}
+
+// SMTPNotifier delivers messages by email through a SMTP server.
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPNotifier creates a SMTP notifier, the credentials are optional.
+func NewSMTPNotifier(addr, from, username, password string) (*SMTPNotifier, error) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	var auth smtp.Auth
+	if username != "" {
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+
+	return &SMTPNotifier{
+		addr: addr,
+		from: from,
+		auth: auth,
+	}, nil
+}
+
+// Send delivers the message as a plain text email.
+func (n *SMTPNotifier) Send(_ context.Context, message *domain.Message) error {
+	body := strings.Join([]string{
+		"From: " + n.from,
+		"To: " + message.To,
+		"Subject: " + message.Subject,
+		"MIME-Version: 1.0",
+		"Content-Type: text/plain; charset=UTF-8",
+		"",
+		message.Body,
+	}, "\r\n")
+
+	return smtp.SendMail(n.addr, n.auth, n.from, []string{message.To}, []byte(body))
+}
+
+// LogNotifier writes messages as JSON lines instead of delivering them, it is used
+// in development and tests. The secrets of a message are redacted, so logs never hold
+// working links or codes.
+type LogNotifier struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+// NewLogNotifier creates a notifier writing to writer.
+func NewLogNotifier(writer io.Writer) *LogNotifier {
+	return &LogNotifier{
+		writer: writer,
+	}
+}
+
+// Send writes the message to the log.
+func (n *LogNotifier) Send(_ context.Context, message *domain.Message) error {
+	redacted := *message
+	for _, secret := range message.Secrets {
+		if secret != "" {
+			redacted.Body = strings.ReplaceAll(redacted.Body, secret, utils.AuditRedacted)
+		}
+	}
+
+	line, err := json.Marshal(&redacted)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	_, err = fmt.Fprintln(n.writer, string(line))
+
+	return err
+}
+
+// AsyncNotifier delivers messages through notifier in the background, so the time of a
+// response does not reveal whether a message was sent. Delivery failures are logged.
+type AsyncNotifier struct {
+	notifier Notifier
+	pending  sync.WaitGroup
+}
+
+// NewAsyncNotifier creates a notifier sending in the background through notifier.
+func NewAsyncNotifier(notifier Notifier) *AsyncNotifier {
+	return &AsyncNotifier{
+		notifier: notifier,
+	}
+}
+
+// Send starts the delivery of the message and returns without waiting for it, the delivery
+// is not canceled with ctx.
+func (n *AsyncNotifier) Send(ctx context.Context, message *domain.Message) error {
+	n.pending.Add(1)
+	go func() {
+		defer n.pending.Done()
+
+		if err := n.notifier.Send(context.WithoutCancel(ctx), message); err != nil {
+			log.Printf("%v: %v", constants.ErrSendNotification, err)
+		}
+	}()
+
+	return nil
+}
+
+// Close waits for the messages being delivered.
+func (n *AsyncNotifier) Close() {
+	n.pending.Wait()
+}
@@ -0,0 +1,104 @@
+package adapters_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogNotifier(t *testing.T) {
+	output := &bytes.Buffer{}
+	notifier := adapters.NewLogNotifier(output)
+
+	message := &domain.Message{To: "cristian@gmail.com", Subject: "subject", Body: "body"}
+
+	err := notifier.Send(context.Background(), message)
+	assert.NoError(t, err)
+
+	var written domain.Message
+	err = json.Unmarshal(output.Bytes(), &written)
+	assert.NoError(t, err)
+	assert.Equal(t, *message, written)
+}
+
+func TestLogNotifierRedactsSecrets(t *testing.T) {
+	output := &bytes.Buffer{}
+	notifier := adapters.NewLogNotifier(output)
+
+	message := &domain.Message{
+		To:      "cristian@gmail.com",
+		Subject: "subject",
+		Body:    "Your code is 123456, or open https://example.com/login?token=abc-token",
+		Secrets: []string{"abc-token", "123456"},
+	}
+
+	err := notifier.Send(context.Background(), message)
+	assert.NoError(t, err)
+	assert.NotContains(t, output.String(), "abc-token")
+	assert.NotContains(t, output.String(), "123456")
+
+	var written domain.Message
+	err = json.Unmarshal(output.Bytes(), &written)
+	assert.NoError(t, err)
+	assert.Equal(t, "Your code is [REDACTED], or open https://example.com/login?token=[REDACTED]", written.Body)
+	assert.Contains(t, message.Body, "abc-token")
+}
+
+func TestNewSMTPNotifier(t *testing.T) {
+	_, err := adapters.NewSMTPNotifier("smtp.example.com:587", "noreply@example.com", "user", "secret")
+	assert.NoError(t, err)
+
+	_, err = adapters.NewSMTPNotifier("smtp.example.com", "noreply@example.com", "", "")
+	assert.Error(t, err)
+}
+
+// blockingNotifier waits for release before delivering a message.
+type blockingNotifier struct {
+	release chan struct{}
+	sent    []*domain.Message
+	err     error
+}
+
+func (n *blockingNotifier) Send(_ context.Context, message *domain.Message) error {
+	<-n.release
+	n.sent = append(n.sent, message)
+
+	return n.err
+}
+
+func TestAsyncNotifier(t *testing.T) {
+	t.Run("should return before the message is delivered and wait for it on close", func(t *testing.T) {
+		blocking := &blockingNotifier{release: make(chan struct{})}
+		notifier := adapters.NewAsyncNotifier(blocking)
+
+		message := &domain.Message{To: "cristian@gmail.com", Subject: "subject", Body: "body"}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		err := notifier.Send(ctx, message)
+		cancel()
+		assert.NoError(t, err)
+
+		close(blocking.release)
+		notifier.Close()
+
+		assert.Equal(t, []*domain.Message{message}, blocking.sent)
+	})
+
+	t.Run("should not return delivery failures", func(t *testing.T) {
+		blocking := &blockingNotifier{release: make(chan struct{}), err: errors.New("smtp error")}
+		close(blocking.release)
+		notifier := adapters.NewAsyncNotifier(blocking)
+
+		err := notifier.Send(context.Background(), &domain.Message{To: "cristian@gmail.com"})
+		notifier.Close()
+
+		assert.NoError(t, err)
+		assert.Len(t, blocking.sent, 1)
+	})
+}
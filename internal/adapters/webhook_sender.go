@@ -0,0 +1,206 @@
+package adapters
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+)
+
+// Headers of a webhook delivery, receivers verify the signature of the timestamp and the body
+// and use the ID of the CloudEvent to skip duplicates.
+const (
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookTimestampHeader = "X-Webhook-Timestamp"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+)
+
+// maxWebhookResponseSize is how much of the response of a webhook is read before closing it.
+const maxWebhookResponseSize = 64 << 10
+
+// ErrWebhookTargetDenied is returned for an endpoint that is not an https URL of a public address.
+var ErrWebhookTargetDenied = errors.New(constants.ErrWebhookTargetDenied)
+
+// reservedPrefixes are ranges that are not reachable on the internet besides the private,
+// loopback, link-local and multicast ones.
+var reservedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+}
+
+// WebhookSender sends the deliveries of events to webhook endpoints.
+type WebhookSender interface {
+	ValidateURL(ctx context.Context, rawURL string) error
+	Send(ctx context.Context, subscription *domain.WebhookSubscription, secret string, delivery *domain.WebhookDelivery) (int, error)
+}
+
+// HTTPWebhookSender posts deliveries to webhook endpoints signed with HMAC-SHA256. Endpoints must
+// be https URLs of public addresses, the address is checked again on every connection so a host
+// resolving later to an internal address is refused, and redirects are not followed.
+type HTTPWebhookSender struct {
+	client       *http.Client
+	allowPrivate bool
+}
+
+// NewHTTPWebhookSender creates a sender waiting up to timeout for each endpoint.
+func NewHTTPWebhookSender(timeout time.Duration) *HTTPWebhookSender {
+	sender := &HTTPWebhookSender{}
+
+	dialer := &net.Dialer{Timeout: timeout, Control: sender.checkConnection}
+	sender.client = &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			ForceAttemptHTTP2:   true,
+			MaxIdleConns:        100,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return sender
+}
+
+// AllowPrivateTargets accepts http endpoints and private, loopback and link-local addresses, it is
+// only meant for tests with local receivers.
+func (s *HTTPWebhookSender) AllowPrivateTargets() *HTTPWebhookSender {
+	s.allowPrivate = true
+	return s
+}
+
+// ValidateURL checks that rawURL is an https URL and its host only resolves to public addresses.
+func (s *HTTPWebhookSender) ValidateURL(ctx context.Context, rawURL string) error {
+	endpoint, err := s.parseURL(rawURL)
+	if err != nil {
+		return err
+	}
+
+	if s.allowPrivate {
+		return nil
+	}
+
+	addresses, err := net.DefaultResolver.LookupNetIP(ctx, "ip", endpoint.Hostname())
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrWebhookTargetDenied, err)
+	}
+
+	for _, address := range addresses {
+		if !publicAddress(address) {
+			return fmt.Errorf("%w: %v resolves to %v", ErrWebhookTargetDenied, endpoint.Hostname(), address)
+		}
+	}
+
+	return nil
+}
+
+// parseURL returns the endpoint of rawURL when it is an absolute https URL.
+func (s *HTTPWebhookSender) parseURL(rawURL string) (*url.URL, error) {
+	endpoint, err := url.Parse(rawURL)
+	if err != nil || endpoint.Hostname() == "" {
+		return nil, ErrWebhookTargetDenied
+	}
+
+	if endpoint.Scheme != "https" && (!s.allowPrivate || endpoint.Scheme != "http") {
+		return nil, ErrWebhookTargetDenied
+	}
+
+	return endpoint, nil
+}
+
+// checkConnection refuses to connect to an address that is not public, it runs after the host
+// was resolved so every address of the host is checked.
+func (s *HTTPWebhookSender) checkConnection(_, address string, _ syscall.RawConn) error {
+	if s.allowPrivate {
+		return nil
+	}
+
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil || !publicAddress(addrPort.Addr()) {
+		return fmt.Errorf("%w: %v", ErrWebhookTargetDenied, address)
+	}
+
+	return nil
+}
+
+// publicAddress reports whether address is a unicast address reachable on the internet.
+func publicAddress(address netip.Addr) bool {
+	address = address.Unmap()
+	if !address.IsGlobalUnicast() || address.IsPrivate() {
+		return false
+	}
+
+	for _, prefix := range reservedPrefixes {
+		if prefix.Contains(address) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Send posts the CloudEvent of the delivery to the URL of the subscription in its content mode
+// and returns the status code of the response, the delivery fails unless the endpoint answers
+// with a 2xx status. Redirects are returned as failures.
+func (s *HTTPWebhookSender) Send(ctx context.Context, subscription *domain.WebhookSubscription, secret string, delivery *domain.WebhookDelivery) (int, error) {
+	var event domain.CloudEvent
+	if err := json.Unmarshal([]byte(delivery.Payload), &event); err != nil {
+		return 0, fmt.Errorf("%v: %w", constants.ErrInvalidCloudEvent, err)
+	}
+
+	if _, err := s.parseURL(subscription.URL); err != nil {
+		return 0, err
+	}
+
+	payload, header, err := EncodeCloudEventHTTP(&event, subscription.Mode)
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+
+	request.Header = header
+	request.Header.Set("User-Agent", "cnm-proyect-go-webhooks")
+	request.Header.Set(WebhookSignatureHeader, utils.SignWebhookPayload(secret, timestamp, payload))
+	request.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(WebhookEventHeader, delivery.EventType)
+	request.Header.Set(WebhookDeliveryHeader, delivery.ID)
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, maxWebhookResponseSize))
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("%v: %v", constants.ErrWebhookRejected, response.Status)
+	}
+
+	return response.StatusCode, nil
+}
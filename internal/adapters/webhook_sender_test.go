@@ -0,0 +1,220 @@
+package adapters_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var userCreatedEvent = &domain.CloudEvent{
+	SpecVersion:     domain.CloudEventsSpecVersion,
+	ID:              "event-1",
+	Source:          "/cnm-proyect-go/users",
+	Type:            domain.CloudEventUserCreated,
+	Subject:         "12345",
+	Time:            time.Date(2024, 1, 1, 12, 30, 0, 0, time.UTC),
+	DataContentType: "application/json",
+	Data:            domain.UserEventData{ID: "12345", Email: "cristian@gmail.com", Enabled: true},
+}
+
+func TestHTTPWebhookSender(t *testing.T) {
+	payload, _ := json.Marshal(userCreatedEvent)
+	delivery := &domain.WebhookDelivery{
+		ID:        "delivery-1",
+		EventID:   "event-1",
+		EventType: domain.CloudEventUserCreated,
+		Payload:   string(payload),
+	}
+
+	testCases := []struct {
+		name        string
+		mode        string
+		statusCode  int
+		contentType string
+		wantErr     bool
+	}{
+		{
+			name:        "should send signed structured CloudEvent accepted by the endpoint",
+			mode:        domain.CloudEventsStructured,
+			statusCode:  http.StatusNoContent,
+			contentType: adapters.CloudEventsContentType,
+		},
+		{
+			name:        "should send signed binary CloudEvent accepted by the endpoint",
+			mode:        domain.CloudEventsBinary,
+			statusCode:  http.StatusOK,
+			contentType: "application/json",
+		},
+		{
+			name:        "should return an error when endpoint rejects the delivery",
+			mode:        domain.CloudEventsStructured,
+			statusCode:  http.StatusInternalServerError,
+			contentType: adapters.CloudEventsContentType,
+			wantErr:     true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var received *http.Request
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				received = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(test.statusCode)
+			}))
+			defer server.Close()
+
+			sender := adapters.NewHTTPWebhookSender(time.Second).AllowPrivateTargets()
+			subscription := &domain.WebhookSubscription{URL: server.URL, Mode: test.mode}
+
+			statusCode, err := sender.Send(context.Background(), subscription, "secret", delivery)
+			assert.Equal(t, test.statusCode, statusCode)
+			assert.Equal(t, test.wantErr, err != nil)
+
+			assert.Equal(t, http.MethodPost, received.Method)
+			assert.Equal(t, test.contentType, received.Header.Get("Content-Type"))
+			assert.Equal(t, domain.CloudEventUserCreated, received.Header.Get(adapters.WebhookEventHeader))
+			assert.Equal(t, delivery.ID, received.Header.Get(adapters.WebhookDeliveryHeader))
+			assert.True(t, utils.VerifyWebhookSignature("secret", received.Header.Get(adapters.WebhookTimestampHeader), received.Header.Get(adapters.WebhookSignatureHeader), body, time.Minute))
+
+			event, err := adapters.DecodeCloudEventHTTP(received.Header, body)
+			assert.NoError(t, err)
+			assert.Equal(t, userCreatedEvent, event)
+		})
+	}
+}
+
+func TestHTTPWebhookSenderUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	sender := adapters.NewHTTPWebhookSender(time.Second).AllowPrivateTargets()
+
+	statusCode, err := sender.Send(context.Background(), &domain.WebhookSubscription{URL: server.URL}, "secret", &domain.WebhookDelivery{Payload: "{}"})
+	assert.Error(t, err)
+	assert.Zero(t, statusCode)
+}
+
+func TestHTTPWebhookSenderValidateURL(t *testing.T) {
+	testCases := []struct {
+		name    string
+		url     string
+		wantErr bool
+	}{
+		{
+			name: "should accept https URL of a public address",
+			url:  "https://203.0.113.10/hooks",
+		},
+		{
+			name:    "should reject http URL",
+			url:     "http://203.0.113.10/hooks",
+			wantErr: true,
+		},
+		{
+			name:    "should reject URL without host",
+			url:     "https:///hooks",
+			wantErr: true,
+		},
+		{
+			name:    "should reject loopback address",
+			url:     "https://127.0.0.1/hooks",
+			wantErr: true,
+		},
+		{
+			name:    "should reject host resolving to loopback address",
+			url:     "https://localhost/hooks",
+			wantErr: true,
+		},
+		{
+			name:    "should reject private address",
+			url:     "https://192.168.1.10/hooks",
+			wantErr: true,
+		},
+		{
+			name:    "should reject link-local address",
+			url:     "https://169.254.169.254/latest/meta-data",
+			wantErr: true,
+		},
+		{
+			name:    "should reject IPv6 unique local address",
+			url:     "https://[fd00::1]/hooks",
+			wantErr: true,
+		},
+		{
+			name:    "should reject IPv4-mapped private address",
+			url:     "https://[::ffff:10.0.0.1]/hooks",
+			wantErr: true,
+		},
+		{
+			name:    "should reject unspecified address",
+			url:     "https://0.0.0.0/hooks",
+			wantErr: true,
+		},
+		{
+			name:    "should reject shared address space",
+			url:     "https://100.64.0.1/hooks",
+			wantErr: true,
+		},
+	}
+
+	sender := adapters.NewHTTPWebhookSender(time.Second)
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			err := sender.ValidateURL(context.Background(), test.url)
+			assert.Equal(t, test.wantErr, err != nil)
+			if test.wantErr {
+				assert.True(t, errors.Is(err, adapters.ErrWebhookTargetDenied))
+			}
+		})
+	}
+}
+
+func TestHTTPWebhookSenderRefusesPrivateTargets(t *testing.T) {
+	var received bool
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = true
+	}))
+	defer server.Close()
+
+	sender := adapters.NewHTTPWebhookSender(time.Second)
+
+	statusCode, err := sender.Send(context.Background(), &domain.WebhookSubscription{URL: server.URL}, "secret", &domain.WebhookDelivery{Payload: "{}"})
+	assert.True(t, errors.Is(err, adapters.ErrWebhookTargetDenied))
+	assert.Zero(t, statusCode)
+	assert.False(t, received)
+
+	statusCode, err = sender.Send(context.Background(), &domain.WebhookSubscription{URL: "http://203.0.113.10/hooks"}, "secret", &domain.WebhookDelivery{Payload: "{}"})
+	assert.True(t, errors.Is(err, adapters.ErrWebhookTargetDenied))
+	assert.Zero(t, statusCode)
+}
+
+func TestHTTPWebhookSenderDoesNotFollowRedirects(t *testing.T) {
+	var redirected bool
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirected = true
+	}))
+	defer target.Close()
+
+	server := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer server.Close()
+
+	sender := adapters.NewHTTPWebhookSender(time.Second).AllowPrivateTargets()
+	payload, _ := json.Marshal(userCreatedEvent)
+
+	statusCode, err := sender.Send(context.Background(), &domain.WebhookSubscription{URL: server.URL}, "secret", &domain.WebhookDelivery{Payload: string(payload)})
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusTemporaryRedirect, statusCode)
+	assert.False(t, redirected)
+}
@@ -2,23 +2,183 @@ package constants
 
 // Map errors users api.
 var (
-	ErrUserNotFound           = "user not found"
-	ErrInvalidUserInput       = "Invalid user input"
-	ErrFailedToCreateUser     = "Failed to create user"
-	ErrFailedToGetUser        = "Failed to get user"
-	ErrFailedToUpdateUser     = "Failed to update user"
-	ErrFailedToDeleteUser     = "Failed to delete user"
-	ErrFailedToGetFile        = "Failed to get file"
-	ErrOnlyAcceptCSVFile      = "Only accept CSV file"
-	ErrOpenFile               = "Failed to open file"
-	ErrProcessCSVFile         = "Failed to process CSV file"
-	ErrInsertUsers            = "Failed to create users"
-	ErrClosingMongoConnection = "Failed closing MongoDB connection"
-	ErrClosingFile            = "Failed closing File"
-	ErrMongoUrlIsNotSet       = "MONGO_URL is not set"
-	ErrMongoDatabaseIsNotSet  = "MONGO_DATABASE is not set"
-	ErrCloseMongoConnection   = "Failed closing MongoDB connection"
-	ErrSetUpDependencies      = "Failed to set up dependencies"
-	ErrCreateMongoIndex       = "Failed create mongo index"
-	ErrUserOrEmailInUse       = "User or Email is in use"
+	ErrUserNotFound             = "user not found"
+	ErrInvalidUserInput         = "Invalid user input"
+	ErrFailedToCreateUser       = "Failed to create user"
+	ErrFailedToGetUser          = "Failed to get user"
+	ErrFailedToUpdateUser       = "Failed to update user"
+	ErrFailedToDeleteUser       = "Failed to delete user"
+	ErrFailedToGetFile          = "Failed to get file"
+	ErrOnlyAcceptCSVFile        = "Only accept CSV file"
+	ErrOpenFile                 = "Failed to open file"
+	ErrProcessCSVFile           = "Failed to process CSV file"
+	ErrInsertUsers              = "Failed to create users"
+	ErrClosingMongoConnection   = "Failed closing MongoDB connection"
+	ErrClosingFile              = "Failed closing File"
+	ErrMongoUrlIsNotSet         = "MONGO_URL is not set"
+	ErrMongoDatabaseIsNotSet    = "MONGO_DATABASE is not set"
+	ErrCloseMongoConnection     = "Failed closing MongoDB connection"
+	ErrSetUpDependencies        = "Failed to set up dependencies"
+	ErrCreateMongoIndex         = "Failed create mongo index"
+	ErrUserOrEmailInUse         = "User or Email is in use"
+	ErrInvalidCredentials       = "Invalid credentials"
+	ErrFailedToLogin            = "Failed to login"
+	ErrRehashPassword           = "Failed to rehash password"
+	ErrUnknownHashAlgorithm     = "PASSWORD_HASH_ALGORITHM must be bcrypt or argon2id"
+	ErrInvalidHashCost          = "PASSWORD_HASH_COST must be a number"
+	ErrUnknownPepper            = "Unknown password pepper"
+	ErrReadPepperFile           = "Failed to read password pepper file"
+	ErrInvalidPepperFile        = "Password pepper file must contain id=secret lines"
+	ErrPasswordPolicy           = "Password does not satisfy the policy"
+	ErrInvalidPasswordPolicy    = "Invalid password policy configuration"
+	ErrOpenBreachedPasswords    = "Failed to open breached passwords file"
+	ErrInvalidBreachedPasswords = "Breached passwords file must contain SHA-1 hashes"
+	ErrCheckBreachedPasswords   = "Failed to check breached passwords"
+	ErrUnknownBreachedMode      = "BREACHED_PASSWORDS_MODE must be file or bloom"
+	ErrPasswordReused           = "Password was used recently"
+	ErrInvalidCurrentPassword   = "Current password is not valid"
+	ErrFailedToChangePassword   = "Failed to change password"
+	ErrRecordAuditEvent         = "Failed to record audit event"
+	ErrInvalidHistorySize       = "PASSWORD_HISTORY_SIZE must be zero or a positive number"
+	ErrInvalidResetToken        = "Reset token is invalid or expired"
+	ErrFailedToResetPassword    = "Failed to reset password"
+	ErrForgotPassword           = "Failed to request password reset"
+	ErrSendNotification         = "Failed to send notification"
+	ErrUnknownNotifier          = "NOTIFIER must be log or smtp"
+	ErrNotifierIsNotSet         = "NOTIFIER must be set unless APP_ENV is development or test"
+	ErrInvalidTokenTTL          = "Token lifetime must be a positive duration"
+	ErrTokenLinkURLIsNotSet     = "Token link URL is not set"
+	ErrEmailAlreadyVerified     = "Email is already verified"
+	ErrInvalidVerificationToken = "Verification token is invalid or expired"
+	ErrFailedToVerifyEmail      = "Failed to verify email"
+	ErrSendEmailVerification    = "Failed to send email verification"
+	ErrSendEmailChangeNotice    = "Failed to send email change notice"
+	ErrLoginLocked              = "Too many failed logins, try again later"
+	ErrRecordFailedLogin        = "Failed to record failed login"
+	ErrInvalidLockoutPolicy     = "Invalid login lockout configuration"
+	ErrFailedToUnlockUser       = "Failed to unlock user"
+	ErrUnknownEncryptionKey     = "Unknown encryption key"
+	ErrDecryptSecret            = "Failed to decrypt secret"
+	ErrMFANotConfigured         = "Multi-factor authentication is not configured"
+	ErrMFAAlreadyEnabled        = "Multi-factor authentication is already enabled"
+	ErrMFANotEnrolled           = "Multi-factor authentication enrollment was not started"
+	ErrInvalidMFACode           = "Invalid multi-factor authentication code"
+	ErrInvalidMFAToken          = "Multi-factor authentication token is invalid or expired"
+	ErrFailedToEnrollMFA        = "Failed to enroll multi-factor authentication"
+	ErrMFAReauthRequired        = "Current password or a recent login is required to replace the enrollment"
+	ErrFailedToVerifyMFA        = "Failed to verify multi-factor authentication"
+	ErrInvalidCBOR              = "Invalid CBOR data"
+	ErrInvalidWebAuthnResponse  = "Invalid WebAuthn response"
+	ErrInvalidWebAuthnChallenge = "Invalid or expired WebAuthn challenge"
+	ErrWebAuthnCredentialExists = "Passkey is already registered"
+	ErrWebAuthnSignCount        = "Passkey signature counter did not increase, the authenticator may be cloned"
+	ErrFailedToRegisterPasskey  = "Failed to register passkey"
+	ErrFailedToLoginPasskey     = "Failed to login with passkey"
+	ErrInvalidWebAuthnSettings  = "Invalid WebAuthn settings"
+	ErrInvalidTrustedProxies    = "Invalid trusted proxy in TRUSTED_PROXIES"
+	ErrRateLimited              = "Too many requests"
+	ErrInvalidPasswordlessLogin = "Login link or code is invalid or expired"
+	ErrFailedPasswordlessLogin  = "Failed to login without password"
+	ErrInvalidPasswordlessLimit = "Invalid passwordless login limit"
+	ErrInvalidResetLimit        = "Invalid password reset limit"
+	ErrInvalidCookieSetting     = "Invalid cookie setting"
+	ErrInvalidSession           = "Session is invalid, expired or revoked"
+	ErrSessionNotFound          = "Session not found"
+	ErrForbidden                = "Not allowed to access this user"
+	ErrAdminRequired            = "Administrator access required"
+	ErrFailedToListSessions     = "Failed to list sessions"
+	ErrFailedToRevokeSession    = "Failed to revoke session"
+	ErrOAuthRequest             = "OAuth request failed"
+	ErrOAuthClientNotFound      = "OAuth client not found"
+	ErrInvalidRedirectURI       = "Redirect URI is invalid or not registered for the client"
+	ErrInvalidOAuthClient       = "Invalid OAuth client registration"
+	ErrFailedToRegisterClient   = "Failed to register OAuth client"
+	ErrFailedToAuthorize        = "Failed to authorize OAuth client"
+	ErrInvalidJWT               = "Invalid JSON Web Token"
+	ErrOpenIDNotConfigured      = "OpenID Connect is not configured"
+	ErrInvalidOpenIDSettings    = "Invalid OpenID Connect settings"
+	ErrRotateSigningKeys        = "Failed to rotate signing keys"
+	ErrInvalidAccessToken       = "Access token is invalid or expired"
+	ErrInsufficientScope        = "Access token does not have the required scope"
+	ErrFailedToGetUserInfo      = "Failed to get user info"
+	ErrFailedToValidateToken    = "Failed to validate access token"
+	ErrInvalidAPIKey            = "API key is invalid or expired"
+	ErrInvalidAPIKeyRequest     = "Invalid API key permissions or expiry"
+	ErrAPIKeyNotFound           = "API key not found"
+	ErrAPIKeyNotAllowed         = "API keys can not be used for this request"
+	ErrMissingPermission        = "API key does not have the required permission"
+	ErrFailedToCreateAPIKey     = "Failed to create API key"
+	ErrFailedToListAPIKeys      = "Failed to list API keys"
+	ErrFailedToRevokeAPIKey     = "Failed to revoke API key"
+	ErrImpersonationDisabled    = "Impersonation is not enabled"
+	ErrImpersonationForbidden   = "Not allowed to impersonate this user"
+	ErrDeniedImpersonating      = "Operation not allowed while impersonating a user"
+	ErrFailedToImpersonate      = "Failed to impersonate user"
+	ErrInvalidAuditQuery        = "Invalid audit log query"
+	ErrFailedToListAudit        = "Failed to list audit log"
+	ErrReadAuditKeyFile         = "Failed to read audit signing key file"
+	ErrInvalidAuditKey          = "Invalid audit signing key"
+	ErrCheckpointAudit          = "Failed to create audit checkpoint"
+	ErrVerifyAudit              = "Failed to verify audit log"
+	ErrRelayOutbox              = "Failed to relay outbox events"
+	ErrPublishEvent             = "Failed to publish event"
+	ErrUnknownEventPublisher    = "Unknown event publisher"
+	ErrInvalidPublisherSetting  = "Invalid event publisher setting"
+	ErrConnectEventBroker       = "Failed to connect to event broker"
+	ErrCloseEventBroker         = "Failed to close event broker"
+	ErrWebhooksNotConfigured    = "Webhooks are not configured"
+	ErrInvalidWebhook           = "Invalid webhook URL or events"
+	ErrWebhookNotFound          = "Webhook not found"
+	ErrWebhookRejected          = "Webhook endpoint rejected the delivery"
+	ErrWebhookTargetDenied      = "Webhook endpoint must be an https URL of a public address"
+	ErrDeliveryNotFound         = "Webhook delivery not found"
+	ErrInvalidDeliveryQuery     = "Invalid webhook delivery query"
+	ErrFailedToCreateWebhook    = "Failed to create webhook"
+	ErrFailedToListWebhooks     = "Failed to list webhooks"
+	ErrFailedToDeleteWebhook    = "Failed to delete webhook"
+	ErrFailedToListDeliveries   = "Failed to list webhook deliveries"
+	ErrFailedToRedeliver        = "Failed to redeliver webhook"
+	ErrDeliverWebhooks          = "Failed to deliver webhooks"
+	ErrInvalidWebhookSetting    = "Invalid webhook setting"
+	ErrInvalidCloudEvent        = "Invalid CloudEvent"
+	ErrEventHistoryLost         = "Events after Last-Event-ID are no longer available"
+	ErrFailedToStreamEvents     = "Failed to stream user events"
+	ErrInvalidUserEventSetting  = "Invalid user event stream setting"
+)
+
+// Map notification messages.
+var (
+	PasswordResetSubject     = "Reset your password"
+	PasswordResetBody        = "Use the following link to choose a new password, it expires in %v:\n\n%v\n\nIf you did not request a password reset you can ignore this message."
+	EmailVerificationSubject = "Verify your email"
+	EmailVerificationBody    = "Use the following link to verify your email, it expires in %v:\n\n%v\n\nIf you did not create an account or change your email you can ignore this message."
+	EmailChangeSubject       = "Your email is being changed"
+	EmailChangeBody          = "A change of the email of your account to %v was requested, it takes effect once the new email is verified.\n\nIf you did not request it, change your password and contact support."
+	PasswordlessSubject      = "Your login code"
+	PasswordlessBody         = "Your login code is %v, it expires in %v. You can also use the following link on the device where you requested it:\n\n%v\n\nIf you did not try to login you can ignore this message."
+)
+
+// Map password policy rules.
+var (
+	RulePasswordMinLength      = "must be at least %d characters long"
+	RulePasswordMaxLength      = "must be at most %d bytes long"
+	RulePasswordLower          = "must contain a lowercase letter"
+	RulePasswordUpper          = "must contain an uppercase letter"
+	RulePasswordDigit          = "must contain a number"
+	RulePasswordSymbol         = "must contain one of the symbols %v"
+	RulePasswordAllowedSymbols = "must only contain letters, numbers and the symbols %v"
+	RulePasswordMaxRepeated    = "must not repeat the same character more than %d times in a row"
+	RulePasswordIdentity       = "must not contain the userName or email"
+	RulePasswordBreached       = "must not appear in a known data breach"
+	RulePasswordRow            = "row %d: password %v"
+)
+
+// Map audit verification failures.
+var (
+	AuditBrokenSequence   = "sequence %d expected, entries are missing or duplicated"
+	AuditBrokenPrevHash   = "previous hash does not match the hash of entry %d"
+	AuditBrokenHash       = "hash does not match the content of the entry"
+	AuditBrokenCheckpoint = "hash does not match the signed checkpoint"
+	AuditBadSignature     = "checkpoint signature is not valid for the verification key"
+	AuditMissingEntries   = "entries up to sequence %d are signed by a checkpoint but missing"
 )
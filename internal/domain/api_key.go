@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// Permissions that can be granted to an API key, requests authenticated with a session have all of them.
+const (
+	PermissionSessionsRead   = "sessions:read"
+	PermissionSessionsWrite  = "sessions:write"
+	PermissionAPIKeysRead    = "api_keys:read"
+	PermissionAuditRead      = "audit:read"
+	PermissionUserEventsRead = "user_events:read"
+)
+
+// APIKeyPermissions are the permissions that can be selected when an API key is created.
+var APIKeyPermissions = []string{PermissionSessionsRead, PermissionSessionsWrite, PermissionAPIKeysRead, PermissionAuditRead, PermissionUserEventsRead}
+
+// APIKey struct of a personal API key of a user, only the hash of the key is stored and the
+// prefix identifies it in listings.
+type APIKey struct {
+	ID          string     `bson:"_id" json:"id"`
+	UserID      string     `bson:"userId" json:"userId"`
+	Name        string     `bson:"name" json:"name"`
+	Prefix      string     `bson:"prefix" json:"prefix"`
+	KeyHash     string     `bson:"keyHash" json:"-"`
+	Permissions []string   `bson:"permissions" json:"permissions"`
+	ExpiresAt   *time.Time `bson:"expiresAt,omitempty" json:"expiresAt,omitempty"`
+	LastUsedAt  *time.Time `bson:"lastUsedAt,omitempty" json:"lastUsedAt,omitempty"`
+	CreatedAt   time.Time  `bson:"createdAt" json:"createdAt"`
+}
+
+// APIKeyRequest struct of request to create an API key, it never expires when ExpiresAt is empty.
+type APIKeyRequest struct {
+	Name        string     `json:"name" binding:"required,max=100"`
+	Permissions []string   `json:"permissions" binding:"required,min=1"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+}
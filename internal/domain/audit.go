@@ -0,0 +1,75 @@
+package domain
+
+import "time"
+
+// Audit actions recorded for users.
+const (
+	AuditActionUserCreated          = "user.created"
+	AuditActionUserImported         = "user.imported"
+	AuditActionUserUpdated          = "user.updated"
+	AuditActionUserDeleted          = "user.deleted"
+	AuditActionPasswordChanged      = "user.password_changed"
+	AuditActionPasswordReset        = "user.password_reset"
+	AuditActionEmailVerified        = "user.email_verified"
+	AuditActionImpersonationStarted = "user.impersonation_started"
+	AuditActionImpersonationEnded   = "user.impersonation_ended"
+)
+
+// AuditEvent struct of audit log entry in BD, entries are only appended. Each entry is chained
+// to the previous one by PrevHash, Hash is the SHA-256 hash of the other fields as stored in BD.
+type AuditEvent struct {
+	ID        string        `bson:"_id,omitempty" json:"id"`
+	Sequence  int64         `bson:"sequence" json:"sequence"`
+	PrevHash  string        `bson:"prevHash" json:"prevHash"`
+	Action    string        `bson:"action" json:"action"`
+	ActorID   string        `bson:"actorId" json:"actorId"`
+	TargetID  string        `bson:"targetId" json:"targetId"`
+	Changes   []AuditChange `bson:"changes,omitempty" json:"changes,omitempty"`
+	RequestID string        `bson:"requestId,omitempty" json:"requestId,omitempty"`
+	IP        string        `bson:"ip,omitempty" json:"ip,omitempty"`
+	CreatedAt time.Time     `bson:"createdAt" json:"createdAt"`
+	Hash      string        `bson:"hash,omitempty" json:"hash"`
+}
+
+// AuditChange struct of the change of a field, the values of secret fields are redacted.
+type AuditChange struct {
+	Field  string      `bson:"field" json:"field"`
+	Before interface{} `bson:"before" json:"before"`
+	After  interface{} `bson:"after" json:"after"`
+}
+
+// AuditInfo struct of the request that makes a change, it is recorded with its audit events.
+type AuditInfo struct {
+	ActorID   string
+	RequestID string
+	IP        string
+}
+
+// AuditQuery struct of the query to page through the audit log, Cursor is the next cursor
+// of the previous page.
+type AuditQuery struct {
+	Limit  int64  `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"`
+}
+
+// AuditCheckpoint struct of a signed checkpoint of the audit log in BD, the Ed25519 signature
+// covers the sequence and hash of the newest entry when it was created.
+type AuditCheckpoint struct {
+	ID        string    `bson:"_id" json:"id"`
+	Sequence  int64     `bson:"sequence" json:"sequence"`
+	Hash      string    `bson:"hash" json:"hash"`
+	KeyID     string    `bson:"keyId" json:"keyId"`
+	Signature []byte    `bson:"signature" json:"signature"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// AuditVerification struct of the result of walking the audit log, when it is not valid the
+// broken fields describe the first entry that does not match the chain or the checkpoints.
+type AuditVerification struct {
+	Valid          bool   `json:"valid"`
+	Entries        int64  `json:"entries"`
+	Checkpoints    int64  `json:"checkpoints"`
+	BrokenSequence int64  `json:"brokenSequence,omitempty"`
+	BrokenEventID  string `json:"brokenEventId,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+}
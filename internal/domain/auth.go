@@ -0,0 +1,7 @@
+package domain
+
+// Credentials struct of login request, login accepts userName or email.
+type Credentials struct {
+	Login    string `json:"login" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
@@ -0,0 +1,46 @@
+package domain
+
+import "time"
+
+// CloudEventsSpecVersion is the version of the CloudEvents specification of published events.
+const CloudEventsSpecVersion = "1.0"
+
+// Types of the user lifecycle events published as CloudEvents.
+const (
+	CloudEventUserCreated = "com.cnmoreno.user.created"
+	CloudEventUserUpdated = "com.cnmoreno.user.updated"
+	CloudEventUserDeleted = "com.cnmoreno.user.deleted"
+)
+
+// CloudEventTypes maps the types of the domain events to the types of the published CloudEvents.
+var CloudEventTypes = map[string]string{
+	EventUserCreated: CloudEventUserCreated,
+	EventUserUpdated: CloudEventUserUpdated,
+	EventUserDeleted: CloudEventUserDeleted,
+}
+
+// Content modes of CloudEvents sent over HTTP: the structured mode sends the whole event as the
+// body and the binary mode sends the data as the body and the attributes as ce- headers.
+const (
+	CloudEventsStructured = "structured"
+	CloudEventsBinary     = "binary"
+)
+
+// CloudEvent struct of a domain event in the CloudEvents 1.0 format, the subject is the ID of the user.
+type CloudEvent struct {
+	SpecVersion     string        `json:"specversion"`
+	ID              string        `json:"id"`
+	Source          string        `json:"source"`
+	Type            string        `json:"type"`
+	Subject         string        `json:"subject,omitempty"`
+	Time            time.Time     `json:"time"`
+	DataContentType string        `json:"datacontenttype,omitempty"`
+	Data            UserEventData `json:"data"`
+}
+
+// CloudEventNotification struct of a CloudEvent streamed to a subscriber, the subscriber resumes
+// the stream after the notification with its ID.
+type CloudEventNotification struct {
+	ID    string
+	Event *CloudEvent
+}
@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// EmailVerificationToken struct of a single use token confirming that a user owns Email, only the hash of the token is stored.
+type EmailVerificationToken struct {
+	ID        string    `bson:"_id,omitempty" json:"-"`
+	UserID    string    `bson:"userId" json:"-"`
+	Email     string    `bson:"email" json:"-"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"-"`
+	CreatedAt time.Time `bson:"createdAt" json:"-"`
+}
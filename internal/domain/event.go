@@ -0,0 +1,41 @@
+package domain
+
+import "time"
+
+// Types of the domain events of users.
+const (
+	EventUserCreated = "UserCreated"
+	EventUserUpdated = "UserUpdated"
+	EventUserDeleted = "UserDeleted"
+)
+
+// UserEventData struct of the user carried by a domain event, secrets are never published.
+type UserEventData struct {
+	ID            string `bson:"id" json:"id"`
+	Name          string `bson:"name,omitempty" json:"name,omitempty"`
+	Email         string `bson:"email,omitempty" json:"email,omitempty"`
+	UserName      string `bson:"userName,omitempty" json:"userName,omitempty"`
+	Enabled       bool   `bson:"enabled" json:"enabled"`
+	EmailVerified bool   `bson:"emailVerified" json:"emailVerified"`
+}
+
+// OutboxEvent struct of a domain event in the outbox in BD. It is written in the same transaction
+// as the change of the user and published by the relay, which claims it until LockedUntil.
+type OutboxEvent struct {
+	ID          string        `bson:"_id" json:"id"`
+	Type        string        `bson:"type" json:"type"`
+	AggregateID string        `bson:"aggregateId" json:"aggregateId"`
+	Data        UserEventData `bson:"data" json:"data"`
+	OccurredAt  time.Time     `bson:"occurredAt" json:"occurredAt"`
+	Attempts    int           `bson:"attempts" json:"-"`
+	LockedUntil time.Time     `bson:"lockedUntil" json:"-"`
+	PublishedAt *time.Time    `bson:"publishedAt,omitempty" json:"-"`
+	ExpiresAt   *time.Time    `bson:"expiresAt,omitempty" json:"-"`
+}
+
+// UserEventChange struct of a domain event of a user read from the change stream of the users
+// in BD, the stream continues after the event with ResumeToken.
+type UserEventChange struct {
+	ResumeToken string
+	Event       *OutboxEvent
+}
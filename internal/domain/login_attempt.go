@@ -0,0 +1,12 @@
+package domain
+
+import "time"
+
+// LoginAttempt struct of failed logins of an account or source IP, ID is the key of the source.
+type LoginAttempt struct {
+	ID          string    `bson:"_id"`
+	Failures    int       `bson:"failures"`
+	Lockouts    int       `bson:"lockouts"`
+	LockedUntil time.Time `bson:"lockedUntil"`
+	ExpiresAt   time.Time `bson:"expiresAt"`
+}
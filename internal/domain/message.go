@@ -0,0 +1,10 @@
+package domain
+
+// Message struct of notification sent to a user, Secrets are the tokens and codes in the body
+// that notifiers writing to logs must redact.
+type Message struct {
+	To      string   `json:"to"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+	Secrets []string `json:"-"`
+}
@@ -0,0 +1,54 @@
+package domain
+
+import "time"
+
+// MFASettings struct of multi-factor authentication of a user, secrets are encrypted
+// and recovery codes hashed.
+type MFASettings struct {
+	Enabled       bool     `bson:"enabled"`
+	Secret        string   `bson:"secret,omitempty"`
+	PendingSecret string   `bson:"pendingSecret,omitempty"`
+	RecoveryCodes []string `bson:"recoveryCodes,omitempty"`
+	LastUsedStep  int64    `bson:"lastUsedStep,omitempty"`
+}
+
+// MFAChallenge struct of a short lived token issued after the password step of a login, only the hash of the token is stored.
+type MFAChallenge struct {
+	ID        string    `bson:"_id,omitempty"`
+	UserID    string    `bson:"userId"`
+	Attempts  int       `bson:"attempts"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+// TOTPEnrollment struct of the authenticator setup returned when enrolling TOTP.
+type TOTPEnrollment struct {
+	Secret     string
+	OTPAuthURI string
+}
+
+// EnrollMFARequest struct of request to start a TOTP enrollment, the current password is only
+// required to replace a pending enrollment without a recent login.
+type EnrollMFARequest struct {
+	CurrentPassword string `json:"currentPassword"`
+}
+
+// ConfirmMFARequest struct of request to confirm the TOTP enrollment.
+type ConfirmMFARequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// MFALoginRequest struct of the second login step, code is a TOTP or recovery code.
+type MFALoginRequest struct {
+	MFAToken string `json:"mfaToken" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// LoginResult struct of a login, when MFAToken is set the login must be completed with MFALoginRequest.
+// SessionToken authenticates the following requests when sessions are enabled.
+type LoginResult struct {
+	User         *User
+	MFAToken     string
+	Session      *Session
+	SessionToken string
+}
@@ -0,0 +1,150 @@
+package domain
+
+import "time"
+
+// OAuth grant types supported by the authorization server.
+const (
+	OAuthGrantAuthorizationCode = "authorization_code"
+	OAuthGrantClientCredentials = "client_credentials"
+)
+
+// OAuthClient struct of an application registered in the authorization server, only the hash
+// of the secret is stored and public clients like single page or native apps have none.
+type OAuthClient struct {
+	ID           string    `bson:"_id" json:"clientId"`
+	Name         string    `bson:"name" json:"name"`
+	SecretHash   string    `bson:"secretHash,omitempty" json:"-"`
+	Public       bool      `bson:"public" json:"public"`
+	RedirectURIs []string  `bson:"redirectUris" json:"redirectUris"`
+	GrantTypes   []string  `bson:"grantTypes" json:"grantTypes"`
+	Scopes       []string  `bson:"scopes" json:"scopes"`
+	CreatedAt    time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// OAuthClientRequest struct of request to register a client.
+type OAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100"`
+	Public       bool     `json:"public"`
+	RedirectURIs []string `json:"redirectUris" binding:"omitempty,dive,required"`
+	GrantTypes   []string `json:"grantTypes" binding:"required,min=1,dive,oneof=authorization_code client_credentials"`
+	Scopes       []string `json:"scopes" binding:"required,min=1,dive,required,excludesall= "`
+}
+
+// OAuthConsent struct of the scopes a user granted to a client.
+type OAuthConsent struct {
+	ID        string    `bson:"_id"`
+	UserID    string    `bson:"userId"`
+	ClientID  string    `bson:"clientId"`
+	Scopes    []string  `bson:"scopes"`
+	CreatedAt time.Time `bson:"createdAt"`
+	UpdatedAt time.Time `bson:"updatedAt"`
+}
+
+// OAuthAuthorizationCode struct of a code issued to a client, only the hash of the code is stored.
+// RedirectURI is the one sent in the authorization request, it is empty when it was omitted.
+type OAuthAuthorizationCode struct {
+	ID            string    `bson:"_id"`
+	ClientID      string    `bson:"clientId"`
+	UserID        string    `bson:"userId"`
+	RedirectURI   string    `bson:"redirectUri,omitempty"`
+	Scopes        []string  `bson:"scopes"`
+	CodeChallenge string    `bson:"codeChallenge"`
+	Nonce         string    `bson:"nonce,omitempty"`
+	ExpiresAt     time.Time `bson:"expiresAt"`
+	CreatedAt     time.Time `bson:"createdAt"`
+}
+
+// OAuthAccessToken struct of an access token issued to a client, only the hash of the token is stored.
+// UserID is empty in tokens of the client credentials grant.
+type OAuthAccessToken struct {
+	ID        string    `bson:"_id"`
+	ClientID  string    `bson:"clientId"`
+	UserID    string    `bson:"userId,omitempty"`
+	Scopes    []string  `bson:"scopes"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+// OAuthAuthorizeRequest struct of an authorization request of the authorization code grant,
+// it is read from the query string the client sent to the user agent.
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `form:"response_type" json:"responseType"`
+	ClientID            string `form:"client_id" json:"clientId" binding:"required"`
+	RedirectURI         string `form:"redirect_uri" json:"redirectUri"`
+	Scope               string `form:"scope" json:"scope"`
+	State               string `form:"state" json:"state"`
+	CodeChallenge       string `form:"code_challenge" json:"codeChallenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"codeChallengeMethod"`
+	Nonce               string `form:"nonce" json:"nonce"`
+}
+
+// OAuthConsentRequest struct of request with the decision of the user on an authorization request.
+type OAuthConsentRequest struct {
+	OAuthAuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// OAuthConsentPrompt struct of the consent the user must give before the client is authorized.
+type OAuthConsentPrompt struct {
+	ClientID   string   `json:"clientId"`
+	ClientName string   `json:"clientName"`
+	Scopes     []string `json:"scopes"`
+}
+
+// OAuthTokenRequest struct of a form encoded request to the token endpoint, confidential
+// clients may send their credentials with HTTP Basic authentication instead of the form.
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+// OAuthTokenResponse struct of a successful response of the token endpoint as defined by RFC 6749.
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+	IDToken     string `json:"id_token,omitempty"`
+}
+
+// OAuthTokenActionRequest struct of a form encoded request to the introspection or revocation
+// endpoints, the client authenticates as on the token endpoint.
+type OAuthTokenActionRequest struct {
+	Token         string `form:"token" binding:"required"`
+	TokenTypeHint string `form:"token_type_hint"`
+	ClientID      string `form:"client_id"`
+	ClientSecret  string `form:"client_secret"`
+}
+
+// OAuthIntrospection struct of a response of the introspection endpoint as defined by RFC 7662,
+// only active is returned for tokens that are not active.
+type OAuthIntrospection struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Audience  string `json:"aud,omitempty"`
+	Issuer    string `json:"iss,omitempty"`
+}
+
+// RevokedToken struct of a token in the revocation list, it is identified by the hash of the
+// token and kept until the token expires.
+type RevokedToken struct {
+	ID        string    `bson:"_id"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+	RevokedAt time.Time `bson:"revokedAt"`
+}
+
+// OAuthErrorResponse struct of an error response of the token endpoint as defined by RFC 6749.
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// PasswordResetToken struct of a single use password reset token, only the hash of the token is stored.
+type PasswordResetToken struct {
+	ID        string    `bson:"_id,omitempty" json:"-"`
+	UserID    string    `bson:"userId" json:"-"`
+	ExpiresAt time.Time `bson:"expiresAt" json:"-"`
+	CreatedAt time.Time `bson:"createdAt" json:"-"`
+}
+
+// ForgotPasswordRequest struct of request to send a password reset link.
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest struct of request to set a new password with a reset token.
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required"`
+}
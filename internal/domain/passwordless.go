@@ -0,0 +1,36 @@
+package domain
+
+import "time"
+
+// PasswordlessChallenge struct of a login by emailed link or code, only hashes of the
+// link token, the code and the nonce of the requesting device are stored.
+type PasswordlessChallenge struct {
+	ID        string    `bson:"_id,omitempty"`
+	UserID    string    `bson:"userId"`
+	Email     string    `bson:"email"`
+	CodeHash  string    `bson:"codeHash"`
+	NonceHash string    `bson:"nonceHash"`
+	Attempts  int       `bson:"attempts"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+// PasswordlessRequest struct of request to receive a login link and code by email.
+type PasswordlessRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// PasswordlessLoginRequest struct of request to login with the token of the emailed
+// link or with the email and the emailed code.
+type PasswordlessLoginRequest struct {
+	Token string `json:"token" binding:"required_without=Code"`
+	Email string `json:"email" binding:"required_with=Code,omitempty,email"`
+	Code  string `json:"code" binding:"required_without=Token,omitempty,len=6,numeric"`
+}
+
+// RateLimit struct of the requests of a key in the current window.
+type RateLimit struct {
+	ID        string    `bson:"_id"`
+	Count     int       `bson:"count"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+}
@@ -2,18 +2,40 @@ package domain
 
 // APIResponse response endpoints.
 type APIResponse struct {
-	Success  bool          `json:"success"`
-	Errors   *Errors       `json:"errors,omitempty"`
-	ID       string        `json:"id,omitempty"`
-	Name     string        `json:"name,omitempty"`
-	Email    string        `json:"email,omitempty"`
-	UserName string        `json:"userName,omitempty"`
-	IDs      []interface{} `json:"ids,omitempty"`
+	Success       bool                  `json:"success"`
+	Errors        *Errors               `json:"errors,omitempty"`
+	ID            string                `json:"id,omitempty"`
+	Name          string                `json:"name,omitempty"`
+	Email         string                `json:"email,omitempty"`
+	PendingEmail  string                `json:"pendingEmail,omitempty"`
+	UserName      string                `json:"userName,omitempty"`
+	IDs           []interface{}         `json:"ids,omitempty"`
+	MFAToken      string                `json:"mfaToken,omitempty"`
+	Secret        string                `json:"secret,omitempty"`
+	OTPAuthURI    string                `json:"otpauthUri,omitempty"`
+	RecoveryCodes []string              `json:"recoveryCodes,omitempty"`
+	PublicKey     interface{}           `json:"publicKey,omitempty"`
+	SessionID     string                `json:"sessionId,omitempty"`
+	SessionToken  string                `json:"sessionToken,omitempty"`
+	Sessions      []Session             `json:"sessions,omitempty"`
+	RedirectURI   string                `json:"redirectUri,omitempty"`
+	Consent       *OAuthConsentPrompt   `json:"consent,omitempty"`
+	Client        *OAuthClient          `json:"client,omitempty"`
+	APIKey        *APIKey               `json:"apiKey,omitempty"`
+	APIKeys       []APIKey              `json:"apiKeys,omitempty"`
+	Impersonation *ImpersonationClaims  `json:"impersonation,omitempty"`
+	AuditEvents   []AuditEvent          `json:"auditEvents,omitempty"`
+	NextCursor    string                `json:"nextCursor,omitempty"`
+	Webhook       *WebhookSubscription  `json:"webhook,omitempty"`
+	Webhooks      []WebhookSubscription `json:"webhooks,omitempty"`
+	Deliveries    []WebhookDelivery     `json:"deliveries,omitempty"`
+	Delivery      *WebhookDelivery      `json:"delivery,omitempty"`
 }
 
 // Errors handles errors in endpoints.
 type Errors struct {
-	Code    string `json:"code,omitempty"`
-	Message string `json:"message,omitempty"`
-	Details string `json:"details,omitempty"`
+	Code       string   `json:"code,omitempty"`
+	Message    string   `json:"message,omitempty"`
+	Details    string   `json:"details,omitempty"`
+	Violations []string `json:"violations,omitempty"`
 }
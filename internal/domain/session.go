@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// Session struct of a login of a user on a device, only the hash of the session token is stored.
+// ActorID is the staff member impersonating the user when the session was issued by impersonation.
+type Session struct {
+	ID         string    `bson:"_id" json:"id"`
+	TokenHash  string    `bson:"tokenHash" json:"-"`
+	UserID     string    `bson:"userId" json:"userId"`
+	ActorID    string    `bson:"actorId,omitempty" json:"actorId,omitempty"`
+	Device     string    `bson:"device" json:"device"`
+	UserAgent  string    `bson:"userAgent" json:"userAgent"`
+	IP         string    `bson:"ip" json:"ip"`
+	CreatedAt  time.Time `bson:"createdAt" json:"createdAt"`
+	LastSeenAt time.Time `bson:"lastSeenAt" json:"lastSeenAt"`
+	ExpiresAt  time.Time `bson:"expiresAt" json:"expiresAt"`
+	Current    bool      `bson:"-" json:"current"`
+}
+
+// ClientInfo struct of the client that sends a login.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+// ImpersonationActor struct of the actor claim of an impersonation token as defined by RFC 8693.
+type ImpersonationActor struct {
+	Subject string `json:"sub"`
+}
+
+// ImpersonationClaims struct of the claims of an impersonation token, the subject is the
+// impersonated user and the actor the staff member acting as them.
+type ImpersonationClaims struct {
+	Subject   string             `json:"sub"`
+	Actor     ImpersonationActor `json:"act"`
+	ExpiresAt time.Time          `json:"exp"`
+}
@@ -2,15 +2,32 @@ package domain
 
 import "time"
 
-// User struct of user in BD.
+// User struct of user in BD, the audit tag redacts secret fields in the audit log or skips them.
 type User struct {
-	ID        string    `bson:"_id,omitempty"`
-	Name      string    `bson:"name" binding:"required" csv:"name" validate:"required"`
-	Email     string    `bson:"email" binding:"required,email" csv:"email" validate:"required,email"`
-	Enabled   bool      `bson:"enabled"`
-	Password  string    `bson:"password" binding:"required,min=8,password" csv:"password" validate:"required,min=8"`
-	UserName  string    `bson:"userName" binding:"required" csv:"username" validate:"required"`
-	CreatedAt time.Time `bson:"createdAt"`
-	UpdatedAt time.Time `bson:"updatedAt"`
-	DeletedAt time.Time `bson:"deletedAt"`
+	ID              string       `bson:"_id,omitempty" audit:"-"`
+	Name            string       `bson:"name" binding:"required" csv:"name" validate:"required"`
+	Email           string       `bson:"email" binding:"required,email" csv:"email" validate:"required,email"`
+	Enabled         bool         `bson:"enabled"`
+	Password        string       `bson:"password" binding:"required,password" csv:"password" validate:"required,min=8" audit:"redact"`
+	UserName        string       `bson:"userName" binding:"required" csv:"username" validate:"required"`
+	PasswordHistory []string     `bson:"passwordHistory,omitempty" json:"-" csv:"-" audit:"redact"`
+	EmailVerified   bool         `bson:"emailVerified" json:"-" csv:"-"`
+	PendingEmail    string       `bson:"pendingEmail,omitempty" json:"-" csv:"-"`
+	MFA             *MFASettings `bson:"mfa,omitempty" json:"-" csv:"-" audit:"redact"`
+	CreatedAt       time.Time    `bson:"createdAt" audit:"-"`
+	UpdatedAt       time.Time    `bson:"updatedAt" audit:"-"`
+	DeletedAt       time.Time    `bson:"deletedAt" audit:"-"`
+}
+
+// UpdateUserRequest struct of fields allowed in user update, the password is changed with ChangePasswordRequest.
+type UpdateUserRequest struct {
+	Name     string `json:"name" binding:"required"`
+	Email    string `json:"email" binding:"required,email"`
+	UserName string `json:"userName" binding:"required"`
+}
+
+// ChangePasswordRequest struct of change password request.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required"`
 }
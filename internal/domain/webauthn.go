@@ -0,0 +1,107 @@
+package domain
+
+import "time"
+
+// WebAuthnCredential struct of a passkey registered by a user, ID is the base64url
+// credential ID and PublicKey the COSE key of the authenticator.
+type WebAuthnCredential struct {
+	ID         string    `bson:"_id"`
+	UserID     string    `bson:"userId"`
+	PublicKey  []byte    `bson:"publicKey"`
+	Algorithm  int64     `bson:"algorithm"`
+	SignCount  uint32    `bson:"signCount"`
+	Format     string    `bson:"format"`
+	AAGUID     []byte    `bson:"aaguid,omitempty"`
+	CreatedAt  time.Time `bson:"createdAt"`
+	LastUsedAt time.Time `bson:"lastUsedAt,omitempty"`
+}
+
+// WebAuthnChallenge struct of a pending WebAuthn ceremony, only the hash of the challenge is stored.
+// UserID is empty in logins where the user is identified by the passkey.
+type WebAuthnChallenge struct {
+	ID        string    `bson:"_id,omitempty"`
+	UserID    string    `bson:"userId,omitempty"`
+	Ceremony  string    `bson:"ceremony"`
+	ExpiresAt time.Time `bson:"expiresAt"`
+	CreatedAt time.Time `bson:"createdAt"`
+}
+
+// WebAuthnRelyingParty struct of the relying party in credential creation options.
+type WebAuthnRelyingParty struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// WebAuthnUser struct of the user in credential creation options, ID is the base64url user handle.
+type WebAuthnUser struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// WebAuthnCredentialParameter struct of a credential type and COSE algorithm accepted by the relying party.
+type WebAuthnCredentialParameter struct {
+	Type      string `json:"type"`
+	Algorithm int64  `json:"alg"`
+}
+
+// WebAuthnCredentialDescriptor struct of a credential allowed or excluded in a ceremony.
+type WebAuthnCredentialDescriptor struct {
+	Type string `json:"type"`
+	ID   string `json:"id"`
+}
+
+// WebAuthnAuthenticatorSelection struct of the authenticator requirements in credential creation options.
+type WebAuthnAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// WebAuthnCreationOptions struct of the options passed to navigator.credentials.create,
+// binary values are base64url encoded.
+type WebAuthnCreationOptions struct {
+	Challenge              string                         `json:"challenge"`
+	RelyingParty           WebAuthnRelyingParty           `json:"rp"`
+	User                   WebAuthnUser                   `json:"user"`
+	CredentialParameters   []WebAuthnCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                          `json:"timeout"`
+	ExcludeCredentials     []WebAuthnCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection WebAuthnAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                         `json:"attestation"`
+}
+
+// WebAuthnRequestOptions struct of the options passed to navigator.credentials.get,
+// without allowed credentials any passkey of the relying party can be used.
+type WebAuthnRequestOptions struct {
+	Challenge        string                         `json:"challenge"`
+	Timeout          int64                          `json:"timeout"`
+	RPID             string                         `json:"rpId"`
+	AllowCredentials []WebAuthnCredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                         `json:"userVerification"`
+}
+
+// WebAuthnRegistrationResponse struct of the credential returned by navigator.credentials.create.
+type WebAuthnRegistrationResponse struct {
+	ID       string `json:"id" binding:"required"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AttestationObject string `json:"attestationObject" binding:"required"`
+	} `json:"response"`
+}
+
+// WebAuthnAssertionResponse struct of the credential returned by navigator.credentials.get.
+type WebAuthnAssertionResponse struct {
+	ID       string `json:"id" binding:"required"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON" binding:"required"`
+		AuthenticatorData string `json:"authenticatorData" binding:"required"`
+		Signature         string `json:"signature" binding:"required"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+// WebAuthnLoginRequest struct of request to begin a passkey login, login is optional
+// and restricts the allowed credentials to the ones of the user.
+type WebAuthnLoginRequest struct {
+	Login string `json:"login"`
+}
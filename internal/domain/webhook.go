@@ -0,0 +1,69 @@
+package domain
+
+import "time"
+
+// States of the delivery of an event to a webhook.
+const (
+	WebhookDeliveryPending    = "pending"
+	WebhookDeliverySucceeded  = "succeeded"
+	WebhookDeliveryDeadLetter = "dead_letter"
+)
+
+// WebhookEvents are the CloudEvent types a webhook can subscribe to.
+var WebhookEvents = []string{CloudEventUserCreated, CloudEventUserUpdated, CloudEventUserDeleted}
+
+// WebhookSubscription struct of an endpoint of a tenant receiving user events in BD, it receives
+// every event when Events is empty. Events are sent as CloudEvents in Mode, and the secret
+// signing the deliveries is stored encrypted.
+type WebhookSubscription struct {
+	ID        string    `bson:"_id" json:"id"`
+	Tenant    string    `bson:"tenant" json:"tenant"`
+	URL       string    `bson:"url" json:"url"`
+	Events    []string  `bson:"events" json:"events"`
+	Mode      string    `bson:"mode" json:"mode"`
+	Secret    string    `bson:"secret" json:"-"`
+	CreatedAt time.Time `bson:"createdAt" json:"createdAt"`
+}
+
+// WebhookSubscriptionRequest struct of request to register a webhook of the tenant of the caller,
+// events are sent in the structured mode when Mode is empty.
+type WebhookSubscriptionRequest struct {
+	URL    string   `json:"url" binding:"required,url,max=2048"`
+	Events []string `json:"events"`
+	Mode   string   `json:"mode" binding:"omitempty,oneof=structured binary"`
+}
+
+// WebhookDelivery struct of the delivery of an event to a webhook in BD. It is attempted again
+// at NextAttemptAt until the endpoint accepts it or it is moved to the dead letter state, History
+// keeps the results of the latest attempts.
+type WebhookDelivery struct {
+	ID             string           `bson:"_id" json:"id"`
+	SubscriptionID string           `bson:"subscriptionId" json:"subscriptionId"`
+	EventID        string           `bson:"eventId" json:"eventId"`
+	EventType      string           `bson:"eventType" json:"eventType"`
+	Payload        string           `bson:"payload" json:"payload"`
+	Status         string           `bson:"status" json:"status"`
+	Attempts       int              `bson:"attempts" json:"attempts"`
+	NextAttemptAt  time.Time        `bson:"nextAttemptAt" json:"nextAttemptAt"`
+	LastStatusCode int              `bson:"lastStatusCode,omitempty" json:"lastStatusCode,omitempty"`
+	LastError      string           `bson:"lastError,omitempty" json:"lastError,omitempty"`
+	History        []WebhookAttempt `bson:"history,omitempty" json:"history,omitempty"`
+	CreatedAt      time.Time        `bson:"createdAt" json:"createdAt"`
+	DeliveredAt    *time.Time       `bson:"deliveredAt,omitempty" json:"deliveredAt,omitempty"`
+}
+
+// WebhookAttempt struct of the result of an attempt of a delivery, StatusCode is empty when the
+// endpoint could not be reached.
+type WebhookAttempt struct {
+	Attempt     int       `bson:"attempt" json:"attempt"`
+	StatusCode  int       `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	Error       string    `bson:"error,omitempty" json:"error,omitempty"`
+	AttemptedAt time.Time `bson:"attemptedAt" json:"attemptedAt"`
+}
+
+// WebhookDeliveryQuery struct of query of a page of the deliveries of a webhook.
+type WebhookDeliveryQuery struct {
+	Limit  int64  `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"`
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded dead_letter"`
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// APIKeyHandlers encapsulates the personal API key HTTP handlers.
+type APIKeyHandlers struct {
+	APIKeyService *usecase.APIKeyService
+}
+
+// CreateAPIKey handles the creation of an API key of a user.
+// It expects a id param with user and a JSON body with the name, permissions and expiry and return the key, which is shown only once.
+func (h *APIKeyHandlers) CreateAPIKey(c *gin.Context) {
+	var request domain.APIKeyRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	key, secret, err := h.APIKeyService.CreateAPIKey(c.Request.Context(), c.Param("id"), &request)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidAPIKeyRequest) {
+			respondWithError(c, http.StatusBadRequest, constants.ErrInvalidAPIKeyRequest, err)
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToCreateAPIKey, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusCreated, domain.APIResponse{
+		Success: true,
+		ID:      key.ID,
+		Secret:  secret,
+		APIKey:  key,
+	})
+}
+
+// ListAPIKeys handles the listing of the API keys of a user.
+// It expects a id param with user and return the keys identified by their prefix.
+func (h *APIKeyHandlers) ListAPIKeys(c *gin.Context) {
+	id := c.Param("id")
+
+	keys, err := h.APIKeyService.ListAPIKeys(c.Request.Context(), id)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToListAPIKeys, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success: true,
+		ID:      id,
+		APIKeys: keys,
+	})
+}
+
+// RevokeAPIKey handles the revocation of an API key of a user.
+// It expects a id param with user and a kid param with key and return status no content.
+func (h *APIKeyHandlers) RevokeAPIKey(c *gin.Context) {
+	err := h.APIKeyService.RevokeAPIKey(c.Request.Context(), c.Param("id"), c.Param("kid"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrAPIKeyNotFound) {
+			respondWithError(c, http.StatusNotFound, constants.ErrAPIKeyNotFound, nil)
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToRevokeAPIKey, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
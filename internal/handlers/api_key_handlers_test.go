@@ -0,0 +1,354 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	apiKeysRoute = "/users/:id/api-keys"
+	apiKeySecret = "cnm_automation-key"
+)
+
+var automationKey = &domain.APIKey{
+	ID:          "key-1",
+	UserID:      "12345",
+	Name:        "CI",
+	Prefix:      "cnm_automati",
+	Permissions: []string{domain.PermissionSessionsRead},
+}
+
+func apiKeyConfigurations() (*mocks.SessionRepository, *mocks.APIKeyRepository, *gin.Engine) {
+	mockSessions := new(mocks.SessionRepository)
+	mockKeys := new(mocks.APIKeyRepository)
+
+	apiKeyService := usecase.NewAPIKeyService(mockKeys)
+	sessionHandler := handlers.SessionHandlers{
+		SessionService: usecase.NewSessionService(mockSessions, time.Hour),
+		APIKeyService:  apiKeyService,
+	}
+	handler := handlers.APIKeyHandlers{APIKeyService: apiKeyService}
+
+	mockSessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).Return(currentUserSession, nil)
+	mockSessions.On("TouchSession", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+
+	router := gin.Default()
+	authenticated := router.Group("", sessionHandler.RequireSession)
+	authenticated.GET(sessionsRoute, sessionHandler.RequireSameUser, sessionHandler.RequirePermission(domain.PermissionSessionsRead), sessionHandler.ListSessions)
+	authenticated.DELETE(sessionsRoute, sessionHandler.RequireSameUser, sessionHandler.RequirePermission(domain.PermissionSessionsWrite), sessionHandler.RevokeAllSessions)
+	authenticated.GET(apiKeysRoute, sessionHandler.RequireSameUser, sessionHandler.RequirePermission(domain.PermissionAPIKeysRead), handler.ListAPIKeys)
+
+	interactive := authenticated.Group("", sessionHandler.DenyAPIKey)
+	interactive.POST(apiKeysRoute, sessionHandler.RequireSameUser, handler.CreateAPIKey)
+	interactive.DELETE(apiKeysRoute+"/:kid", sessionHandler.RequireSameUser, handler.RevokeAPIKey)
+	interactive.POST("/auth/logout", sessionHandler.Logout)
+
+	return mockSessions, mockKeys, router
+}
+
+func TestAPIKeyAuthentication(t *testing.T) {
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		key           *domain.APIKey
+		errKey        error
+		statusCode    int
+	}{
+		{
+			name:          "should authenticate request with API key granted the permission",
+			method:        "GET",
+			path:          "/users/12345/sessions",
+			authorization: "ApiKey " + apiKeySecret,
+			key:           automationKey,
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:          "should return an error when API key is not granted the permission",
+			method:        "DELETE",
+			path:          "/users/12345/sessions",
+			authorization: "ApiKey " + apiKeySecret,
+			key:           automationKey,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when API key belongs to another user",
+			method:        "GET",
+			path:          "/users/67890/sessions",
+			authorization: "ApiKey " + apiKeySecret,
+			key:           automationKey,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when API key is used for a request made in person",
+			method:        "POST",
+			path:          "/auth/logout",
+			authorization: "ApiKey " + apiKeySecret,
+			key:           automationKey,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when API key is revoked or expired",
+			method:        "GET",
+			path:          "/users/12345/sessions",
+			authorization: "ApiKey " + apiKeySecret,
+			errKey:        mongo.ErrNoDocuments,
+			statusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:          "should return an error when API key does not have the prefix",
+			method:        "GET",
+			path:          "/users/12345/sessions",
+			authorization: "ApiKey " + sessionToken,
+			statusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:          "should return an error when bd return an error obtaining API key",
+			method:        "GET",
+			path:          "/users/12345/sessions",
+			authorization: "ApiKey " + apiKeySecret,
+			errKey:        errors.New(errorValue),
+			statusCode:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockSessions, mockKeys, router := apiKeyConfigurations()
+
+			mockKeys.On("TouchAPIKey", mock.Anything, utils.HashToken(apiKeySecret)).Return(test.key, test.errKey)
+			mockSessions.On("ListSessions", mock.Anything, "12345").Return([]domain.Session{*currentUserSession}, nil)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest(test.method, test.path, test.authorization))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusUnauthorized {
+				assert.Equal(t, "ApiKey", resp.Header().Get("WWW-Authenticate"))
+			}
+
+			if test.statusCode == http.StatusOK {
+				var response domain.APIResponse
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.False(t, response.Sessions[0].Current)
+			}
+		})
+	}
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	future := time.Now().Add(24 * time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	testCases := []struct {
+		name          string
+		authorization string
+		body          string
+		errRepo       error
+		statusCode    int
+	}{
+		{
+			name:          "should create API key and show it once",
+			authorization: "Bearer " + sessionToken,
+			body:          `{"name":"CI","permissions":["sessions:read","api_keys:read","sessions:read"],"expiresAt":"` + future.Format(time.RFC3339) + `"}`,
+			statusCode:    http.StatusCreated,
+		},
+		{
+			name:          "should return an error when permission is unknown",
+			authorization: "Bearer " + sessionToken,
+			body:          `{"name":"CI","permissions":["users:delete"]}`,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "should return an error when expiry is in the past",
+			authorization: "Bearer " + sessionToken,
+			body:          `{"name":"CI","permissions":["sessions:read"],"expiresAt":"` + past.Format(time.RFC3339) + `"}`,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "should return an error when permissions are missing",
+			authorization: "Bearer " + sessionToken,
+			body:          `{"name":"CI"}`,
+			statusCode:    http.StatusBadRequest,
+		},
+		{
+			name:          "should return an error when API key creates another API key",
+			authorization: "ApiKey " + apiKeySecret,
+			body:          `{"name":"CI","permissions":["sessions:read"]}`,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when bd return an error creating API key",
+			authorization: "Bearer " + sessionToken,
+			body:          `{"name":"CI","permissions":["sessions:read"]}`,
+			errRepo:       errors.New(errorValue),
+			statusCode:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, mockKeys, router := apiKeyConfigurations()
+
+			mockKeys.On("TouchAPIKey", mock.Anything, utils.HashToken(apiKeySecret)).Return(automationKey, nil)
+			mockKeys.On("CreateAPIKey", mock.Anything, mock.Anything).Return(test.errRepo)
+
+			req, _ := http.NewRequest("POST", "/users/12345/api-keys", bytes.NewBufferString(test.body))
+			req.Header.Set("Authorization", test.authorization)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode != http.StatusCreated {
+				if test.errRepo == nil {
+					mockKeys.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything)
+				}
+				return
+			}
+
+			var response domain.APIResponse
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.True(t, strings.HasPrefix(response.Secret, "cnm_"))
+			assert.Equal(t, response.Secret[:12], response.APIKey.Prefix)
+			assert.Equal(t, []string{"api_keys:read", "sessions:read"}, response.APIKey.Permissions)
+			assert.NotContains(t, resp.Body.String(), "keyHash")
+
+			stored := mockKeys.Calls[len(mockKeys.Calls)-1].Arguments.Get(1).(*domain.APIKey)
+			assert.Equal(t, utils.HashToken(response.Secret), stored.KeyHash)
+			assert.Equal(t, "12345", stored.UserID)
+			assert.WithinDuration(t, future, *stored.ExpiresAt, time.Second)
+		})
+	}
+}
+
+func TestListAPIKeys(t *testing.T) {
+	testCases := []struct {
+		name          string
+		authorization string
+		errRepo       error
+		statusCode    int
+	}{
+		{
+			name:          "should list API keys of the user of the session",
+			authorization: "Bearer " + sessionToken,
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:          "should return an error when API key is not granted the permission",
+			authorization: "ApiKey " + apiKeySecret,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when bd return an error listing API keys",
+			authorization: "Bearer " + sessionToken,
+			errRepo:       errors.New(errorValue),
+			statusCode:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, mockKeys, router := apiKeyConfigurations()
+
+			mockKeys.On("TouchAPIKey", mock.Anything, utils.HashToken(apiKeySecret)).Return(automationKey, nil)
+			mockKeys.On("ListAPIKeys", mock.Anything, "12345").Return([]domain.APIKey{*automationKey}, test.errRepo)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("GET", "/users/12345/api-keys", test.authorization))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusOK {
+				var response domain.APIResponse
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.Equal(t, "cnm_automati", response.APIKeys[0].Prefix)
+			}
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	testCases := []struct {
+		name       string
+		errRepo    error
+		statusCode int
+	}{
+		{
+			name:       "should revoke API key of the user",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "should return an error when API key does not exist",
+			errRepo:    mongo.ErrNoDocuments,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "should return an error when bd return an error revoking API key",
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, mockKeys, router := apiKeyConfigurations()
+
+			mockKeys.On("DeleteAPIKey", mock.Anything, "12345", "key-1").Return(test.errRepo)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("DELETE", "/users/12345/api-keys/key-1", "Bearer "+sessionToken))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			mockKeys.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteUserRevokesAPIKeys(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	mockKeys := new(mocks.APIKeyRepository)
+	mockAudit := new(mocks.AuditRepository)
+	mockTokenRevoker := new(oauthTokenRevokerMock)
+	mockAudit.On("RecordEvent", mock.Anything, mock.Anything).Return(nil)
+
+	userService := usecase.NewUserService(mockRepo, mockAudit, func(password, hash string) bool {
+		return password == hash
+	}).WithAPIKeyRevoker(usecase.NewAPIKeyService(mockKeys)).WithOAuthTokenRevoker(mockTokenRevoker)
+	handler := handlers.UserHandlers{UserService: userService}
+	router := gin.Default()
+	router.DELETE("/users/:id", handler.DeleteUser)
+
+	mockRepo.On("DeleteUser", mock.Anything, "12345").Return(nil)
+	mockKeys.On("DeleteUserAPIKeys", mock.Anything, "12345").Return(int64(2), nil)
+	mockTokenRevoker.On("RevokeUserTokens", mock.Anything, "12345").Return(nil)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, sessionRequest("DELETE", "/users/12345", ""))
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	mockKeys.AssertExpectations(t)
+	mockTokenRevoker.AssertExpectations(t)
+}
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// passwordlessCookie binds a passwordless login to the device that requested it.
+const passwordlessCookie = "passwordless_nonce"
+
+// AuthHandlers encapsulates the authentication HTTP handlers, SecureCookies
+// restricts the cookies set by the handlers to HTTPS.
+type AuthHandlers struct {
+	AuthService   *usecase.AuthService
+	SecureCookies bool
+}
+
+// Login handles the authentication of a user.
+// It expects a JSON body with login and password and return the authenticated user.
+func (h *AuthHandlers) Login(c *gin.Context) {
+	var credentials domain.Credentials
+
+	if err := c.ShouldBindJSON(&credentials); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	result, err := h.AuthService.Login(c.Request.Context(), &credentials, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidCredentials):
+			respondWithError(c, http.StatusUnauthorized, constants.ErrInvalidCredentials, nil)
+		case errors.Is(err, usecase.ErrLoginLocked):
+			respondWithLockout(c, err)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToLogin, err)
+		}
+		return
+	}
+
+	respondWithLogin(c, result)
+}
+
+// LoginMFA handles the second step of the login of a user with multi-factor authentication.
+// It expects a JSON body with the MFA token and a TOTP or recovery code and return the authenticated user.
+func (h *AuthHandlers) LoginMFA(c *gin.Context) {
+	var request domain.MFALoginRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	result, err := h.AuthService.VerifyMFA(c.Request.Context(), &request, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidMFAToken):
+			respondWithError(c, http.StatusUnauthorized, constants.ErrInvalidMFAToken, nil)
+		case errors.Is(err, usecase.ErrInvalidMFACode):
+			respondWithError(c, http.StatusUnauthorized, constants.ErrInvalidMFACode, nil)
+		case errors.Is(err, usecase.ErrLoginLocked):
+			respondWithLockout(c, err)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToVerifyMFA, err)
+		}
+		return
+	}
+
+	respondWithLogin(c, result)
+}
+
+// RequestPasswordless handles the request of a login link and code by email.
+// It expects a JSON body with email, sets the cookie binding the login to the device and return status accepted.
+func (h *AuthHandlers) RequestPasswordless(c *gin.Context) {
+	var request domain.PasswordlessRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	nonce, err := h.AuthService.RequestPasswordless(c.Request.Context(), request.Email, c.ClientIP())
+	if err != nil {
+		if errors.Is(err, usecase.ErrRateLimited) {
+			respondWithRateLimit(c, err)
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedPasswordlessLogin, err)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(passwordlessCookie, nonce, 0, "/auth/passwordless", "", h.SecureCookies, true)
+	c.Status(http.StatusAccepted)
+}
+
+// LoginPasswordless handles the authentication of a user with an emailed link or code.
+// It expects a JSON body with the link token or the email and code and the cookie set
+// when the login was requested, and return the authenticated user.
+func (h *AuthHandlers) LoginPasswordless(c *gin.Context) {
+	var request domain.PasswordlessLoginRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	nonce, _ := c.Cookie(passwordlessCookie)
+
+	result, err := h.AuthService.LoginPasswordless(c.Request.Context(), &request, nonce, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidPasswordlessLogin):
+			respondWithError(c, http.StatusUnauthorized, constants.ErrInvalidPasswordlessLogin, nil)
+		case errors.Is(err, usecase.ErrLoginLocked):
+			respondWithLockout(c, err)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedPasswordlessLogin, err)
+		}
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(passwordlessCookie, "", -1, "/auth/passwordless", "", h.SecureCookies, true)
+	respondWithLogin(c, result)
+}
+
+// UnlockUser handles the removal of the login lockout of a user by an administrator.
+// It expects a id param with user and return status no content.
+func (h *AuthHandlers) UnlockUser(c *gin.Context) {
+	id := c.Param("id")
+
+	err := h.AuthService.UnlockUser(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			respondWithError(c, http.StatusNotFound, constants.ErrUserNotFound, nil)
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToUnlockUser, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func respondWithLogin(c *gin.Context, result *domain.LoginResult) {
+	if result.MFAToken != "" {
+		respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+			Success:  true,
+			MFAToken: result.MFAToken,
+		})
+		return
+	}
+
+	response := domain.APIResponse{
+		Success:  true,
+		ID:       result.User.ID,
+		Name:     result.User.Name,
+		Email:    result.User.Email,
+		UserName: result.User.UserName,
+	}
+
+	if result.Session != nil {
+		response.SessionID = result.Session.ID
+		response.SessionToken = result.SessionToken
+	}
+
+	respondWithSuccess(c, http.StatusOK, response)
+}
+
+// clientInfo returns the source IP and user agent of the request.
+func clientInfo(c *gin.Context) domain.ClientInfo {
+	return domain.ClientInfo{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+}
+
+// respondWithLockout answers a locked login with the seconds to wait in Retry-After.
+func respondWithLockout(c *gin.Context, err error) {
+	var lockoutErr *usecase.LockoutError
+	if errors.As(err, &lockoutErr) {
+		setRetryAfter(c, lockoutErr.RetryAfter)
+	}
+
+	respondWithError(c, http.StatusTooManyRequests, constants.ErrLoginLocked, nil)
+}
+
+// respondWithRateLimit responds too many requests with the seconds to wait of a RateLimitError.
+func respondWithRateLimit(c *gin.Context, err error) {
+	var rateLimitErr *usecase.RateLimitError
+	if errors.As(err, &rateLimitErr) {
+		setRetryAfter(c, rateLimitErr.RetryAfter)
+	}
+
+	respondWithError(c, http.StatusTooManyRequests, constants.ErrRateLimited, nil)
+}
+
+// setRetryAfter sets the seconds to wait before retrying a request.
+func setRetryAfter(c *gin.Context, retryAfter time.Duration) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+}
@@ -0,0 +1,608 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const loginRoute = "/auth/login"
+
+type valuesLoginTestCases struct {
+	name        string
+	body        *domain.Credentials
+	isErrorBody bool
+	user        *domain.User
+	err         error
+	match       bool
+	newHash     string
+	errRehash   error
+	statusCode  int
+}
+
+var credentials = &domain.Credentials{
+	Login:    "cristian",
+	Password: "Test123*",
+}
+
+var storedUser = &domain.User{
+	ID:       "12345",
+	Name:     "Cristian",
+	Email:    "cristian@gmail.com",
+	UserName: "cristian",
+	Password: "$2a$10$hash",
+}
+
+func TestLogin(t *testing.T) {
+	testCases := []valuesLoginTestCases{
+		{
+			name:       "should login user",
+			body:       credentials,
+			user:       storedUser,
+			match:      true,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should login user and rehash outdated password",
+			body:       credentials,
+			user:       storedUser,
+			match:      true,
+			newHash:    "$argon2id$v=19$m=65536,t=3,p=4$salt$hash",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should login user when storing rehashed password fails",
+			body:       credentials,
+			user:       storedUser,
+			match:      true,
+			newHash:    "$argon2id$v=19$m=65536,t=3,p=4$salt$hash",
+			errRehash:  errors.New(errorValue),
+			statusCode: http.StatusOK,
+		},
+		{
+			name:        "should return an error when is an invalid body for login",
+			isErrorBody: true,
+			statusCode:  http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when user does not exist",
+			body:       credentials,
+			err:        mongo.ErrNoDocuments,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when password is different",
+			body:       credentials,
+			user:       storedUser,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when bd return an error on login",
+			body:       credentials,
+			err:        errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, handler, router := authConfigurations(func(password, hash string) (bool, string, error) {
+				return test.match, test.newHash, nil
+			})
+
+			router.POST(loginRoute, handler.Login)
+
+			bodyBytes, _ := json.Marshal(test.body)
+
+			mockRepo.On("GetUserByLogin", mock.Anything, credentials.Login).Return(test.user, test.err)
+			if test.newHash != "" {
+				mockRepo.On("UpdatePasswordHash", mock.Anything, storedUser.ID, test.newHash).Return(test.errRehash).Once()
+			}
+
+			req, _ := mockRequestEndPoint(test.isErrorBody, "POST", loginRoute, bytes.NewBuffer(bodyBytes))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if test.statusCode == http.StatusOK {
+				var response domain.APIResponse
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, storedUser.ID, response.ID)
+				mockRepo.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func authConfigurations(verifyPassword usecase.VerifyPasswordFunc) (*mocks.UserRepository, handlers.AuthHandlers, *gin.Engine) {
+	mockRepo := new(mocks.UserRepository)
+
+	authService := usecase.NewAuthService(mockRepo, verifyPassword)
+
+	handler := handlers.AuthHandlers{AuthService: authService}
+
+	router := gin.Default()
+
+	return mockRepo, handler, router
+}
+
+type valuesLockoutTestCases struct {
+	name          string
+	user          *domain.User
+	errUser       error
+	match         bool
+	ipAttempt     *domain.LoginAttempt
+	userAttempt   *domain.LoginAttempt
+	failures      int
+	lockouts      int
+	lockFor       time.Duration
+	statusCode    int
+	resetsAttempt bool
+}
+
+var lockoutPolicy = usecase.LockoutPolicy{
+	Threshold:    3,
+	IPThreshold:  10,
+	BaseDuration: time.Minute,
+	MaxDuration:  time.Hour,
+	Window:       15 * time.Minute,
+}
+
+func TestLoginLockout(t *testing.T) {
+	testCases := []valuesLockoutTestCases{
+		{
+			name:          "should login and reset failed logins of the account",
+			user:          storedUser,
+			match:         true,
+			statusCode:    http.StatusOK,
+			resetsAttempt: true,
+		},
+		{
+			name:       "should count failed login below the threshold",
+			user:       storedUser,
+			failures:   2,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should lock account when the threshold is reached",
+			user:       storedUser,
+			failures:   3,
+			lockFor:    time.Minute,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should double the lock of an account locked before",
+			user:       storedUser,
+			failures:   3,
+			lockouts:   2,
+			lockFor:    4 * time.Minute,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should cap the lock of an account to the max duration",
+			user:       storedUser,
+			failures:   3,
+			lockouts:   10,
+			lockFor:    time.Hour,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:        "should reject a locked account even with valid password",
+			user:        storedUser,
+			match:       true,
+			userAttempt: &domain.LoginAttempt{LockedUntil: time.Now().Add(time.Minute)},
+			statusCode:  http.StatusTooManyRequests,
+		},
+		{
+			name:       "should reject a locked source IP",
+			ipAttempt:  &domain.LoginAttempt{LockedUntil: time.Now().Add(time.Minute)},
+			statusCode: http.StatusTooManyRequests,
+		},
+		{
+			name:       "should count failed login of source IP when user does not exist",
+			errUser:    mongo.ErrNoDocuments,
+			failures:   1,
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, handler, router := authConfigurations(func(password, hash string) (bool, string, error) {
+				return test.match, "", nil
+			})
+			mockAttempts := new(mocks.LoginAttemptRepository)
+			handler.AuthService.WithLockout(mockAttempts, lockoutPolicy)
+
+			router.POST(loginRoute, handler.Login)
+
+			mockRepo.On("GetUserByLogin", mock.Anything, credentials.Login).Return(test.user, test.errUser)
+			mockAttempts.On("GetLoginAttempt", mock.Anything, "ip:192.0.2.1").Return(attemptOrMissing(test.ipAttempt))
+			mockAttempts.On("GetLoginAttempt", mock.Anything, "user:12345").Return(attemptOrMissing(test.userAttempt))
+			mockAttempts.On("RecordFailedLogin", mock.Anything, "ip:192.0.2.1", mock.Anything).
+				Return(&domain.LoginAttempt{Failures: 1}, nil)
+			mockAttempts.On("RecordFailedLogin", mock.Anything, "user:12345", mock.Anything).
+				Return(&domain.LoginAttempt{Failures: test.failures, Lockouts: test.lockouts}, nil)
+			mockAttempts.On("LockLogin", mock.Anything, "user:12345", mock.MatchedBy(func(lockedUntil time.Time) bool {
+				return time.Until(lockedUntil).Round(time.Second) == test.lockFor
+			}), mock.Anything).Return(nil)
+			mockAttempts.On("ResetLoginAttempts", mock.Anything, "user:12345").Return(nil)
+
+			bodyBytes, _ := json.Marshal(credentials)
+
+			req, _ := http.NewRequest("POST", loginRoute, bytes.NewBuffer(bodyBytes))
+			req.RemoteAddr = "192.0.2.1:4321"
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusTooManyRequests {
+				assert.Equal(t, "60", resp.Header().Get("Retry-After"))
+				mockAttempts.AssertNotCalled(t, "RecordFailedLogin", mock.Anything, mock.Anything, mock.Anything)
+			}
+
+			if test.lockFor > 0 {
+				mockAttempts.AssertCalled(t, "LockLogin", mock.Anything, "user:12345", mock.Anything, mock.Anything)
+			} else {
+				mockAttempts.AssertNotCalled(t, "LockLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+
+			if test.resetsAttempt {
+				mockAttempts.AssertCalled(t, "ResetLoginAttempts", mock.Anything, "user:12345")
+			}
+		})
+	}
+}
+
+func TestUnlockUser(t *testing.T) {
+	testCases := []valuesLockoutTestCases{
+		{
+			name:       "should unlock user",
+			user:       storedUser,
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "should return an error when user does not exist",
+			errUser:    mongo.ErrNoDocuments,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "should return an error when bd return an error getting user",
+			errUser:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, handler, router := authConfigurations(nil)
+			mockAttempts := new(mocks.LoginAttemptRepository)
+			handler.AuthService.WithLockout(mockAttempts, lockoutPolicy)
+
+			router.POST("/admin/users/:id/unlock", handler.UnlockUser)
+
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(test.user, test.errUser)
+			mockAttempts.On("ResetLoginAttempts", mock.Anything, "user:12345").Return(nil)
+
+			req, _ := http.NewRequest("POST", "/admin/users/12345/unlock", nil)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusNoContent {
+				mockAttempts.AssertExpectations(t)
+			}
+		})
+	}
+}
+
+func attemptOrMissing(attempt *domain.LoginAttempt) (*domain.LoginAttempt, error) {
+	if attempt == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return attempt, nil
+}
+
+const passwordlessNonce = "device-nonce"
+
+var passwordlessPolicy = usecase.PasswordlessPolicy{
+	TTL:          10 * time.Minute,
+	AddressLimit: 5,
+	IPLimit:      20,
+	Window:       15 * time.Minute,
+}
+
+type valuesPasswordlessTestCases struct {
+	name          string
+	body          interface{}
+	user          *domain.User
+	errUser       error
+	count         int
+	errChallenge  error
+	challenge     *domain.PasswordlessChallenge
+	nonce         string
+	statusCode    int
+	sendsMessage  bool
+	countsFailure bool
+}
+
+// mailNotifier writes messages as JSON lines with their secrets, as a mail server receives them.
+type mailNotifier struct {
+	writer io.Writer
+}
+
+func (n mailNotifier) Send(_ context.Context, message *domain.Message) error {
+	return json.NewEncoder(n.writer).Encode(message)
+}
+
+func passwordlessConfigurations(buffer *bytes.Buffer) (*mocks.UserRepository, *mocks.PasswordlessRepository, *mocks.RateLimitRepository, *mocks.LoginAttemptRepository, handlers.AuthHandlers, *gin.Engine) {
+	mockRepo, handler, router := authConfigurations(nil)
+	mockPasswordless := new(mocks.PasswordlessRepository)
+	mockRateLimits := new(mocks.RateLimitRepository)
+	mockAttempts := new(mocks.LoginAttemptRepository)
+
+	handler.AuthService.WithLockout(mockAttempts, lockoutPolicy).
+		WithPasswordless(mockPasswordless, mockRateLimits, mailNotifier{writer: buffer}, "https://example.com/login", passwordlessPolicy)
+
+	return mockRepo, mockPasswordless, mockRateLimits, mockAttempts, handler, router
+}
+
+func TestRequestPasswordless(t *testing.T) {
+	testCases := []valuesPasswordlessTestCases{
+		{
+			name:         "should send login link and code",
+			body:         domain.PasswordlessRequest{Email: storedUser.Email},
+			user:         storedUser,
+			count:        1,
+			statusCode:   http.StatusAccepted,
+			sendsMessage: true,
+		},
+		{
+			name:         "should send login link and code when the case of the email differs",
+			body:         domain.PasswordlessRequest{Email: "Cristian@Gmail.com"},
+			user:         storedUser,
+			count:        1,
+			statusCode:   http.StatusAccepted,
+			sendsMessage: true,
+		},
+		{
+			name:       "should not send login link and code when user does not exist",
+			body:       domain.PasswordlessRequest{Email: storedUser.Email},
+			errUser:    mongo.ErrNoDocuments,
+			count:      1,
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:       "should return an error when the address requested too many logins",
+			body:       domain.PasswordlessRequest{Email: storedUser.Email},
+			user:       storedUser,
+			count:      6,
+			statusCode: http.StatusTooManyRequests,
+		},
+		{
+			name:       "should return an error when email is not valid",
+			body:       domain.PasswordlessRequest{Email: "cristian"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should return an error when bd return an error storing the login",
+			body:         domain.PasswordlessRequest{Email: storedUser.Email},
+			user:         storedUser,
+			count:        1,
+			errChallenge: errors.New(errorValue),
+			statusCode:   http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			mockRepo, mockPasswordless, mockRateLimits, _, handler, router := passwordlessConfigurations(&buffer)
+
+			router.POST("/auth/passwordless", handler.RequestPasswordless)
+
+			mockRateLimits.On("Hit", mock.Anything, "passwordless:ip:", passwordlessPolicy.Window).
+				Return(&domain.RateLimit{Count: 1, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+			mockRateLimits.On("Hit", mock.Anything, "passwordless:email:cristian@gmail.com", passwordlessPolicy.Window).
+				Return(&domain.RateLimit{Count: test.count, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+			mockRepo.On("GetUserByEmail", mock.Anything, storedUser.Email).Return(test.user, test.errUser)
+			mockPasswordless.On("CreateChallenge", mock.Anything, mock.Anything).Return(test.errChallenge)
+
+			bodyBytes, _ := json.Marshal(test.body)
+
+			req, _ := mockRequestEndPoint(false, "POST", "/auth/passwordless", bytes.NewBuffer(bodyBytes))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusTooManyRequests {
+				assert.Equal(t, "60", resp.Header().Get("Retry-After"))
+			}
+
+			if test.statusCode != http.StatusAccepted {
+				return
+			}
+
+			cookies := resp.Result().Cookies()
+			assert.Len(t, cookies, 1)
+			assert.True(t, cookies[0].HttpOnly)
+			assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+
+			if !test.sendsMessage {
+				assert.Empty(t, buffer.String())
+				mockPasswordless.AssertNotCalled(t, "CreateChallenge", mock.Anything, mock.Anything)
+				return
+			}
+
+			var message domain.Message
+			err := json.Unmarshal(buffer.Bytes(), &message)
+			assert.NoError(t, err)
+			assert.Equal(t, storedUser.Email, message.To)
+
+			code := regexp.MustCompile(`code is ([0-9]{6})`).FindStringSubmatch(message.Body)[1]
+			token := regexp.MustCompile(`token=([A-Za-z0-9_-]+)`).FindStringSubmatch(message.Body)[1]
+
+			challenge := mockPasswordless.Calls[0].Arguments.Get(1).(*domain.PasswordlessChallenge)
+			assert.Equal(t, utils.HashToken(token), challenge.ID)
+			assert.Equal(t, utils.HashToken(code), challenge.CodeHash)
+			assert.Equal(t, utils.HashToken(cookies[0].Value), challenge.NonceHash)
+			assert.Equal(t, storedUser.ID, challenge.UserID)
+		})
+	}
+}
+
+func TestLoginPasswordless(t *testing.T) {
+	linkChallenge := &domain.PasswordlessChallenge{UserID: storedUser.ID, Email: storedUser.Email}
+
+	testCases := []valuesPasswordlessTestCases{
+		{
+			name:       "should login with the link on the requesting device",
+			body:       domain.PasswordlessLoginRequest{Token: "link-token"},
+			user:       storedUser,
+			challenge:  linkChallenge,
+			nonce:      passwordlessNonce,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should login with the code on the requesting device",
+			body:       domain.PasswordlessLoginRequest{Email: storedUser.Email, Code: "123456"},
+			user:       storedUser,
+			challenge:  linkChallenge,
+			nonce:      passwordlessNonce,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when the cookie of the device is missing",
+			body:       domain.PasswordlessLoginRequest{Token: "link-token"},
+			user:       storedUser,
+			challenge:  linkChallenge,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when the link is used on other device",
+			body:       domain.PasswordlessLoginRequest{Token: "link-token"},
+			user:       storedUser,
+			challenge:  linkChallenge,
+			nonce:      "other-nonce",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when neither link nor code are sent",
+			body:       domain.PasswordlessRequest{Email: storedUser.Email},
+			nonce:      passwordlessNonce,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when the link is invalid or used",
+			body:       domain.PasswordlessLoginRequest{Token: "link-token"},
+			user:       storedUser,
+			nonce:      passwordlessNonce,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "should count a failed login when the code is wrong",
+			body:          domain.PasswordlessLoginRequest{Email: storedUser.Email, Code: "123456"},
+			user:          storedUser,
+			nonce:         passwordlessNonce,
+			statusCode:    http.StatusUnauthorized,
+			countsFailure: true,
+		},
+		{
+			name:       "should return an error when the code is not six digits",
+			body:       domain.PasswordlessLoginRequest{Email: storedUser.Email, Code: "12345a"},
+			nonce:      passwordlessNonce,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:         "should return an error when bd return an error consuming the link",
+			body:         domain.PasswordlessLoginRequest{Token: "link-token"},
+			user:         storedUser,
+			nonce:        passwordlessNonce,
+			errChallenge: errors.New(errorValue),
+			statusCode:   http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, mockPasswordless, _, mockAttempts, handler, router := passwordlessConfigurations(&bytes.Buffer{})
+
+			router.POST("/auth/passwordless/verify", handler.LoginPasswordless)
+
+			challenge, errChallenge := test.challenge, test.errChallenge
+			if challenge == nil && errChallenge == nil {
+				errChallenge = mongo.ErrNoDocuments
+			}
+
+			nonceHash := utils.HashToken(passwordlessNonce)
+			mockPasswordless.On("ConsumeLinkChallenge", mock.Anything, utils.HashToken("link-token"), nonceHash).Return(challenge, errChallenge)
+			mockPasswordless.On("ConsumeLinkChallenge", mock.Anything, mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+			mockPasswordless.On("ConsumeCodeChallenge", mock.Anything, storedUser.Email, utils.HashToken("123456"), nonceHash, 5).Return(challenge, errChallenge)
+			mockPasswordless.On("RecordFailedCode", mock.Anything, storedUser.Email, nonceHash).Return(nil)
+			mockRepo.On("GetUserByID", mock.Anything, storedUser.ID).Return(test.user, test.errUser)
+			mockRepo.On("GetUserByEmail", mock.Anything, storedUser.Email).Return(test.user, test.errUser)
+			mockAttempts.On("GetLoginAttempt", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+			mockAttempts.On("RecordFailedLogin", mock.Anything, mock.Anything, mock.Anything).Return(&domain.LoginAttempt{Failures: 1}, nil)
+			mockAttempts.On("ResetLoginAttempts", mock.Anything, "user:12345").Return(nil)
+
+			bodyBytes, _ := json.Marshal(test.body)
+
+			req, _ := mockRequestEndPoint(false, "POST", "/auth/passwordless/verify", bytes.NewBuffer(bodyBytes))
+			if test.nonce != "" {
+				req.AddCookie(&http.Cookie{Name: "passwordless_nonce", Value: test.nonce})
+			}
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusOK {
+				var response domain.APIResponse
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, storedUser.ID, response.ID)
+				assert.Equal(t, -1, resp.Result().Cookies()[0].MaxAge)
+				mockAttempts.AssertCalled(t, "ResetLoginAttempts", mock.Anything, "user:12345")
+			}
+
+			if test.countsFailure {
+				mockPasswordless.AssertCalled(t, "RecordFailedCode", mock.Anything, storedUser.Email, nonceHash)
+				mockAttempts.AssertCalled(t, "RecordFailedLogin", mock.Anything, "user:12345", mock.Anything)
+			} else {
+				mockAttempts.AssertNotCalled(t, "RecordFailedLogin", mock.Anything, "user:12345", mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MFAHandlers encapsulates the multi-factor authentication HTTP handlers.
+type MFAHandlers struct {
+	MFAService *usecase.MFAService
+}
+
+// EnrollTOTP handles the start of a TOTP enrollment of the user of the session.
+// It expects a id param with user and an optional JSON body with the current password, required to
+// replace a pending enrollment without a recent login, and return the secret and otpauth URI for
+// the authenticator app.
+func (h *MFAHandlers) EnrollTOTP(c *gin.Context) {
+	id := c.Param("id")
+
+	var request domain.EnrollMFARequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+			return
+		}
+	}
+
+	enrollment, err := h.MFAService.EnrollTOTP(c.Request.Context(), id, &request, currentSession(c).CreatedAt)
+	if err != nil {
+		respondWithMFAError(c, err, constants.ErrFailedToEnrollMFA)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:    true,
+		ID:         id,
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.OTPAuthURI,
+	})
+}
+
+// TOTPQRCode handles the QR code of a pending TOTP enrollment.
+// It expects a id param with user and return a PNG image.
+func (h *MFAHandlers) TOTPQRCode(c *gin.Context) {
+	id := c.Param("id")
+
+	image, err := h.MFAService.TOTPQRCode(c.Request.Context(), id)
+	if err != nil {
+		respondWithMFAError(c, err, constants.ErrFailedToEnrollMFA)
+		return
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", image)
+}
+
+// ConfirmTOTP handles the confirmation of a TOTP enrollment with a code of the authenticator app.
+// It expects a JSON body with the code and return the recovery codes.
+func (h *MFAHandlers) ConfirmTOTP(c *gin.Context) {
+	id := c.Param("id")
+
+	var request domain.ConfirmMFARequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	codes, err := h.MFAService.ConfirmTOTP(c.Request.Context(), id, request.Code)
+	if err != nil {
+		respondWithMFAError(c, err, constants.ErrFailedToEnrollMFA)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:       true,
+		ID:            id,
+		RecoveryCodes: codes,
+	})
+}
+
+func respondWithMFAError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, mongo.ErrNoDocuments):
+		respondWithError(c, http.StatusNotFound, constants.ErrUserNotFound, nil)
+	case errors.Is(err, usecase.ErrMFANotConfigured):
+		respondWithError(c, http.StatusServiceUnavailable, constants.ErrMFANotConfigured, nil)
+	case errors.Is(err, usecase.ErrMFAAlreadyEnabled):
+		respondWithError(c, http.StatusConflict, constants.ErrMFAAlreadyEnabled, nil)
+	case errors.Is(err, usecase.ErrMFAReauthRequired):
+		respondWithError(c, http.StatusForbidden, constants.ErrMFAReauthRequired, nil)
+	case errors.Is(err, usecase.ErrMFANotEnrolled):
+		respondWithError(c, http.StatusConflict, constants.ErrMFANotEnrolled, nil)
+	case errors.Is(err, usecase.ErrInvalidMFACode):
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidMFACode, nil)
+	default:
+		respondWithError(c, http.StatusInternalServerError, message, err)
+	}
+}
@@ -0,0 +1,455 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	mfaRoute   = "/users/:id/mfa/totp"
+	totpSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+)
+
+type valuesMFATestCases struct {
+	name        string
+	user        *domain.User
+	err         error
+	errRepo     error
+	code        string
+	isErrorBody bool
+	noSecretBox bool
+	statusCode  int
+}
+
+func mfaConfigurations(t *testing.T, withSecretBox bool) (*mocks.UserRepository, *mocks.MFARepository, *mocks.MFAChallengeRepository, *utils.SecretBox, *usecase.MFAService) {
+	mockRepo := new(mocks.UserRepository)
+	mockMFA := new(mocks.MFARepository)
+	mockChallenges := new(mocks.MFAChallengeRepository)
+
+	var secretBox *utils.SecretBox
+	if withSecretBox {
+		var err error
+		secretBox, err = utils.NewSecretBox("v1", map[string][]byte{"v1": []byte("mfa-secret")})
+		assert.NoError(t, err)
+	}
+
+	mfaService := usecase.NewMFAService(mockRepo, mockMFA, mockChallenges, secretBox, "cnm", time.Minute)
+
+	return mockRepo, mockMFA, mockChallenges, secretBox, mfaService
+}
+
+func pendingMFAUser(t *testing.T, secretBox *utils.SecretBox) *domain.User {
+	encrypted, err := secretBox.Encrypt(totpSecret)
+	assert.NoError(t, err)
+
+	return &domain.User{ID: "12345", Email: "cristian@gmail.com", MFA: &domain.MFASettings{PendingSecret: encrypted}}
+}
+
+func currentTOTPCode(t *testing.T) string {
+	code, err := utils.TOTPCode(totpSecret, utils.TOTPStep(time.Now()))
+	assert.NoError(t, err)
+
+	return code
+}
+
+type valuesEnrollTOTPTestCases struct {
+	name            string
+	user            *domain.User
+	err             error
+	errRepo         error
+	noSecretBox     bool
+	pending         bool
+	currentPassword string
+	loggedInAt      time.Time
+	authorization   string
+	statusCode      int
+}
+
+// mfaSessionHandlers returns session handlers where sessionToken is a session of the user 12345
+// logged in at loggedInAt and adminToken a session of another user.
+func mfaSessionHandlers(loggedInAt time.Time) handlers.SessionHandlers {
+	mockSessions := new(mocks.SessionRepository)
+	mockSessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).
+		Return(&domain.Session{ID: "current", UserID: "12345", CreatedAt: loggedInAt}, nil)
+	mockSessions.On("TouchSession", mock.Anything, utils.HashToken(adminToken)).Return(adminSession, nil)
+	mockSessions.On("TouchSession", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+
+	return handlers.SessionHandlers{SessionService: usecase.NewSessionService(mockSessions, time.Hour)}
+}
+
+func TestEnrollTOTP(t *testing.T) {
+	testCases := []valuesEnrollTOTPTestCases{
+		{
+			name:          "should start TOTP enrollment",
+			authorization: "Bearer " + sessionToken,
+			user:          &domain.User{ID: "12345", Email: "cristian@gmail.com"},
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:          "should return an error when user does not exist",
+			authorization: "Bearer " + sessionToken,
+			err:           mongo.ErrNoDocuments,
+			statusCode:    http.StatusNotFound,
+		},
+		{
+			name:          "should return an error when MFA is already enabled",
+			authorization: "Bearer " + sessionToken,
+			user:          &domain.User{ID: "12345", MFA: &domain.MFASettings{Enabled: true}},
+			statusCode:    http.StatusConflict,
+		},
+		{
+			name:          "should return an error when encryption key is not configured",
+			authorization: "Bearer " + sessionToken,
+			noSecretBox:   true,
+			statusCode:    http.StatusServiceUnavailable,
+		},
+		{
+			name:          "should return an error when bd return an error storing secret",
+			authorization: "Bearer " + sessionToken,
+			user:          &domain.User{ID: "12345", Email: "cristian@gmail.com"},
+			errRepo:       errors.New(errorValue),
+			statusCode:    http.StatusInternalServerError,
+		},
+		{
+			name:          "should replace a pending enrollment after a recent login",
+			authorization: "Bearer " + sessionToken,
+			pending:       true,
+			loggedInAt:    time.Now(),
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:            "should replace a pending enrollment with the current password",
+			authorization:   "Bearer " + sessionToken,
+			pending:         true,
+			currentPassword: "Current123*",
+			statusCode:      http.StatusOK,
+		},
+		{
+			name:          "should return an error replacing a pending enrollment without reauthentication",
+			authorization: "Bearer " + sessionToken,
+			pending:       true,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:            "should return an error replacing a pending enrollment with a wrong password",
+			authorization:   "Bearer " + sessionToken,
+			pending:         true,
+			currentPassword: "Wrong123*",
+			statusCode:      http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when session belongs to another user",
+			user:          &domain.User{ID: "12345", Email: "cristian@gmail.com"},
+			authorization: "Bearer " + adminToken,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:       "should return an error when request is not authenticated",
+			user:       &domain.User{ID: "12345", Email: "cristian@gmail.com"},
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, mockMFA, _, secretBox, mfaService := mfaConfigurations(t, !test.noSecretBox)
+			mfaService.WithReauthentication(func(password, hash string) bool {
+				return password == hash
+			}, time.Minute)
+			handler := handlers.MFAHandlers{MFAService: mfaService}
+			sessionHandler := mfaSessionHandlers(test.loggedInAt)
+			router := gin.Default()
+
+			router.POST(mfaRoute, sessionHandler.RequireSession, sessionHandler.RequireSameUser, handler.EnrollTOTP)
+
+			user := test.user
+			if test.pending {
+				user = pendingMFAUser(t, secretBox)
+				user.Password = "Current123*"
+			}
+
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(user, test.err)
+			mockMFA.On("SetPendingTOTPSecret", mock.Anything, "12345", mock.Anything).Return(test.errRepo)
+
+			req := sessionRequest("POST", "/users/12345/mfa/totp", test.authorization)
+			if test.currentPassword != "" {
+				bodyBytes, _ := json.Marshal(domain.EnrollMFARequest{CurrentPassword: test.currentPassword})
+				req, _ = http.NewRequest("POST", "/users/12345/mfa/totp", bytes.NewBuffer(bodyBytes))
+				req.Header.Set("Authorization", test.authorization)
+			}
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusOK {
+				var response domain.APIResponse
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Contains(t, response.OTPAuthURI, "secret="+response.Secret)
+
+				stored := mockMFA.Calls[0].Arguments.String(2)
+				assert.NotContains(t, stored, response.Secret)
+
+				decrypted, err := secretBox.Decrypt(stored)
+				assert.NoError(t, err)
+				assert.Equal(t, response.Secret, decrypted)
+			}
+
+			if test.statusCode == http.StatusForbidden || test.statusCode == http.StatusUnauthorized {
+				mockMFA.AssertNotCalled(t, "SetPendingTOTPSecret", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestTOTPQRCode(t *testing.T) {
+	_, _, _, secretBox, _ := mfaConfigurations(t, true)
+
+	testCases := []valuesMFATestCases{
+		{
+			name:       "should return QR code of pending enrollment",
+			user:       pendingMFAUser(t, secretBox),
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when enrollment was not started",
+			user:       &domain.User{ID: "12345"},
+			statusCode: http.StatusConflict,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, _, _, _, mfaService := mfaConfigurations(t, true)
+			handler := handlers.MFAHandlers{MFAService: mfaService}
+			router := gin.Default()
+
+			router.GET(mfaRoute+"/qr", handler.TOTPQRCode)
+
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(test.user, test.err)
+
+			req, _ := http.NewRequest("GET", "/users/12345/mfa/totp/qr", nil)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusOK {
+				assert.Equal(t, "image/png", resp.Header().Get("Content-Type"))
+				assert.True(t, bytes.HasPrefix(resp.Body.Bytes(), []byte("\x89PNG")))
+			}
+		})
+	}
+}
+
+func TestConfirmTOTP(t *testing.T) {
+	testCases := []valuesMFATestCases{
+		{
+			name:       "should enable TOTP and return recovery codes",
+			code:       "current",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when code is not valid",
+			code:       "000000",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:        "should return an error when is an invalid body for confirm TOTP",
+			isErrorBody: true,
+			statusCode:  http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when enrollment changed while confirming",
+			code:       "current",
+			errRepo:    mongo.ErrNoDocuments,
+			statusCode: http.StatusConflict,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, mockMFA, _, secretBox, mfaService := mfaConfigurations(t, true)
+			handler := handlers.MFAHandlers{MFAService: mfaService}
+			router := gin.Default()
+			user := pendingMFAUser(t, secretBox)
+
+			router.POST(mfaRoute+"/confirm", handler.ConfirmTOTP)
+
+			code := test.code
+			if code == "current" {
+				code = currentTOTPCode(t)
+			}
+
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(user, nil)
+			mockMFA.On("EnableTOTP", mock.Anything, "12345", user.MFA.PendingSecret, mock.MatchedBy(func(hashes []string) bool {
+				return len(hashes) == 10
+			}), mock.Anything).Return(test.errRepo)
+
+			bodyBytes, _ := json.Marshal(domain.ConfirmMFARequest{Code: code})
+
+			req, _ := mockRequestEndPoint(test.isErrorBody, "POST", "/users/12345/mfa/totp/confirm", bytes.NewBuffer(bodyBytes))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusOK {
+				var response domain.APIResponse
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Len(t, response.RecoveryCodes, 10)
+
+				hashes := mockMFA.Calls[0].Arguments.Get(3).([]string)
+				assert.Equal(t, utils.HashRecoveryCode(response.RecoveryCodes[0]), hashes[0])
+			}
+		})
+	}
+}
+
+func TestLoginMFA(t *testing.T) {
+	testCases := []valuesMFATestCases{
+		{
+			name:       "should complete login with TOTP code",
+			code:       "current",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should complete login with recovery code",
+			code:       "abcde-fghij",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when TOTP code was already used",
+			code:       "current",
+			errRepo:    mongo.ErrNoDocuments,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when recovery code was already used",
+			code:       "abcde-fghij",
+			errRepo:    mongo.ErrNoDocuments,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when TOTP code is not valid",
+			code:       "000000",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when MFA token is invalid, expired or out of attempts",
+			code:       "current",
+			err:        mongo.ErrNoDocuments,
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:        "should return an error when is an invalid body for MFA login",
+			isErrorBody: true,
+			statusCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, mockMFA, mockChallenges, secretBox, mfaService := mfaConfigurations(t, true)
+			handler := handlers.AuthHandlers{AuthService: usecase.NewAuthService(mockRepo, nil).WithMFA(mfaService)}
+			router := gin.Default()
+
+			router.POST(loginRoute+"/mfa", handler.LoginMFA)
+
+			secret, err := secretBox.Encrypt(totpSecret)
+			assert.NoError(t, err)
+			user := &domain.User{ID: "12345", UserName: "cristian", MFA: &domain.MFASettings{Enabled: true, Secret: secret}}
+
+			code := test.code
+			if code == "current" {
+				code = currentTOTPCode(t)
+			}
+
+			mockChallenges.On("AttemptChallenge", mock.Anything, utils.HashToken("mfa-token"), 5).
+				Return(&domain.MFAChallenge{UserID: "12345", Attempts: 1}, test.err)
+			mockChallenges.On("ConsumeChallenge", mock.Anything, utils.HashToken("mfa-token")).
+				Return(&domain.MFAChallenge{UserID: "12345", Attempts: 1}, nil)
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(user, nil)
+			mockMFA.On("UseTOTPStep", mock.Anything, "12345", mock.Anything).Return(test.errRepo)
+			mockMFA.On("UseRecoveryCode", mock.Anything, "12345", utils.HashRecoveryCode("abcde-fghij")).Return(test.errRepo)
+
+			bodyBytes, _ := json.Marshal(domain.MFALoginRequest{MFAToken: "mfa-token", Code: code})
+
+			req, _ := mockRequestEndPoint(test.isErrorBody, "POST", loginRoute+"/mfa", bytes.NewBuffer(bodyBytes))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusOK {
+				var response domain.APIResponse
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, "12345", response.ID)
+				mockChallenges.AssertCalled(t, "ConsumeChallenge", mock.Anything, utils.HashToken("mfa-token"))
+			} else {
+				mockChallenges.AssertNotCalled(t, "ConsumeChallenge", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestLoginRequiresMFA(t *testing.T) {
+	mockRepo, _, mockChallenges, _, mfaService := mfaConfigurations(t, true)
+	handler := handlers.AuthHandlers{AuthService: usecase.NewAuthService(mockRepo, func(password, hash string) (bool, string, error) {
+		return true, "", nil
+	}).WithMFA(mfaService)}
+	router := gin.Default()
+
+	router.POST(loginRoute, handler.Login)
+
+	mockRepo.On("GetUserByLogin", mock.Anything, credentials.Login).
+		Return(&domain.User{ID: "12345", MFA: &domain.MFASettings{Enabled: true}}, nil)
+	mockChallenges.On("CreateChallenge", mock.Anything, mock.MatchedBy(func(challenge *domain.MFAChallenge) bool {
+		return challenge.UserID == "12345" && challenge.ExpiresAt.After(time.Now())
+	})).Return(nil)
+
+	bodyBytes, _ := json.Marshal(credentials)
+
+	req, _ := http.NewRequest("POST", loginRoute, bytes.NewBuffer(bodyBytes))
+
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response domain.APIResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Empty(t, response.ID)
+	assert.NotEmpty(t, response.MFAToken)
+
+	challenge := mockChallenges.Calls[0].Arguments.Get(1).(*domain.MFAChallenge)
+	assert.Equal(t, utils.HashToken(response.MFAToken), challenge.ID)
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// accessTokenKey is the key of the access token of the request in the gin context.
+const accessTokenKey = "accessToken"
+
+// OAuthHandlers encapsulates the HTTP handlers of the OAuth 2.0 authorization server.
+type OAuthHandlers struct {
+	OAuthService *usecase.OAuthService
+}
+
+// RegisterClient handles the registration of an OAuth client.
+// It expects a JSON body with the client and return the client ID and, for confidential clients, the secret.
+func (h *OAuthHandlers) RegisterClient(c *gin.Context) {
+	var request domain.OAuthClientRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	client, secret, err := h.OAuthService.RegisterClient(c.Request.Context(), &request)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidOAuthClient):
+			respondWithError(c, http.StatusBadRequest, constants.ErrInvalidOAuthClient, err)
+		case errors.Is(err, usecase.ErrInvalidRedirectURI):
+			respondWithError(c, http.StatusBadRequest, constants.ErrInvalidRedirectURI, err)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToRegisterClient, err)
+		}
+		return
+	}
+
+	respondWithSuccess(c, http.StatusCreated, domain.APIResponse{
+		Success: true,
+		ID:      client.ID,
+		Secret:  secret,
+		Client:  client,
+	})
+}
+
+// Authorize handles an authorization request of the user of the session, the consent page
+// forwards the query string the client sent. It return the redirect back to the client or
+// the consent the user must give first.
+func (h *OAuthHandlers) Authorize(c *gin.Context) {
+	var request domain.OAuthAuthorizeRequest
+
+	if err := c.ShouldBindQuery(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	authorization, err := h.OAuthService.Authorize(c.Request.Context(), currentSession(c).UserID, &request)
+	if err != nil {
+		respondWithAuthorizeError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:     true,
+		RedirectURI: authorization.RedirectURI,
+		Consent:     authorization.Consent,
+	})
+}
+
+// Consent handles the decision of the user of the session on an authorization request.
+// It expects a JSON body with the authorization request and approve and return the redirect back to the client.
+func (h *OAuthHandlers) Consent(c *gin.Context) {
+	var request domain.OAuthConsentRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	authorization, err := h.OAuthService.Consent(c.Request.Context(), currentSession(c).UserID, &request)
+	if err != nil {
+		respondWithAuthorizeError(c, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:     true,
+		RedirectURI: authorization.RedirectURI,
+	})
+}
+
+// Token handles the token endpoint of the authorization code and client credentials grants.
+// It expects a form encoded body and answers with the access token or error defined by RFC 6749.
+func (h *OAuthHandlers) Token(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+	c.Header("Pragma", "no-cache")
+
+	var request domain.OAuthTokenRequest
+
+	if err := c.ShouldBindWith(&request, binding.FormPost); err != nil {
+		respondWithOAuthError(c, &usecase.OAuthError{Code: usecase.OAuthErrInvalidRequest, Description: "grant_type is required"}, false)
+		return
+	}
+
+	basicAuth, err := clientBasicAuth(c, &request.ClientID, &request.ClientSecret)
+	if err != nil {
+		respondWithOAuthError(c, err, false)
+		return
+	}
+
+	response, err := h.OAuthService.Token(c.Request.Context(), &request)
+	if err != nil {
+		respondWithOAuthError(c, err, basicAuth)
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// Introspect handles the introspection endpoint of RFC 7662 for resource servers authenticated as confidential clients.
+// It expects a form encoded body with the token and return whether it is active and its claims.
+func (h *OAuthHandlers) Introspect(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	var request domain.OAuthTokenActionRequest
+
+	if err := c.ShouldBindWith(&request, binding.FormPost); err != nil {
+		respondWithOAuthError(c, &usecase.OAuthError{Code: usecase.OAuthErrInvalidRequest, Description: "token is required"}, false)
+		return
+	}
+
+	basicAuth, err := clientBasicAuth(c, &request.ClientID, &request.ClientSecret)
+	if err != nil {
+		respondWithOAuthError(c, err, false)
+		return
+	}
+
+	introspection, err := h.OAuthService.Introspect(c.Request.Context(), &request)
+	if err != nil {
+		respondWithOAuthError(c, err, basicAuth)
+		return
+	}
+
+	c.JSON(http.StatusOK, introspection)
+}
+
+// Revoke handles the revocation endpoint of RFC 7009 for the tokens issued to the client.
+// It expects a form encoded body with the token and answers with an empty body, also for invalid tokens.
+func (h *OAuthHandlers) Revoke(c *gin.Context) {
+	var request domain.OAuthTokenActionRequest
+
+	if err := c.ShouldBindWith(&request, binding.FormPost); err != nil {
+		respondWithOAuthError(c, &usecase.OAuthError{Code: usecase.OAuthErrInvalidRequest, Description: "token is required"}, false)
+		return
+	}
+
+	basicAuth, err := clientBasicAuth(c, &request.ClientID, &request.ClientSecret)
+	if err != nil {
+		respondWithOAuthError(c, err, false)
+		return
+	}
+
+	if err := h.OAuthService.Revoke(c.Request.Context(), &request); err != nil {
+		respondWithOAuthError(c, err, basicAuth)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// RequireAccessToken is a middleware that authenticates the request with the OAuth access token
+// sent as bearer token, expired and revoked tokens are rejected. The token must have been
+// granted the scope when it is not empty.
+func (h *OAuthHandlers) RequireAccessToken(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		accessToken, found := bearerToken(c)
+		if !found || accessToken == "" {
+			c.Header("WWW-Authenticate", "Bearer")
+			c.JSON(http.StatusUnauthorized, domain.OAuthErrorResponse{Error: usecase.OAuthErrInvalidRequest, ErrorDescription: constants.ErrInvalidAccessToken})
+			c.Abort()
+			return
+		}
+
+		token, err := h.OAuthService.ValidateAccessToken(c.Request.Context(), accessToken)
+		if err != nil {
+			if errors.Is(err, usecase.ErrInvalidAccessToken) {
+				respondWithBearerError(c, http.StatusUnauthorized, "invalid_token", constants.ErrInvalidAccessToken, "")
+			} else {
+				respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToValidateToken, err)
+			}
+			c.Abort()
+			return
+		}
+
+		if scope != "" && !slices.Contains(token.Scopes, scope) {
+			respondWithBearerError(c, http.StatusForbidden, "insufficient_scope", constants.ErrInsufficientScope, scope)
+			c.Abort()
+			return
+		}
+
+		c.Set(accessTokenKey, token)
+		c.Next()
+	}
+}
+
+// clientBasicAuth reads the client credentials sent with HTTP Basic authentication into the
+// client ID and secret of the request, they are form encoded before base64 as required by RFC 6749.
+func clientBasicAuth(c *gin.Context, requestClientID *string, requestSecret *string) (bool, error) {
+	username, password, ok := c.Request.BasicAuth()
+	if !ok {
+		return false, nil
+	}
+
+	clientID, errID := url.QueryUnescape(username)
+	secret, errSecret := url.QueryUnescape(password)
+	if errID != nil || errSecret != nil {
+		return true, &usecase.OAuthError{Code: usecase.OAuthErrInvalidClient, Description: "client authentication failed"}
+	}
+
+	if *requestSecret != "" || (*requestClientID != "" && *requestClientID != clientID) {
+		return true, &usecase.OAuthError{Code: usecase.OAuthErrInvalidRequest, Description: "only one client authentication method can be used"}
+	}
+
+	*requestClientID = clientID
+	*requestSecret = secret
+
+	return true, nil
+}
+
+// respondWithAuthorizeError answers authorization requests that can not be redirected back to the client.
+func respondWithAuthorizeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, usecase.ErrOAuthClientNotFound):
+		respondWithError(c, http.StatusBadRequest, constants.ErrOAuthClientNotFound, nil)
+	case errors.Is(err, usecase.ErrInvalidRedirectURI):
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidRedirectURI, nil)
+	default:
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToAuthorize, err)
+	}
+}
+
+// respondWithOAuthError answers the token endpoint with the error format of RFC 6749, failed
+// client authentication asks for HTTP Basic when the client used it.
+func respondWithOAuthError(c *gin.Context, err error, basicAuth bool) {
+	var oauthErr *usecase.OAuthError
+	if !errors.As(err, &oauthErr) {
+		c.JSON(http.StatusInternalServerError, domain.OAuthErrorResponse{
+			Error:            "server_error",
+			ErrorDescription: constants.ErrFailedToAuthorize,
+		})
+		return
+	}
+
+	status := http.StatusBadRequest
+	if oauthErr.Code == usecase.OAuthErrInvalidClient {
+		status = http.StatusUnauthorized
+		if basicAuth {
+			c.Header("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+	}
+
+	c.JSON(status, domain.OAuthErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
+
+// respondWithBearerError answers a request with a rejected access token with the error of RFC 6750,
+// insufficient_scope errors name the required scope.
+func respondWithBearerError(c *gin.Context, status int, code string, description string, scope string) {
+	challenge := fmt.Sprintf("Bearer error=%q", code)
+	if scope != "" {
+		challenge += fmt.Sprintf(", scope=%q", scope)
+	}
+
+	c.Header("WWW-Authenticate", challenge)
+	c.JSON(status, domain.OAuthErrorResponse{Error: code, ErrorDescription: description})
+}
+
+// currentAccessToken returns the access token set by RequireAccessToken.
+func currentAccessToken(c *gin.Context) *domain.OAuthAccessToken {
+	return c.MustGet(accessTokenKey).(*domain.OAuthAccessToken)
+}
@@ -0,0 +1,837 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	oauthRedirectURI  = "https://app.example.com/callback"
+	oauthClientSecret = "client-secret"
+	oauthVerifier     = "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+)
+
+var publicClient = &domain.OAuthClient{
+	ID:           "spa",
+	Name:         "Single page app",
+	Public:       true,
+	RedirectURIs: []string{oauthRedirectURI},
+	GrantTypes:   []string{domain.OAuthGrantAuthorizationCode},
+	Scopes:       []string{"profile", "email"},
+}
+
+var confidentialClient = &domain.OAuthClient{
+	ID:           "backend",
+	Name:         "Backend",
+	SecretHash:   utils.HashToken(oauthClientSecret),
+	RedirectURIs: []string{oauthRedirectURI},
+	GrantTypes:   []string{domain.OAuthGrantAuthorizationCode, domain.OAuthGrantClientCredentials},
+	Scopes:       []string{"profile", "reports"},
+}
+
+type oauthMocks struct {
+	users    *mocks.UserRepository
+	clients  *mocks.OAuthClientRepository
+	consents *mocks.OAuthConsentRepository
+	codes    *mocks.OAuthCodeRepository
+	tokens   *mocks.OAuthTokenRepository
+	revoked  *mocks.RevokedTokenRepository
+}
+
+func oauthConfigurations() (*oauthMocks, *gin.Engine) {
+	repos := &oauthMocks{
+		users:    new(mocks.UserRepository),
+		clients:  new(mocks.OAuthClientRepository),
+		consents: new(mocks.OAuthConsentRepository),
+		codes:    new(mocks.OAuthCodeRepository),
+		tokens:   new(mocks.OAuthTokenRepository),
+		revoked:  new(mocks.RevokedTokenRepository),
+	}
+
+	oauthService := usecase.NewOAuthService(repos.users, repos.clients, repos.consents, repos.codes, repos.tokens, repos.revoked, time.Minute, time.Hour)
+	handler := handlers.OAuthHandlers{OAuthService: oauthService}
+
+	mockSessions, sessionHandler, router := sessionConfigurations()
+	mockSessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).Return(currentUserSession, nil)
+
+	repos.clients.On("GetClient", mock.Anything, publicClient.ID).Return(publicClient, nil)
+	repos.clients.On("GetClient", mock.Anything, confidentialClient.ID).Return(confidentialClient, nil)
+	repos.clients.On("GetClient", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+
+	authenticated := router.Group("", sessionHandler.RequireSession)
+	authenticated.GET("/oauth/authorize", handler.Authorize)
+	authenticated.POST("/oauth/authorize", handler.Consent)
+	router.POST("/oauth/token", handler.Token)
+	router.POST("/oauth/introspect", handler.Introspect)
+	router.POST("/oauth/revoke", handler.Revoke)
+
+	adminHandler := adminConfigurations()
+	router.POST("/admin/oauth/clients", adminHandler.RequireSession, adminHandler.RequireAdmin, handler.RegisterClient)
+
+	return repos, router
+}
+
+func authorizeQuery(values map[string]string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {publicClient.ID},
+		"redirect_uri":          {oauthRedirectURI},
+		"scope":                 {"profile"},
+		"state":                 {"xyz"},
+		"code_challenge":        {utils.PKCEChallenge(oauthVerifier)},
+		"code_challenge_method": {utils.PKCEMethodS256},
+	}
+
+	for key, value := range values {
+		if value == "" {
+			query.Del(key)
+		} else {
+			query.Set(key, value)
+		}
+	}
+
+	return query.Encode()
+}
+
+type valuesAuthorizeTestCases struct {
+	name       string
+	query      map[string]string
+	consent    *domain.OAuthConsent
+	statusCode int
+	redirect   map[string]string
+	prompt     bool
+}
+
+func TestAuthorize(t *testing.T) {
+	testCases := []valuesAuthorizeTestCases{
+		{
+			name:       "should ask consent when user did not grant the scopes",
+			statusCode: http.StatusOK,
+			prompt:     true,
+		},
+		{
+			name:       "should ask consent when user granted other scopes",
+			consent:    &domain.OAuthConsent{Scopes: []string{"email"}},
+			statusCode: http.StatusOK,
+			prompt:     true,
+		},
+		{
+			name:       "should redirect with code when user already granted the scopes",
+			consent:    &domain.OAuthConsent{Scopes: []string{"email", "profile"}},
+			statusCode: http.StatusOK,
+			redirect:   map[string]string{"state": "xyz"},
+		},
+		{
+			name:       "should return an error when client does not exist",
+			query:      map[string]string{"client_id": "unknown"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when redirect uri is not registered",
+			query:      map[string]string{"redirect_uri": "https://evil.example.com/callback"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when client id is missing",
+			query:      map[string]string{"client_id": ""},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should redirect with error when code challenge is missing",
+			query:      map[string]string{"code_challenge": ""},
+			statusCode: http.StatusOK,
+			redirect:   map[string]string{"error": usecase.OAuthErrInvalidRequest, "state": "xyz"},
+		},
+		{
+			name:       "should redirect with error when code challenge method is plain",
+			query:      map[string]string{"code_challenge_method": "plain"},
+			statusCode: http.StatusOK,
+			redirect:   map[string]string{"error": usecase.OAuthErrInvalidRequest, "state": "xyz"},
+		},
+		{
+			name:       "should redirect with error when response type is not code",
+			query:      map[string]string{"response_type": "token"},
+			statusCode: http.StatusOK,
+			redirect:   map[string]string{"error": usecase.OAuthErrUnsupportedResponseType, "state": "xyz"},
+		},
+		{
+			name:       "should redirect with error when scope is not allowed",
+			query:      map[string]string{"scope": "admin"},
+			statusCode: http.StatusOK,
+			redirect:   map[string]string{"error": usecase.OAuthErrInvalidScope, "state": "xyz"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, router := oauthConfigurations()
+
+			consentErr := error(nil)
+			if test.consent == nil {
+				consentErr = mongo.ErrNoDocuments
+			}
+			repos.consents.On("GetConsent", mock.Anything, "12345", publicClient.ID).Return(test.consent, consentErr)
+			repos.codes.On("CreateCode", mock.Anything, mock.Anything).Return(nil)
+
+			req := sessionRequest("GET", "/oauth/authorize?"+authorizeQuery(test.query), "Bearer "+sessionToken)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if test.statusCode != http.StatusOK {
+				return
+			}
+
+			var response domain.APIResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			if test.prompt {
+				assert.Empty(t, response.RedirectURI)
+				assert.Equal(t, publicClient.Name, response.Consent.ClientName)
+				assert.Equal(t, []string{"profile"}, response.Consent.Scopes)
+				repos.codes.AssertNotCalled(t, "CreateCode", mock.Anything, mock.Anything)
+				return
+			}
+
+			redirect, err := url.Parse(response.RedirectURI)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(response.RedirectURI, oauthRedirectURI+"?"))
+			for key, value := range test.redirect {
+				assert.Equal(t, value, redirect.Query().Get(key))
+			}
+			if test.redirect["error"] == "" {
+				assert.NotEmpty(t, redirect.Query().Get("code"))
+				repos.codes.AssertNumberOfCalls(t, "CreateCode", 1)
+			} else {
+				repos.codes.AssertNotCalled(t, "CreateCode", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestAuthorizeRequiresSession(t *testing.T) {
+	_, router := oauthConfigurations()
+
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, sessionRequest("GET", "/oauth/authorize?"+authorizeQuery(nil), ""))
+
+	assert.Equal(t, http.StatusUnauthorized, resp.Code)
+}
+
+func TestConsent(t *testing.T) {
+	testCases := []struct {
+		name       string
+		approve    bool
+		errGrant   error
+		statusCode int
+	}{
+		{
+			name:       "should remember consent and redirect with code when user approves",
+			approve:    true,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should redirect with access denied when user denies",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when bd return an error storing consent",
+			approve:    true,
+			errGrant:   errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, router := oauthConfigurations()
+
+			repos.consents.On("GrantConsent", mock.Anything, "12345", publicClient.ID, []string{"profile"}).Return(test.errGrant)
+			repos.codes.On("CreateCode", mock.Anything, mock.MatchedBy(func(code *domain.OAuthAuthorizationCode) bool {
+				return code.UserID == "12345" && code.CodeChallenge == utils.PKCEChallenge(oauthVerifier)
+			})).Return(nil)
+
+			query, _ := url.ParseQuery(authorizeQuery(nil))
+			bodyBytes, _ := json.Marshal(domain.OAuthConsentRequest{
+				OAuthAuthorizeRequest: domain.OAuthAuthorizeRequest{
+					ResponseType:        query.Get("response_type"),
+					ClientID:            query.Get("client_id"),
+					RedirectURI:         query.Get("redirect_uri"),
+					Scope:               query.Get("scope"),
+					State:               query.Get("state"),
+					CodeChallenge:       query.Get("code_challenge"),
+					CodeChallengeMethod: query.Get("code_challenge_method"),
+				},
+				Approve: test.approve,
+			})
+
+			req, _ := http.NewRequest("POST", "/oauth/authorize", bytes.NewBuffer(bodyBytes))
+			req.Header.Set("Authorization", "Bearer "+sessionToken)
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if test.statusCode != http.StatusOK {
+				return
+			}
+
+			var response domain.APIResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			redirect, err := url.Parse(response.RedirectURI)
+			assert.NoError(t, err)
+			assert.Equal(t, "xyz", redirect.Query().Get("state"))
+
+			if test.approve {
+				assert.NotEmpty(t, redirect.Query().Get("code"))
+				repos.consents.AssertExpectations(t)
+			} else {
+				assert.Equal(t, usecase.OAuthErrAccessDenied, redirect.Query().Get("error"))
+				repos.consents.AssertNotCalled(t, "GrantConsent", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type valuesTokenTestCases struct {
+	name       string
+	form       url.Values
+	basicAuth  []string
+	code       *domain.OAuthAuthorizationCode
+	errCode    error
+	errUser    error
+	statusCode int
+	oauthError string
+	userID     string
+	scope      string
+}
+
+func TestToken(t *testing.T) {
+	issuedCode := &domain.OAuthAuthorizationCode{
+		ClientID:      publicClient.ID,
+		UserID:        "12345",
+		RedirectURI:   oauthRedirectURI,
+		Scopes:        []string{"profile"},
+		CodeChallenge: utils.PKCEChallenge(oauthVerifier),
+	}
+	codeForm := url.Values{
+		"grant_type":    {domain.OAuthGrantAuthorizationCode},
+		"code":          {"the-code"},
+		"redirect_uri":  {oauthRedirectURI},
+		"code_verifier": {oauthVerifier},
+		"client_id":     {publicClient.ID},
+	}
+	withForm := func(values map[string]string) url.Values {
+		form := url.Values{}
+		for key, value := range codeForm {
+			form[key] = value
+		}
+		for key, value := range values {
+			form.Set(key, value)
+		}
+		return form
+	}
+
+	testCases := []valuesTokenTestCases{
+		{
+			name:       "should exchange code of public client with code verifier",
+			form:       codeForm,
+			code:       issuedCode,
+			statusCode: http.StatusOK,
+			userID:     "12345",
+			scope:      "profile",
+		},
+		{
+			name:       "should return an error when code verifier does not match",
+			form:       withForm(map[string]string{"code_verifier": strings.Repeat("a", 43)}),
+			code:       issuedCode,
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidGrant,
+		},
+		{
+			name:       "should return an error when code verifier is missing",
+			form:       withForm(map[string]string{"code_verifier": ""}),
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidRequest,
+		},
+		{
+			name:       "should return an error when code was already used or expired",
+			form:       codeForm,
+			errCode:    mongo.ErrNoDocuments,
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidGrant,
+		},
+		{
+			name:       "should return an error when redirect uri differs from authorization request",
+			form:       withForm(map[string]string{"redirect_uri": "https://app.example.com/other"}),
+			code:       issuedCode,
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidGrant,
+		},
+		{
+			name:       "should return an error when code was issued to another client",
+			form:       withForm(map[string]string{"client_id": confidentialClient.ID, "client_secret": oauthClientSecret}),
+			code:       issuedCode,
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidGrant,
+		},
+		{
+			name:       "should return an error when user of code was deleted",
+			form:       codeForm,
+			code:       issuedCode,
+			errUser:    mongo.ErrNoDocuments,
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidGrant,
+		},
+		{
+			name:       "should return an error when public client sends a secret",
+			form:       withForm(map[string]string{"client_secret": "guess"}),
+			statusCode: http.StatusUnauthorized,
+			oauthError: usecase.OAuthErrInvalidClient,
+		},
+		{
+			name:       "should issue token to confidential client with client credentials in body",
+			form:       url.Values{"grant_type": {domain.OAuthGrantClientCredentials}, "client_id": {confidentialClient.ID}, "client_secret": {oauthClientSecret}, "scope": {"reports"}},
+			statusCode: http.StatusOK,
+			scope:      "reports",
+		},
+		{
+			name:       "should issue token to confidential client with basic authentication",
+			form:       url.Values{"grant_type": {domain.OAuthGrantClientCredentials}},
+			basicAuth:  []string{confidentialClient.ID, oauthClientSecret},
+			statusCode: http.StatusOK,
+			scope:      "profile reports",
+		},
+		{
+			name:       "should return an error when client secret is wrong",
+			form:       url.Values{"grant_type": {domain.OAuthGrantClientCredentials}},
+			basicAuth:  []string{confidentialClient.ID, "wrong"},
+			statusCode: http.StatusUnauthorized,
+			oauthError: usecase.OAuthErrInvalidClient,
+		},
+		{
+			name:       "should return an error when client is unknown",
+			form:       url.Values{"grant_type": {domain.OAuthGrantClientCredentials}, "client_id": {"unknown"}, "client_secret": {oauthClientSecret}},
+			statusCode: http.StatusUnauthorized,
+			oauthError: usecase.OAuthErrInvalidClient,
+		},
+		{
+			name:       "should return an error when client uses two authentication methods",
+			form:       url.Values{"grant_type": {domain.OAuthGrantClientCredentials}, "client_secret": {oauthClientSecret}},
+			basicAuth:  []string{confidentialClient.ID, oauthClientSecret},
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidRequest,
+		},
+		{
+			name:       "should return an error when scope is not allowed for the client",
+			form:       url.Values{"grant_type": {domain.OAuthGrantClientCredentials}, "scope": {"admin"}},
+			basicAuth:  []string{confidentialClient.ID, oauthClientSecret},
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidScope,
+		},
+		{
+			name:       "should return an error when client is not allowed to use the grant",
+			form:       url.Values{"grant_type": {domain.OAuthGrantClientCredentials}, "client_id": {publicClient.ID}},
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrUnauthorizedClient,
+		},
+		{
+			name:       "should return an error when grant type is not supported",
+			form:       url.Values{"grant_type": {"password"}, "client_id": {publicClient.ID}},
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrUnsupportedGrantType,
+		},
+		{
+			name:       "should return an error when grant type is missing",
+			form:       url.Values{"client_id": {publicClient.ID}},
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidRequest,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, router := oauthConfigurations()
+
+			repos.codes.On("ConsumeCode", mock.Anything, utils.HashToken("the-code")).Return(test.code, test.errCode).Once()
+			repos.users.On("GetUserByID", mock.Anything, "12345").Return(storedUser, test.errUser)
+			repos.tokens.On("CreateToken", mock.Anything, mock.Anything).Return(nil)
+
+			req, _ := http.NewRequest("POST", "/oauth/token", strings.NewReader(test.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if test.basicAuth != nil {
+				req.SetBasicAuth(test.basicAuth[0], test.basicAuth[1])
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			assert.Equal(t, "no-store", resp.Header().Get("Cache-Control"))
+
+			if test.oauthError != "" {
+				var response domain.OAuthErrorResponse
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, test.oauthError, response.Error)
+				repos.tokens.AssertNotCalled(t, "CreateToken", mock.Anything, mock.Anything)
+				if test.basicAuth != nil && test.statusCode == http.StatusUnauthorized {
+					assert.Contains(t, resp.Header().Get("WWW-Authenticate"), "Basic")
+				}
+				return
+			}
+
+			var response domain.OAuthTokenResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Equal(t, "Bearer", response.TokenType)
+			assert.Equal(t, int64(3600), response.ExpiresIn)
+			assert.Equal(t, test.scope, response.Scope)
+
+			token := repos.tokens.Calls[0].Arguments.Get(1).(*domain.OAuthAccessToken)
+			assert.Equal(t, utils.HashToken(response.AccessToken), token.ID)
+			assert.Equal(t, test.userID, token.UserID)
+		})
+	}
+}
+
+func TestRegisterClient(t *testing.T) {
+	testCases := []struct {
+		name          string
+		authorization string
+		body          domain.OAuthClientRequest
+		errRepo       error
+		statusCode    int
+		secret        bool
+	}{
+		{
+			name:       "should register confidential client with secret",
+			body:       domain.OAuthClientRequest{Name: "Backend", RedirectURIs: []string{oauthRedirectURI}, GrantTypes: []string{"client_credentials", "authorization_code"}, Scopes: []string{"reports"}},
+			statusCode: http.StatusCreated,
+			secret:     true,
+		},
+		{
+			name:       "should register public client without secret",
+			body:       domain.OAuthClientRequest{Name: "SPA", Public: true, RedirectURIs: []string{"http://localhost:3000/callback"}, GrantTypes: []string{"authorization_code"}, Scopes: []string{"profile"}},
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "should return an error when redirect uri is not valid",
+			body:       domain.OAuthClientRequest{Name: "SPA", Public: true, RedirectURIs: []string{"http://app.example.com/callback"}, GrantTypes: []string{"authorization_code"}, Scopes: []string{"profile"}},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when authorization code client has no redirect uri",
+			body:       domain.OAuthClientRequest{Name: "SPA", GrantTypes: []string{"authorization_code"}, Scopes: []string{"profile"}},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when public client uses client credentials",
+			body:       domain.OAuthClientRequest{Name: "SPA", Public: true, GrantTypes: []string{"client_credentials"}, Scopes: []string{"profile"}},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when grant type is not supported",
+			body:       domain.OAuthClientRequest{Name: "SPA", GrantTypes: []string{"password"}, Scopes: []string{"profile"}},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when bd return an error registering client",
+			body:       domain.OAuthClientRequest{Name: "Backend", GrantTypes: []string{"client_credentials"}, Scopes: []string{"reports"}},
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:          "should return an error when user is not administrator",
+			authorization: "Bearer " + sessionToken,
+			body:          domain.OAuthClientRequest{Name: "Backend", GrantTypes: []string{"client_credentials"}, Scopes: []string{"reports"}},
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when request is not authenticated",
+			authorization: "none",
+			body:          domain.OAuthClientRequest{Name: "Backend", GrantTypes: []string{"client_credentials"}, Scopes: []string{"reports"}},
+			statusCode:    http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, router := oauthConfigurations()
+
+			repos.clients.On("CreateClient", mock.Anything, mock.Anything).Return(test.errRepo)
+
+			bodyBytes, _ := json.Marshal(test.body)
+			req, _ := http.NewRequest("POST", "/admin/oauth/clients", bytes.NewBuffer(bodyBytes))
+			switch test.authorization {
+			case "":
+				req.Header.Set("Authorization", "Bearer "+adminToken)
+			case "none":
+			default:
+				req.Header.Set("Authorization", test.authorization)
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if test.statusCode != http.StatusCreated {
+				if test.statusCode == http.StatusForbidden || test.statusCode == http.StatusUnauthorized {
+					repos.clients.AssertNotCalled(t, "CreateClient", mock.Anything, mock.Anything)
+				}
+				return
+			}
+
+			var response domain.APIResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.NotEmpty(t, response.ID)
+			assert.NotContains(t, resp.Body.String(), "secretHash")
+
+			client := repos.clients.Calls[0].Arguments.Get(1).(*domain.OAuthClient)
+			if test.secret {
+				assert.NotEmpty(t, response.Secret)
+				assert.Equal(t, utils.HashToken(response.Secret), client.SecretHash)
+			} else {
+				assert.Empty(t, response.Secret)
+				assert.Empty(t, client.SecretHash)
+			}
+		})
+	}
+}
+
+func tokenActionRequest(path string, form url.Values, basicAuth []string) *http.Request {
+	req, _ := http.NewRequest("POST", path, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if basicAuth != nil {
+		req.SetBasicAuth(basicAuth[0], basicAuth[1])
+	}
+
+	return req
+}
+
+func TestIntrospect(t *testing.T) {
+	issuedToken := &domain.OAuthAccessToken{
+		ClientID:  publicClient.ID,
+		UserID:    "12345",
+		Scopes:    []string{"profile", "email"},
+		ExpiresAt: time.Now().Add(time.Hour),
+		CreatedAt: time.Now(),
+	}
+	tokenForm := url.Values{"token": {"access-token"}}
+
+	testCases := []struct {
+		name       string
+		form       url.Values
+		basicAuth  []string
+		token      *domain.OAuthAccessToken
+		errToken   error
+		revoked    bool
+		statusCode int
+		oauthError string
+		active     bool
+	}{
+		{
+			name:       "should return active token to a confidential client",
+			form:       tokenForm,
+			basicAuth:  []string{confidentialClient.ID, oauthClientSecret},
+			token:      issuedToken,
+			statusCode: http.StatusOK,
+			active:     true,
+		},
+		{
+			name:       "should return inactive when token is expired or unknown",
+			form:       tokenForm,
+			basicAuth:  []string{confidentialClient.ID, oauthClientSecret},
+			errToken:   mongo.ErrNoDocuments,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return inactive when token is in the revocation list",
+			form:       tokenForm,
+			basicAuth:  []string{confidentialClient.ID, oauthClientSecret},
+			token:      issuedToken,
+			revoked:    true,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when client secret is wrong",
+			form:       tokenForm,
+			basicAuth:  []string{confidentialClient.ID, "wrong"},
+			statusCode: http.StatusUnauthorized,
+			oauthError: usecase.OAuthErrInvalidClient,
+		},
+		{
+			name:       "should return an error when client is public",
+			form:       url.Values{"token": {"access-token"}, "client_id": {publicClient.ID}},
+			statusCode: http.StatusUnauthorized,
+			oauthError: usecase.OAuthErrInvalidClient,
+		},
+		{
+			name:       "should return an error when token is missing",
+			form:       url.Values{},
+			basicAuth:  []string{confidentialClient.ID, oauthClientSecret},
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidRequest,
+		},
+		{
+			name:       "should return an error when bd return an error obtaining token",
+			form:       tokenForm,
+			basicAuth:  []string{confidentialClient.ID, oauthClientSecret},
+			errToken:   errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+			oauthError: "server_error",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, router := oauthConfigurations()
+
+			repos.tokens.On("GetToken", mock.Anything, utils.HashToken("access-token")).Return(test.token, test.errToken)
+			repos.revoked.On("IsRevoked", mock.Anything, utils.HashToken("access-token")).Return(test.revoked, nil)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, tokenActionRequest("/oauth/introspect", test.form, test.basicAuth))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			assert.Equal(t, "no-store", resp.Header().Get("Cache-Control"))
+
+			if test.oauthError != "" {
+				var response domain.OAuthErrorResponse
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.Equal(t, test.oauthError, response.Error)
+				return
+			}
+
+			var response map[string]interface{}
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+
+			if !test.active {
+				assert.Equal(t, map[string]interface{}{"active": false}, response)
+				return
+			}
+
+			assert.Equal(t, true, response["active"])
+			assert.Equal(t, "profile email", response["scope"])
+			assert.Equal(t, publicClient.ID, response["client_id"])
+			assert.Equal(t, "12345", response["sub"])
+			assert.Equal(t, "Bearer", response["token_type"])
+			assert.Equal(t, float64(issuedToken.ExpiresAt.Unix()), response["exp"])
+		})
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	issuedToken := &domain.OAuthAccessToken{
+		ClientID:  publicClient.ID,
+		UserID:    "12345",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	publicForm := url.Values{"token": {"access-token"}, "client_id": {publicClient.ID}}
+
+	testCases := []struct {
+		name       string
+		form       url.Values
+		basicAuth  []string
+		token      *domain.OAuthAccessToken
+		errToken   error
+		errRevoke  error
+		statusCode int
+		oauthError string
+		revoked    bool
+	}{
+		{
+			name:       "should revoke token of the client",
+			form:       publicForm,
+			token:      issuedToken,
+			statusCode: http.StatusOK,
+			revoked:    true,
+		},
+		{
+			name:       "should ignore token that is expired or unknown",
+			form:       publicForm,
+			errToken:   mongo.ErrNoDocuments,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should ignore token issued to another client",
+			form:       url.Values{"token": {"access-token"}},
+			basicAuth:  []string{confidentialClient.ID, oauthClientSecret},
+			token:      issuedToken,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when client secret is wrong",
+			form:       url.Values{"token": {"access-token"}},
+			basicAuth:  []string{confidentialClient.ID, "wrong"},
+			statusCode: http.StatusUnauthorized,
+			oauthError: usecase.OAuthErrInvalidClient,
+		},
+		{
+			name:       "should return an error when token is missing",
+			form:       url.Values{"client_id": {publicClient.ID}},
+			statusCode: http.StatusBadRequest,
+			oauthError: usecase.OAuthErrInvalidRequest,
+		},
+		{
+			name:       "should return an error when bd return an error revoking token",
+			form:       publicForm,
+			token:      issuedToken,
+			errRevoke:  errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+			oauthError: "server_error",
+			revoked:    true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, router := oauthConfigurations()
+
+			repos.tokens.On("GetToken", mock.Anything, utils.HashToken("access-token")).Return(test.token, test.errToken)
+			repos.revoked.On("RevokeToken", mock.Anything, utils.HashToken("access-token"), issuedToken.ExpiresAt).Return(test.errRevoke)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, tokenActionRequest("/oauth/revoke", test.form, test.basicAuth))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.revoked {
+				repos.revoked.AssertCalled(t, "RevokeToken", mock.Anything, utils.HashToken("access-token"), issuedToken.ExpiresAt)
+			} else {
+				repos.revoked.AssertNotCalled(t, "RevokeToken", mock.Anything, mock.Anything, mock.Anything)
+			}
+
+			if test.oauthError != "" {
+				var response domain.OAuthErrorResponse
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+				assert.Equal(t, test.oauthError, response.Error)
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// jwksMaxAge is how many seconds clients may cache the JWKS, after a rotation clients refetch
+// it when a token is signed with an unknown key ID.
+const jwksMaxAge = 300
+
+// OpenIDHandlers encapsulates the OpenID Connect HTTP handlers.
+type OpenIDHandlers struct {
+	OAuthService *usecase.OAuthService
+}
+
+// Discovery handles the OpenID Provider metadata document.
+// It return the endpoints and capabilities of the provider.
+func (h *OpenIDHandlers) Discovery(c *gin.Context) {
+	discovery, err := h.OAuthService.Discovery()
+	if err != nil {
+		respondWithError(c, http.StatusServiceUnavailable, constants.ErrOpenIDNotConfigured, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, discovery)
+}
+
+// JWKS handles the JSON Web Key Set with the current and previous keys signing ID tokens.
+// It return the public keys identified by their key ID.
+func (h *OpenIDHandlers) JWKS(c *gin.Context) {
+	jwks, err := h.OAuthService.JWKS()
+	if err != nil {
+		respondWithError(c, http.StatusServiceUnavailable, constants.ErrOpenIDNotConfigured, nil)
+		return
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", jwksMaxAge))
+	c.JSON(http.StatusOK, jwks)
+}
+
+// UserInfo handles the claims of the user of the access token authenticated by RequireAccessToken.
+// It return the claims of the scopes granted to the token.
+func (h *OpenIDHandlers) UserInfo(c *gin.Context) {
+	info, err := h.OAuthService.UserInfo(c.Request.Context(), currentAccessToken(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidAccessToken):
+			respondWithBearerError(c, http.StatusUnauthorized, "invalid_token", constants.ErrInvalidAccessToken, "")
+		case errors.Is(err, usecase.ErrInsufficientScope):
+			respondWithBearerError(c, http.StatusForbidden, "insufficient_scope", constants.ErrInsufficientScope, usecase.ScopeOpenID)
+		case errors.Is(err, usecase.ErrOpenIDNotConfigured):
+			respondWithError(c, http.StatusServiceUnavailable, constants.ErrOpenIDNotConfigured, nil)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToGetUserInfo, err)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, info)
+}
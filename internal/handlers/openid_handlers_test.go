@@ -0,0 +1,441 @@
+package handlers_test
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const oidcIssuer = "https://id.example.com"
+
+var oidcUser = &domain.User{
+	ID:            "12345",
+	Name:          "Cristian",
+	Email:         "cristian@gmail.com",
+	UserName:      "cristian",
+	EmailVerified: true,
+	UpdatedAt:     time.Unix(1700000000, 0),
+}
+
+func openIDConfigurations(t *testing.T, storedKeys []domain.SigningKey) (*oauthMocks, *mocks.SigningKeyRepository, *usecase.SigningKeyService, *gin.Engine) {
+	repos, router := oauthConfigurations()
+
+	secretBox, err := utils.NewSecretBox("v1", map[string][]byte{"v1": []byte("oidc-secret")})
+	assert.NoError(t, err)
+
+	mockKeys := new(mocks.SigningKeyRepository)
+	mockKeys.On("ListKeys", mock.Anything).Return(storedKeys, nil)
+	mockKeys.On("CreateKey", mock.Anything, mock.Anything).Return(nil)
+
+	signingKeys := usecase.NewSigningKeyService(mockKeys, secretBox, 24*time.Hour)
+	assert.NoError(t, signingKeys.Refresh(context.Background()))
+
+	oauthService := usecase.NewOAuthService(repos.users, repos.clients, repos.consents, repos.codes, repos.tokens, repos.revoked, time.Minute, time.Hour).
+		WithOpenID(signingKeys, usecase.OpenIDConfig{
+			Issuer:           oidcIssuer,
+			AuthorizationURL: "https://app.example.com/consent",
+			IDTokenTTL:       time.Hour,
+		})
+
+	handler := handlers.OpenIDHandlers{OAuthService: oauthService}
+	oauthHandler := handlers.OAuthHandlers{OAuthService: oauthService}
+
+	router.GET("/.well-known/openid-configuration", handler.Discovery)
+	router.GET("/.well-known/jwks.json", handler.JWKS)
+	router.GET("/userinfo", oauthHandler.RequireAccessToken(""), handler.UserInfo)
+	router.POST("/oidc/token", oauthHandler.Token)
+	router.POST("/oidc/introspect", oauthHandler.Introspect)
+	router.POST("/oidc/revoke", oauthHandler.Revoke)
+
+	return repos, mockKeys, signingKeys, router
+}
+
+func fetchJWKS(t *testing.T, router *gin.Engine) domain.JWKS {
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest("GET", "/.well-known/jwks.json", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Contains(t, resp.Header().Get("Cache-Control"), "max-age")
+
+	var jwks domain.JWKS
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &jwks))
+
+	return jwks
+}
+
+func TestDiscovery(t *testing.T) {
+	_, _, _, router := openIDConfigurations(t, nil)
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, httptest.NewRequest("GET", "/.well-known/openid-configuration", nil))
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var discovery domain.OIDCDiscovery
+	assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &discovery))
+	assert.Equal(t, oidcIssuer, discovery.Issuer)
+	assert.Equal(t, "https://app.example.com/consent", discovery.AuthorizationEndpoint)
+	assert.Equal(t, oidcIssuer+"/.well-known/jwks.json", discovery.JWKSURI)
+	assert.Equal(t, []string{"RS256"}, discovery.IDTokenSigningAlgValuesSupported)
+	assert.Equal(t, []string{"S256"}, discovery.CodeChallengeMethodsSupported)
+}
+
+func TestOpenIDNotConfigured(t *testing.T) {
+	repos := &oauthMocks{tokens: new(mocks.OAuthTokenRepository), revoked: new(mocks.RevokedTokenRepository)}
+	repos.tokens.On("GetToken", mock.Anything, utils.HashToken("token")).Return(&domain.OAuthAccessToken{UserID: "12345", Scopes: []string{"openid"}}, nil)
+	repos.revoked.On("IsRevoked", mock.Anything, utils.HashToken("token")).Return(false, nil)
+
+	oauthService := usecase.NewOAuthService(repos.users, repos.clients, repos.consents, repos.codes, repos.tokens, repos.revoked, time.Minute, time.Hour)
+	handler := handlers.OpenIDHandlers{OAuthService: oauthService}
+	oauthHandler := handlers.OAuthHandlers{OAuthService: oauthService}
+	router := gin.Default()
+	router.GET("/.well-known/openid-configuration", handler.Discovery)
+	router.GET("/.well-known/jwks.json", handler.JWKS)
+	router.GET("/userinfo", oauthHandler.RequireAccessToken(""), handler.UserInfo)
+
+	for _, path := range []string{"/.well-known/openid-configuration", "/.well-known/jwks.json"} {
+		resp := httptest.NewRecorder()
+		router.ServeHTTP(resp, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+	}
+
+	resp := httptest.NewRecorder()
+	router.ServeHTTP(resp, sessionRequest("GET", "/userinfo", "Bearer token"))
+	assert.Equal(t, http.StatusServiceUnavailable, resp.Code)
+}
+
+func TestSigningKeyRotation(t *testing.T) {
+	_, mockKeys, _, router := openIDConfigurations(t, nil)
+
+	mockKeys.AssertNumberOfCalls(t, "CreateKey", 1)
+	created := mockKeys.Calls[1].Arguments.Get(1).(*domain.SigningKey)
+	assert.Equal(t, "RS256", created.Algorithm)
+	assert.Equal(t, created.CreatedAt.Add(48*time.Hour), created.ExpiresAt)
+	assert.NotContains(t, created.PrivateKey, "MII")
+
+	jwks := fetchJWKS(t, router)
+	assert.Len(t, jwks.Keys, 1)
+	assert.Equal(t, created.ID, jwks.Keys[0].KeyID)
+
+	testCases := []struct {
+		name    string
+		age     time.Duration
+		rotated bool
+	}{
+		{
+			name: "should keep current key before rotation interval",
+			age:  time.Hour,
+		},
+		{
+			name:    "should publish a new key and the previous one after rotation interval",
+			age:     25 * time.Hour,
+			rotated: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			stored := *created
+			stored.CreatedAt = time.Now().Add(-test.age)
+
+			_, mockKeys, _, router := openIDConfigurations(t, []domain.SigningKey{stored})
+
+			jwks := fetchJWKS(t, router)
+			if test.rotated {
+				mockKeys.AssertNumberOfCalls(t, "CreateKey", 1)
+				assert.Len(t, jwks.Keys, 2)
+				assert.NotEqual(t, created.ID, jwks.Keys[0].KeyID)
+				assert.Equal(t, created.ID, jwks.Keys[1].KeyID)
+			} else {
+				mockKeys.AssertNotCalled(t, "CreateKey", mock.Anything, mock.Anything)
+				assert.Len(t, jwks.Keys, 1)
+				assert.Equal(t, created.ID, jwks.Keys[0].KeyID)
+			}
+		})
+	}
+}
+
+func TestSigningKeyRefreshFails(t *testing.T) {
+	mockKeys := new(mocks.SigningKeyRepository)
+	mockKeys.On("ListKeys", mock.Anything).Return(nil, errors.New(errorValue))
+
+	secretBox, err := utils.NewSecretBox("v1", map[string][]byte{"v1": []byte("oidc-secret")})
+	assert.NoError(t, err)
+
+	signingKeys := usecase.NewSigningKeyService(mockKeys, secretBox, time.Hour)
+
+	assert.Error(t, signingKeys.Refresh(context.Background()))
+	_, err = signingKeys.Sign(domain.IDTokenClaims{})
+	assert.ErrorIs(t, err, usecase.ErrOpenIDNotConfigured)
+}
+
+func TestTokenIssuesIDToken(t *testing.T) {
+	testCases := []struct {
+		name    string
+		scopes  []string
+		idToken bool
+		claims  func(t *testing.T, claims domain.IDTokenClaims)
+	}{
+		{
+			name:    "should issue ID token with profile and email claims",
+			scopes:  []string{"openid", "profile", "email"},
+			idToken: true,
+			claims: func(t *testing.T, claims domain.IDTokenClaims) {
+				assert.Equal(t, "Cristian", claims.Name)
+				assert.Equal(t, "cristian", claims.PreferredUsername)
+				assert.Equal(t, int64(1700000000), claims.UpdatedAt)
+				assert.Equal(t, "cristian@gmail.com", claims.Email)
+				assert.True(t, *claims.EmailVerified)
+			},
+		},
+		{
+			name:    "should issue ID token with only subject without profile and email scopes",
+			scopes:  []string{"openid"},
+			idToken: true,
+			claims: func(t *testing.T, claims domain.IDTokenClaims) {
+				assert.Empty(t, claims.Name)
+				assert.Empty(t, claims.Email)
+				assert.Nil(t, claims.EmailVerified)
+			},
+		},
+		{
+			name:   "should not issue ID token without openid scope",
+			scopes: []string{"profile"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, _, _, router := openIDConfigurations(t, nil)
+
+			repos.codes.On("ConsumeCode", mock.Anything, utils.HashToken("the-code")).Return(&domain.OAuthAuthorizationCode{
+				ClientID:      publicClient.ID,
+				UserID:        "12345",
+				RedirectURI:   oauthRedirectURI,
+				Scopes:        test.scopes,
+				CodeChallenge: utils.PKCEChallenge(oauthVerifier),
+				Nonce:         "n-0S6_WzA2Mj",
+			}, nil)
+			repos.users.On("GetUserByID", mock.Anything, "12345").Return(oidcUser, nil)
+			repos.tokens.On("CreateToken", mock.Anything, mock.Anything).Return(nil)
+
+			form := url.Values{
+				"grant_type":    {domain.OAuthGrantAuthorizationCode},
+				"code":          {"the-code"},
+				"redirect_uri":  {oauthRedirectURI},
+				"code_verifier": {oauthVerifier},
+				"client_id":     {publicClient.ID},
+			}
+			req, _ := http.NewRequest("POST", "/oidc/token", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, http.StatusOK, resp.Code)
+
+			var response domain.OAuthTokenResponse
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+
+			if !test.idToken {
+				assert.Empty(t, response.IDToken)
+				return
+			}
+
+			jwks := fetchJWKS(t, router)
+			keyFunc := func(kid string) (*rsa.PublicKey, bool) {
+				for _, jwk := range jwks.Keys {
+					if jwk.KeyID == kid {
+						key, err := utils.ParseRSAPublicJWK(jwk)
+						return key, err == nil
+					}
+				}
+				return nil, false
+			}
+
+			var claims domain.IDTokenClaims
+			assert.NoError(t, utils.VerifyJWT(response.IDToken, keyFunc, &claims))
+			assert.Equal(t, oidcIssuer, claims.Issuer)
+			assert.Equal(t, "12345", claims.Subject)
+			assert.Equal(t, publicClient.ID, claims.Audience)
+			assert.Equal(t, "n-0S6_WzA2Mj", claims.Nonce)
+			assert.Equal(t, utils.AccessTokenHash(response.AccessToken), claims.AccessTokenHash)
+			assert.InDelta(t, time.Now().Add(time.Hour).Unix(), claims.ExpiresAt, 5)
+			test.claims(t, claims)
+		})
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	testCases := []struct {
+		name          string
+		authorization string
+		token         *domain.OAuthAccessToken
+		errToken      error
+		revoked       bool
+		errUser       error
+		statusCode    int
+		authenticate  string
+	}{
+		{
+			name:          "should return claims of the granted scopes",
+			authorization: "Bearer access-token",
+			token:         &domain.OAuthAccessToken{ClientID: publicClient.ID, UserID: "12345", Scopes: []string{"openid", "email"}},
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:         "should return an error when token is missing",
+			statusCode:   http.StatusUnauthorized,
+			authenticate: "Bearer",
+		},
+		{
+			name:          "should return an error when token is revoked or expired",
+			authorization: "Bearer access-token",
+			errToken:      mongo.ErrNoDocuments,
+			statusCode:    http.StatusUnauthorized,
+			authenticate:  `Bearer error="invalid_token"`,
+		},
+		{
+			name:          "should return an error when token is in the revocation list",
+			authorization: "Bearer access-token",
+			token:         &domain.OAuthAccessToken{ClientID: publicClient.ID, UserID: "12345", Scopes: []string{"openid"}},
+			revoked:       true,
+			statusCode:    http.StatusUnauthorized,
+			authenticate:  `Bearer error="invalid_token"`,
+		},
+		{
+			name:          "should return an error when token was not granted openid",
+			authorization: "Bearer access-token",
+			token:         &domain.OAuthAccessToken{ClientID: publicClient.ID, UserID: "12345", Scopes: []string{"profile"}},
+			statusCode:    http.StatusForbidden,
+			authenticate:  `Bearer error="insufficient_scope", scope="openid"`,
+		},
+		{
+			name:          "should return an error when token was issued to a client without user",
+			authorization: "Bearer access-token",
+			token:         &domain.OAuthAccessToken{ClientID: confidentialClient.ID, Scopes: []string{"openid"}},
+			statusCode:    http.StatusForbidden,
+			authenticate:  `Bearer error="insufficient_scope", scope="openid"`,
+		},
+		{
+			name:          "should return an error when user of token was deleted",
+			authorization: "Bearer access-token",
+			token:         &domain.OAuthAccessToken{ClientID: publicClient.ID, UserID: "12345", Scopes: []string{"openid"}},
+			errUser:       mongo.ErrNoDocuments,
+			statusCode:    http.StatusUnauthorized,
+			authenticate:  `Bearer error="invalid_token"`,
+		},
+		{
+			name:          "should return an error when bd return an error obtaining token",
+			authorization: "Bearer access-token",
+			errToken:      errors.New(errorValue),
+			statusCode:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, _, _, router := openIDConfigurations(t, nil)
+
+			repos.tokens.On("GetToken", mock.Anything, utils.HashToken("access-token")).Return(test.token, test.errToken)
+			repos.revoked.On("IsRevoked", mock.Anything, utils.HashToken("access-token")).Return(test.revoked, nil)
+			repos.users.On("GetUserByID", mock.Anything, "12345").Return(oidcUser, test.errUser)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("GET", "/userinfo", test.authorization))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			assert.Equal(t, test.authenticate, resp.Header().Get("WWW-Authenticate"))
+
+			if test.statusCode == http.StatusOK {
+				var info map[string]interface{}
+				assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &info))
+				assert.Equal(t, map[string]interface{}{
+					"sub":            "12345",
+					"email":          "cristian@gmail.com",
+					"email_verified": true,
+				}, info)
+			}
+		})
+	}
+}
+
+func TestIntrospectAndRevokeIDToken(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour).Unix()
+
+	testCases := []struct {
+		name      string
+		claims    domain.IDTokenClaims
+		basicAuth []string
+		revokes   bool
+	}{
+		{
+			name:      "should return inactive ID token and revoke it for its audience",
+			claims:    domain.IDTokenClaims{UserInfo: domain.UserInfo{Subject: "12345"}, Issuer: oidcIssuer, Audience: confidentialClient.ID, ExpiresAt: expiresAt},
+			basicAuth: []string{confidentialClient.ID, oauthClientSecret},
+			revokes:   true,
+		},
+		{
+			name:      "should return inactive and not revoke when ID token is expired",
+			claims:    domain.IDTokenClaims{UserInfo: domain.UserInfo{Subject: "12345"}, Issuer: oidcIssuer, Audience: confidentialClient.ID, ExpiresAt: time.Now().Add(-time.Minute).Unix()},
+			basicAuth: []string{confidentialClient.ID, oauthClientSecret},
+		},
+		{
+			name:      "should return inactive and not revoke when ID token has another issuer",
+			claims:    domain.IDTokenClaims{UserInfo: domain.UserInfo{Subject: "12345"}, Issuer: "https://other.example.com", Audience: confidentialClient.ID, ExpiresAt: expiresAt},
+			basicAuth: []string{confidentialClient.ID, oauthClientSecret},
+		},
+		{
+			name:      "should return inactive and not revoke when ID token was issued to another client",
+			claims:    domain.IDTokenClaims{UserInfo: domain.UserInfo{Subject: "12345"}, Issuer: oidcIssuer, Audience: publicClient.ID, ExpiresAt: expiresAt},
+			basicAuth: []string{confidentialClient.ID, oauthClientSecret},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, _, signingKeys, router := openIDConfigurations(t, nil)
+
+			idToken, err := signingKeys.Sign(test.claims)
+			assert.NoError(t, err)
+
+			repos.revoked.On("RevokeToken", mock.Anything, utils.HashToken(idToken), time.Unix(test.claims.ExpiresAt, 0)).Return(nil)
+
+			resp := httptest.NewRecorder()
+			router.ServeHTTP(resp, tokenActionRequest("/oidc/introspect", url.Values{"token": {idToken}}, test.basicAuth))
+			assert.Equal(t, http.StatusOK, resp.Code)
+
+			var introspection domain.OAuthIntrospection
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &introspection))
+			assert.False(t, introspection.Active)
+			assert.Empty(t, introspection.Subject)
+
+			resp = httptest.NewRecorder()
+			router.ServeHTTP(resp, tokenActionRequest("/oidc/revoke", url.Values{"token": {idToken}}, test.basicAuth))
+			assert.Equal(t, http.StatusOK, resp.Code)
+
+			if test.revokes {
+				repos.revoked.AssertCalled(t, "RevokeToken", mock.Anything, utils.HashToken(idToken), time.Unix(test.claims.ExpiresAt, 0))
+			} else {
+				repos.revoked.AssertNotCalled(t, "RevokeToken", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,382 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Keys of the session or API key of the request in the gin context.
+const (
+	sessionKey = "session"
+	apiKeyKey  = "apiKey"
+)
+
+// Header of the ID of a request, it is taken from the client when it is valid or generated.
+const (
+	requestIDHeader    = "X-Request-ID"
+	maxRequestIDLength = 128
+)
+
+// SessionHandlers encapsulates the session HTTP handlers and middlewares, API keys are
+// accepted by the middlewares when APIKeyService is set.
+type SessionHandlers struct {
+	SessionService *usecase.SessionService
+	APIKeyService  *usecase.APIKeyService
+}
+
+// IdentifyRequest is a middleware that carries the audit info of the request in its context:
+// the request ID, the client IP and the actor when the request is authenticated with a valid
+// session or API key. Invalid credentials are ignored, they are rejected by RequireSession.
+func (h *SessionHandlers) IdentifyRequest(c *gin.Context) {
+	info := domain.AuditInfo{
+		RequestID: c.GetHeader(requestIDHeader),
+		IP:        c.ClientIP(),
+	}
+	if !validRequestID(info.RequestID) {
+		info.RequestID = primitive.NewObjectID().Hex()
+	}
+	c.Header(requestIDHeader, info.RequestID)
+
+	ctx := c.Request.Context()
+	if secret, found := apiKeyToken(c); found {
+		if h.APIKeyService != nil {
+			if key, err := h.APIKeyService.ValidateAPIKey(ctx, secret); err == nil {
+				c.Set(apiKeyKey, key)
+				info.ActorID = key.UserID
+			}
+		}
+	} else if token, found := bearerToken(c); found {
+		if session, err := h.SessionService.ValidateSession(ctx, token); err == nil {
+			c.Set(sessionKey, session)
+			info.ActorID = sessionActorID(session)
+		}
+	}
+
+	c.Request = c.Request.WithContext(usecase.WithAuditInfo(ctx, info))
+	c.Next()
+}
+
+// RequireSession is a middleware that authenticates the request with the session token
+// sent as bearer token or with an API key sent with the ApiKey scheme, revoked and
+// expired sessions and keys are rejected. The session or key identified by IdentifyRequest is reused.
+func (h *SessionHandlers) RequireSession(c *gin.Context) {
+	_, hasSession := c.Get(sessionKey)
+	_, hasAPIKey := c.Get(apiKeyKey)
+	if hasSession || hasAPIKey {
+		c.Next()
+		return
+	}
+
+	if key, found := apiKeyToken(c); found && h.APIKeyService != nil {
+		h.requireAPIKey(c, key)
+		return
+	}
+
+	token, found := bearerToken(c)
+	if !found {
+		c.Header("WWW-Authenticate", "Bearer")
+		respondWithError(c, http.StatusUnauthorized, constants.ErrInvalidSession, nil)
+		c.Abort()
+		return
+	}
+
+	session, err := h.SessionService.ValidateSession(c.Request.Context(), token)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidSession) {
+			c.Header("WWW-Authenticate", "Bearer")
+			respondWithError(c, http.StatusUnauthorized, constants.ErrInvalidSession, nil)
+		} else {
+			respondWithError(c, http.StatusInternalServerError, constants.ErrInvalidSession, err)
+		}
+		c.Abort()
+		return
+	}
+
+	c.Set(sessionKey, session)
+	setAuditActor(c, sessionActorID(session))
+	c.Next()
+}
+
+// requireAPIKey authenticates the request with the API key and records its use.
+func (h *SessionHandlers) requireAPIKey(c *gin.Context, secret string) {
+	key, err := h.APIKeyService.ValidateAPIKey(c.Request.Context(), secret)
+	if err != nil {
+		if errors.Is(err, usecase.ErrInvalidAPIKey) {
+			c.Header("WWW-Authenticate", "ApiKey")
+			respondWithError(c, http.StatusUnauthorized, constants.ErrInvalidAPIKey, nil)
+		} else {
+			respondWithError(c, http.StatusInternalServerError, constants.ErrInvalidAPIKey, err)
+		}
+		c.Abort()
+		return
+	}
+
+	c.Set(apiKeyKey, key)
+	setAuditActor(c, key.UserID)
+	c.Next()
+}
+
+// RequirePermission is a middleware that only allows API keys granted the permission,
+// sessions have all the permissions. It must run after RequireSession.
+func (h *SessionHandlers) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key, found := currentAPIKey(c); found && !slices.Contains(key.Permissions, permission) {
+			respondWithError(c, http.StatusForbidden, constants.ErrMissingPermission, nil)
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireAdmin is a middleware that only allows administrators, impersonation sessions are
+// rejected because the staff member acts as the impersonated user. It must run after RequireSession.
+func (h *SessionHandlers) RequireAdmin(c *gin.Context) {
+	if session, found := c.Get(sessionKey); found && session.(*domain.Session).ActorID != "" {
+		respondWithError(c, http.StatusForbidden, constants.ErrDeniedImpersonating, nil)
+		c.Abort()
+		return
+	}
+
+	if !h.SessionService.IsAdmin(currentUserID(c)) {
+		respondWithError(c, http.StatusForbidden, constants.ErrAdminRequired, nil)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// DenyAPIKey is a middleware that only allows requests authenticated with a session, it
+// protects the requests a user must make in person. It must run after RequireSession.
+func (h *SessionHandlers) DenyAPIKey(c *gin.Context) {
+	if _, found := currentAPIKey(c); found {
+		respondWithError(c, http.StatusForbidden, constants.ErrAPIKeyNotAllowed, nil)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// DenyImpersonation is a middleware that rejects sensitive operations, like password and MFA
+// changes, on requests authenticated with an impersonation session. It also checks the bearer
+// token on routes that do not require a session.
+func (h *SessionHandlers) DenyImpersonation(c *gin.Context) {
+	session, found := c.Get(sessionKey)
+	if !found {
+		token, hasToken := bearerToken(c)
+		if !hasToken {
+			c.Next()
+			return
+		}
+
+		var err error
+		session, err = h.SessionService.ValidateSession(c.Request.Context(), token)
+		if err != nil {
+			if errors.Is(err, usecase.ErrInvalidSession) {
+				c.Next()
+				return
+			}
+			respondWithError(c, http.StatusInternalServerError, constants.ErrInvalidSession, err)
+			c.Abort()
+			return
+		}
+	}
+
+	if session.(*domain.Session).ActorID != "" {
+		respondWithError(c, http.StatusForbidden, constants.ErrDeniedImpersonating, nil)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// RequireSameUser is a middleware that only allows the user of the session or API key to access the id param.
+// It must run after RequireSession.
+func (h *SessionHandlers) RequireSameUser(c *gin.Context) {
+	if currentUserID(c) != c.Param("id") {
+		respondWithError(c, http.StatusForbidden, constants.ErrForbidden, nil)
+		c.Abort()
+		return
+	}
+
+	c.Next()
+}
+
+// ListSessions handles the listing of the active sessions of a user.
+// It expects a id param with user and return the sessions, the one making the request is marked as current.
+func (h *SessionHandlers) ListSessions(c *gin.Context) {
+	id := c.Param("id")
+
+	var currentID string
+	if session, found := c.Get(sessionKey); found {
+		currentID = session.(*domain.Session).ID
+	}
+
+	sessions, err := h.SessionService.ListSessions(c.Request.Context(), id, currentID)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToListSessions, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:  true,
+		ID:       id,
+		Sessions: sessions,
+	})
+}
+
+// RevokeSession handles the sign out of a session of a user.
+// It expects a id param with user and a sid param with session and return status no content.
+func (h *SessionHandlers) RevokeSession(c *gin.Context) {
+	err := h.SessionService.RevokeSession(c.Request.Context(), c.Param("id"), c.Param("sid"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrSessionNotFound) {
+			respondWithError(c, http.StatusNotFound, constants.ErrSessionNotFound, nil)
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToRevokeSession, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeAllSessions handles the sign out of a user everywhere, including the session making the request.
+// It expects a id param with user and return status no content.
+func (h *SessionHandlers) RevokeAllSessions(c *gin.Context) {
+	err := h.SessionService.RevokeUserSessions(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToRevokeSession, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Logout handles the sign out of the session making the request, it ends an impersonation.
+// It return status no content.
+func (h *SessionHandlers) Logout(c *gin.Context) {
+	err := h.SessionService.Logout(c.Request.Context(), currentSession(c))
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToRevokeSession, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// Impersonate handles the sign in of a staff member of the session as the user, for support.
+// It expects a id param with user and return the short-lived session token and its claims.
+func (h *SessionHandlers) Impersonate(c *gin.Context) {
+	session, token, err := h.SessionService.Impersonate(c.Request.Context(), currentSession(c), c.Param("id"), clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrImpersonationDisabled):
+			respondWithError(c, http.StatusForbidden, constants.ErrImpersonationDisabled, nil)
+		case errors.Is(err, usecase.ErrImpersonationForbidden):
+			respondWithError(c, http.StatusForbidden, constants.ErrImpersonationForbidden, nil)
+		case errors.Is(err, mongo.ErrNoDocuments):
+			respondWithError(c, http.StatusNotFound, constants.ErrUserNotFound, nil)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToImpersonate, err)
+		}
+		return
+	}
+
+	respondWithSuccess(c, http.StatusCreated, domain.APIResponse{
+		Success:      true,
+		ID:           session.UserID,
+		SessionID:    session.ID,
+		SessionToken: token,
+		Impersonation: &domain.ImpersonationClaims{
+			Subject:   session.UserID,
+			Actor:     domain.ImpersonationActor{Subject: session.ActorID},
+			ExpiresAt: session.ExpiresAt,
+		},
+	})
+}
+
+// bearerToken returns the token of the Authorization header with the Bearer scheme.
+func bearerToken(c *gin.Context) (string, bool) {
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+	return strings.TrimSpace(token), found
+}
+
+// apiKeyToken returns the key of the Authorization header with the ApiKey scheme.
+func apiKeyToken(c *gin.Context) (string, bool) {
+	key, found := strings.CutPrefix(c.GetHeader("Authorization"), "ApiKey ")
+
+	return strings.TrimSpace(key), found
+}
+
+// currentSession returns the session set by RequireSession, the request must not be
+// authenticated with an API key.
+func currentSession(c *gin.Context) *domain.Session {
+	return c.MustGet(sessionKey).(*domain.Session)
+}
+
+// currentAPIKey returns the API key set by RequireSession when the request was authenticated with one.
+func currentAPIKey(c *gin.Context) (*domain.APIKey, bool) {
+	key, found := c.Get(apiKeyKey)
+	if !found {
+		return nil, false
+	}
+
+	return key.(*domain.APIKey), true
+}
+
+// currentUserID returns the user of the session or API key set by RequireSession.
+func currentUserID(c *gin.Context) string {
+	if key, found := currentAPIKey(c); found {
+		return key.UserID
+	}
+
+	return currentSession(c).UserID
+}
+
+// sessionActorID returns the user that acts with a session, the staff member when it was
+// issued by impersonation.
+func sessionActorID(session *domain.Session) string {
+	if session.ActorID != "" {
+		return session.ActorID
+	}
+
+	return session.UserID
+}
+
+// setAuditActor sets the actor of the audit info of the request.
+func setAuditActor(c *gin.Context, actorID string) {
+	info, _ := usecase.AuditInfoFrom(c.Request.Context())
+	info.ActorID = actorID
+	c.Request = c.Request.WithContext(usecase.WithAuditInfo(c.Request.Context(), info))
+}
+
+// validRequestID reports whether a request ID sent by the client can be recorded, it must be
+// short and only contain letters, numbers and the symbols -_.:
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		return false
+	}
+
+	for _, char := range requestID {
+		if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || strings.ContainsRune("-_.:", char)) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,590 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	sessionsRoute = "/users/:id/sessions"
+	sessionToken  = "session-token"
+)
+
+type valuesSessionTestCases struct {
+	name          string
+	authorization string
+	path          string
+	session       *domain.Session
+	errSession    error
+	errRepo       error
+	statusCode    int
+}
+
+var currentUserSession = &domain.Session{
+	ID:     "current",
+	UserID: "12345",
+	Device: "Firefox on Linux",
+}
+
+func sessionConfigurations() (*mocks.SessionRepository, handlers.SessionHandlers, *gin.Engine) {
+	mockSessions := new(mocks.SessionRepository)
+
+	handler := handlers.SessionHandlers{SessionService: usecase.NewSessionService(mockSessions, time.Hour)}
+
+	router := gin.Default()
+	authenticated := router.Group("", handler.RequireSession)
+	authenticated.GET(sessionsRoute, handler.RequireSameUser, handler.ListSessions)
+	authenticated.DELETE(sessionsRoute, handler.RequireSameUser, handler.RevokeAllSessions)
+	authenticated.DELETE(sessionsRoute+"/:sid", handler.RequireSameUser, handler.RevokeSession)
+	authenticated.POST("/auth/logout", handler.Logout)
+
+	return mockSessions, handler, router
+}
+
+func sessionRequest(method string, path string, authorization string) *http.Request {
+	req, _ := http.NewRequest(method, path, nil)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+
+	return req
+}
+
+func TestRequireSession(t *testing.T) {
+	testCases := []valuesSessionTestCases{
+		{
+			name:          "should authenticate request with active session",
+			authorization: "Bearer " + sessionToken,
+			path:          "/users/12345/sessions",
+			session:       currentUserSession,
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:       "should return an error when token is missing",
+			path:       "/users/12345/sessions",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "should return an error when authorization is not bearer",
+			authorization: "Basic " + sessionToken,
+			path:          "/users/12345/sessions",
+			statusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:          "should return an error when session is revoked or expired",
+			authorization: "Bearer " + sessionToken,
+			path:          "/users/12345/sessions",
+			errSession:    mongo.ErrNoDocuments,
+			statusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:          "should return an error when bd return an error validating session",
+			authorization: "Bearer " + sessionToken,
+			path:          "/users/12345/sessions",
+			errSession:    errors.New(errorValue),
+			statusCode:    http.StatusInternalServerError,
+		},
+		{
+			name:          "should return an error when session belongs to another user",
+			authorization: "Bearer " + sessionToken,
+			path:          "/users/67890/sessions",
+			session:       currentUserSession,
+			statusCode:    http.StatusForbidden,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockSessions, _, router := sessionConfigurations()
+
+			mockSessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).Return(test.session, test.errSession)
+			mockSessions.On("ListSessions", mock.Anything, "12345").Return([]domain.Session{*currentUserSession}, nil)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("GET", test.path, test.authorization))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if test.statusCode == http.StatusUnauthorized {
+				assert.Equal(t, "Bearer", resp.Header().Get("WWW-Authenticate"))
+			}
+			if test.statusCode != http.StatusOK {
+				mockSessions.AssertNotCalled(t, "ListSessions", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	testCases := []valuesSessionTestCases{
+		{
+			name:       "should list sessions marking the current one",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when bd return an error listing sessions",
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockSessions, _, router := sessionConfigurations()
+
+			sessions := []domain.Session{{ID: "other", UserID: "12345"}, *currentUserSession}
+
+			mockSessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).Return(currentUserSession, nil)
+			mockSessions.On("ListSessions", mock.Anything, "12345").Return(sessions, test.errRepo)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("GET", "/users/12345/sessions", "Bearer "+sessionToken))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if test.statusCode == http.StatusOK {
+				var response domain.APIResponse
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Len(t, response.Sessions, 2)
+				assert.False(t, response.Sessions[0].Current)
+				assert.True(t, response.Sessions[1].Current)
+				assert.NotContains(t, resp.Body.String(), "tokenHash")
+			}
+		})
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	testCases := []valuesSessionTestCases{
+		{
+			name:       "should revoke session of user",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "should return an error when session does not exist",
+			errRepo:    mongo.ErrNoDocuments,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "should return an error when bd return an error revoking session",
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockSessions, _, router := sessionConfigurations()
+
+			mockSessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).Return(currentUserSession, nil)
+			mockSessions.On("DeleteSession", mock.Anything, "12345", "other").Return(test.errRepo).Once()
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("DELETE", "/users/12345/sessions/other", "Bearer "+sessionToken))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			mockSessions.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	testCases := []valuesSessionTestCases{
+		{
+			name:       "should sign out user everywhere",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "should return an error when bd return an error revoking sessions",
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockSessions, _, router := sessionConfigurations()
+
+			mockSessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).Return(currentUserSession, nil)
+			mockSessions.On("DeleteUserSessions", mock.Anything, "12345").Return(int64(2), test.errRepo).Once()
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("DELETE", "/users/12345/sessions", "Bearer "+sessionToken))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			mockSessions.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLogout(t *testing.T) {
+	testCases := []valuesSessionTestCases{
+		{
+			name:       "should revoke the session making the request",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "should logout when session was revoked concurrently",
+			errRepo:    mongo.ErrNoDocuments,
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "should return an error when bd return an error on logout",
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockSessions, _, router := sessionConfigurations()
+
+			mockSessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).Return(currentUserSession, nil)
+			mockSessions.On("DeleteSession", mock.Anything, "12345", "current").Return(test.errRepo).Once()
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("POST", "/auth/logout", "Bearer "+sessionToken))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			mockSessions.AssertExpectations(t)
+		})
+	}
+}
+
+func TestLoginStartsSession(t *testing.T) {
+	mockRepo, handler, router := authConfigurations(func(password, hash string) (bool, string, error) {
+		return true, "", nil
+	})
+	mockSessions := new(mocks.SessionRepository)
+	handler.AuthService.WithSessions(usecase.NewSessionService(mockSessions, time.Hour))
+
+	router.POST(loginRoute, handler.Login)
+
+	mockRepo.On("GetUserByLogin", mock.Anything, credentials.Login).Return(storedUser, nil)
+	mockSessions.On("CreateSession", mock.Anything, mock.MatchedBy(func(session *domain.Session) bool {
+		return session.UserID == storedUser.ID && session.Device == "Firefox on Linux" && session.IP == "192.0.2.1"
+	})).Return(nil).Once()
+
+	bodyBytes, _ := json.Marshal(credentials)
+	req, _ := http.NewRequest("POST", loginRoute, bytes.NewBuffer(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (X11; Linux x86_64; rv:128.0) Gecko/20100101 Firefox/128.0")
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response domain.APIResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.SessionID)
+	assert.NotEmpty(t, response.SessionToken)
+
+	session := mockSessions.Calls[0].Arguments.Get(1).(*domain.Session)
+	assert.Equal(t, utils.HashToken(response.SessionToken), session.TokenHash)
+	mockSessions.AssertExpectations(t)
+}
+
+const (
+	adminToken         = "admin-token"
+	impersonationToken = "impersonation-token"
+)
+
+var adminSession = &domain.Session{ID: "admin-session", UserID: "support-1"}
+
+var impersonationSession = &domain.Session{ID: "impersonation", UserID: "12345", ActorID: "support-1"}
+
+type impersonationMocks struct {
+	sessions *mocks.SessionRepository
+	users    *mocks.UserRepository
+	audit    *mocks.AuditRepository
+}
+
+func impersonationConfigurations(adminIDs []string) (*impersonationMocks, *gin.Engine) {
+	repos := &impersonationMocks{
+		sessions: new(mocks.SessionRepository),
+		users:    new(mocks.UserRepository),
+		audit:    new(mocks.AuditRepository),
+	}
+
+	sessionService := usecase.NewSessionService(repos.sessions, time.Hour)
+	if adminIDs != nil {
+		sessionService.WithImpersonation(repos.users, repos.audit, 15*time.Minute, adminIDs)
+	}
+	handler := handlers.SessionHandlers{SessionService: sessionService}
+
+	repos.sessions.On("TouchSession", mock.Anything, utils.HashToken(adminToken)).Return(adminSession, nil)
+	repos.sessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).Return(currentUserSession, nil)
+	repos.sessions.On("TouchSession", mock.Anything, utils.HashToken(impersonationToken)).Return(impersonationSession, nil)
+	repos.sessions.On("TouchSession", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+
+	router := gin.Default()
+	router.POST("/users/:id/password", handler.DenyImpersonation, func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	authenticated := router.Group("", handler.RequireSession)
+	authenticated.POST("/admin/users/:id/impersonate", handler.Impersonate)
+	authenticated.POST("/users/:id/api-keys", handler.DenyImpersonation, func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+	authenticated.POST("/auth/logout", handler.Logout)
+
+	return repos, router
+}
+
+func TestImpersonate(t *testing.T) {
+	testCases := []struct {
+		name          string
+		adminIDs      []string
+		authorization string
+		path          string
+		errUser       error
+		errAudit      error
+		statusCode    int
+	}{
+		{
+			name:          "should issue a short-lived session acted by the admin",
+			adminIDs:      []string{"support-1"},
+			authorization: "Bearer " + adminToken,
+			path:          "/admin/users/12345/impersonate",
+			statusCode:    http.StatusCreated,
+		},
+		{
+			name:          "should return an error when impersonation is not enabled",
+			authorization: "Bearer " + adminToken,
+			path:          "/admin/users/12345/impersonate",
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when user is not an admin",
+			adminIDs:      []string{"support-1"},
+			authorization: "Bearer " + sessionToken,
+			path:          "/admin/users/67890/impersonate",
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when impersonating from an impersonation session",
+			adminIDs:      []string{"support-1", "12345"},
+			authorization: "Bearer " + impersonationToken,
+			path:          "/admin/users/67890/impersonate",
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:       "should return an error when session is missing",
+			adminIDs:   []string{"support-1"},
+			path:       "/admin/users/12345/impersonate",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "should return an error when user does not exist",
+			adminIDs:      []string{"support-1"},
+			authorization: "Bearer " + adminToken,
+			path:          "/admin/users/12345/impersonate",
+			errUser:       mongo.ErrNoDocuments,
+			statusCode:    http.StatusNotFound,
+		},
+		{
+			name:          "should revoke the session when the start can not be audited",
+			adminIDs:      []string{"support-1"},
+			authorization: "Bearer " + adminToken,
+			path:          "/admin/users/12345/impersonate",
+			errAudit:      errors.New(errorValue),
+			statusCode:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, router := impersonationConfigurations(test.adminIDs)
+
+			repos.users.On("GetUserByID", mock.Anything, "12345").Return(storedUser, test.errUser)
+			repos.sessions.On("CreateSession", mock.Anything, mock.Anything).Return(nil)
+			repos.sessions.On("DeleteSession", mock.Anything, "12345", mock.Anything).Return(nil)
+			repos.audit.On("RecordEvent", mock.Anything, mock.Anything).Return(test.errAudit)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("POST", test.path, test.authorization))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.errAudit != nil {
+				repos.sessions.AssertCalled(t, "DeleteSession", mock.Anything, "12345", mock.Anything)
+			}
+
+			if test.statusCode != http.StatusCreated {
+				if test.errAudit == nil {
+					repos.sessions.AssertNotCalled(t, "CreateSession", mock.Anything, mock.Anything)
+				}
+				return
+			}
+
+			var response domain.APIResponse
+			assert.NoError(t, json.Unmarshal(resp.Body.Bytes(), &response))
+			assert.NotEmpty(t, response.SessionToken)
+			assert.Equal(t, "12345", response.Impersonation.Subject)
+			assert.Equal(t, "support-1", response.Impersonation.Actor.Subject)
+			assert.WithinDuration(t, time.Now().Add(15*time.Minute), response.Impersonation.ExpiresAt, 5*time.Second)
+
+			session := repos.sessions.Calls[len(repos.sessions.Calls)-1].Arguments.Get(1).(*domain.Session)
+			assert.Equal(t, utils.HashToken(response.SessionToken), session.TokenHash)
+			assert.Equal(t, "support-1", session.ActorID)
+
+			repos.audit.AssertCalled(t, "RecordEvent", mock.Anything, &domain.AuditEvent{
+				Action:   domain.AuditActionImpersonationStarted,
+				ActorID:  "support-1",
+				TargetID: "12345",
+			})
+		})
+	}
+}
+
+func TestDenyImpersonation(t *testing.T) {
+	testCases := []struct {
+		name          string
+		path          string
+		authorization string
+		statusCode    int
+	}{
+		{
+			name:          "should deny password change with impersonation session",
+			path:          "/users/12345/password",
+			authorization: "Bearer " + impersonationToken,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should deny API key creation with impersonation session",
+			path:          "/users/12345/api-keys",
+			authorization: "Bearer " + impersonationToken,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should allow password change with session of the user",
+			path:          "/users/12345/password",
+			authorization: "Bearer " + sessionToken,
+			statusCode:    http.StatusNoContent,
+		},
+		{
+			name:       "should leave password change without session to the handler",
+			path:       "/users/12345/password",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:          "should leave password change with unknown token to the handler",
+			path:          "/users/12345/password",
+			authorization: "Bearer unknown",
+			statusCode:    http.StatusNoContent,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, router := impersonationConfigurations([]string{"support-1"})
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("POST", test.path, test.authorization))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+		})
+	}
+}
+
+func TestLogoutEndsImpersonation(t *testing.T) {
+	repos, router := impersonationConfigurations([]string{"support-1"})
+
+	repos.sessions.On("DeleteSession", mock.Anything, "12345", "impersonation").Return(nil).Once()
+	repos.audit.On("RecordEvent", mock.Anything, &domain.AuditEvent{
+		Action:   domain.AuditActionImpersonationEnded,
+		ActorID:  "support-1",
+		TargetID: "12345",
+	}).Return(nil).Once()
+
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, sessionRequest("POST", "/auth/logout", "Bearer "+impersonationToken))
+
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	repos.sessions.AssertCalled(t, "DeleteSession", mock.Anything, "12345", "impersonation")
+	repos.audit.AssertExpectations(t)
+}
+
+// adminConfigurations returns session handlers where the user of adminSession is an administrator,
+// the sessions of adminToken, sessionToken and impersonationToken are valid.
+func adminConfigurations() handlers.SessionHandlers {
+	mockSessions := new(mocks.SessionRepository)
+	mockSessions.On("TouchSession", mock.Anything, utils.HashToken(adminToken)).Return(adminSession, nil)
+	mockSessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).Return(currentUserSession, nil)
+	mockSessions.On("TouchSession", mock.Anything, utils.HashToken(impersonationToken)).Return(impersonationSession, nil)
+	mockSessions.On("TouchSession", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+
+	return handlers.SessionHandlers{SessionService: usecase.NewSessionService(mockSessions, time.Hour).WithAdmins([]string{adminSession.UserID})}
+}
+
+func TestRequireAdmin(t *testing.T) {
+	testCases := []struct {
+		name          string
+		authorization string
+		statusCode    int
+	}{
+		{
+			name:          "should allow administrator",
+			authorization: "Bearer " + adminToken,
+			statusCode:    http.StatusNoContent,
+		},
+		{
+			name:          "should return an error when user is not administrator",
+			authorization: "Bearer " + sessionToken,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when administrator is impersonating a user",
+			authorization: "Bearer " + impersonationToken,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:       "should return an error when request is not authenticated",
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			handler := adminConfigurations()
+
+			router := gin.Default()
+			router.GET("/admin/check", handler.RequireSession, handler.RequireAdmin, func(c *gin.Context) {
+				c.Status(http.StatusNoContent)
+			})
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("GET", "/admin/check", test.authorization))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+		})
+	}
+}
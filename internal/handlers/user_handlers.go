@@ -3,7 +3,9 @@ package handlers
 import (
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 
@@ -12,12 +14,19 @@ import (
 	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
 
 	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/gin-contrib/sse"
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
 )
 
+// userEventsHeartbeat is how often a comment is sent on an idle stream of user events, so
+// proxies and load balancers keep the connection open.
+const userEventsHeartbeat = 15 * time.Second
+
 // UserHandlers encapsulates the user-related HTTP handlers.
 type UserHandlers struct {
 	UserService *usecase.UserService
+	UserEvents  usecase.UserEventStream
 }
 
 // CreateUser handles the creation of a new user in database.
@@ -26,7 +35,7 @@ func (h *UserHandlers) CreateUser(c *gin.Context) {
 	var user domain.User
 
 	if err := c.ShouldBindJSON(&user); err != nil {
-		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, withPasswordViolations(err, &user))
 		return
 	}
 
@@ -70,16 +79,22 @@ func (h *UserHandlers) GetUserByID(c *gin.Context) {
 }
 
 // UpdateUser handles the update user by id in database.
-// It expects a JSON body with update user information and return the user.
+// It expects a JSON body with name, email and userName and return the user.
 func (h *UserHandlers) UpdateUser(c *gin.Context) {
 	id := c.Param("id")
 
-	var updateFields domain.User
-	if err := c.ShouldBindJSON(&updateFields); err != nil {
+	var request domain.UpdateUserRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
 		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
 		return
 	}
 
+	updateFields := domain.User{
+		Name:     request.Name,
+		Email:    request.Email,
+		UserName: request.UserName,
+	}
+
 	user, err := h.UserService.UpdateUser(c.Request.Context(), id, &updateFields)
 
 	if err != nil {
@@ -95,15 +110,153 @@ func (h *UserHandlers) UpdateUser(c *gin.Context) {
 		return
 	}
 
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:      true,
+		ID:           id,
+		Name:         user.Name,
+		Email:        user.Email,
+		PendingEmail: user.PendingEmail,
+		UserName:     user.UserName,
+	})
+}
+
+// SendEmailVerification handles the request of a new email verification link.
+// It expects a id param with user and return status accepted.
+func (h *UserHandlers) SendEmailVerification(c *gin.Context) {
+	id := c.Param("id")
+
+	err := h.UserService.SendEmailVerification(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			respondWithError(c, http.StatusNotFound, constants.ErrUserNotFound, nil)
+		case errors.Is(err, usecase.ErrEmailAlreadyVerified):
+			respondWithError(c, http.StatusConflict, constants.ErrEmailAlreadyVerified, nil)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrSendEmailVerification, err)
+		}
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// VerifyEmail handles the confirmation of an email with the token sent by email.
+// It expects a token query param and return the user with the verified email.
+func (h *UserHandlers) VerifyEmail(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidVerificationToken, nil)
+		return
+	}
+
+	user, err := h.UserService.VerifyEmail(c.Request.Context(), token)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidVerificationToken):
+			respondWithError(c, http.StatusBadRequest, constants.ErrInvalidVerificationToken, nil)
+		case mongo.IsDuplicateKeyError(err):
+			respondWithError(c, http.StatusBadRequest, constants.ErrUserOrEmailInUse, err)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToVerifyEmail, err)
+		}
+		return
+	}
+
 	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
 		Success:  true,
-		ID:       id,
-		Name:     user.Name,
+		ID:       user.ID,
 		Email:    user.Email,
 		UserName: user.UserName,
 	})
 }
 
+// ChangePassword handles the change of password of a user.
+// It expects a JSON body with the current and new password and return status no content.
+func (h *UserHandlers) ChangePassword(c *gin.Context) {
+	id := c.Param("id")
+
+	var request domain.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	err := h.UserService.ChangePassword(c.Request.Context(), id, &request)
+	if err != nil {
+		var policyErr *utils.PasswordPolicyError
+
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			respondWithError(c, http.StatusNotFound, constants.ErrUserNotFound, nil)
+		case errors.Is(err, usecase.ErrInvalidCurrentPassword):
+			respondWithError(c, http.StatusForbidden, constants.ErrInvalidCurrentPassword, nil)
+		case errors.Is(err, usecase.ErrLoginLocked):
+			respondWithLockout(c, err)
+		case errors.Is(err, usecase.ErrPasswordReused):
+			respondWithError(c, http.StatusBadRequest, constants.ErrPasswordReused, nil)
+		case errors.As(err, &policyErr):
+			respondWithError(c, http.StatusBadRequest, constants.ErrPasswordPolicy, err)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToChangePassword, err)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ForgotPassword handles the request of a password reset link.
+// It expects a JSON body with the email and return status accepted whether the email exists or not, or
+// too many requests when the email or client made too many requests.
+func (h *UserHandlers) ForgotPassword(c *gin.Context) {
+	var request domain.ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	if err := h.UserService.ForgotPassword(c.Request.Context(), request.Email, c.ClientIP()); err != nil {
+		if errors.Is(err, usecase.ErrRateLimited) {
+			respondWithRateLimit(c, err)
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, constants.ErrForgotPassword, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// ResetPassword handles the reset of password with a reset token.
+// It expects a JSON body with the token and new password and return status no content.
+func (h *UserHandlers) ResetPassword(c *gin.Context) {
+	var request domain.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	err := h.UserService.ResetPassword(c.Request.Context(), &request)
+	if err != nil {
+		var policyErr *utils.PasswordPolicyError
+
+		switch {
+		case errors.Is(err, usecase.ErrInvalidResetToken):
+			respondWithError(c, http.StatusBadRequest, constants.ErrInvalidResetToken, nil)
+		case errors.Is(err, usecase.ErrPasswordReused):
+			respondWithError(c, http.StatusBadRequest, constants.ErrPasswordReused, nil)
+		case errors.As(err, &policyErr):
+			respondWithError(c, http.StatusBadRequest, constants.ErrPasswordPolicy, err)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToResetPassword, err)
+		}
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
 // DeleteUser handles the delete user by ID in database.
 // It expects a id param with user and return status no content.
 func (h *UserHandlers) DeleteUser(c *gin.Context) {
@@ -123,6 +276,78 @@ func (h *UserHandlers) DeleteUser(c *gin.Context) {
 	c.Status(http.StatusNoContent)
 }
 
+// StreamUserEvents handles the stream of the creations, updates and deletions of users as server-sent events,
+// it is only served to administrators. It resumes after the Last-Event-ID header and sends each change as a
+// CloudEvent named after its type.
+func (h *UserHandlers) StreamUserEvents(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	notifications, err := h.UserEvents.Subscribe(ctx, c.GetHeader("Last-Event-ID"))
+	if err != nil {
+		if errors.Is(err, usecase.ErrEventHistoryLost) {
+			respondWithError(c, http.StatusGone, constants.ErrEventHistoryLost, nil)
+			return
+		}
+
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToStreamEvents, err)
+		return
+	}
+
+	c.Header("Content-Type", sse.ContentType)
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(userEventsHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case notification, ok := <-notifications:
+			if !ok {
+				return false
+			}
+
+			c.Render(-1, sse.Event{
+				Id:    notification.ID,
+				Event: notification.Event.Type,
+				Data:  notification.Event,
+			})
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": heartbeat\n\n")
+			return err == nil
+		case <-ctx.Done():
+			return false
+		}
+	})
+}
+
+// ListAuditEvents handles the audit log of a user from newest to oldest.
+// It expects a id param with user and the limit and cursor query params, and return a page
+// of events with the cursor of the next page.
+func (h *UserHandlers) ListAuditEvents(c *gin.Context) {
+	var query domain.AuditQuery
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidAuditQuery, err)
+		return
+	}
+
+	events, nextCursor, err := h.UserService.ListAuditEvents(c.Request.Context(), c.Param("id"), &query)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToListAudit, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:     true,
+		AuditEvents: events,
+		NextCursor:  nextCursor,
+	})
+}
+
 // CreateBatchUser handles create batch of user in database.
 func (h *UserHandlers) CreateBatchUser(c *gin.Context) {
 	file, err := c.FormFile("file")
@@ -137,13 +362,20 @@ func (h *UserHandlers) CreateBatchUser(c *gin.Context) {
 	if message != "" {
 		if message == constants.ErrOpenFile {
 			respondWithError(c, http.StatusInternalServerError, message, nil)
+			return
 		}
 		respondWithError(c, http.StatusBadRequest, message, nil)
+		return
 	}
 
 	usersIDsResponse, err := h.UserService.CreateUserBatch(c.Request.Context(), &users)
 
 	if err != nil {
+		var policyErr *utils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			respondWithError(c, http.StatusBadRequest, constants.ErrPasswordPolicy, err)
+			return
+		}
 		if mongo.IsDuplicateKeyError(err) {
 			respondWithError(c, http.StatusBadRequest, constants.ErrUserOrEmailInUse, err)
 			return
@@ -166,6 +398,11 @@ func respondWithError(c *gin.Context, code int, message string, err error) {
 
 	if err != nil {
 		apiErr.Details = err.Error()
+
+		var policyErr *utils.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			apiErr.Violations = policyErr.Violations
+		}
 	}
 
 	c.JSON(code, domain.APIResponse{
@@ -173,6 +410,26 @@ func respondWithError(c *gin.Context, code int, message string, err error) {
 		Errors:  apiErr})
 }
 
+// withPasswordViolations adds the failed password policy rules to a binding error.
+func withPasswordViolations(err error, user *domain.User) error {
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+
+	for _, fieldError := range validationErrors {
+		if fieldError.Tag() != "password" {
+			continue
+		}
+
+		if policyErr := utils.PasswordViolations(user.Password, user.UserName, user.Email); policyErr != nil {
+			return errors.Join(err, policyErr)
+		}
+	}
+
+	return err
+}
+
 func respondWithSuccess(c *gin.Context, code int, response domain.APIResponse) {
 	c.JSON(code, response)
 }
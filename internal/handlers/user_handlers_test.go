@@ -1,7 +1,9 @@
 package handlers_test
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,11 +12,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
 
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
 	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
 	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
 	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
@@ -55,8 +60,8 @@ const (
 	withID         = "%v/:id"
 	filePath       = "testUser.csv"
 	fileContent    = `name,email,password,username
-John Doe,john@example.com,secretpassword,johndoe
-Jane Smith,jane@example.com,anotherpassword,janesmith`
+John Doe,john@example.com,Secret123*,johndoe
+Jane Smith,jane@example.com,Another456#,janesmith`
 )
 
 var userRequest = &domain.User{
@@ -295,7 +300,8 @@ func TestUpdateUser(t *testing.T) {
 
 			bodyBytes, _ := json.Marshal(test.body)
 
-			mockRepo.On("UpdateUser", mock.Anything, test.id, test.body).Return(test.userResponse, test.err)
+			mockRepo.On("GetUserByID", mock.Anything, test.id).Return(userRequest, nil)
+			mockRepo.On("UpdateUser", mock.Anything, test.id, updateFields(test.body)).Return(test.userResponse, test.err)
 
 			req, _ := mockRequestEndPoint(test.isErrorBody, "PATCH", fmt.Sprintf("%v/%v", route, test.id), bytes.NewBuffer(bodyBytes))
 
@@ -449,10 +455,27 @@ func mockRequestEndPoint(isError bool, method string, api string, body io.Reader
 	return req, nil
 }
 
+func updateFields(user *domain.User) *domain.User {
+	if user == nil {
+		return nil
+	}
+
+	return &domain.User{
+		Name:     user.Name,
+		Email:    user.Email,
+		UserName: user.UserName,
+	}
+}
+
 func configurations() (*mocks.UserRepository, handlers.UserHandlers, *gin.Engine) {
 	mockRepo := new(mocks.UserRepository)
 
-	userService := usecase.NewUserService(mockRepo)
+	mockAudit := new(mocks.AuditRepository)
+	mockAudit.On("RecordEvent", mock.Anything, mock.Anything).Return(nil)
+
+	userService := usecase.NewUserService(mockRepo, mockAudit, func(password, hash string) bool {
+		return password == hash
+	})
 
 	handler := handlers.UserHandlers{UserService: userService}
 
@@ -462,3 +485,1381 @@ func configurations() (*mocks.UserRepository, handlers.UserHandlers, *gin.Engine
 
 	return mockRepo, handler, router
 }
+
+func TestCreateUserPasswordPolicy(t *testing.T) {
+	_, handler, router := configurations()
+
+	router.POST(route, handler.CreateUser)
+
+	bodyBytes, _ := json.Marshal(&domain.User{
+		Name:     "Cristian",
+		Email:    "cristian@gmail.com",
+		Password: "cristian1",
+		UserName: "cristian",
+	})
+
+	req, _ := mockRequestEndPoint(false, "POST", route, bytes.NewBuffer(bodyBytes))
+
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	var response domain.APIResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, []string{
+		"must contain an uppercase letter",
+		"must contain one of the symbols " + utils.DefaultPasswordSymbols,
+		"must not contain the userName or email",
+	}, response.Errors.Violations)
+}
+
+func TestCreateBatchUserPasswordPolicy(t *testing.T) {
+	mockRepo, handler, router := configurations()
+
+	router.POST(route, handler.CreateBatchUser)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filePath)
+	assert.NoError(t, err)
+	_, err = part.Write([]byte(`name,email,password,username
+John Doe,john@example.com,Secret123*,johndoe
+Jane Smith,jane@example.com,janesmith1,janesmith`))
+	assert.NoError(t, err)
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", route, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	var response domain.APIResponse
+	err = json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Equal(t, []string{
+		"row 2: password must contain an uppercase letter",
+		"row 2: password must contain one of the symbols " + utils.DefaultPasswordSymbols,
+		"row 2: password must not contain the userName or email",
+	}, response.Errors.Violations)
+	mockRepo.AssertNotCalled(t, "CreateUserBatch", mock.Anything, mock.Anything)
+}
+
+type valuesChangePasswordTestCases struct {
+	name        string
+	body        *domain.ChangePasswordRequest
+	isErrorBody bool
+	user        *domain.User
+	err         error
+	errUpdate   error
+	errRevoke   error
+	errTokens   error
+	statusCode  int
+	violations  []string
+}
+
+type sessionRevokerMock struct {
+	mock.Mock
+}
+
+func (m *sessionRevokerMock) RevokeUserSessions(ctx context.Context, userID string) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+type oauthTokenRevokerMock struct {
+	mock.Mock
+}
+
+func (m *oauthTokenRevokerMock) RevokeUserTokens(ctx context.Context, userID string) error {
+	return m.Called(ctx, userID).Error(0)
+}
+
+func TestChangePassword(t *testing.T) {
+	passwordUser := &domain.User{
+		ID:              "12345",
+		UserName:        "cristian",
+		Email:           "cristian@gmail.com",
+		Password:        "Current123*",
+		PasswordHistory: []string{"Previous123*", "Current123*"},
+	}
+
+	testCases := []valuesChangePasswordTestCases{
+		{
+			name:       "should change password and revoke sessions and OAuth tokens",
+			body:       &domain.ChangePasswordRequest{CurrentPassword: "Current123*", NewPassword: "Brand123*"},
+			user:       passwordUser,
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:        "should return an error when is an invalid body for change password",
+			isErrorBody: true,
+			statusCode:  http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when user does not exist",
+			body:       &domain.ChangePasswordRequest{CurrentPassword: "Current123*", NewPassword: "Brand123*"},
+			err:        mongo.ErrNoDocuments,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "should return an error when current password is not valid",
+			body:       &domain.ChangePasswordRequest{CurrentPassword: "Wrong123*", NewPassword: "Brand123*"},
+			user:       passwordUser,
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "should return an error when new password does not satisfy the policy",
+			body:       &domain.ChangePasswordRequest{CurrentPassword: "Current123*", NewPassword: "cristian1*"},
+			user:       passwordUser,
+			statusCode: http.StatusBadRequest,
+			violations: []string{"must contain an uppercase letter", "must not contain the userName or email"},
+		},
+		{
+			name:       "should return an error when new password is in the history",
+			body:       &domain.ChangePasswordRequest{CurrentPassword: "Current123*", NewPassword: "Previous123*"},
+			user:       passwordUser,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when bd return an error changing password",
+			body:       &domain.ChangePasswordRequest{CurrentPassword: "Current123*", NewPassword: "Brand123*"},
+			user:       passwordUser,
+			errUpdate:  errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:       "should return an error when sessions can not be revoked",
+			body:       &domain.ChangePasswordRequest{CurrentPassword: "Current123*", NewPassword: "Brand123*"},
+			user:       passwordUser,
+			errRevoke:  errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:       "should return an error when OAuth tokens can not be revoked",
+			body:       &domain.ChangePasswordRequest{CurrentPassword: "Current123*", NewPassword: "Brand123*"},
+			user:       passwordUser,
+			errTokens:  errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			mockAudit := new(mocks.AuditRepository)
+			mockRevoker := new(sessionRevokerMock)
+			mockTokenRevoker := new(oauthTokenRevokerMock)
+
+			userService := usecase.NewUserService(mockRepo, mockAudit, func(password, hash string) bool {
+				return password == hash
+			}).WithSessionRevoker(mockRevoker).WithOAuthTokenRevoker(mockTokenRevoker)
+			handler := handlers.UserHandlers{UserService: userService}
+			router := gin.Default()
+
+			router.POST(fmt.Sprintf(withID, route)+"/password", handler.ChangePassword)
+
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(test.user, test.err)
+			mockRepo.On("UpdatePassword", mock.Anything, "12345", "Brand123*").Return(test.errUpdate)
+			mockRevoker.On("RevokeUserSessions", mock.Anything, "12345").Return(test.errRevoke)
+			mockTokenRevoker.On("RevokeUserTokens", mock.Anything, "12345").Return(test.errTokens)
+			mockAudit.On("RecordEvent", mock.Anything, mock.MatchedBy(func(event *domain.AuditEvent) bool {
+				return event.Action == domain.AuditActionPasswordChanged && event.TargetID == "12345"
+			})).Return(nil)
+
+			bodyBytes, _ := json.Marshal(test.body)
+
+			req, _ := mockRequestEndPoint(test.isErrorBody, "POST", route+"/12345/password", bytes.NewBuffer(bodyBytes))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusNoContent {
+				mockRepo.AssertExpectations(t)
+				mockRevoker.AssertExpectations(t)
+				mockTokenRevoker.AssertExpectations(t)
+				mockAudit.AssertExpectations(t)
+			} else {
+				mockAudit.AssertNotCalled(t, "RecordEvent", mock.Anything, mock.Anything)
+			}
+
+			if test.violations != nil {
+				var response domain.APIResponse
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, test.violations, response.Errors.Violations)
+			}
+		})
+	}
+}
+
+type valuesChangePasswordLockoutTestCases struct {
+	name            string
+	currentPassword string
+	userAttempt     *domain.LoginAttempt
+	failures        int
+	locks           bool
+	statusCode      int
+}
+
+func TestChangePasswordLockout(t *testing.T) {
+	passwordUser := &domain.User{ID: "12345", UserName: "cristian", Email: "cristian@gmail.com", Password: "Current123*"}
+
+	testCases := []valuesChangePasswordLockoutTestCases{
+		{
+			name:            "should change password and reset failed logins of the user",
+			currentPassword: "Current123*",
+			statusCode:      http.StatusNoContent,
+		},
+		{
+			name:            "should count a wrong current password as a failed login",
+			currentPassword: "Wrong123*",
+			failures:        1,
+			statusCode:      http.StatusForbidden,
+		},
+		{
+			name:            "should lock the user when the threshold is reached",
+			currentPassword: "Wrong123*",
+			failures:        3,
+			locks:           true,
+			statusCode:      http.StatusForbidden,
+		},
+		{
+			name:            "should reject a locked user even with the current password",
+			currentPassword: "Current123*",
+			userAttempt:     &domain.LoginAttempt{LockedUntil: time.Now().Add(time.Minute)},
+			statusCode:      http.StatusTooManyRequests,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			mockAudit := new(mocks.AuditRepository)
+			mockAttempts := new(mocks.LoginAttemptRepository)
+
+			userService := usecase.NewUserService(mockRepo, mockAudit, func(password, hash string) bool {
+				return password == hash
+			}).WithLockout(mockAttempts, lockoutPolicy)
+			handler := handlers.UserHandlers{UserService: userService}
+			router := gin.Default()
+
+			router.POST(fmt.Sprintf(withID, route)+"/password", handler.ChangePassword)
+
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(passwordUser, nil)
+			mockRepo.On("UpdatePassword", mock.Anything, "12345", "Brand123*").Return(nil)
+			mockAudit.On("RecordEvent", mock.Anything, mock.Anything).Return(nil)
+			mockAttempts.On("GetLoginAttempt", mock.Anything, "user:12345").Return(attemptOrMissing(test.userAttempt))
+			mockAttempts.On("RecordFailedLogin", mock.Anything, "user:12345", mock.Anything).
+				Return(&domain.LoginAttempt{Failures: test.failures}, nil)
+			mockAttempts.On("LockLogin", mock.Anything, "user:12345", mock.Anything, mock.Anything).Return(nil)
+			mockAttempts.On("ResetLoginAttempts", mock.Anything, "user:12345").Return(nil)
+
+			bodyBytes, _ := json.Marshal(&domain.ChangePasswordRequest{CurrentPassword: test.currentPassword, NewPassword: "Brand123*"})
+
+			req, _ := http.NewRequest("POST", route+"/12345/password", bytes.NewBuffer(bodyBytes))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			switch test.statusCode {
+			case http.StatusNoContent:
+				mockAttempts.AssertCalled(t, "ResetLoginAttempts", mock.Anything, "user:12345")
+			case http.StatusForbidden:
+				mockAttempts.AssertCalled(t, "RecordFailedLogin", mock.Anything, "user:12345", mock.Anything)
+			case http.StatusTooManyRequests:
+				assert.Equal(t, "60", resp.Header().Get("Retry-After"))
+				mockAttempts.AssertNotCalled(t, "RecordFailedLogin", mock.Anything, mock.Anything, mock.Anything)
+				mockRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+			}
+
+			if test.locks {
+				mockAttempts.AssertCalled(t, "LockLogin", mock.Anything, "user:12345", mock.Anything, mock.Anything)
+			} else {
+				mockAttempts.AssertNotCalled(t, "LockLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type valuesForgotPasswordTestCases struct {
+	name        string
+	email       string
+	isErrorBody bool
+	user        *domain.User
+	err         error
+	errToken    error
+	errNotifier bool
+	count       int
+	sent        bool
+	statusCode  int
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New(errorValue)
+}
+
+var resetPolicy = usecase.PasswordResetPolicy{TTL: time.Hour, AddressLimit: 5, IPLimit: 20, Window: 15 * time.Minute}
+
+func TestForgotPassword(t *testing.T) {
+	resetUser := &domain.User{ID: "12345", Email: "cristian@gmail.com", UserName: "cristian"}
+
+	testCases := []valuesForgotPasswordTestCases{
+		{
+			name:       "should send a reset link when email exists",
+			email:      "cristian@gmail.com",
+			user:       resetUser,
+			sent:       true,
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:       "should return accepted without sending when email does not exist",
+			email:      "unknown@gmail.com",
+			err:        mongo.ErrNoDocuments,
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:       "should send the reset link to the stored email when case differs",
+			email:      "Cristian@Gmail.com",
+			user:       resetUser,
+			sent:       true,
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:       "should return an error when email requested too many links",
+			email:      "cristian@gmail.com",
+			user:       resetUser,
+			count:      resetPolicy.AddressLimit + 1,
+			statusCode: http.StatusTooManyRequests,
+		},
+		{
+			name:        "should return accepted when notifier fails",
+			email:       "cristian@gmail.com",
+			user:        resetUser,
+			errNotifier: true,
+			statusCode:  http.StatusAccepted,
+		},
+		{
+			name:        "should return an error when is an invalid body for forgot password",
+			isErrorBody: true,
+			statusCode:  http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when bd return an error storing token",
+			email:      "cristian@gmail.com",
+			user:       resetUser,
+			errToken:   errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			mockReset := new(mocks.PasswordResetRepository)
+			outbox := &bytes.Buffer{}
+
+			var writer io.Writer = outbox
+			if test.errNotifier {
+				writer = failingWriter{}
+			}
+
+			mockRateLimits := new(mocks.RateLimitRepository)
+
+			userService := usecase.NewUserService(mockRepo, new(mocks.AuditRepository), nil).
+				WithPasswordReset(mockReset, mockRateLimits, adapters.NewLogNotifier(writer), "https://example.com/reset", resetPolicy)
+			handler := handlers.UserHandlers{UserService: userService}
+			router := gin.Default()
+
+			router.POST("/auth/password/forgot", handler.ForgotPassword)
+
+			mockRateLimits.On("Hit", mock.Anything, "password_reset:ip:", resetPolicy.Window).
+				Return(&domain.RateLimit{Count: 1, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+			mockRateLimits.On("Hit", mock.Anything, "password_reset:email:"+strings.ToLower(test.email), resetPolicy.Window).
+				Return(&domain.RateLimit{Count: test.count, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+			mockRepo.On("GetUserByEmail", mock.Anything, strings.ToLower(test.email)).Return(test.user, test.err)
+			mockReset.On("CreateResetToken", mock.Anything, mock.MatchedBy(func(token *domain.PasswordResetToken) bool {
+				return token.UserID == "12345" && len(token.ID) == 64 && token.ExpiresAt.After(time.Now())
+			})).Return(test.errToken)
+
+			bodyBytes, _ := json.Marshal(domain.ForgotPasswordRequest{Email: test.email})
+
+			req, _ := mockRequestEndPoint(test.isErrorBody, "POST", "/auth/password/forgot", bytes.NewBuffer(bodyBytes))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusTooManyRequests {
+				assert.Equal(t, "60", resp.Header().Get("Retry-After"))
+				mockRepo.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+			}
+
+			if !test.sent {
+				assert.Empty(t, outbox.String())
+				return
+			}
+
+			var message domain.Message
+			err := json.Unmarshal(outbox.Bytes(), &message)
+			assert.NoError(t, err)
+			assert.Equal(t, test.user.Email, message.To)
+			assert.Contains(t, message.Body, "https://example.com/reset?token=")
+		})
+	}
+}
+
+type valuesResetPasswordTestCases struct {
+	name        string
+	body        *domain.ResetPasswordRequest
+	isErrorBody bool
+	errToken    error
+	errUser     error
+	errUpdate   error
+	statusCode  int
+}
+
+func TestResetPassword(t *testing.T) {
+	resetUser := &domain.User{
+		ID:              "12345",
+		UserName:        "cristian",
+		Email:           "cristian@gmail.com",
+		Password:        "Current123*",
+		PasswordHistory: []string{"Previous123*", "Current123*"},
+	}
+
+	testCases := []valuesResetPasswordTestCases{
+		{
+			name:       "should reset password and revoke sessions",
+			body:       &domain.ResetPasswordRequest{Token: "reset-token", NewPassword: "Brand123*"},
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:        "should return an error when is an invalid body for reset password",
+			isErrorBody: true,
+			statusCode:  http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when token is invalid, expired or used",
+			body:       &domain.ResetPasswordRequest{Token: "reset-token", NewPassword: "Brand123*"},
+			errToken:   mongo.ErrNoDocuments,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when user of token does not exist",
+			body:       &domain.ResetPasswordRequest{Token: "reset-token", NewPassword: "Brand123*"},
+			errUser:    mongo.ErrNoDocuments,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when new password does not satisfy the policy",
+			body:       &domain.ResetPasswordRequest{Token: "reset-token", NewPassword: "short"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when new password is in the history",
+			body:       &domain.ResetPasswordRequest{Token: "reset-token", NewPassword: "Previous123*"},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when bd return an error consuming token",
+			body:       &domain.ResetPasswordRequest{Token: "reset-token", NewPassword: "Brand123*"},
+			errToken:   errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:       "should return an error when bd return an error resetting password",
+			body:       &domain.ResetPasswordRequest{Token: "reset-token", NewPassword: "Brand123*"},
+			errUpdate:  errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			mockAudit := new(mocks.AuditRepository)
+			mockReset := new(mocks.PasswordResetRepository)
+			mockRevoker := new(sessionRevokerMock)
+
+			userService := usecase.NewUserService(mockRepo, mockAudit, func(password, hash string) bool {
+				return password == hash
+			}).WithSessionRevoker(mockRevoker).
+				WithPasswordReset(mockReset, new(mocks.RateLimitRepository), adapters.NewLogNotifier(io.Discard), "https://example.com/reset", resetPolicy)
+			handler := handlers.UserHandlers{UserService: userService}
+			router := gin.Default()
+
+			router.POST("/auth/password/reset", handler.ResetPassword)
+
+			mockReset.On("ConsumeResetToken", mock.Anything, utils.HashToken("reset-token")).
+				Return(&domain.PasswordResetToken{UserID: "12345"}, test.errToken)
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(resetUser, test.errUser)
+			mockRepo.On("UpdatePassword", mock.Anything, "12345", "Brand123*").Return(test.errUpdate)
+			mockRevoker.On("RevokeUserSessions", mock.Anything, "12345").Return(nil)
+			mockAudit.On("RecordEvent", mock.Anything, mock.MatchedBy(func(event *domain.AuditEvent) bool {
+				return event.Action == domain.AuditActionPasswordReset && event.TargetID == "12345"
+			})).Return(nil)
+
+			bodyBytes, _ := json.Marshal(test.body)
+
+			req, _ := mockRequestEndPoint(test.isErrorBody, "POST", "/auth/password/reset", bytes.NewBuffer(bodyBytes))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusNoContent {
+				mockReset.AssertExpectations(t)
+				mockRevoker.AssertExpectations(t)
+				mockAudit.AssertExpectations(t)
+			} else {
+				mockAudit.AssertNotCalled(t, "RecordEvent", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type valuesEmailVerificationTestCases struct {
+	name       string
+	user       *domain.User
+	err        error
+	errToken   error
+	sentTo     string
+	statusCode int
+}
+
+func emailVerificationConfigurations(writer io.Writer) (*mocks.UserRepository, *mocks.EmailVerificationRepository, handlers.UserHandlers, *gin.Engine) {
+	mockRepo, _, router := configurations()
+	mockAudit := new(mocks.AuditRepository)
+	mockAudit.On("RecordEvent", mock.Anything, mock.Anything).Return(nil)
+	mockVerification := new(mocks.EmailVerificationRepository)
+
+	userService := usecase.NewUserService(mockRepo, mockAudit, nil).
+		WithEmailVerification(mockVerification, adapters.NewLogNotifier(writer), "https://example.com/verify-email", time.Hour)
+
+	return mockRepo, mockVerification, handlers.UserHandlers{UserService: userService}, router
+}
+
+func TestSendEmailVerification(t *testing.T) {
+	testCases := []valuesEmailVerificationTestCases{
+		{
+			name:       "should send verification link to unverified email",
+			user:       &domain.User{ID: "12345", Email: "cristian@gmail.com"},
+			sentTo:     "cristian@gmail.com",
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:       "should send verification link to pending email",
+			user:       &domain.User{ID: "12345", Email: "cristian@gmail.com", EmailVerified: true, PendingEmail: "new@gmail.com"},
+			sentTo:     "new@gmail.com",
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:       "should return an error when email is already verified",
+			user:       &domain.User{ID: "12345", Email: "cristian@gmail.com", EmailVerified: true},
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "should return an error when user does not exist",
+			err:        mongo.ErrNoDocuments,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "should return an error when bd return an error storing token",
+			user:       &domain.User{ID: "12345", Email: "cristian@gmail.com"},
+			errToken:   errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			outbox := &bytes.Buffer{}
+			mockRepo, mockVerification, handler, router := emailVerificationConfigurations(outbox)
+
+			router.POST(fmt.Sprintf(withID, route)+"/verify-email/send", handler.SendEmailVerification)
+
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(test.user, test.err)
+			mockVerification.On("CreateVerificationToken", mock.Anything, mock.MatchedBy(func(token *domain.EmailVerificationToken) bool {
+				return token.UserID == "12345" && token.Email == test.sentTo && len(token.ID) == 64
+			})).Return(test.errToken)
+
+			req, _ := http.NewRequest("POST", route+"/12345/verify-email/send", nil)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode != http.StatusAccepted {
+				assert.Empty(t, outbox.String())
+				return
+			}
+
+			var message domain.Message
+			err := json.Unmarshal(outbox.Bytes(), &message)
+			assert.NoError(t, err)
+			assert.Equal(t, test.sentTo, message.To)
+			assert.Contains(t, message.Body, "https://example.com/verify-email?token=")
+		})
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	testCases := []valuesEmailVerificationTestCases{
+		{
+			name:       "should verify email of the token",
+			user:       &domain.User{ID: "12345", Email: "new@gmail.com", EmailVerified: true},
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when token is invalid, expired or used",
+			errToken:   mongo.ErrNoDocuments,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when email of token is no longer pending",
+			err:        mongo.ErrNoDocuments,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name: "should return an error when email is in use by other user",
+			err: mongo.WriteError{
+				Code:    11000,
+				Message: errorDuplicate,
+			},
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when bd return an error verifying email",
+			err:        errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, mockVerification, handler, router := emailVerificationConfigurations(io.Discard)
+
+			router.GET("/verify-email", handler.VerifyEmail)
+
+			mockVerification.On("ConsumeVerificationToken", mock.Anything, utils.HashToken("verify-token")).
+				Return(&domain.EmailVerificationToken{UserID: "12345", Email: "new@gmail.com"}, test.errToken)
+			mockRepo.On("VerifyEmail", mock.Anything, "12345", "new@gmail.com").Return(test.user, test.err)
+
+			req, _ := http.NewRequest("GET", "/verify-email?token=verify-token", nil)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusOK {
+				var response domain.APIResponse
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, test.user.Email, response.Email)
+			}
+		})
+	}
+
+	t.Run("should return an error when token is missing", func(t *testing.T) {
+		_, _, handler, router := emailVerificationConfigurations(io.Discard)
+
+		router.GET("/verify-email", handler.VerifyEmail)
+
+		req, _ := http.NewRequest("GET", "/verify-email", nil)
+
+		resp := httptest.NewRecorder()
+
+		router.ServeHTTP(resp, req)
+
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+}
+
+func TestUpdateUserEmailVerification(t *testing.T) {
+	outbox := &bytes.Buffer{}
+	mockRepo, mockVerification, handler, router := emailVerificationConfigurations(outbox)
+
+	router.PATCH(fmt.Sprintf(withID, route), handler.UpdateUser)
+
+	mockRepo.On("GetUserByID", mock.Anything, "12345").Return(&domain.User{
+		ID:       "12345",
+		Name:     userRequest.Name,
+		Email:    "old@gmail.com",
+		UserName: userRequest.UserName,
+	}, nil)
+	mockRepo.On("UpdateUser", mock.Anything, "12345", updateFields(userRequest)).Return(&domain.User{
+		ID:           "12345",
+		Name:         userRequest.Name,
+		Email:        "old@gmail.com",
+		PendingEmail: userRequest.Email,
+		UserName:     userRequest.UserName,
+	}, nil)
+	mockVerification.On("CreateVerificationToken", mock.Anything, mock.MatchedBy(func(token *domain.EmailVerificationToken) bool {
+		return token.Email == userRequest.Email
+	})).Return(nil)
+
+	bodyBytes, _ := json.Marshal(userRequest)
+
+	req, _ := http.NewRequest("PATCH", route+"/12345", bytes.NewBuffer(bodyBytes))
+
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusOK, resp.Code)
+
+	var response domain.APIResponse
+	err := json.Unmarshal(resp.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "old@gmail.com", response.Email)
+	assert.Equal(t, userRequest.Email, response.PendingEmail)
+
+	decoder := json.NewDecoder(outbox)
+
+	var verification, notice domain.Message
+	assert.NoError(t, decoder.Decode(&verification))
+	assert.NoError(t, decoder.Decode(&notice))
+	assert.Equal(t, userRequest.Email, verification.To)
+	assert.Equal(t, "old@gmail.com", notice.To)
+	assert.Equal(t, "Your email is being changed", notice.Subject)
+	assert.Contains(t, notice.Body, userRequest.Email)
+}
+
+func TestUserMutationsAuthorization(t *testing.T) {
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		authorization string
+		statusCode    int
+	}{
+		{
+			name:          "should delete user of the session",
+			method:        "DELETE",
+			path:          route + "/12345",
+			authorization: "Bearer " + sessionToken,
+			statusCode:    http.StatusNoContent,
+		},
+		{
+			name:       "should return an error when update is not authenticated",
+			method:     "PATCH",
+			path:       route + "/12345",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "should return an error when update targets other user",
+			method:        "PATCH",
+			path:          route + "/12345",
+			authorization: "Bearer " + adminToken,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:       "should return an error when delete is not authenticated",
+			method:     "DELETE",
+			path:       route + "/12345",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "should return an error when delete is made impersonating the user",
+			method:        "DELETE",
+			path:          route + "/12345",
+			authorization: "Bearer " + impersonationToken,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:       "should return an error when verification link is requested without session",
+			method:     "POST",
+			path:       route + "/12345/verify-email/send",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:          "should return an error when verification link is requested for other user",
+			method:        "POST",
+			path:          route + "/12345/verify-email/send",
+			authorization: "Bearer " + adminToken,
+			statusCode:    http.StatusForbidden,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, _, handler, router := emailVerificationConfigurations(io.Discard)
+			sessionHandler := adminConfigurations()
+
+			mockRepo.On("DeleteUser", mock.Anything, "12345").Return(nil)
+
+			authenticated := router.Group("", sessionHandler.RequireSession, sessionHandler.DenyAPIKey, sessionHandler.RequireSameUser, sessionHandler.DenyImpersonation)
+			authenticated.PATCH(fmt.Sprintf(withID, route), handler.UpdateUser)
+			authenticated.DELETE(fmt.Sprintf(withID, route), handler.DeleteUser)
+			authenticated.POST(fmt.Sprintf(withID, route)+"/verify-email/send", handler.SendEmailVerification)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest(test.method, test.path, test.authorization))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode != http.StatusNoContent {
+				mockRepo.AssertNotCalled(t, "DeleteUser", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+type auditMocks struct {
+	users    *mocks.UserRepository
+	audit    *mocks.AuditRepository
+	sessions *mocks.SessionRepository
+}
+
+func auditConfigurations() (*auditMocks, *gin.Engine) {
+	repos := &auditMocks{
+		users:    new(mocks.UserRepository),
+		audit:    new(mocks.AuditRepository),
+		sessions: new(mocks.SessionRepository),
+	}
+
+	userHandler := handlers.UserHandlers{UserService: usecase.NewUserService(repos.users, repos.audit, func(password, hash string) bool {
+		return password == hash
+	})}
+	sessionHandler := handlers.SessionHandlers{SessionService: usecase.NewSessionService(repos.sessions, time.Hour)}
+
+	repos.sessions.On("TouchSession", mock.Anything, utils.HashToken(sessionToken)).Return(currentUserSession, nil)
+	repos.sessions.On("TouchSession", mock.Anything, utils.HashToken(impersonationToken)).Return(impersonationSession, nil)
+	repos.sessions.On("TouchSession", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+
+	utils.NewValidator()
+
+	router := gin.Default()
+	router.Use(sessionHandler.IdentifyRequest)
+	router.POST(route, userHandler.CreateUser)
+	router.PATCH(fmt.Sprintf(withID, route), userHandler.UpdateUser)
+	router.DELETE(fmt.Sprintf(withID, route), userHandler.DeleteUser)
+	router.POST(route+"/batch", userHandler.CreateBatchUser)
+
+	authenticated := router.Group("", sessionHandler.RequireSession)
+	authenticated.GET(route+"/:id/audit", sessionHandler.RequireSameUser, sessionHandler.RequirePermission(domain.PermissionAuditRead), userHandler.ListAuditEvents)
+
+	return repos, router
+}
+
+func TestUserMutationsAreAudited(t *testing.T) {
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		body          *domain.User
+		authorization string
+		requestID     string
+		generatedID   bool
+		statusCode    int
+		action        string
+		actorID       string
+		changes       []domain.AuditChange
+	}{
+		{
+			name:       "should record the created user with the password redacted",
+			method:     "POST",
+			path:       route,
+			body:       userRequest,
+			requestID:  "req-1",
+			statusCode: http.StatusCreated,
+			action:     domain.AuditActionUserCreated,
+			changes: []domain.AuditChange{
+				{Field: "name", After: userRequest.Name},
+				{Field: "email", After: userRequest.Email},
+				{Field: "password", After: utils.AuditRedacted},
+				{Field: "userName", After: userRequest.UserName},
+			},
+		},
+		{
+			name:          "should record the changed fields and the actor of the session",
+			method:        "PATCH",
+			path:          route + "/12345",
+			body:          &domain.User{Name: "Cristian Moreno", Email: userRequest.Email, UserName: userRequest.UserName},
+			authorization: "Bearer " + sessionToken,
+			requestID:     "req-2",
+			statusCode:    http.StatusOK,
+			action:        domain.AuditActionUserUpdated,
+			actorID:       "12345",
+			changes:       []domain.AuditChange{{Field: "name", Before: userRequest.Name, After: "Cristian Moreno"}},
+		},
+		{
+			name:          "should record the staff member as actor of an impersonation session",
+			method:        "DELETE",
+			path:          route + "/12345",
+			authorization: "Bearer " + impersonationToken,
+			generatedID:   true,
+			statusCode:    http.StatusNoContent,
+			action:        domain.AuditActionUserDeleted,
+			actorID:       "support-1",
+			changes:       []domain.AuditChange{{Field: "enabled", Before: true, After: false}},
+		},
+		{
+			name:          "should record an anonymous change when the session is invalid",
+			method:        "DELETE",
+			path:          route + "/12345",
+			authorization: "Bearer invalid",
+			requestID:     "invalid request id",
+			generatedID:   true,
+			statusCode:    http.StatusNoContent,
+			action:        domain.AuditActionUserDeleted,
+			changes:       []domain.AuditChange{{Field: "enabled", Before: true, After: false}},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, router := auditConfigurations()
+
+			repos.users.On("CreateUser", mock.Anything, mock.Anything).Return("12345", nil)
+			repos.users.On("GetUserByID", mock.Anything, "12345").Return(&domain.User{
+				ID:       "12345",
+				Name:     userRequest.Name,
+				Email:    userRequest.Email,
+				UserName: userRequest.UserName,
+				Enabled:  true,
+			}, nil)
+			repos.users.On("UpdateUser", mock.Anything, "12345", mock.Anything).Return(&domain.User{
+				ID:       "12345",
+				Name:     "Cristian Moreno",
+				Email:    userRequest.Email,
+				UserName: userRequest.UserName,
+				Enabled:  true,
+			}, nil)
+			repos.users.On("DeleteUser", mock.Anything, "12345").Return(nil)
+
+			var recorded *domain.AuditEvent
+			repos.audit.On("RecordEvent", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+				recorded = args.Get(1).(*domain.AuditEvent)
+			}).Return(nil).Once()
+
+			var body io.Reader
+			if test.body != nil {
+				bodyBytes, _ := json.Marshal(test.body)
+				body = bytes.NewBuffer(bodyBytes)
+			}
+			req, _ := http.NewRequest(test.method, test.path, body)
+			if test.authorization != "" {
+				req.Header.Set("Authorization", test.authorization)
+			}
+			if test.requestID != "" {
+				req.Header.Set("X-Request-ID", test.requestID)
+			}
+			req.RemoteAddr = "203.0.113.7:4000"
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if !assert.NotNil(t, recorded) {
+				return
+			}
+			assert.Equal(t, test.action, recorded.Action)
+			assert.Equal(t, "12345", recorded.TargetID)
+			assert.Equal(t, test.actorID, recorded.ActorID)
+			assert.Equal(t, test.changes, recorded.Changes)
+			assert.Equal(t, "203.0.113.7", recorded.IP)
+			assert.Equal(t, resp.Header().Get("X-Request-ID"), recorded.RequestID)
+			if test.generatedID {
+				assert.NotEmpty(t, recorded.RequestID)
+				assert.NotEqual(t, test.requestID, recorded.RequestID)
+			} else {
+				assert.Equal(t, test.requestID, recorded.RequestID)
+			}
+		})
+	}
+}
+
+func TestCreateBatchUserIsAudited(t *testing.T) {
+	repos, router := auditConfigurations()
+
+	repos.users.On("CreateUserBatch", mock.Anything, mock.Anything).Return([]interface{}{"12345", "123456"}, nil)
+	repos.audit.On("RecordEvent", mock.Anything, mock.Anything).Return(nil)
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, err := writer.CreateFormFile("file", filePath)
+	assert.NoError(t, err)
+	_, err = part.Write([]byte(fileContent))
+	assert.NoError(t, err)
+	writer.Close()
+
+	req, _ := http.NewRequest("POST", route+"/batch", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	resp := httptest.NewRecorder()
+
+	router.ServeHTTP(resp, req)
+
+	assert.Equal(t, http.StatusCreated, resp.Code)
+	for id, email := range map[string]string{"12345": "john@example.com", "123456": "jane@example.com"} {
+		repos.audit.AssertCalled(t, "RecordEvent", mock.Anything, mock.MatchedBy(func(event *domain.AuditEvent) bool {
+			return event.Action == domain.AuditActionUserImported && event.TargetID == id &&
+				slices.Contains(event.Changes, domain.AuditChange{Field: "email", After: email}) &&
+				slices.Contains(event.Changes, domain.AuditChange{Field: "password", After: utils.AuditRedacted})
+		}))
+	}
+}
+
+func TestListAuditEvents(t *testing.T) {
+	events := []domain.AuditEvent{
+		{ID: "event3", Action: domain.AuditActionUserUpdated, TargetID: "12345"},
+		{ID: "event2", Action: domain.AuditActionPasswordChanged, TargetID: "12345"},
+		{ID: "event1", Action: domain.AuditActionUserCreated, TargetID: "12345"},
+	}
+
+	testCases := []struct {
+		name       string
+		path       string
+		cursor     string
+		limit      int64
+		events     []domain.AuditEvent
+		errRepo    error
+		statusCode int
+		ids        []string
+		nextCursor string
+	}{
+		{
+			name:       "should return a page of events and the cursor of the next page",
+			path:       "/users/12345/audit?limit=2",
+			limit:      3,
+			events:     events,
+			statusCode: http.StatusOK,
+			ids:        []string{"event3", "event2"},
+			nextCursor: "event2",
+		},
+		{
+			name:       "should return the last page without cursor",
+			path:       "/users/12345/audit?limit=2&cursor=event2",
+			cursor:     "event2",
+			limit:      3,
+			events:     events[2:],
+			statusCode: http.StatusOK,
+			ids:        []string{"event1"},
+		},
+		{
+			name:       "should use the default page size when limit is missing",
+			path:       "/users/12345/audit",
+			limit:      21,
+			events:     events,
+			statusCode: http.StatusOK,
+			ids:        []string{"event3", "event2", "event1"},
+		},
+		{
+			name:       "should return an error when limit is out of range",
+			path:       "/users/12345/audit?limit=500",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when the audit log of other user is requested",
+			path:       "/users/other/audit",
+			statusCode: http.StatusForbidden,
+		},
+		{
+			name:       "should return an error when bd return an error listing events",
+			path:       "/users/12345/audit",
+			limit:      21,
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, router := auditConfigurations()
+
+			repos.audit.On("ListEvents", mock.Anything, "12345", test.cursor, test.limit).Return(test.events, test.errRepo)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, sessionRequest("GET", test.path, "Bearer "+sessionToken))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if test.statusCode != http.StatusOK {
+				return
+			}
+
+			var response domain.APIResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+
+			var ids []string
+			for _, event := range response.AuditEvents {
+				ids = append(ids, event.ID)
+			}
+			assert.Equal(t, test.ids, ids)
+			assert.Equal(t, test.nextCursor, response.NextCursor)
+		})
+	}
+}
+
+// userEventKeys are the API keys accepted by the stream of user events of userEventsConfigurations.
+var userEventKeys = map[string]*domain.APIKey{
+	"cnm_admin-events": {ID: "key-admin", UserID: adminSession.UserID, Permissions: []string{domain.PermissionUserEventsRead}},
+	"cnm_admin-audit":  {ID: "key-audit", UserID: adminSession.UserID, Permissions: []string{domain.PermissionAuditRead}},
+	"cnm_user-events":  {ID: "key-user", UserID: "12345", Permissions: []string{domain.PermissionUserEventsRead}},
+}
+
+// userEventsConfigurations returns a server streaming userEvents to the administrators of
+// adminConfigurations and the API keys of userEventKeys.
+func userEventsConfigurations(userEvents usecase.UserEventStream) (*mocks.UserRepository, *usecase.UserService, *httptest.Server) {
+	mockRepo, handler, router := configurations()
+
+	if broadcaster, ok := userEvents.(*usecase.UserEventBroadcaster); ok {
+		handler.UserService.WithEventPublisher(broadcaster, "/cnm-proyect-go/users")
+	}
+	handler.UserEvents = userEvents
+
+	mockKeys := new(mocks.APIKeyRepository)
+	for secret, key := range userEventKeys {
+		mockKeys.On("TouchAPIKey", mock.Anything, utils.HashToken(secret)).Return(key, nil)
+	}
+	mockKeys.On("TouchAPIKey", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+
+	sessionHandler := adminConfigurations()
+	sessionHandler.APIKeyService = usecase.NewAPIKeyService(mockKeys)
+
+	router.GET(route+"/events", sessionHandler.RequireSession, sessionHandler.RequireAdmin, sessionHandler.RequirePermission(domain.PermissionUserEventsRead), handler.StreamUserEvents)
+
+	return mockRepo, handler.UserService, httptest.NewServer(router)
+}
+
+// openUserEvents connects to the stream of user events as the administrator of adminToken resuming
+// after lastEventID.
+func openUserEvents(t *testing.T, server *httptest.Server, lastEventID string) *http.Response {
+	t.Helper()
+
+	return openUserEventsAs(t, server, "Bearer "+adminToken, lastEventID)
+}
+
+// openUserEventsAs connects to the stream of user events with authorization resuming after lastEventID.
+func openUserEventsAs(t *testing.T, server *httptest.Server, authorization string, lastEventID string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest("GET", server.URL+route+"/events", nil)
+	assert.NoError(t, err)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	resp, err := server.Client().Do(req)
+	assert.NoError(t, err)
+
+	return resp
+}
+
+// readUserEvent reads the fields of the next server-sent event, skipping comments.
+func readUserEvent(t *testing.T, reader *bufio.Reader) map[string]string {
+	t.Helper()
+
+	fields := map[string]string{}
+	for {
+		line, err := reader.ReadString('\n')
+		assert.NoError(t, err)
+
+		line = strings.TrimRight(line, "\n")
+		if line == "" && len(fields) > 0 {
+			return fields
+		}
+		if name, value, found := strings.Cut(line, ":"); found && name != "" {
+			fields[name] = value
+		}
+	}
+}
+
+func TestStreamUserEventsBroadcaster(t *testing.T) {
+	mockRepo, userService, server := userEventsConfigurations(usecase.NewUserEventBroadcaster(10))
+	defer server.Close()
+	ctx := context.Background()
+
+	mockRepo.On("CreateUser", mock.Anything, mock.Anything).Return("12345", nil)
+	mockRepo.On("DeleteUser", mock.Anything, "12345").Return(nil)
+	mockRepo.On("GetUserByID", mock.Anything, "12345").Return(userResponse, nil)
+	mockRepo.On("UpdateUser", mock.Anything, "12345", mock.Anything).Return(&domain.User{ID: "12345", Name: "updated", Email: userResponse.Email, UserName: userResponse.UserName}, nil)
+
+	resp := openUserEvents(t, server, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	_, err := userService.CreateUser(ctx, &domain.User{Name: "Cristian", Email: "cristian@gmail.com", UserName: "cristian"})
+	assert.NoError(t, err)
+
+	created := readUserEvent(t, bufio.NewReader(resp.Body))
+	resp.Body.Close()
+	assert.NotEmpty(t, created["id"])
+	assert.Equal(t, domain.CloudEventUserCreated, created["event"])
+
+	var event domain.CloudEvent
+	assert.NoError(t, json.Unmarshal([]byte(created["data"]), &event))
+	assert.Equal(t, created["id"], event.ID)
+	assert.Equal(t, "12345", event.Subject)
+	assert.Equal(t, "cristian@gmail.com", event.Data.Email)
+
+	_, err = userService.UpdateUser(ctx, "12345", &domain.User{Name: "updated"})
+	assert.NoError(t, err)
+	assert.NoError(t, userService.DeleteUser(ctx, "12345"))
+
+	resp = openUserEvents(t, server, created["id"])
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reader := bufio.NewReader(resp.Body)
+	assert.Equal(t, domain.CloudEventUserUpdated, readUserEvent(t, reader)["event"])
+	assert.Equal(t, domain.CloudEventUserDeleted, readUserEvent(t, reader)["event"])
+
+	gone := openUserEvents(t, server, "unknown")
+	defer gone.Body.Close()
+	assert.Equal(t, http.StatusGone, gone.StatusCode)
+}
+
+func TestStreamUserEventsAuthorization(t *testing.T) {
+	testCases := []struct {
+		name          string
+		authorization string
+		statusCode    int
+	}{
+		{
+			name:          "should stream to administrator",
+			authorization: "Bearer " + adminToken,
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:          "should stream to API key of administrator with user events permission",
+			authorization: "ApiKey cnm_admin-events",
+			statusCode:    http.StatusOK,
+		},
+		{
+			name:          "should return an error when API key of administrator has not the user events permission",
+			authorization: "ApiKey cnm_admin-audit",
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when API key with user events permission is not of an administrator",
+			authorization: "ApiKey cnm_user-events",
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when user is not administrator",
+			authorization: "Bearer " + sessionToken,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when administrator is impersonating a user",
+			authorization: "Bearer " + impersonationToken,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:       "should return an error when request is not authenticated",
+			statusCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, _, server := userEventsConfigurations(usecase.NewUserEventBroadcaster(10))
+			defer server.Close()
+
+			resp := openUserEventsAs(t, server, test.authorization, "")
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.statusCode, resp.StatusCode)
+			if test.statusCode != http.StatusOK {
+				assert.NotEqual(t, "text/event-stream", resp.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+func TestStreamUserEventsChangeStream(t *testing.T) {
+	testCases := []struct {
+		name        string
+		lastEventID string
+		errRepo     error
+		statusCode  int
+	}{
+		{
+			name:        "should stream changes of users resuming after Last-Event-ID",
+			lastEventID: "token-0",
+			statusCode:  http.StatusOK,
+		},
+		{
+			name:        "should return an error when change stream can not be resumed",
+			lastEventID: "token-0",
+			errRepo:     mongo.CommandError{Code: 286, Name: "ChangeStreamHistoryLost"},
+			statusCode:  http.StatusGone,
+		},
+		{
+			name:       "should return an error when change stream fails",
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			streamRepo := new(mocks.UserEventStreamRepository)
+			_, _, server := userEventsConfigurations(usecase.NewChangeStreamUserEvents(streamRepo, "/cnm-proyect-go/users"))
+			defer server.Close()
+
+			changes := make(chan domain.UserEventChange, 1)
+			changes <- domain.UserEventChange{
+				ResumeToken: "token-1",
+				Event: &domain.OutboxEvent{
+					ID:          "event-1",
+					Type:        domain.EventUserUpdated,
+					AggregateID: "12345",
+					Data:        domain.UserEventData{ID: "12345", Name: "updated", Enabled: true},
+				},
+			}
+
+			if test.errRepo != nil {
+				streamRepo.On("WatchUserEvents", mock.Anything, test.lastEventID).Return(nil, test.errRepo)
+			} else {
+				streamRepo.On("WatchUserEvents", mock.Anything, test.lastEventID).Return((<-chan domain.UserEventChange)(changes), nil)
+			}
+
+			resp := openUserEvents(t, server, test.lastEventID)
+			defer resp.Body.Close()
+
+			assert.Equal(t, test.statusCode, resp.StatusCode)
+			if test.statusCode != http.StatusOK {
+				return
+			}
+
+			fields := readUserEvent(t, bufio.NewReader(resp.Body))
+			assert.Equal(t, "token-1", fields["id"])
+			assert.Equal(t, domain.CloudEventUserUpdated, fields["event"])
+
+			var event domain.CloudEvent
+			assert.NoError(t, json.Unmarshal([]byte(fields["data"]), &event))
+			assert.Equal(t, "event-1", event.ID)
+			assert.Equal(t, "/cnm-proyect-go/users", event.Source)
+			assert.Equal(t, "updated", event.Data.Name)
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WebAuthnHandlers encapsulates the passkey HTTP handlers.
+type WebAuthnHandlers struct {
+	WebAuthnService *usecase.WebAuthnService
+	AuthService     *usecase.AuthService
+}
+
+// BeginRegistration handles the start of the registration of a passkey of the user of the session.
+// It expects a id param with user and return the options for navigator.credentials.create, the
+// challenge is bound to the user of the session.
+func (h *WebAuthnHandlers) BeginRegistration(c *gin.Context) {
+	id := currentSession(c).UserID
+
+	options, err := h.WebAuthnService.BeginRegistration(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			respondWithError(c, http.StatusNotFound, constants.ErrUserNotFound, nil)
+			return
+		}
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToRegisterPasskey, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:   true,
+		ID:        id,
+		PublicKey: options,
+	})
+}
+
+// FinishRegistration handles the verification and storage of a new passkey of the user of the session.
+// It expects a JSON body with the credential returned by the authenticator and return the credential ID,
+// the challenge must have been issued to the user of the session.
+func (h *WebAuthnHandlers) FinishRegistration(c *gin.Context) {
+	id := currentSession(c).UserID
+
+	var response domain.WebAuthnRegistrationResponse
+	if err := c.ShouldBindJSON(&response); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	credential, err := h.WebAuthnService.FinishRegistration(c.Request.Context(), id, &response)
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidWebAuthnResponse):
+			respondWithError(c, http.StatusBadRequest, constants.ErrInvalidWebAuthnResponse, err)
+		case errors.Is(err, usecase.ErrInvalidWebAuthnChallenge):
+			respondWithError(c, http.StatusBadRequest, constants.ErrInvalidWebAuthnChallenge, nil)
+		case errors.Is(err, usecase.ErrWebAuthnCredentialExists):
+			respondWithError(c, http.StatusConflict, constants.ErrWebAuthnCredentialExists, nil)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToRegisterPasskey, err)
+		}
+		return
+	}
+
+	respondWithSuccess(c, http.StatusCreated, domain.APIResponse{
+		Success: true,
+		ID:      credential.ID,
+	})
+}
+
+// BeginLogin handles the start of a login with a passkey.
+// It expects an optional JSON body with login and return the options for navigator.credentials.get.
+func (h *WebAuthnHandlers) BeginLogin(c *gin.Context) {
+	var request domain.WebAuthnLoginRequest
+
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+			return
+		}
+	}
+
+	options, err := h.WebAuthnService.BeginLogin(c.Request.Context(), request.Login)
+	if err != nil {
+		respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToLoginPasskey, err)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:   true,
+		PublicKey: options,
+	})
+}
+
+// FinishLogin handles the authentication of a user with a passkey.
+// It expects a JSON body with the assertion returned by the authenticator and return the authenticated user.
+func (h *WebAuthnHandlers) FinishLogin(c *gin.Context) {
+	var response domain.WebAuthnAssertionResponse
+
+	if err := c.ShouldBindJSON(&response); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	result, err := h.AuthService.LoginWebAuthn(c.Request.Context(), &response, clientInfo(c))
+	if err != nil {
+		switch {
+		case errors.Is(err, usecase.ErrInvalidWebAuthnResponse):
+			respondWithError(c, http.StatusUnauthorized, constants.ErrInvalidWebAuthnResponse, nil)
+		case errors.Is(err, usecase.ErrInvalidWebAuthnChallenge):
+			respondWithError(c, http.StatusUnauthorized, constants.ErrInvalidWebAuthnChallenge, nil)
+		case errors.Is(err, usecase.ErrWebAuthnSignCount):
+			respondWithError(c, http.StatusUnauthorized, constants.ErrWebAuthnSignCount, nil)
+		case errors.Is(err, usecase.ErrLoginLocked):
+			respondWithLockout(c, err)
+		default:
+			respondWithError(c, http.StatusInternalServerError, constants.ErrFailedToLoginPasskey, err)
+		}
+		return
+	}
+
+	respondWithLogin(c, result)
+}
@@ -0,0 +1,731 @@
+package handlers_test
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	webAuthnRPID      = "localhost"
+	webAuthnOrigin    = "http://localhost:8080"
+	webAuthnChallenge = "c29mdHdhcmUtYXV0aGVudGljYXRvci1jaGFsbGVuZ2U"
+)
+
+var webAuthnUser = &domain.User{ID: "12345", Name: "Cristian", UserName: "cristian", Email: "cristian@gmail.com"}
+
+// cborPair keeps the order of the entries of an encoded CBOR map.
+type cborPair struct {
+	key   interface{}
+	value interface{}
+}
+
+// encodeCBOR encodes the values used by authenticators with the CTAP2 canonical lengths.
+func encodeCBOR(value interface{}) []byte {
+	switch v := value.(type) {
+	case int:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []interface{}:
+		encoded := cborHead(4, uint64(len(v)))
+		for _, item := range v {
+			encoded = append(encoded, encodeCBOR(item)...)
+		}
+		return encoded
+	case []cborPair:
+		encoded := cborHead(5, uint64(len(v)))
+		for _, pair := range v {
+			encoded = append(encoded, encodeCBOR(pair.key)...)
+			encoded = append(encoded, encodeCBOR(pair.value)...)
+		}
+		return encoded
+	default:
+		panic("unsupported CBOR value")
+	}
+}
+
+func cborHead(major byte, argument uint64) []byte {
+	switch {
+	case argument < 24:
+		return []byte{major<<5 | byte(argument)}
+	case argument <= 0xff:
+		return []byte{major<<5 | 24, byte(argument)}
+	case argument <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(argument))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(argument))
+	}
+}
+
+// softAuthenticator is a WebAuthn authenticator with a P-256 key kept in memory.
+type softAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	aaguid       []byte
+	signCount    uint32
+	origin       string
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	return &softAuthenticator{
+		key:          key,
+		credentialID: []byte("soft-credential-id"),
+		aaguid:       bytes.Repeat([]byte{0xaa}, 16),
+		origin:       webAuthnOrigin,
+	}
+}
+
+func (a *softAuthenticator) encodedID() string {
+	return base64.RawURLEncoding.EncodeToString(a.credentialID)
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	return encodeCBOR([]cborPair{
+		{1, 2},
+		{3, utils.COSEAlgES256},
+		{-1, 1},
+		{-2, a.key.X.FillBytes(make([]byte, 32))},
+		{-3, a.key.Y.FillBytes(make([]byte, 32))},
+	})
+}
+
+func (a *softAuthenticator) authenticatorData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(webAuthnRPID))
+	flags := byte(0x01 | 0x04)
+	if attested {
+		flags |= 0x40
+	}
+
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	if attested {
+		data = append(data, a.aaguid...)
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialID)))
+		data = append(data, a.credentialID...)
+		data = append(data, a.coseKey()...)
+	}
+
+	return data
+}
+
+func (a *softAuthenticator) clientData(ceremony, challenge string) []byte {
+	clientData, _ := json.Marshal(utils.ClientData{Type: ceremony, Challenge: challenge, Origin: a.origin})
+	return clientData
+}
+
+func (a *softAuthenticator) sign(t *testing.T, key *ecdsa.PrivateKey, authData, clientData []byte) []byte {
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+
+	signature, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	assert.NoError(t, err)
+
+	return signature
+}
+
+// register creates the credential with the attestation formats none, packed (self
+// attestation) or packed-x5c (attestation certificate).
+func (a *softAuthenticator) register(t *testing.T, challenge, format string) *domain.WebAuthnRegistrationResponse {
+	authData := a.authenticatorData(true)
+	clientData := a.clientData(utils.WebAuthnCeremonyCreate, challenge)
+
+	statement := []cborPair{}
+	switch format {
+	case "packed":
+		statement = []cborPair{
+			{"alg", utils.COSEAlgES256},
+			{"sig", a.sign(t, a.key, authData, clientData)},
+		}
+	case "packed-x5c":
+		format = "packed"
+		attestationKey, certificate := attestationCertificate(t, a.aaguid)
+		statement = []cborPair{
+			{"alg", utils.COSEAlgES256},
+			{"sig", a.sign(t, attestationKey, authData, clientData)},
+			{"x5c", []interface{}{certificate}},
+		}
+	}
+
+	attestationObject := encodeCBOR([]cborPair{
+		{"fmt", format},
+		{"attStmt", statement},
+		{"authData", authData},
+	})
+
+	var response domain.WebAuthnRegistrationResponse
+	response.ID = a.encodedID()
+	response.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+	response.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(attestationObject)
+
+	return &response
+}
+
+// assert signs the challenge with the credential and the current signature counter.
+func (a *softAuthenticator) assert(t *testing.T, challenge string, userHandle string) *domain.WebAuthnAssertionResponse {
+	authData := a.authenticatorData(false)
+	clientData := a.clientData(utils.WebAuthnCeremonyGet, challenge)
+
+	var response domain.WebAuthnAssertionResponse
+	response.ID = a.encodedID()
+	response.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+	response.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	response.Response.Signature = base64.RawURLEncoding.EncodeToString(a.sign(t, a.key, authData, clientData))
+	response.Response.UserHandle = base64.RawURLEncoding.EncodeToString([]byte(userHandle))
+
+	return &response
+}
+
+func (a *softAuthenticator) credential(signCount uint32) *domain.WebAuthnCredential {
+	return &domain.WebAuthnCredential{
+		ID:        a.encodedID(),
+		UserID:    webAuthnUser.ID,
+		PublicKey: a.coseKey(),
+		Algorithm: utils.COSEAlgES256,
+		SignCount: signCount,
+		Format:    "none",
+	}
+}
+
+func attestationCertificate(t *testing.T, aaguid []byte) (*ecdsa.PrivateKey, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	aaguidValue, err := asn1.Marshal(aaguid)
+	assert.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject: pkix.Name{
+			Country:            []string{"CO"},
+			Organization:       []string{"Soft Authenticators"},
+			OrganizationalUnit: []string{"Authenticator Attestation"},
+			CommonName:         "Soft Authenticator",
+		},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		BasicConstraintsValid: true,
+		ExtraExtensions: []pkix.Extension{
+			{Id: asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}, Value: aaguidValue},
+		},
+	}
+
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+
+	return key, certificate
+}
+
+func webAuthnConfigurations() (*mocks.UserRepository, *mocks.WebAuthnRepository, *mocks.WebAuthnChallengeRepository, handlers.WebAuthnHandlers) {
+	mockRepo := new(mocks.UserRepository)
+	mockCredentials := new(mocks.WebAuthnRepository)
+	mockChallenges := new(mocks.WebAuthnChallengeRepository)
+
+	config := utils.WebAuthnConfig{RPID: webAuthnRPID, Origins: []string{webAuthnOrigin}}
+	webAuthnService := usecase.NewWebAuthnService(mockRepo, mockCredentials, mockChallenges, config, "cnm", "direct", time.Minute)
+	authService := usecase.NewAuthService(mockRepo, nil).WithWebAuthn(webAuthnService)
+
+	return mockRepo, mockCredentials, mockChallenges, handlers.WebAuthnHandlers{WebAuthnService: webAuthnService, AuthService: authService}
+}
+
+func storedChallenge(userID, ceremony string) *domain.WebAuthnChallenge {
+	return &domain.WebAuthnChallenge{ID: utils.HashToken(webAuthnChallenge), UserID: userID, Ceremony: ceremony}
+}
+
+type valuesWebAuthnTestCases struct {
+	name          string
+	authorization string
+	user          *domain.User
+	errUser       error
+	login         string
+	credentials   []domain.WebAuthnCredential
+	allowed       int
+	statusCode    int
+}
+
+func TestBeginWebAuthnRegistration(t *testing.T) {
+	authenticator := newSoftAuthenticator(t)
+
+	testCases := []valuesWebAuthnTestCases{
+		{
+			name:        "should return options excluding registered passkeys",
+			user:        webAuthnUser,
+			credentials: []domain.WebAuthnCredential{*authenticator.credential(0)},
+			statusCode:  http.StatusOK,
+		},
+		{
+			name:       "should return an error when user does not exist",
+			errUser:    mongo.ErrNoDocuments,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "should return an error when bd return an error getting user",
+			errUser:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:          "should return an error when session belongs to another user",
+			authorization: "Bearer " + adminToken,
+			user:          webAuthnUser,
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when request is not authenticated",
+			authorization: "Bearer unknown",
+			user:          webAuthnUser,
+			statusCode:    http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, mockCredentials, mockChallenges, handler := webAuthnConfigurations()
+			sessionHandler := adminConfigurations()
+			router := gin.Default()
+
+			router.POST("/users/:id/webauthn/register/begin", sessionHandler.RequireSession, sessionHandler.RequireSameUser, handler.BeginRegistration)
+
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(test.user, test.errUser)
+			mockCredentials.On("ListCredentials", mock.Anything, "12345").Return(test.credentials, nil)
+			mockChallenges.On("CreateChallenge", mock.Anything, mock.Anything).Return(nil)
+
+			req := sessionRequest("POST", "/users/12345/webauthn/register/begin", webAuthnAuthorization(test.authorization))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusOK {
+				var response struct {
+					PublicKey domain.WebAuthnCreationOptions `json:"publicKey"`
+				}
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+
+				options := response.PublicKey
+				assert.Equal(t, webAuthnRPID, options.RelyingParty.ID)
+				assert.Equal(t, base64.RawURLEncoding.EncodeToString([]byte("12345")), options.User.ID)
+				assert.Equal(t, "direct", options.Attestation)
+				assert.Equal(t, authenticator.encodedID(), options.ExcludeCredentials[0].ID)
+
+				challenge := mockChallenges.Calls[0].Arguments.Get(1).(*domain.WebAuthnChallenge)
+				assert.Equal(t, utils.HashToken(options.Challenge), challenge.ID)
+				assert.Equal(t, "12345", challenge.UserID)
+				assert.Equal(t, utils.WebAuthnCeremonyCreate, challenge.Ceremony)
+			} else {
+				mockChallenges.AssertNotCalled(t, "CreateChallenge", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+// webAuthnAuthorization returns authorization or the session of the user 12345 when it is empty.
+func webAuthnAuthorization(authorization string) string {
+	if authorization == "" {
+		return "Bearer " + sessionToken
+	}
+
+	return authorization
+}
+
+type valuesWebAuthnRegistrationTestCases struct {
+	name          string
+	authorization string
+	format        string
+	origin        string
+	challenge     *domain.WebAuthnChallenge
+	tamper        bool
+	errCreate     error
+	statusCode    int
+}
+
+func TestFinishWebAuthnRegistration(t *testing.T) {
+	testCases := []valuesWebAuthnRegistrationTestCases{
+		{
+			name:       "should register passkey with none attestation",
+			format:     "none",
+			challenge:  storedChallenge("12345", utils.WebAuthnCeremonyCreate),
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "should register passkey with packed self attestation",
+			format:     "packed",
+			challenge:  storedChallenge("12345", utils.WebAuthnCeremonyCreate),
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "should register passkey with packed attestation certificate",
+			format:     "packed-x5c",
+			challenge:  storedChallenge("12345", utils.WebAuthnCeremonyCreate),
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "should return an error when attestation signature is not valid",
+			format:     "packed",
+			challenge:  storedChallenge("12345", utils.WebAuthnCeremonyCreate),
+			tamper:     true,
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when origin is not allowed",
+			format:     "none",
+			origin:     "https://evil.example",
+			challenge:  storedChallenge("12345", utils.WebAuthnCeremonyCreate),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when challenge is unknown or expired",
+			format:     "none",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when challenge was issued to other user",
+			format:     "none",
+			challenge:  storedChallenge("67890", utils.WebAuthnCeremonyCreate),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when challenge was issued for a login",
+			format:     "none",
+			challenge:  storedChallenge("", utils.WebAuthnCeremonyGet),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when passkey is already registered",
+			format:     "none",
+			challenge:  storedChallenge("12345", utils.WebAuthnCeremonyCreate),
+			errCreate:  mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}},
+			statusCode: http.StatusConflict,
+		},
+		{
+			name:       "should return an error when bd return an error storing passkey",
+			format:     "none",
+			challenge:  storedChallenge("12345", utils.WebAuthnCeremonyCreate),
+			errCreate:  errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:          "should return an error when session belongs to the user of the challenge but not of the path",
+			authorization: "Bearer " + adminToken,
+			format:        "none",
+			challenge:     storedChallenge(adminSession.UserID, utils.WebAuthnCeremonyCreate),
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when request is not authenticated",
+			authorization: "Bearer unknown",
+			format:        "none",
+			challenge:     storedChallenge("12345", utils.WebAuthnCeremonyCreate),
+			statusCode:    http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			_, mockCredentials, mockChallenges, handler := webAuthnConfigurations()
+			sessionHandler := adminConfigurations()
+			router := gin.Default()
+
+			router.POST("/users/:id/webauthn/register/finish", sessionHandler.RequireSession, sessionHandler.RequireSameUser, handler.FinishRegistration)
+
+			authenticator := newSoftAuthenticator(t)
+			if test.origin != "" {
+				authenticator.origin = test.origin
+			}
+
+			body := authenticator.register(t, webAuthnChallenge, test.format)
+			if test.tamper {
+				clientData := append(authenticator.clientData(utils.WebAuthnCeremonyCreate, webAuthnChallenge), ' ')
+				body.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+			}
+
+			mockChallenges.On("ConsumeChallenge", mock.Anything, utils.HashToken(webAuthnChallenge)).Return(attemptChallenge(test.challenge))
+			mockChallenges.On("ConsumeChallenge", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+			mockCredentials.On("CreateCredential", mock.Anything, mock.Anything).Return(test.errCreate)
+
+			bodyBytes, _ := json.Marshal(body)
+
+			req, _ := mockRequestEndPoint(false, "POST", "/users/12345/webauthn/register/finish", bytes.NewBuffer(bodyBytes))
+			req.Header.Set("Authorization", webAuthnAuthorization(test.authorization))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusCreated {
+				credential := mockCredentials.Calls[0].Arguments.Get(1).(*domain.WebAuthnCredential)
+				assert.Equal(t, authenticator.encodedID(), credential.ID)
+				assert.Equal(t, "12345", credential.UserID)
+				assert.Equal(t, authenticator.coseKey(), credential.PublicKey)
+				assert.Equal(t, authenticator.aaguid, credential.AAGUID)
+				assert.Equal(t, test.format != "none", credential.Format == "packed")
+			} else if test.errCreate == nil {
+				mockCredentials.AssertNotCalled(t, "CreateCredential", mock.Anything, mock.Anything)
+			}
+
+			if test.statusCode == http.StatusForbidden || test.statusCode == http.StatusUnauthorized {
+				mockChallenges.AssertNotCalled(t, "ConsumeChallenge", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestBeginWebAuthnLogin(t *testing.T) {
+	authenticator := newSoftAuthenticator(t)
+
+	testCases := []valuesWebAuthnTestCases{
+		{
+			name:        "should return options allowing the passkeys of the user",
+			login:       "cristian",
+			user:        webAuthnUser,
+			credentials: []domain.WebAuthnCredential{*authenticator.credential(0)},
+			allowed:     1,
+			statusCode:  http.StatusOK,
+		},
+		{
+			name:       "should return options for any passkey without login",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return options for any passkey when user does not exist",
+			login:      "unknown",
+			errUser:    mongo.ErrNoDocuments,
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when bd return an error getting user",
+			login:      "cristian",
+			errUser:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, mockCredentials, mockChallenges, handler := webAuthnConfigurations()
+			router := gin.Default()
+
+			router.POST("/auth/webauthn/login/begin", handler.BeginLogin)
+
+			mockRepo.On("GetUserByLogin", mock.Anything, test.login).Return(test.user, test.errUser)
+			mockCredentials.On("ListCredentials", mock.Anything, "12345").Return(test.credentials, nil)
+			mockChallenges.On("CreateChallenge", mock.Anything, mock.Anything).Return(nil)
+
+			var body *bytes.Buffer
+			if test.login != "" {
+				bodyBytes, _ := json.Marshal(domain.WebAuthnLoginRequest{Login: test.login})
+				body = bytes.NewBuffer(bodyBytes)
+			} else {
+				body = &bytes.Buffer{}
+			}
+
+			req, _ := mockRequestEndPoint(false, "POST", "/auth/webauthn/login/begin", body)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusOK {
+				var response struct {
+					PublicKey domain.WebAuthnRequestOptions `json:"publicKey"`
+				}
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, webAuthnRPID, response.PublicKey.RPID)
+				assert.Len(t, response.PublicKey.AllowCredentials, test.allowed)
+
+				challenge := mockChallenges.Calls[0].Arguments.Get(1).(*domain.WebAuthnChallenge)
+				assert.Equal(t, utils.HashToken(response.PublicKey.Challenge), challenge.ID)
+				assert.Equal(t, utils.WebAuthnCeremonyGet, challenge.Ceremony)
+				if test.allowed > 0 {
+					assert.Equal(t, "12345", challenge.UserID)
+				} else {
+					assert.Empty(t, challenge.UserID)
+				}
+			}
+		})
+	}
+}
+
+type valuesWebAuthnLoginTestCases struct {
+	name            string
+	challenge       *domain.WebAuthnChallenge
+	storedSignCount uint32
+	signCount       uint32
+	errCredential   error
+	userHandle      string
+	otherKey        bool
+	errUpdate       error
+	statusCode      int
+	countsFailure   bool
+}
+
+func TestFinishWebAuthnLogin(t *testing.T) {
+	testCases := []valuesWebAuthnLoginTestCases{
+		{
+			name:            "should login with passkey",
+			challenge:       storedChallenge("", utils.WebAuthnCeremonyGet),
+			storedSignCount: 4,
+			signCount:       5,
+			userHandle:      "12345",
+			statusCode:      http.StatusOK,
+		},
+		{
+			name:       "should login with passkey of authenticator without signature counter",
+			challenge:  storedChallenge("12345", utils.WebAuthnCeremonyGet),
+			statusCode: http.StatusOK,
+		},
+		{
+			name:            "should return an error when signature counter did not increase",
+			challenge:       storedChallenge("", utils.WebAuthnCeremonyGet),
+			storedSignCount: 9,
+			signCount:       4,
+			statusCode:      http.StatusUnauthorized,
+			countsFailure:   true,
+		},
+		{
+			name:            "should return an error when signature counter was updated concurrently",
+			challenge:       storedChallenge("", utils.WebAuthnCeremonyGet),
+			storedSignCount: 4,
+			signCount:       5,
+			errUpdate:       mongo.ErrNoDocuments,
+			statusCode:      http.StatusUnauthorized,
+			countsFailure:   true,
+		},
+		{
+			name:          "should return an error when signature is not valid",
+			challenge:     storedChallenge("", utils.WebAuthnCeremonyGet),
+			otherKey:      true,
+			statusCode:    http.StatusUnauthorized,
+			countsFailure: true,
+		},
+		{
+			name:          "should return an error when passkey is not registered",
+			challenge:     storedChallenge("", utils.WebAuthnCeremonyGet),
+			errCredential: mongo.ErrNoDocuments,
+			statusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when user handle does not match",
+			challenge:  storedChallenge("", utils.WebAuthnCeremonyGet),
+			userHandle: "67890",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when challenge was issued to other user",
+			challenge:  storedChallenge("67890", utils.WebAuthnCeremonyGet),
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when challenge is unknown or expired",
+			statusCode: http.StatusUnauthorized,
+		},
+		{
+			name:            "should return an error when bd return an error updating counter",
+			challenge:       storedChallenge("", utils.WebAuthnCeremonyGet),
+			storedSignCount: 4,
+			signCount:       5,
+			errUpdate:       errors.New(errorValue),
+			statusCode:      http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockRepo, mockCredentials, mockChallenges, handler := webAuthnConfigurations()
+			mockAttempts := new(mocks.LoginAttemptRepository)
+			handler.AuthService.WithLockout(mockAttempts, lockoutPolicy)
+			router := gin.Default()
+
+			router.POST("/auth/webauthn/login/finish", handler.FinishLogin)
+
+			authenticator := newSoftAuthenticator(t)
+			credential := authenticator.credential(test.storedSignCount)
+			authenticator.signCount = test.signCount
+			if test.otherKey {
+				authenticator.key, _ = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			}
+
+			body := authenticator.assert(t, webAuthnChallenge, test.userHandle)
+
+			mockChallenges.On("ConsumeChallenge", mock.Anything, utils.HashToken(webAuthnChallenge)).Return(attemptChallenge(test.challenge))
+			mockCredentials.On("GetCredential", mock.Anything, authenticator.encodedID()).Return(credential, test.errCredential)
+			mockCredentials.On("UpdateSignCount", mock.Anything, credential.ID, test.storedSignCount, authenticator.signCount).Return(test.errUpdate)
+			mockRepo.On("GetUserByID", mock.Anything, "12345").Return(webAuthnUser, nil)
+			mockAttempts.On("GetLoginAttempt", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+			mockAttempts.On("RecordFailedLogin", mock.Anything, mock.Anything, mock.Anything).Return(&domain.LoginAttempt{Failures: 1}, nil)
+			mockAttempts.On("ResetLoginAttempts", mock.Anything, "user:12345").Return(nil)
+
+			bodyBytes, _ := json.Marshal(body)
+
+			req, _ := mockRequestEndPoint(false, "POST", "/auth/webauthn/login/finish", bytes.NewBuffer(bodyBytes))
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+
+			if test.statusCode == http.StatusOK {
+				var response domain.APIResponse
+				err := json.Unmarshal(resp.Body.Bytes(), &response)
+				assert.NoError(t, err)
+				assert.Equal(t, "12345", response.ID)
+				mockAttempts.AssertCalled(t, "ResetLoginAttempts", mock.Anything, "user:12345")
+			}
+
+			if test.countsFailure {
+				mockAttempts.AssertCalled(t, "RecordFailedLogin", mock.Anything, "user:12345", mock.Anything)
+			} else {
+				mockAttempts.AssertNotCalled(t, "RecordFailedLogin", mock.Anything, "user:12345", mock.Anything)
+			}
+		})
+	}
+}
+
+func attemptChallenge(challenge *domain.WebAuthnChallenge) (*domain.WebAuthnChallenge, error) {
+	if challenge == nil {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return challenge, nil
+}
@@ -0,0 +1,123 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookHandlers encapsulates the webhook subscription HTTP handlers.
+type WebhookHandlers struct {
+	WebhookService *usecase.WebhookService
+}
+
+// CreateWebhook handles the registration of a webhook of the tenant of the caller, the user of the session.
+// It expects a JSON body with the URL and events and return the webhook and the secret signing its deliveries, which is shown only once.
+func (h *WebhookHandlers) CreateWebhook(c *gin.Context) {
+	var request domain.WebhookSubscriptionRequest
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidUserInput, err)
+		return
+	}
+
+	webhook, secret, err := h.WebhookService.CreateWebhook(c.Request.Context(), currentSession(c).UserID, &request)
+	if err != nil {
+		respondWithWebhookError(c, err, constants.ErrFailedToCreateWebhook)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusCreated, domain.APIResponse{
+		Success: true,
+		ID:      webhook.ID,
+		Secret:  secret,
+		Webhook: webhook,
+	})
+}
+
+// ListWebhooks handles the listing of the webhooks of the tenant of the caller.
+// It return the webhooks of the tenant.
+func (h *WebhookHandlers) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.WebhookService.ListWebhooks(c.Request.Context(), currentSession(c).UserID)
+	if err != nil {
+		respondWithWebhookError(c, err, constants.ErrFailedToListWebhooks)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:  true,
+		Webhooks: webhooks,
+	})
+}
+
+// DeleteWebhook handles the removal of a webhook of the tenant of the caller.
+// It expects a id param with webhook and return status no content.
+func (h *WebhookHandlers) DeleteWebhook(c *gin.Context) {
+	err := h.WebhookService.DeleteWebhook(c.Request.Context(), currentSession(c).UserID, c.Param("id"))
+	if err != nil {
+		respondWithWebhookError(c, err, constants.ErrFailedToDeleteWebhook)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries handles the listing of the delivery log of a webhook of the tenant of the caller.
+// It expects a id param with webhook and optional limit, cursor and status queries and return a page of deliveries.
+func (h *WebhookHandlers) ListDeliveries(c *gin.Context) {
+	var query domain.WebhookDeliveryQuery
+
+	if err := c.ShouldBindQuery(&query); err != nil {
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidDeliveryQuery, err)
+		return
+	}
+
+	deliveries, nextCursor, err := h.WebhookService.ListDeliveries(c.Request.Context(), currentSession(c).UserID, c.Param("id"), &query)
+	if err != nil {
+		respondWithWebhookError(c, err, constants.ErrFailedToListDeliveries)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusOK, domain.APIResponse{
+		Success:    true,
+		Deliveries: deliveries,
+		NextCursor: nextCursor,
+	})
+}
+
+// Redeliver handles sending again a delivery of a webhook of the tenant of the caller.
+// It expects a id param with webhook and a did param with delivery and return the delivery scheduled to be sent.
+func (h *WebhookHandlers) Redeliver(c *gin.Context) {
+	delivery, err := h.WebhookService.Redeliver(c.Request.Context(), currentSession(c).UserID, c.Param("id"), c.Param("did"))
+	if err != nil {
+		respondWithWebhookError(c, err, constants.ErrFailedToRedeliver)
+		return
+	}
+
+	respondWithSuccess(c, http.StatusAccepted, domain.APIResponse{
+		Success:  true,
+		ID:       delivery.ID,
+		Delivery: delivery,
+	})
+}
+
+// respondWithWebhookError responds with the status of an error of a webhook, unexpected
+// errors are reported with message.
+func respondWithWebhookError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, usecase.ErrWebhooksNotConfigured):
+		respondWithError(c, http.StatusServiceUnavailable, constants.ErrWebhooksNotConfigured, nil)
+	case errors.Is(err, usecase.ErrInvalidWebhook):
+		respondWithError(c, http.StatusBadRequest, constants.ErrInvalidWebhook, err)
+	case errors.Is(err, usecase.ErrWebhookNotFound):
+		respondWithError(c, http.StatusNotFound, constants.ErrWebhookNotFound, nil)
+	case errors.Is(err, usecase.ErrDeliveryNotFound):
+		respondWithError(c, http.StatusNotFound, constants.ErrDeliveryNotFound, nil)
+	default:
+		respondWithError(c, http.StatusInternalServerError, message, err)
+	}
+}
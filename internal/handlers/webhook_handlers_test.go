@@ -0,0 +1,553 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/handlers"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	webhooksRoute     = "/admin/webhooks"
+	webhookMaxAttempt = 3
+	webhookURL        = "https://203.0.113.10/hooks"
+)
+
+type webhookRepos struct {
+	subscriptions *mocks.WebhookSubscriptionRepository
+	deliveries    *mocks.WebhookDeliveryRepository
+}
+
+func webhookSecretBox() *utils.SecretBox {
+	secretBox, _ := utils.NewSecretBox("k1", map[string][]byte{"k1": []byte("webhook-encryption-key")})
+
+	return secretBox
+}
+
+// webhookConfigurations returns the webhook routes for the administrator of adminToken, the
+// webhooks are sent with sender.
+func webhookConfigurations(secretBox *utils.SecretBox, sender *adapters.HTTPWebhookSender) (webhookRepos, *usecase.WebhookService, *gin.Engine) {
+	repos := webhookRepos{
+		subscriptions: new(mocks.WebhookSubscriptionRepository),
+		deliveries:    new(mocks.WebhookDeliveryRepository),
+	}
+
+	webhookService := usecase.NewWebhookService(repos.subscriptions, repos.deliveries, sender, secretBox, webhookMaxAttempt, time.Minute)
+	handler := handlers.WebhookHandlers{WebhookService: webhookService}
+	sessionHandler := adminConfigurations()
+
+	router := gin.Default()
+	admin := router.Group("", sessionHandler.RequireSession, sessionHandler.RequireAdmin)
+	admin.POST(webhooksRoute, handler.CreateWebhook)
+	admin.GET(webhooksRoute, handler.ListWebhooks)
+	admin.DELETE(webhooksRoute+"/:id", handler.DeleteWebhook)
+	admin.GET(webhooksRoute+"/:id/deliveries", handler.ListDeliveries)
+	admin.POST(webhooksRoute+"/:id/deliveries/:did/redeliver", handler.Redeliver)
+
+	return repos, webhookService, router
+}
+
+// webhookRequest returns a request of the administrator of adminToken.
+func webhookRequest(method string, path string, body io.Reader) *http.Request {
+	req, _ := http.NewRequest(method, path, body)
+	req.Header.Set("Authorization", "Bearer "+adminToken)
+
+	return req
+}
+
+// webhookSubscription returns a structured webhook of the administrator of adminToken to url
+// signed with secret, encrypted as in BD.
+func webhookSubscription(url string, secret string) *domain.WebhookSubscription {
+	encrypted, _ := webhookSecretBox().Encrypt(secret)
+
+	return &domain.WebhookSubscription{
+		ID:     "webhook-1",
+		Tenant: adminSession.UserID,
+		URL:    url,
+		Events: []string{},
+		Mode:   domain.CloudEventsStructured,
+		Secret: encrypted,
+	}
+}
+
+func TestCreateWebhook(t *testing.T) {
+	testCases := []struct {
+		name          string
+		authorization string
+		body          string
+		secretBox     *utils.SecretBox
+		errRepo       error
+		events        []string
+		mode          string
+		statusCode    int
+	}{
+		{
+			name:       "should register webhook with secret shown once",
+			body:       `{"url":"https://203.0.113.10/hooks","events":["com.cnmoreno.user.deleted","com.cnmoreno.user.created","com.cnmoreno.user.created"],"mode":"binary"}`,
+			secretBox:  webhookSecretBox(),
+			events:     []string{domain.CloudEventUserCreated, domain.CloudEventUserDeleted},
+			mode:       domain.CloudEventsBinary,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "should register structured webhook receiving every event when events are empty",
+			body:       `{"url":"https://203.0.113.10/hooks"}`,
+			secretBox:  webhookSecretBox(),
+			events:     []string{},
+			mode:       domain.CloudEventsStructured,
+			statusCode: http.StatusCreated,
+		},
+		{
+			name:       "should return an error when event is unknown",
+			body:       `{"url":"https://203.0.113.10/hooks","events":["UserCreated"]}`,
+			secretBox:  webhookSecretBox(),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when mode is unknown",
+			body:       `{"url":"https://203.0.113.10/hooks","mode":"batched"}`,
+			secretBox:  webhookSecretBox(),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when url is not http",
+			body:       `{"url":"ftp://203.0.113.10/hooks"}`,
+			secretBox:  webhookSecretBox(),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when url is http",
+			body:       `{"url":"http://203.0.113.10/hooks"}`,
+			secretBox:  webhookSecretBox(),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when url is a loopback address",
+			body:       `{"url":"https://127.0.0.1:8080/hooks"}`,
+			secretBox:  webhookSecretBox(),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when url is a private address",
+			body:       `{"url":"https://10.0.0.5/hooks"}`,
+			secretBox:  webhookSecretBox(),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when url is a link-local address",
+			body:       `{"url":"https://169.254.169.254/latest/meta-data"}`,
+			secretBox:  webhookSecretBox(),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when url is an IPv6 loopback address",
+			body:       `{"url":"https://[::1]/hooks"}`,
+			secretBox:  webhookSecretBox(),
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:          "should return an error when user is not administrator",
+			authorization: "Bearer " + sessionToken,
+			body:          `{"url":"https://203.0.113.10/hooks"}`,
+			secretBox:     webhookSecretBox(),
+			statusCode:    http.StatusForbidden,
+		},
+		{
+			name:          "should return an error when request is not authenticated",
+			authorization: "none",
+			body:          `{"url":"https://203.0.113.10/hooks"}`,
+			secretBox:     webhookSecretBox(),
+			statusCode:    http.StatusUnauthorized,
+		},
+		{
+			name:       "should return an error when webhooks are not configured",
+			body:       `{"url":"https://203.0.113.10/hooks"}`,
+			statusCode: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "should return an error when bd return an error registering webhook",
+			body:       `{"url":"https://203.0.113.10/hooks"}`,
+			secretBox:  webhookSecretBox(),
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, _, router := webhookConfigurations(test.secretBox, adapters.NewHTTPWebhookSender(time.Second))
+
+			repos.subscriptions.On("CreateSubscription", mock.Anything, mock.Anything).Return(test.errRepo)
+
+			req := webhookRequest("POST", webhooksRoute, strings.NewReader(test.body))
+			switch test.authorization {
+			case "":
+			case "none":
+				req.Header.Del("Authorization")
+			default:
+				req.Header.Set("Authorization", test.authorization)
+			}
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, req)
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if test.statusCode != http.StatusCreated {
+				if test.errRepo == nil {
+					repos.subscriptions.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+				}
+				return
+			}
+
+			var response domain.APIResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.True(t, strings.HasPrefix(response.Secret, "whsec_"))
+			assert.Equal(t, test.events, response.Webhook.Events)
+			assert.Equal(t, test.mode, response.Webhook.Mode)
+
+			subscription := repos.subscriptions.Calls[0].Arguments.Get(1).(*domain.WebhookSubscription)
+			assert.Equal(t, adminSession.UserID, subscription.Tenant)
+			assert.NotContains(t, subscription.Secret, response.Secret)
+			assert.NotContains(t, resp.Body.String(), subscription.Secret)
+
+			secret, err := test.secretBox.Decrypt(subscription.Secret)
+			assert.NoError(t, err)
+			assert.Equal(t, response.Secret, secret)
+		})
+	}
+}
+
+func TestListAndDeleteWebhooks(t *testing.T) {
+	testCases := []struct {
+		name       string
+		method     string
+		path       string
+		tenant     string
+		errGet     error
+		errRepo    error
+		statusCode int
+	}{
+		{
+			name:       "should list webhooks of the tenant of the caller",
+			method:     "GET",
+			path:       webhooksRoute + "?tenant=acme",
+			statusCode: http.StatusOK,
+		},
+		{
+			name:       "should return an error when bd return an error listing webhooks",
+			method:     "GET",
+			path:       webhooksRoute,
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+		{
+			name:       "should delete webhook and its delivery log",
+			method:     "DELETE",
+			path:       webhooksRoute + "/webhook-1",
+			statusCode: http.StatusNoContent,
+		},
+		{
+			name:       "should return an error when webhook to delete does not exist",
+			method:     "DELETE",
+			path:       webhooksRoute + "/webhook-1",
+			errGet:     mongo.ErrNoDocuments,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "should return an error when webhook to delete belongs to another tenant",
+			method:     "DELETE",
+			path:       webhooksRoute + "/webhook-1",
+			tenant:     "acme",
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, _, router := webhookConfigurations(webhookSecretBox(), adapters.NewHTTPWebhookSender(time.Second))
+
+			subscription := webhookSubscription(webhookURL, "whsec_secret")
+			if test.tenant != "" {
+				subscription.Tenant = test.tenant
+			}
+
+			repos.subscriptions.On("ListSubscriptions", mock.Anything, adminSession.UserID).Return([]domain.WebhookSubscription{*subscription}, test.errRepo)
+			repos.subscriptions.On("GetSubscription", mock.Anything, "webhook-1").Return(subscription, test.errGet)
+			repos.subscriptions.On("DeleteSubscription", mock.Anything, "webhook-1").Return(test.errRepo)
+			repos.deliveries.On("DeleteDeliveries", mock.Anything, "webhook-1").Return(nil)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, webhookRequest(test.method, test.path, nil))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			assert.NotContains(t, resp.Body.String(), "secret")
+			if test.statusCode == http.StatusNoContent {
+				repos.deliveries.AssertCalled(t, "DeleteDeliveries", mock.Anything, "webhook-1")
+			}
+			if test.statusCode == http.StatusNotFound {
+				repos.subscriptions.AssertNotCalled(t, "DeleteSubscription", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	deliveries := []domain.WebhookDelivery{
+		{ID: "delivery-3", SubscriptionID: "webhook-1", Status: domain.WebhookDeliveryDeadLetter},
+		{ID: "delivery-2", SubscriptionID: "webhook-1", Status: domain.WebhookDeliveryDeadLetter},
+		{ID: "delivery-1", SubscriptionID: "webhook-1", Status: domain.WebhookDeliveryDeadLetter},
+	}
+
+	testCases := []struct {
+		name       string
+		query      string
+		limit      int64
+		tenant     string
+		errGet     error
+		statusCode int
+		count      int
+		nextCursor string
+	}{
+		{
+			name:       "should list a page of deliveries with the cursor of the next page",
+			query:      "?limit=2&status=dead_letter&cursor=delivery-4",
+			limit:      3,
+			statusCode: http.StatusOK,
+			count:      2,
+			nextCursor: "delivery-2",
+		},
+		{
+			name:       "should return an error when status is unknown",
+			query:      "?status=lost",
+			statusCode: http.StatusBadRequest,
+		},
+		{
+			name:       "should return an error when webhook does not exist",
+			errGet:     mongo.ErrNoDocuments,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "should return an error when webhook belongs to another tenant",
+			tenant:     "acme",
+			statusCode: http.StatusNotFound,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, _, router := webhookConfigurations(webhookSecretBox(), adapters.NewHTTPWebhookSender(time.Second))
+
+			subscription := webhookSubscription(webhookURL, "whsec_secret")
+			if test.tenant != "" {
+				subscription.Tenant = test.tenant
+			}
+
+			repos.subscriptions.On("GetSubscription", mock.Anything, "webhook-1").Return(subscription, test.errGet)
+			repos.deliveries.On("ListDeliveries", mock.Anything, "webhook-1", domain.WebhookDeliveryDeadLetter, "delivery-4", test.limit).Return(deliveries, nil)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, webhookRequest("GET", webhooksRoute+"/webhook-1/deliveries"+test.query, nil))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if test.statusCode != http.StatusOK {
+				return
+			}
+
+			var response domain.APIResponse
+			err := json.Unmarshal(resp.Body.Bytes(), &response)
+			assert.NoError(t, err)
+			assert.Len(t, response.Deliveries, test.count)
+			assert.Equal(t, test.nextCursor, response.NextCursor)
+		})
+	}
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	testCases := []struct {
+		name       string
+		tenant     string
+		errRepo    error
+		statusCode int
+	}{
+		{
+			name:       "should schedule delivery to be sent again",
+			statusCode: http.StatusAccepted,
+		},
+		{
+			name:       "should return an error when webhook belongs to another tenant",
+			tenant:     "acme",
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "should return an error when delivery does not exist",
+			errRepo:    mongo.ErrNoDocuments,
+			statusCode: http.StatusNotFound,
+		},
+		{
+			name:       "should return an error when bd return an error redelivering",
+			errRepo:    errors.New(errorValue),
+			statusCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			repos, _, router := webhookConfigurations(webhookSecretBox(), adapters.NewHTTPWebhookSender(time.Second))
+
+			subscription := webhookSubscription(webhookURL, "whsec_secret")
+			if test.tenant != "" {
+				subscription.Tenant = test.tenant
+			}
+
+			delivery := &domain.WebhookDelivery{ID: "delivery-1", SubscriptionID: "webhook-1", Status: domain.WebhookDeliveryPending}
+			repos.subscriptions.On("GetSubscription", mock.Anything, "webhook-1").Return(subscription, nil)
+			repos.deliveries.On("Redeliver", mock.Anything, "webhook-1", "delivery-1").Return(delivery, test.errRepo)
+
+			resp := httptest.NewRecorder()
+
+			router.ServeHTTP(resp, webhookRequest("POST", webhooksRoute+"/webhook-1/deliveries/delivery-1/redeliver", nil))
+
+			assert.Equal(t, test.statusCode, resp.Code)
+			if test.tenant != "" {
+				repos.deliveries.AssertNotCalled(t, "Redeliver", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	event := &domain.CloudEvent{
+		SpecVersion:     domain.CloudEventsSpecVersion,
+		ID:              "event1",
+		Source:          "/cnm-proyect-go/users",
+		Type:            domain.CloudEventUserCreated,
+		Subject:         "12345",
+		Time:            time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		DataContentType: "application/json",
+		Data:            domain.UserEventData{ID: "12345", Email: "cristian@gmail.com", Enabled: true},
+	}
+
+	testCases := []struct {
+		name           string
+		mode           string
+		receiverStatus int
+		attempts       int
+		errGet         error
+		status         string
+		retried        bool
+	}{
+		{
+			name:           "should send signed delivery accepted by the receiver",
+			mode:           domain.CloudEventsStructured,
+			receiverStatus: http.StatusOK,
+			attempts:       1,
+			status:         domain.WebhookDeliverySucceeded,
+		},
+		{
+			name:           "should send signed delivery in binary mode accepted by the receiver",
+			mode:           domain.CloudEventsBinary,
+			receiverStatus: http.StatusOK,
+			attempts:       1,
+			status:         domain.WebhookDeliverySucceeded,
+		},
+		{
+			name:           "should retry with backoff when receiver fails",
+			receiverStatus: http.StatusServiceUnavailable,
+			attempts:       2,
+			status:         domain.WebhookDeliveryPending,
+			retried:        true,
+		},
+		{
+			name:           "should move delivery to dead letter after the last attempt",
+			receiverStatus: http.StatusInternalServerError,
+			attempts:       webhookMaxAttempt,
+			status:         domain.WebhookDeliveryDeadLetter,
+		},
+		{
+			name:     "should move delivery to dead letter when webhook was deleted",
+			attempts: 1,
+			errGet:   mongo.ErrNoDocuments,
+			status:   domain.WebhookDeliveryDeadLetter,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			var signed bool
+			var body []byte
+			var header http.Header
+			receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+				header = r.Header
+				signed = utils.VerifyWebhookSignature("whsec_secret", r.Header.Get(adapters.WebhookTimestampHeader), r.Header.Get(adapters.WebhookSignatureHeader), body, time.Minute)
+				w.WriteHeader(test.receiverStatus)
+			}))
+			defer receiver.Close()
+
+			repos, webhookService, _ := webhookConfigurations(webhookSecretBox(), adapters.NewHTTPWebhookSender(time.Second).AllowPrivateTargets())
+			ctx := context.Background()
+			subscription := webhookSubscription(receiver.URL, "whsec_secret")
+			subscription.Mode = test.mode
+
+			repos.subscriptions.On("ListSubscriptionsForEvent", ctx, domain.CloudEventUserCreated).Return([]domain.WebhookSubscription{*subscription}, nil)
+			repos.deliveries.On("CreateDelivery", ctx, mock.Anything).Return(nil).Once()
+			repos.deliveries.On("CreateDelivery", ctx, mock.Anything).Return(mongo.WriteException{WriteErrors: mongo.WriteErrors{{Code: 11000}}}).Once()
+
+			assert.NoError(t, webhookService.Publish(ctx, event))
+			assert.NoError(t, webhookService.Publish(ctx, event))
+
+			delivery := repos.deliveries.Calls[0].Arguments.Get(1).(*domain.WebhookDelivery)
+			assert.Equal(t, "webhook-1", delivery.SubscriptionID)
+			assert.Equal(t, domain.WebhookDeliveryPending, delivery.Status)
+			delivery.Attempts = test.attempts
+
+			repos.deliveries.On("ClaimDelivery", ctx, time.Minute).Return(delivery, nil).Once()
+			repos.deliveries.On("ClaimDelivery", ctx, time.Minute).Return(nil, mongo.ErrNoDocuments).Once()
+			repos.subscriptions.On("GetSubscription", ctx, "webhook-1").Return(subscription, test.errGet)
+			repos.deliveries.On("SaveAttempt", ctx, delivery, mock.Anything).Return(nil)
+
+			before := time.Now()
+			delivered, err := webhookService.Dispatch(ctx)
+			assert.NoError(t, err)
+
+			assert.Equal(t, test.status, delivery.Status)
+			assert.Equal(t, test.status == domain.WebhookDeliverySucceeded, delivered == 1)
+			repos.deliveries.AssertCalled(t, "SaveAttempt", ctx, delivery, &delivery.History[0])
+
+			if test.errGet != nil {
+				assert.Nil(t, body)
+				return
+			}
+
+			assert.True(t, signed)
+			assert.Equal(t, test.receiverStatus, delivery.LastStatusCode)
+			assert.Equal(t, test.status != domain.WebhookDeliverySucceeded, delivery.LastError != "")
+			if test.retried {
+				assert.WithinDuration(t, before.Add(time.Minute), delivery.NextAttemptAt, 5*time.Second)
+			}
+
+			received, err := adapters.DecodeCloudEventHTTP(header, body)
+			assert.NoError(t, err)
+			assert.Equal(t, event, received)
+		})
+	}
+}
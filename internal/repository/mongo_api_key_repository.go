@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// APIKeyService struct of API keys in Mongo collection.
+type APIKeyService struct {
+	apiKeyCollection IMongoCollectionInterface
+}
+
+// NewAPIKeyRepository join to Mongo API keys collection.
+func NewAPIKeyRepository(collection IMongoCollectionInterface) *APIKeyService {
+	return &APIKeyService{
+		apiKeyCollection: collection,
+	}
+}
+
+// CreateAPIKey handles to store an API key in database, the key hash must be unique.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, key *domain.APIKey) error {
+	key.CreatedAt = time.Now()
+
+	_, err := s.apiKeyCollection.InsertOne(ctx, key)
+
+	return err
+}
+
+// TouchAPIKey handles to obtain an unexpired API key by the hash of the key in database
+// and records the time it was last used.
+func (s *APIKeyService) TouchAPIKey(ctx context.Context, keyHash string) (*domain.APIKey, error) {
+	var key domain.APIKey
+
+	now := time.Now()
+
+	filter := bson.M{
+		"keyHash": keyHash,
+		"$or": bson.A{
+			bson.M{"expiresAt": bson.M{"$exists": false}},
+			bson.M{"expiresAt": bson.M{"$gt": now}},
+		},
+	}
+
+	update := bson.M{"$set": bson.M{"lastUsedAt": now}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err := s.apiKeyCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&key)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+// ListAPIKeys handles to obtain the API keys of a user in database, the newest first.
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := s.apiKeyCollection.Find(ctx, bson.M{"userId": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []domain.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// DeleteAPIKey handles to revoke an API key of a user in database, it fails when the key does not exist.
+func (s *APIKeyService) DeleteAPIKey(ctx context.Context, userID string, id string) error {
+	filter := bson.M{
+		"_id":    id,
+		"userId": userID,
+	}
+
+	return s.apiKeyCollection.FindOneAndDelete(ctx, filter).Err()
+}
+
+// DeleteUserAPIKeys handles to revoke all the API keys of a user in database and returns how many were revoked.
+func (s *APIKeyService) DeleteUserAPIKeys(ctx context.Context, userID string) (int64, error) {
+	result, err := s.apiKeyCollection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
@@ -0,0 +1,80 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestAPIKeyRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store, touch, list and delete API keys when method is called",
+		},
+		{
+			name:    "should throw an error when API key database fails",
+			isError: true,
+			err:     errors.New("api key error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			apiKeyService := repository.NewAPIKeyRepository(mockCollection)
+			ctx := context.Background()
+
+			keyDoc := bson.M{"_id": "key-1", "userId": "12345", "prefix": "cnm_automati", "keyHash": "hash"}
+
+			mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(key *domain.APIKey) bool {
+				return !key.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			touchResult := mongo.NewSingleResultFromDocument(keyDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["keyHash"] == "hash" && len(filter["$or"].(bson.A)) == 2
+			}), mock.MatchedBy(func(update bson.M) bool {
+				return update["$set"].(bson.M)["lastUsedAt"] != nil
+			}), mock.Anything).Return(touchResult).Once()
+
+			cursor, _ := mongo.NewCursorFromDocuments([]interface{}{keyDoc}, test.err, nil)
+			mockCollection.On("Find", ctx, bson.M{"userId": "12345"}, mock.Anything).Return(cursor, test.err).Once()
+
+			deleteResult := mongo.NewSingleResultFromDocument(keyDoc, test.err, nil)
+			mockCollection.On("FindOneAndDelete", ctx, bson.M{"_id": "key-1", "userId": "12345"}).Return(deleteResult).Once()
+
+			mockCollection.On("DeleteMany", ctx, bson.M{"userId": "12345"}).Return(&mongo.DeleteResult{DeletedCount: 1}, test.err).Once()
+
+			err := apiKeyService.CreateAPIKey(ctx, &domain.APIKey{ID: "key-1", UserID: "12345"})
+			key, touchErr := apiKeyService.TouchAPIKey(ctx, "hash")
+			keys, listErr := apiKeyService.ListAPIKeys(ctx, "12345")
+			deleteErr := apiKeyService.DeleteAPIKey(ctx, "12345", "key-1")
+			deleted, deleteManyErr := apiKeyService.DeleteUserAPIKeys(ctx, "12345")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, touchErr)
+				assert.Error(t, listErr)
+				assert.Error(t, deleteErr)
+				assert.Error(t, deleteManyErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, touchErr)
+				assert.NoError(t, listErr)
+				assert.NoError(t, deleteErr)
+				assert.NoError(t, deleteManyErr)
+				assert.Equal(t, "cnm_automati", key.Prefix)
+				assert.Len(t, keys, 1)
+				assert.Equal(t, int64(1), deleted)
+			}
+		})
+	}
+}
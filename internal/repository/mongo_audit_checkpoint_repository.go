@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditCheckpointService struct of signed audit log checkpoints in Mongo collection.
+type AuditCheckpointService struct {
+	checkpointCollection IMongoCollectionInterface
+}
+
+// NewAuditCheckpointRepository join to Mongo audit checkpoints collection.
+func NewAuditCheckpointRepository(collection IMongoCollectionInterface) *AuditCheckpointService {
+	return &AuditCheckpointService{
+		checkpointCollection: collection,
+	}
+}
+
+// CreateCheckpoint handles to store a signed checkpoint in database, it fails with a duplicate
+// key error when the sequence is already signed.
+func (s *AuditCheckpointService) CreateCheckpoint(ctx context.Context, checkpoint *domain.AuditCheckpoint) error {
+	_, err := s.checkpointCollection.InsertOne(ctx, checkpoint)
+
+	return err
+}
+
+// LastCheckpoint handles to obtain the checkpoint of the newest sequence in database.
+func (s *AuditCheckpointService) LastCheckpoint(ctx context.Context) (*domain.AuditCheckpoint, error) {
+	var checkpoint domain.AuditCheckpoint
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}})
+
+	if err := s.checkpointCollection.FindOne(ctx, bson.M{}, opts).Decode(&checkpoint); err != nil {
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// ListCheckpoints handles to obtain the checkpoints in sequence order in database.
+func (s *AuditCheckpointService) ListCheckpoints(ctx context.Context) ([]domain.AuditCheckpoint, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}})
+
+	cursor, err := s.checkpointCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := []domain.AuditCheckpoint{}
+	if err := cursor.All(ctx, &checkpoints); err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
@@ -0,0 +1,117 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var checkpointDoc = bson.M{"_id": "checkpoint1", "sequence": int64(7), "hash": "hash7", "keyId": "key1"}
+
+func TestCreateCheckpoint(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store audit checkpoint when method is called",
+		},
+		{
+			name:    "should throw an error when sequence is already signed",
+			isError: true,
+			err:     mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			checkpointService := repository.NewAuditCheckpointRepository(mockCollection)
+			ctx := context.Background()
+			checkpoint := &domain.AuditCheckpoint{ID: "checkpoint1", Sequence: 7, Hash: "hash7"}
+
+			mockCollection.On("InsertOne", ctx, checkpoint).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			err := checkpointService.CreateCheckpoint(ctx, checkpoint)
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLastCheckpoint(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should return the checkpoint of the newest sequence",
+		},
+		{
+			name:    "should throw an error when there is no checkpoint",
+			isError: true,
+			err:     mongo.ErrNoDocuments,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			checkpointService := repository.NewAuditCheckpointRepository(mockCollection)
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(checkpointDoc, test.err, nil)
+			mockCollection.On("FindOne", ctx, bson.M{}, mock.Anything).Return(singleResult).Once()
+
+			checkpoint, err := checkpointService.LastCheckpoint(ctx)
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, int64(7), checkpoint.Sequence)
+				assert.Equal(t, "hash7", checkpoint.Hash)
+			}
+		})
+	}
+}
+
+func TestListCheckpoints(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should list audit checkpoints when method is called",
+		},
+		{
+			name:    "should throw an error when listing checkpoints fails",
+			isError: true,
+			err:     errors.New("checkpoint error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			checkpointService := repository.NewAuditCheckpointRepository(mockCollection)
+			ctx := context.Background()
+
+			cursor, _ := mongo.NewCursorFromDocuments([]interface{}{checkpointDoc}, nil, nil)
+			mockCollection.On("Find", ctx, bson.M{}, mock.Anything).Return(cursor, test.err).Once()
+
+			checkpoints, err := checkpointService.ListCheckpoints(ctx)
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, checkpoints, 1)
+				assert.Equal(t, "key1", checkpoints[0].KeyID)
+			}
+		})
+	}
+}
@@ -0,0 +1,157 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/x/bsonx/bsoncore"
+)
+
+// auditHashField is the field of an audit entry that is excluded from its own hash.
+const auditHashField = "hash"
+
+// maxAuditAppendAttempts is how many times an event is chained again when another writer
+// appended an entry with the same sequence first.
+const maxAuditAppendAttempts = 5
+
+// AuditService struct of audit log in Mongo collection.
+type AuditService struct {
+	auditCollection IMongoCollectionInterface
+}
+
+// NewAuditRepository join to Mongo audit collection.
+func NewAuditRepository(collection IMongoCollectionInterface) *AuditService {
+	return &AuditService{
+		auditCollection: collection,
+	}
+}
+
+// RecordEvent handles to append an event to the audit log in database. The event is chained to
+// the newest entry, the unique index on sequence makes concurrent writers chain it again.
+func (s *AuditService) RecordEvent(ctx context.Context, event *domain.AuditEvent) error {
+	event.CreatedAt = time.Now()
+
+	var err error
+	for range maxAuditAppendAttempts {
+		if err = s.appendEvent(ctx, event); !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+
+	return err
+}
+
+// appendEvent chains the event to the newest entry and inserts it.
+func (s *AuditService) appendEvent(ctx context.Context, event *domain.AuditEvent) error {
+	last, err := s.LastEvent(ctx)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	event.ID = primitive.NewObjectID().Hex()
+	event.Sequence = 1
+	event.PrevHash = ""
+	event.Hash = ""
+	if last != nil {
+		event.Sequence = last.Sequence + 1
+		event.PrevHash = last.Hash
+	}
+
+	entry, err := bson.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if event.Hash, err = HashAuditEntry(entry); err != nil {
+		return err
+	}
+
+	_, err = s.auditCollection.InsertOne(ctx, event)
+
+	return err
+}
+
+// LastEvent handles to obtain the newest entry of the audit log chain in database.
+func (s *AuditService) LastEvent(ctx context.Context) (*domain.AuditEvent, error) {
+	var event domain.AuditEvent
+
+	opts := options.FindOne().SetSort(bson.D{{Key: "sequence", Value: -1}})
+
+	result := s.auditCollection.FindOne(ctx, bson.M{"sequence": bson.M{"$exists": true}}, opts)
+	if err := result.Decode(&event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// WalkChain handles to read the entries of the audit log chain in sequence order in database,
+// as they are stored so their hash can be checked. It stops when visit returns false.
+func (s *AuditService) WalkChain(ctx context.Context, visit func(entry bson.Raw) bool) error {
+	opts := options.Find().SetSort(bson.D{{Key: "sequence", Value: 1}})
+
+	cursor, err := s.auditCollection.Find(ctx, bson.M{"sequence": bson.M{"$exists": true}}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		if !visit(cursor.Current) {
+			return nil
+		}
+	}
+
+	return cursor.Err()
+}
+
+// ListEvents handles to obtain the events of a target from newest to oldest in database, the
+// events start after the cursor, which is the ID of the last event of the previous page.
+func (s *AuditService) ListEvents(ctx context.Context, targetID string, cursor string, limit int64) ([]domain.AuditEvent, error) {
+	filter := bson.M{"targetId": targetID}
+	if cursor != "" {
+		filter["_id"] = bson.M{"$lt": cursor}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+
+	result, err := s.auditCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []domain.AuditEvent{}
+	if err := result.All(ctx, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// HashAuditEntry returns the SHA-256 hash of an audit entry encoded as in BD. The hash field
+// is excluded, so the hash covers every other field including the hash of the previous entry.
+func HashAuditEntry(entry bson.Raw) (string, error) {
+	elements, err := bsoncore.Document(entry).Elements()
+	if err != nil {
+		return "", err
+	}
+
+	kept := make([][]byte, 0, len(elements))
+	for _, element := range elements {
+		if element.Key() != auditHashField {
+			kept = append(kept, element)
+		}
+	}
+
+	sum := sha256.Sum256(bsoncore.BuildDocument(nil, kept...))
+
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,207 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestRecordEvent(t *testing.T) {
+	testCases := []struct {
+		name      string
+		last      interface{}
+		errLast   error
+		errInsert error
+		attempts  int
+		isError   bool
+		sequence  int64
+		prevHash  string
+	}{
+		{
+			name:     "should record the first audit event of the chain",
+			errLast:  mongo.ErrNoDocuments,
+			attempts: 1,
+			sequence: 1,
+		},
+		{
+			name:     "should chain audit event to the newest entry",
+			last:     bson.M{"_id": "event1", "sequence": int64(1), "hash": "hash1"},
+			attempts: 1,
+			sequence: 2,
+			prevHash: "hash1",
+		},
+		{
+			name:      "should chain audit event again when other writer appended the same sequence",
+			last:      bson.M{"_id": "event1", "sequence": int64(1), "hash": "hash1"},
+			errInsert: mongo.WriteException{WriteErrors: []mongo.WriteError{{Code: 11000}}},
+			attempts:  5,
+			isError:   true,
+		},
+		{
+			name:     "should throw an error when reading the newest entry fails",
+			errLast:  errors.New("find error"),
+			attempts: 0,
+			isError:  true,
+		},
+		{
+			name:      "should throw an error when audit database fails",
+			errLast:   mongo.ErrNoDocuments,
+			errInsert: errors.New("record event error"),
+			attempts:  1,
+			isError:   true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			auditService := repository.NewAuditRepository(mockCollection)
+			ctx := context.Background()
+
+			last := test.last
+			if last == nil {
+				last = bson.M{}
+			}
+			mockCollection.On("FindOne", ctx, bson.M{"sequence": bson.M{"$exists": true}}, mock.Anything).
+				Return(mongo.NewSingleResultFromDocument(last, test.errLast, nil))
+
+			var inserted *domain.AuditEvent
+			mockCollection.On("InsertOne", ctx, mock.AnythingOfType("*domain.AuditEvent")).Run(func(args mock.Arguments) {
+				inserted = args.Get(1).(*domain.AuditEvent)
+			}).Return(&mongo.InsertOneResult{}, test.errInsert)
+
+			err := auditService.RecordEvent(ctx, &domain.AuditEvent{
+				Action:   domain.AuditActionPasswordChanged,
+				ActorID:  "12345",
+				TargetID: "12345",
+			})
+
+			mockCollection.AssertNumberOfCalls(t, "InsertOne", test.attempts)
+			if test.isError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.NotEmpty(t, inserted.ID)
+			assert.False(t, inserted.CreatedAt.IsZero())
+			assert.Equal(t, test.sequence, inserted.Sequence)
+			assert.Equal(t, test.prevHash, inserted.PrevHash)
+
+			stored, _ := bson.Marshal(inserted)
+			hash, err := repository.HashAuditEntry(stored)
+			assert.NoError(t, err)
+			assert.Equal(t, hash, inserted.Hash)
+		})
+	}
+}
+
+func TestHashAuditEntry(t *testing.T) {
+	event := &domain.AuditEvent{ID: "event1", Sequence: 1, Action: domain.AuditActionUserCreated, TargetID: "12345"}
+	entry, _ := bson.Marshal(event)
+
+	hash, err := repository.HashAuditEntry(entry)
+	assert.NoError(t, err)
+
+	event.Hash = hash
+	stored, _ := bson.Marshal(event)
+	storedHash, err := repository.HashAuditEntry(stored)
+	assert.NoError(t, err)
+	assert.Equal(t, hash, storedHash, "the hash field must not be covered by the hash")
+
+	event.TargetID = "other"
+	edited, _ := bson.Marshal(event)
+	editedHash, err := repository.HashAuditEntry(edited)
+	assert.NoError(t, err)
+	assert.NotEqual(t, hash, editedHash)
+
+	_, err = repository.HashAuditEntry(bson.Raw{0x01})
+	assert.Error(t, err)
+}
+
+func TestWalkChain(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should visit the entries of the chain in sequence order until visit stops",
+		},
+		{
+			name:    "should throw an error when reading the chain fails",
+			isError: true,
+			err:     errors.New("audit error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			auditService := repository.NewAuditRepository(mockCollection)
+			ctx := context.Background()
+
+			cursor, _ := mongo.NewCursorFromDocuments([]interface{}{
+				bson.M{"_id": "event1", "sequence": int64(1)},
+				bson.M{"_id": "event2", "sequence": int64(2)},
+				bson.M{"_id": "event3", "sequence": int64(3)},
+			}, nil, nil)
+			mockCollection.On("Find", ctx, bson.M{"sequence": bson.M{"$exists": true}}, mock.Anything).Return(cursor, test.err).Once()
+
+			var visited []string
+			err := auditService.WalkChain(ctx, func(entry bson.Raw) bool {
+				visited = append(visited, entry.Lookup("_id").StringValue())
+				return len(visited) < 2
+			})
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, []string{"event1", "event2"}, visited)
+			}
+		})
+	}
+}
+
+func TestListEvents(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should list audit events of target after the cursor when method is called",
+		},
+		{
+			name:    "should throw an error when listing audit events fails",
+			isError: true,
+			err:     errors.New("audit error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			auditService := repository.NewAuditRepository(mockCollection)
+			ctx := context.Background()
+
+			eventDoc := bson.M{"_id": "event1", "action": domain.AuditActionUserUpdated, "targetId": "12345"}
+			cursor, _ := mongo.NewCursorFromDocuments([]interface{}{eventDoc}, nil, nil)
+			mockCollection.On("Find", ctx, bson.M{
+				"targetId": "12345",
+				"_id":      bson.M{"$lt": "event2"},
+			}, mock.Anything).Return(cursor, test.err).Once()
+
+			events, err := auditService.ListEvents(ctx, "12345", "event2", 21)
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, events, 1)
+				assert.Equal(t, domain.AuditActionUserUpdated, events[0].Action)
+			}
+		})
+	}
+}
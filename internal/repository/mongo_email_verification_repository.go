@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// EmailVerificationService struct of email verification tokens in Mongo collection.
+type EmailVerificationService struct {
+	verificationCollection IMongoCollectionInterface
+}
+
+// NewEmailVerificationRepository join to Mongo email verification collection.
+func NewEmailVerificationRepository(collection IMongoCollectionInterface) *EmailVerificationService {
+	return &EmailVerificationService{
+		verificationCollection: collection,
+	}
+}
+
+// CreateVerificationToken handles to store a verification token in database, the ID of the token must be its hash.
+func (s *EmailVerificationService) CreateVerificationToken(ctx context.Context, token *domain.EmailVerificationToken) error {
+	token.CreatedAt = time.Now()
+
+	_, err := s.verificationCollection.InsertOne(ctx, token)
+
+	return err
+}
+
+// ConsumeVerificationToken handles to obtain and delete an unexpired verification token in database.
+func (s *EmailVerificationService) ConsumeVerificationToken(ctx context.Context, tokenHash string) (*domain.EmailVerificationToken, error) {
+	var token domain.EmailVerificationToken
+
+	filter := bson.M{
+		"_id":       tokenHash,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	err := s.verificationCollection.FindOneAndDelete(ctx, filter).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
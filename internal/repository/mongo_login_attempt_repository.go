@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// LoginAttemptService struct of failed logins in Mongo collection.
+type LoginAttemptService struct {
+	attemptCollection IMongoCollectionInterface
+}
+
+// NewLoginAttemptRepository join to Mongo login attempts collection.
+func NewLoginAttemptRepository(collection IMongoCollectionInterface) *LoginAttemptService {
+	return &LoginAttemptService{
+		attemptCollection: collection,
+	}
+}
+
+// GetLoginAttempt handles to obtain the failed logins of a source in database.
+func (s *LoginAttemptService) GetLoginAttempt(ctx context.Context, key string) (*domain.LoginAttempt, error) {
+	var attempt domain.LoginAttempt
+
+	err := s.attemptCollection.FindOne(ctx, bson.M{"_id": key}).Decode(&attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// RecordFailedLogin handles to count a failed login of a source in database, the
+// document expires at expiresAt unless a later expiration was already set.
+func (s *LoginAttemptService) RecordFailedLogin(ctx context.Context, key string, expiresAt time.Time) (*domain.LoginAttempt, error) {
+	update := bson.M{
+		"$inc": bson.M{"failures": 1},
+		"$max": bson.M{"expiresAt": expiresAt},
+	}
+
+	var attempt domain.LoginAttempt
+	optionsUpdate := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := s.attemptCollection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, optionsUpdate).Decode(&attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &attempt, nil
+}
+
+// LockLogin handles to lock a source until lockedUntil in database, the failures
+// are cleared and the lockouts counted to increase the next lock.
+func (s *LoginAttemptService) LockLogin(ctx context.Context, key string, lockedUntil time.Time, expiresAt time.Time) error {
+	update := bson.M{
+		"$set": bson.M{
+			"failures":    0,
+			"lockedUntil": lockedUntil,
+		},
+		"$inc": bson.M{"lockouts": 1},
+		"$max": bson.M{"expiresAt": expiresAt},
+	}
+
+	result := s.attemptCollection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update)
+	if result.Err() != nil {
+		return result.Err()
+	}
+
+	return nil
+}
+
+// ResetLoginAttempts handles to delete the failed logins and lock of a source in database.
+func (s *LoginAttemptService) ResetLoginAttempts(ctx context.Context, key string) error {
+	err := s.attemptCollection.FindOneAndDelete(ctx, bson.M{"_id": key}).Err()
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	return nil
+}
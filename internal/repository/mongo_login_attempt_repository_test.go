@@ -0,0 +1,163 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var attemptDoc = bson.M{
+	"_id":      "user:12345",
+	"failures": 2,
+	"lockouts": 1,
+}
+
+func TestGetLoginAttempt(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should get login attempt when method is called",
+		},
+		{
+			name:    "should throw an error when source has no failed logins",
+			isError: true,
+			err:     mongo.ErrNoDocuments,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			attemptService := repository.NewLoginAttemptRepository(mockCollection)
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(attemptDoc, test.err, nil)
+			mockCollection.On("FindOne", ctx, bson.M{"_id": "user:12345"}).Return(singleResult).Once()
+
+			attempt, err := attemptService.GetLoginAttempt(ctx, "user:12345")
+
+			if test.isError {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, 2, attempt.Failures)
+				assert.Equal(t, 1, attempt.Lockouts)
+			}
+		})
+	}
+}
+
+func TestRecordFailedLogin(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should count failed login when method is called",
+		},
+		{
+			name:    "should throw an error when login attempts database fails",
+			isError: true,
+			err:     errors.New("record failed login error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			attemptService := repository.NewLoginAttemptRepository(mockCollection)
+			ctx := context.Background()
+			expiresAt := time.Now().Add(time.Hour)
+
+			singleResult := mongo.NewSingleResultFromDocument(attemptDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": "user:12345"}, mock.MatchedBy(func(update bson.M) bool {
+				return update["$inc"].(bson.M)["failures"] == 1 && update["$max"].(bson.M)["expiresAt"] == expiresAt
+			}), mock.Anything).Return(singleResult).Once()
+
+			attempt, err := attemptService.RecordFailedLogin(ctx, "user:12345", expiresAt)
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, 2, attempt.Failures)
+			}
+		})
+	}
+}
+
+func TestLockLogin(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should lock source and clear failures when method is called",
+		},
+		{
+			name:    "should throw an error when login attempts database fails",
+			isError: true,
+			err:     errors.New("lock login error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			attemptService := repository.NewLoginAttemptRepository(mockCollection)
+			ctx := context.Background()
+			lockedUntil := time.Now().Add(time.Minute)
+
+			singleResult := mongo.NewSingleResultFromDocument(attemptDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": "user:12345"}, mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				return set["failures"] == 0 && set["lockedUntil"] == lockedUntil && update["$inc"].(bson.M)["lockouts"] == 1
+			})).Return(singleResult).Once()
+
+			err := attemptService.LockLogin(ctx, "user:12345", lockedUntil, lockedUntil.Add(time.Hour))
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestResetLoginAttempts(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should delete login attempts when method is called",
+		},
+		{
+			name: "should ignore sources without failed logins",
+			err:  mongo.ErrNoDocuments,
+		},
+		{
+			name:    "should throw an error when login attempts database fails",
+			isError: true,
+			err:     errors.New("reset login attempts error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			attemptService := repository.NewLoginAttemptRepository(mockCollection)
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(attemptDoc, test.err, nil)
+			mockCollection.On("FindOneAndDelete", ctx, bson.M{"_id": "user:12345"}).Return(singleResult).Once()
+
+			err := attemptService.ResetLoginAttempts(ctx, "user:12345")
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
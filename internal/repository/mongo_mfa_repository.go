@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// MFAService struct of multi-factor authentication settings in Mongo users collection.
+type MFAService struct {
+	userCollection IMongoCollectionInterface
+}
+
+// NewMFARepository join to Mongo users collection.
+func NewMFARepository(collection IMongoCollectionInterface) *MFAService {
+	return &MFAService{
+		userCollection: collection,
+	}
+}
+
+// SetPendingTOTPSecret handles to store the encrypted secret of a TOTP enrollment
+// in database, it replaces a previous enrollment that was not confirmed.
+func (s *MFAService) SetPendingTOTPSecret(ctx context.Context, id string, secret string) error {
+	filter := bson.M{
+		"_id":         id,
+		"enabled":     true,
+		"mfa.enabled": bson.M{"$ne": true},
+	}
+
+	update := bson.M{"$set": bson.M{
+		"mfa.pendingSecret": secret,
+		"updatedAt":         time.Now(),
+	}}
+
+	return s.userCollection.FindOneAndUpdate(ctx, filter, update).Err()
+}
+
+// EnableTOTP handles to enable TOTP with the pending secret in database, the step
+// of the confirmation code is stored so it can not be used again to login.
+func (s *MFAService) EnableTOTP(ctx context.Context, id string, secret string, recoveryCodes []string, step int64) error {
+	filter := bson.M{
+		"_id":               id,
+		"enabled":           true,
+		"mfa.pendingSecret": secret,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"mfa.enabled":       true,
+			"mfa.secret":        secret,
+			"mfa.recoveryCodes": recoveryCodes,
+			"mfa.lastUsedStep":  step,
+			"updatedAt":         time.Now(),
+		},
+		"$unset": bson.M{"mfa.pendingSecret": ""},
+	}
+
+	return s.userCollection.FindOneAndUpdate(ctx, filter, update).Err()
+}
+
+// UseTOTPStep handles to store the step of a used TOTP code in database, it fails
+// when the same or a later step was already used.
+func (s *MFAService) UseTOTPStep(ctx context.Context, id string, step int64) error {
+	filter := bson.M{
+		"_id":         id,
+		"enabled":     true,
+		"mfa.enabled": true,
+		"$or": bson.A{
+			bson.M{"mfa.lastUsedStep": bson.M{"$lt": step}},
+			bson.M{"mfa.lastUsedStep": bson.M{"$exists": false}},
+		},
+	}
+
+	update := bson.M{"$set": bson.M{"mfa.lastUsedStep": step}}
+
+	return s.userCollection.FindOneAndUpdate(ctx, filter, update).Err()
+}
+
+// UseRecoveryCode handles to remove a recovery code of a user in database, it fails when the code does not exist.
+func (s *MFAService) UseRecoveryCode(ctx context.Context, id string, codeHash string) error {
+	filter := bson.M{
+		"_id":               id,
+		"enabled":           true,
+		"mfa.enabled":       true,
+		"mfa.recoveryCodes": codeHash,
+	}
+
+	update := bson.M{"$pull": bson.M{"mfa.recoveryCodes": codeHash}}
+
+	return s.userCollection.FindOneAndUpdate(ctx, filter, update).Err()
+}
+
+// MFAChallengeService struct of pending multi-factor logins in Mongo collection.
+type MFAChallengeService struct {
+	challengeCollection IMongoCollectionInterface
+}
+
+// NewMFAChallengeRepository join to Mongo MFA challenges collection.
+func NewMFAChallengeRepository(collection IMongoCollectionInterface) *MFAChallengeService {
+	return &MFAChallengeService{
+		challengeCollection: collection,
+	}
+}
+
+// CreateChallenge handles to store a MFA challenge in database, the ID of the challenge must be the hash of its token.
+func (s *MFAChallengeService) CreateChallenge(ctx context.Context, challenge *domain.MFAChallenge) error {
+	challenge.CreatedAt = time.Now()
+
+	_, err := s.challengeCollection.InsertOne(ctx, challenge)
+
+	return err
+}
+
+// AttemptChallenge handles to count an attempt to complete an unexpired MFA challenge and obtain it
+// in database, while less than maxAttempts attempts were counted.
+func (s *MFAChallengeService) AttemptChallenge(ctx context.Context, tokenHash string, maxAttempts int) (*domain.MFAChallenge, error) {
+	var challenge domain.MFAChallenge
+
+	filter := bson.M{
+		"_id":       tokenHash,
+		"attempts":  bson.M{"$lt": maxAttempts},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	update := bson.M{"$inc": bson.M{"attempts": 1}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err := s.challengeCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+// ConsumeChallenge handles to obtain and delete an unexpired MFA challenge in database.
+func (s *MFAChallengeService) ConsumeChallenge(ctx context.Context, tokenHash string) (*domain.MFAChallenge, error) {
+	var challenge domain.MFAChallenge
+
+	filter := bson.M{
+		"_id":       tokenHash,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	err := s.challengeCollection.FindOneAndDelete(ctx, filter).Decode(&challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
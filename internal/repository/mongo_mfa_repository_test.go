@@ -0,0 +1,154 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+type valuesMFATestCases struct {
+	name    string
+	call    func(mfaService *repository.MFAService, ctx context.Context) error
+	filter  func(filter bson.M) bool
+	update  func(update bson.M) bool
+	err     error
+	isError bool
+}
+
+func TestMFARepository(t *testing.T) {
+	testCases := []valuesMFATestCases{
+		{
+			name: "should store pending secret of users without MFA",
+			call: func(mfaService *repository.MFAService, ctx context.Context) error {
+				return mfaService.SetPendingTOTPSecret(ctx, "12345", "v1:secret")
+			},
+			filter: func(filter bson.M) bool {
+				return filter["mfa.enabled"] != nil
+			},
+			update: func(update bson.M) bool {
+				return update["$set"].(bson.M)["mfa.pendingSecret"] == "v1:secret"
+			},
+		},
+		{
+			name: "should enable TOTP only with the confirmed pending secret",
+			call: func(mfaService *repository.MFAService, ctx context.Context) error {
+				return mfaService.EnableTOTP(ctx, "12345", "v1:secret", []string{"hash"}, 42)
+			},
+			filter: func(filter bson.M) bool {
+				return filter["mfa.pendingSecret"] == "v1:secret"
+			},
+			update: func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				return set["mfa.enabled"] == true && set["mfa.secret"] == "v1:secret" && set["mfa.lastUsedStep"] == int64(42)
+			},
+		},
+		{
+			name: "should throw an error when TOTP step was already used",
+			call: func(mfaService *repository.MFAService, ctx context.Context) error {
+				return mfaService.UseTOTPStep(ctx, "12345", 42)
+			},
+			filter: func(filter bson.M) bool {
+				_, ok := filter["$or"]
+				return ok
+			},
+			update: func(update bson.M) bool {
+				return update["$set"].(bson.M)["mfa.lastUsedStep"] == int64(42)
+			},
+			err:     mongo.ErrNoDocuments,
+			isError: true,
+		},
+		{
+			name: "should remove used recovery code",
+			call: func(mfaService *repository.MFAService, ctx context.Context) error {
+				return mfaService.UseRecoveryCode(ctx, "12345", "hash")
+			},
+			filter: func(filter bson.M) bool {
+				return filter["mfa.recoveryCodes"] == "hash"
+			},
+			update: func(update bson.M) bool {
+				return update["$pull"].(bson.M)["mfa.recoveryCodes"] == "hash"
+			},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			mfaService := repository.NewMFARepository(mockCollection)
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(userDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, mock.MatchedBy(test.filter), mock.MatchedBy(test.update)).Return(singleResult).Once()
+
+			err := test.call(mfaService, ctx)
+
+			if test.isError {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMFAChallengeRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store, attempt and consume MFA challenge when method is called",
+		},
+		{
+			name:    "should throw an error when MFA challenge database fails",
+			isError: true,
+			err:     errors.New("mfa challenge error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			challengeService := repository.NewMFAChallengeRepository(mockCollection)
+			ctx := context.Background()
+
+			mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(challenge *domain.MFAChallenge) bool {
+				return !challenge.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			attemptResult := mongo.NewSingleResultFromDocument(bson.M{"_id": "tokenhash", "userId": "12345", "attempts": 1}, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["_id"] == "tokenhash" && filter["attempts"].(bson.M)["$lt"] == 5 && filter["expiresAt"] != nil
+			}), bson.M{"$inc": bson.M{"attempts": 1}}, mock.Anything).Return(attemptResult).Once()
+
+			singleResult := mongo.NewSingleResultFromDocument(bson.M{"_id": "tokenhash", "userId": "12345"}, test.err, nil)
+			mockCollection.On("FindOneAndDelete", ctx, mock.Anything).Return(singleResult).Once()
+
+			err := challengeService.CreateChallenge(ctx, &domain.MFAChallenge{
+				ID:        "tokenhash",
+				UserID:    "12345",
+				ExpiresAt: time.Now().Add(time.Minute),
+			})
+			attempted, attemptErr := challengeService.AttemptChallenge(ctx, "tokenhash", 5)
+			challenge, consumeErr := challengeService.ConsumeChallenge(ctx, "tokenhash")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, attemptErr)
+				assert.Error(t, consumeErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, attemptErr)
+				assert.NoError(t, consumeErr)
+				assert.Equal(t, 1, attempted.Attempts)
+				assert.Equal(t, "12345", challenge.UserID)
+			}
+		})
+	}
+}
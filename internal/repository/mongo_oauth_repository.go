@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// OAuthClientService struct of OAuth clients in Mongo collection.
+type OAuthClientService struct {
+	clientCollection IMongoCollectionInterface
+}
+
+// NewOAuthClientRepository join to Mongo OAuth clients collection.
+func NewOAuthClientRepository(collection IMongoCollectionInterface) *OAuthClientService {
+	return &OAuthClientService{
+		clientCollection: collection,
+	}
+}
+
+// CreateClient handles to store a registered client in database.
+func (s *OAuthClientService) CreateClient(ctx context.Context, client *domain.OAuthClient) error {
+	client.CreatedAt = time.Now()
+
+	_, err := s.clientCollection.InsertOne(ctx, client)
+
+	return err
+}
+
+// GetClient handles to obtain a client by its client ID in database.
+func (s *OAuthClientService) GetClient(ctx context.Context, id string) (*domain.OAuthClient, error) {
+	var client domain.OAuthClient
+
+	err := s.clientCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &client, nil
+}
+
+// OAuthConsentService struct of OAuth consents in Mongo collection.
+type OAuthConsentService struct {
+	consentCollection IMongoCollectionInterface
+}
+
+// NewOAuthConsentRepository join to Mongo OAuth consents collection.
+func NewOAuthConsentRepository(collection IMongoCollectionInterface) *OAuthConsentService {
+	return &OAuthConsentService{
+		consentCollection: collection,
+	}
+}
+
+// GetConsent handles to obtain the scopes a user granted to a client in database.
+func (s *OAuthConsentService) GetConsent(ctx context.Context, userID string, clientID string) (*domain.OAuthConsent, error) {
+	var consent domain.OAuthConsent
+
+	err := s.consentCollection.FindOne(ctx, bson.M{"_id": consentID(userID, clientID)}).Decode(&consent)
+	if err != nil {
+		return nil, err
+	}
+
+	return &consent, nil
+}
+
+// GrantConsent handles to add scopes to the consent of a user for a client in database,
+// the consent is created on the first grant.
+func (s *OAuthConsentService) GrantConsent(ctx context.Context, userID string, clientID string, scopes []string) error {
+	now := time.Now()
+
+	update := bson.M{
+		"$addToSet": bson.M{"scopes": bson.M{"$each": scopes}},
+		"$set":      bson.M{"updatedAt": now},
+		"$setOnInsert": bson.M{
+			"userId":    userID,
+			"clientId":  clientID,
+			"createdAt": now,
+		},
+	}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	return s.consentCollection.FindOneAndUpdate(ctx, bson.M{"_id": consentID(userID, clientID)}, update, opts).Err()
+}
+
+// OAuthCodeService struct of OAuth authorization codes in Mongo collection.
+type OAuthCodeService struct {
+	codeCollection IMongoCollectionInterface
+}
+
+// NewOAuthCodeRepository join to Mongo OAuth authorization codes collection.
+func NewOAuthCodeRepository(collection IMongoCollectionInterface) *OAuthCodeService {
+	return &OAuthCodeService{
+		codeCollection: collection,
+	}
+}
+
+// CreateCode handles to store an authorization code in database, the ID of the code must be its hash.
+func (s *OAuthCodeService) CreateCode(ctx context.Context, code *domain.OAuthAuthorizationCode) error {
+	code.CreatedAt = time.Now()
+
+	_, err := s.codeCollection.InsertOne(ctx, code)
+
+	return err
+}
+
+// ConsumeCode handles to obtain and delete an unexpired authorization code in database,
+// so a code can only be exchanged once.
+func (s *OAuthCodeService) ConsumeCode(ctx context.Context, codeHash string) (*domain.OAuthAuthorizationCode, error) {
+	var code domain.OAuthAuthorizationCode
+
+	filter := bson.M{
+		"_id":       codeHash,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	err := s.codeCollection.FindOneAndDelete(ctx, filter).Decode(&code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &code, nil
+}
+
+// OAuthTokenService struct of OAuth access tokens in Mongo collection.
+type OAuthTokenService struct {
+	tokenCollection IMongoCollectionInterface
+}
+
+// NewOAuthTokenRepository join to Mongo OAuth access tokens collection.
+func NewOAuthTokenRepository(collection IMongoCollectionInterface) *OAuthTokenService {
+	return &OAuthTokenService{
+		tokenCollection: collection,
+	}
+}
+
+// CreateToken handles to store an access token in database, the ID of the token must be its hash.
+func (s *OAuthTokenService) CreateToken(ctx context.Context, token *domain.OAuthAccessToken) error {
+	token.CreatedAt = time.Now()
+
+	_, err := s.tokenCollection.InsertOne(ctx, token)
+
+	return err
+}
+
+// GetToken handles to obtain an unexpired access token by its hash in database.
+func (s *OAuthTokenService) GetToken(ctx context.Context, tokenHash string) (*domain.OAuthAccessToken, error) {
+	var token domain.OAuthAccessToken
+
+	filter := bson.M{
+		"_id":       tokenHash,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	err := s.tokenCollection.FindOne(ctx, filter).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// DeleteUserTokens handles to revoke all the access tokens issued for a user in database and returns how many were revoked.
+func (s *OAuthTokenService) DeleteUserTokens(ctx context.Context, userID string) (int64, error) {
+	result, err := s.tokenCollection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
+
+func consentID(userID string, clientID string) string {
+	return userID + ":" + clientID
+}
@@ -0,0 +1,166 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestOAuthClientRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store and obtain OAuth client when method is called",
+		},
+		{
+			name:    "should throw an error when OAuth client database fails",
+			isError: true,
+			err:     errors.New("client error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			clientService := repository.NewOAuthClientRepository(mockCollection)
+			ctx := context.Background()
+
+			mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(client *domain.OAuthClient) bool {
+				return !client.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			singleResult := mongo.NewSingleResultFromDocument(bson.M{"_id": "client", "name": "App", "secretHash": "hash"}, test.err, nil)
+			mockCollection.On("FindOne", ctx, bson.M{"_id": "client"}).Return(singleResult).Once()
+
+			err := clientService.CreateClient(ctx, &domain.OAuthClient{ID: "client", Name: "App"})
+			client, getErr := clientService.GetClient(ctx, "client")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, getErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, getErr)
+				assert.Equal(t, "hash", client.SecretHash)
+			}
+		})
+	}
+}
+
+func TestOAuthConsentRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should grant and obtain consent when method is called",
+		},
+		{
+			name:    "should throw an error when consent database fails",
+			isError: true,
+			err:     errors.New("consent error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			consentService := repository.NewOAuthConsentRepository(mockCollection)
+			ctx := context.Background()
+
+			consentDoc := bson.M{"_id": "12345:client", "userId": "12345", "clientId": "client", "scopes": bson.A{"profile"}}
+
+			singleResult := mongo.NewSingleResultFromDocument(consentDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": "12345:client"}, mock.MatchedBy(func(update bson.M) bool {
+				scopes := update["$addToSet"].(bson.M)["scopes"].(bson.M)["$each"].([]string)
+				return len(scopes) == 1 && scopes[0] == "profile" && update["$setOnInsert"].(bson.M)["clientId"] == "client"
+			}), mock.Anything).Return(singleResult).Once()
+
+			consentResult := mongo.NewSingleResultFromDocument(consentDoc, test.err, nil)
+			mockCollection.On("FindOne", ctx, bson.M{"_id": "12345:client"}).Return(consentResult).Once()
+
+			err := consentService.GrantConsent(ctx, "12345", "client", []string{"profile"})
+			consent, getErr := consentService.GetConsent(ctx, "12345", "client")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, getErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, getErr)
+				assert.Equal(t, []string{"profile"}, consent.Scopes)
+			}
+		})
+	}
+}
+
+func TestOAuthCodeAndTokenRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store and consume code and store and obtain token when method is called",
+		},
+		{
+			name:    "should throw an error when code or token database fails",
+			isError: true,
+			err:     errors.New("code error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCodes := new(mocks.MongoCollectionInterface)
+			mockTokens := new(mocks.MongoCollectionInterface)
+			codeService := repository.NewOAuthCodeRepository(mockCodes)
+			tokenService := repository.NewOAuthTokenRepository(mockTokens)
+			ctx := context.Background()
+
+			mockCodes.On("InsertOne", ctx, mock.MatchedBy(func(code *domain.OAuthAuthorizationCode) bool {
+				return !code.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			singleResult := mongo.NewSingleResultFromDocument(bson.M{"_id": "codehash", "clientId": "client", "userId": "12345"}, test.err, nil)
+			mockCodes.On("FindOneAndDelete", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["_id"] == "codehash" && filter["expiresAt"] != nil
+			})).Return(singleResult).Once()
+
+			mockTokens.On("InsertOne", ctx, mock.MatchedBy(func(token *domain.OAuthAccessToken) bool {
+				return !token.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			tokenResult := mongo.NewSingleResultFromDocument(bson.M{"_id": "tokenhash", "clientId": "client"}, test.err, nil)
+			mockTokens.On("FindOne", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["_id"] == "tokenhash" && filter["expiresAt"] != nil
+			})).Return(tokenResult).Once()
+
+			mockTokens.On("DeleteMany", ctx, bson.M{"userId": "12345"}).Return(&mongo.DeleteResult{DeletedCount: 2}, test.err).Once()
+
+			err := codeService.CreateCode(ctx, &domain.OAuthAuthorizationCode{ID: "codehash", ExpiresAt: time.Now().Add(time.Minute)})
+			code, consumeErr := codeService.ConsumeCode(ctx, "codehash")
+			tokenErr := tokenService.CreateToken(ctx, &domain.OAuthAccessToken{ID: "tokenhash", ExpiresAt: time.Now().Add(time.Hour)})
+			token, getErr := tokenService.GetToken(ctx, "tokenhash")
+			deleted, deleteErr := tokenService.DeleteUserTokens(ctx, "12345")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, consumeErr)
+				assert.Error(t, tokenErr)
+				assert.Error(t, getErr)
+				assert.Error(t, deleteErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, consumeErr)
+				assert.NoError(t, tokenErr)
+				assert.NoError(t, getErr)
+				assert.NoError(t, deleteErr)
+				assert.Equal(t, "client", code.ClientID)
+				assert.Equal(t, "client", token.ClientID)
+				assert.Equal(t, int64(2), deleted)
+			}
+		})
+	}
+}
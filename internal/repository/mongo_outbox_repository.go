@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TransactionRunner runs fn in a database transaction, the writes of fn must use the context it receives.
+type TransactionRunner interface {
+	WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// OutboxService struct of outbox of domain events in Mongo collection.
+type OutboxService struct {
+	outboxCollection IMongoCollectionInterface
+}
+
+// NewOutboxRepository join to Mongo outbox collection.
+func NewOutboxRepository(collection IMongoCollectionInterface) *OutboxService {
+	return &OutboxService{
+		outboxCollection: collection,
+	}
+}
+
+// ClaimEvent handles to lock the oldest pending event for lease in database, so other relays
+// skip it while it is published. Events are claimed in order, it fails with ErrNoDocuments when
+// there is no pending event or the oldest one is locked or waiting to be retried.
+func (s *OutboxService) ClaimEvent(ctx context.Context, lease time.Duration) (*domain.OutboxEvent, error) {
+	pending := bson.M{
+		"publishedAt": bson.M{"$exists": false},
+		"lockedUntil": bson.M{"$exists": true},
+	}
+
+	findOpts := options.FindOne().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1})
+
+	var oldest domain.OutboxEvent
+	if err := s.outboxCollection.FindOne(ctx, pending, findOpts).Decode(&oldest); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	filter := bson.M{
+		"_id":         oldest.ID,
+		"publishedAt": bson.M{"$exists": false},
+		"lockedUntil": bson.M{"$lte": now},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"lockedUntil": now.Add(lease)},
+		"$inc": bson.M{"attempts": 1},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var event domain.OutboxEvent
+	if err := s.outboxCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&event); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+// MarkPublished handles to mark an event as published in database, it is removed after retention.
+// Its lockedUntil is removed so the event leaves the index of pending events.
+func (s *OutboxService) MarkPublished(ctx context.Context, id string, retention time.Duration) error {
+	now := time.Now()
+
+	update := bson.M{
+		"$set": bson.M{
+			"publishedAt": now,
+			"expiresAt":   now.Add(retention),
+		},
+		"$unset": bson.M{"lockedUntil": ""},
+	}
+
+	return s.outboxCollection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update).Err()
+}
+
+// ReleaseEvent handles to unlock an event that could not be published in database, it is
+// claimed again after retryAt.
+func (s *OutboxService) ReleaseEvent(ctx context.Context, id string, retryAt time.Time) error {
+	update := bson.M{"$set": bson.M{"lockedUntil": retryAt}}
+
+	return s.outboxCollection.FindOneAndUpdate(ctx, bson.M{"_id": id}, update).Err()
+}
+
+// NewUserEvent returns a pending event of eventType for the user.
+func NewUserEvent(eventType string, user *domain.User) *domain.OutboxEvent {
+	now := time.Now()
+
+	return &domain.OutboxEvent{
+		ID:          primitive.NewObjectID().Hex(),
+		Type:        eventType,
+		AggregateID: user.ID,
+		Data: domain.UserEventData{
+			ID:            user.ID,
+			Name:          user.Name,
+			Email:         user.Email,
+			UserName:      user.UserName,
+			Enabled:       user.Enabled,
+			EmailVerified: user.EmailVerified,
+		},
+		OccurredAt:  now,
+		LockedUntil: now,
+	}
+}
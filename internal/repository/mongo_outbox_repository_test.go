@@ -0,0 +1,235 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var outboxDoc = bson.M{
+	"_id":         "event1",
+	"type":        domain.EventUserCreated,
+	"aggregateId": "12345",
+	"data":        bson.M{"id": "12345", "email": "test@example.com", "enabled": true},
+	"attempts":    1,
+}
+
+// transactionRunner runs fn directly and records whether it failed, as Mongo aborts the transaction.
+type transactionRunner struct {
+	calls   int
+	aborted bool
+}
+
+func (r *transactionRunner) WithTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	r.calls++
+	err := fn(ctx)
+	r.aborted = err != nil
+
+	return err
+}
+
+func TestClaimEvent(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should claim the oldest pending event when method is called",
+		},
+		{
+			name:    "should throw an error when the oldest pending event is locked",
+			isError: true,
+			err:     mongo.ErrNoDocuments,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			outboxService := repository.NewOutboxRepository(mockCollection)
+			ctx := context.Background()
+
+			mockCollection.On("FindOne", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["publishedAt"] != nil && filter["lockedUntil"] != nil
+			}), mock.Anything).Return(mongo.NewSingleResultFromDocument(bson.M{"_id": "event1"}, nil, nil)).Once()
+
+			singleResult := mongo.NewSingleResultFromDocument(outboxDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["_id"] == "event1" && filter["lockedUntil"] != nil
+			}), mock.MatchedBy(func(update bson.M) bool {
+				lockedUntil := update["$set"].(bson.M)["lockedUntil"].(time.Time)
+				return time.Until(lockedUntil) > 20*time.Second && update["$inc"] != nil
+			}), mock.Anything).Return(singleResult).Once()
+
+			event, err := outboxService.ClaimEvent(ctx, 30*time.Second)
+
+			if test.isError {
+				assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, domain.EventUserCreated, event.Type)
+				assert.Equal(t, "test@example.com", event.Data.Email)
+			}
+		})
+	}
+}
+
+func TestClaimEventWithoutPendingEvents(t *testing.T) {
+	mockCollection := new(mocks.MongoCollectionInterface)
+	outboxService := repository.NewOutboxRepository(mockCollection)
+	ctx := context.Background()
+
+	mockCollection.On("FindOne", ctx, mock.Anything, mock.Anything).Return(mongo.NewSingleResultFromDocument(bson.M{}, mongo.ErrNoDocuments, nil))
+
+	_, err := outboxService.ClaimEvent(ctx, 30*time.Second)
+
+	assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+	mockCollection.AssertNotCalled(t, "FindOneAndUpdate", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestMarkPublishedAndReleaseEvent(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should mark event as published and release it when methods are called",
+		},
+		{
+			name:    "should throw an error when database fails",
+			isError: true,
+			err:     errors.New("outbox error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			outboxService := repository.NewOutboxRepository(mockCollection)
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(outboxDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": "event1"}, mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				return set["publishedAt"] != nil && set["expiresAt"] != nil && update["$unset"].(bson.M)["lockedUntil"] != nil
+			})).Return(singleResult).Once()
+			mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": "event1"}, mock.MatchedBy(func(update bson.M) bool {
+				return update["$unset"] == nil && update["$set"].(bson.M)["lockedUntil"] != nil
+			})).Return(singleResult).Once()
+
+			publishErr := outboxService.MarkPublished(ctx, "event1", time.Hour)
+			releaseErr := outboxService.ReleaseEvent(ctx, "event1", time.Now().Add(time.Minute))
+
+			if test.isError {
+				assert.Error(t, publishErr)
+				assert.Error(t, releaseErr)
+			} else {
+				assert.NoError(t, publishErr)
+				assert.NoError(t, releaseErr)
+			}
+		})
+	}
+}
+
+func TestUserChangesWriteOutboxEvents(t *testing.T) {
+	testCases := []struct {
+		name      string
+		eventType string
+		change    func(ctx context.Context, userService *repository.UserService) error
+		errOutbox error
+		events    int
+	}{
+		{
+			name:      "should write a created event with the new user",
+			eventType: domain.EventUserCreated,
+			change: func(ctx context.Context, userService *repository.UserService) error {
+				_, err := userService.CreateUser(ctx, &domain.User{Name: "Cristian", Email: "cristian@gmail.com", Password: "Test123*"})
+				return err
+			},
+			events: 1,
+		},
+		{
+			name:      "should write a created event for each imported user",
+			eventType: domain.EventUserCreated,
+			change: func(ctx context.Context, userService *repository.UserService) error {
+				_, err := userService.CreateUserBatch(ctx, &[]domain.User{{Email: "one@gmail.com"}, {Email: "two@gmail.com"}})
+				return err
+			},
+			events: 2,
+		},
+		{
+			name:      "should write an updated event with the updated user",
+			eventType: domain.EventUserUpdated,
+			change: func(ctx context.Context, userService *repository.UserService) error {
+				_, err := userService.UpdateUser(ctx, "12345", userRequest)
+				return err
+			},
+			events: 1,
+		},
+		{
+			name:      "should write a deleted event",
+			eventType: domain.EventUserDeleted,
+			change: func(ctx context.Context, userService *repository.UserService) error {
+				return userService.DeleteUser(ctx, "12345")
+			},
+			events: 1,
+		},
+		{
+			name:      "should abort the change when the event can not be written",
+			eventType: domain.EventUserUpdated,
+			change: func(ctx context.Context, userService *repository.UserService) error {
+				_, err := userService.UpdateUser(ctx, "12345", userRequest)
+				return err
+			},
+			errOutbox: errors.New("outbox error"),
+			events:    1,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockUsers := new(mocks.MongoCollectionInterface)
+			mockOutbox := new(mocks.MongoCollectionInterface)
+			transactions := &transactionRunner{}
+			userService := repository.NewUserRepository(mockUsers, func(s string) (string, error) {
+				return "hashPassword", nil
+			}).WithOutbox(mockOutbox, transactions)
+			ctx := context.Background()
+
+			mockUsers.On("InsertOne", ctx, mock.Anything).Return(&mongo.InsertOneResult{}, nil)
+			mockUsers.On("InsertMany", ctx, mock.Anything).Return(&mongo.InsertManyResult{InsertedIDs: []interface{}{"1", "2"}}, nil)
+			mockUsers.On("FindOneAndUpdate", ctx, mock.Anything, mock.Anything, mock.Anything).Return(mongo.NewSingleResultFromDocument(userDoc, nil, nil))
+			mockUsers.On("FindOneAndUpdate", ctx, mock.Anything, mock.Anything).Return(mongo.NewSingleResultFromDocument(userDoc, nil, nil))
+
+			var written []*domain.OutboxEvent
+			mockOutbox.On("InsertMany", ctx, mock.Anything).Run(func(args mock.Arguments) {
+				for _, document := range args.Get(1).([]interface{}) {
+					written = append(written, document.(*domain.OutboxEvent))
+				}
+			}).Return(&mongo.InsertManyResult{}, test.errOutbox).Once()
+
+			err := test.change(ctx, userService)
+
+			assert.Equal(t, 1, transactions.calls)
+			assert.Len(t, written, test.events)
+			for _, event := range written {
+				assert.Equal(t, test.eventType, event.Type)
+				assert.NotEmpty(t, event.ID)
+				assert.Equal(t, event.AggregateID, event.Data.ID)
+				assert.Nil(t, event.PublishedAt)
+			}
+
+			if test.errOutbox != nil {
+				assert.Error(t, err)
+				assert.True(t, transactions.aborted)
+			} else {
+				assert.NoError(t, err)
+				assert.False(t, transactions.aborted)
+				assert.NotEmpty(t, written[0].AggregateID)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// PasswordResetService struct of password reset tokens in Mongo collection.
+type PasswordResetService struct {
+	resetCollection IMongoCollectionInterface
+}
+
+// NewPasswordResetRepository join to Mongo password reset collection.
+func NewPasswordResetRepository(collection IMongoCollectionInterface) *PasswordResetService {
+	return &PasswordResetService{
+		resetCollection: collection,
+	}
+}
+
+// CreateResetToken handles to store a reset token in database, the ID of the token must be its hash.
+func (s *PasswordResetService) CreateResetToken(ctx context.Context, token *domain.PasswordResetToken) error {
+	token.CreatedAt = time.Now()
+
+	_, err := s.resetCollection.InsertOne(ctx, token)
+
+	return err
+}
+
+// ConsumeResetToken handles to obtain and delete an unexpired reset token in database,
+// the token is deleted in the same operation so it can only be used once.
+func (s *PasswordResetService) ConsumeResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error) {
+	var token domain.PasswordResetToken
+
+	filter := bson.M{
+		"_id":       tokenHash,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	err := s.resetCollection.FindOneAndDelete(ctx, filter).Decode(&token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
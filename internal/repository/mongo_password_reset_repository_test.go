@@ -0,0 +1,91 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestCreateResetToken(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store reset token when method is called",
+		},
+		{
+			name:    "should throw an error when reset token database fails",
+			isError: true,
+			err:     errors.New("create reset token error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			resetService := repository.NewPasswordResetRepository(mockCollection)
+			ctx := context.Background()
+
+			mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(token *domain.PasswordResetToken) bool {
+				return token.ID == "tokenhash" && !token.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			err := resetService.CreateResetToken(ctx, &domain.PasswordResetToken{
+				ID:        "tokenhash",
+				UserID:    "12345",
+				ExpiresAt: time.Now().Add(time.Hour),
+			})
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestConsumeResetToken(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should obtain and delete an unexpired reset token when method is called",
+		},
+		{
+			name:    "should throw an error when token does not exist, expired or was used",
+			isError: true,
+			err:     mongo.ErrNoDocuments,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			resetService := repository.NewPasswordResetRepository(mockCollection)
+			ctx := context.Background()
+
+			tokenDoc := bson.M{"_id": "tokenhash", "userId": "12345"}
+			singleResult := mongo.NewSingleResultFromDocument(tokenDoc, test.err, nil)
+
+			mockCollection.On("FindOneAndDelete", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				_, expires := filter["expiresAt"]
+				return filter["_id"] == "tokenhash" && expires
+			})).Return(singleResult).Once()
+
+			token, err := resetService.ConsumeResetToken(ctx, "tokenhash")
+
+			if test.isError {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "12345", token.UserID)
+			}
+		})
+	}
+}
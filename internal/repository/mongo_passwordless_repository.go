@@ -0,0 +1,81 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// PasswordlessService struct of logins by emailed link or code in Mongo collection.
+type PasswordlessService struct {
+	challengeCollection IMongoCollectionInterface
+}
+
+// NewPasswordlessRepository join to Mongo passwordless logins collection.
+func NewPasswordlessRepository(collection IMongoCollectionInterface) *PasswordlessService {
+	return &PasswordlessService{
+		challengeCollection: collection,
+	}
+}
+
+// CreateChallenge handles to store a passwordless login in database, the ID must be the hash of the link token.
+func (s *PasswordlessService) CreateChallenge(ctx context.Context, challenge *domain.PasswordlessChallenge) error {
+	challenge.CreatedAt = time.Now()
+
+	_, err := s.challengeCollection.InsertOne(ctx, challenge)
+
+	return err
+}
+
+// ConsumeLinkChallenge handles to obtain and delete an unexpired passwordless login by
+// the hash of its link token in database, only from the device that requested it.
+func (s *PasswordlessService) ConsumeLinkChallenge(ctx context.Context, tokenHash string, nonceHash string) (*domain.PasswordlessChallenge, error) {
+	filter := bson.M{
+		"_id":       tokenHash,
+		"nonceHash": nonceHash,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	return s.consume(ctx, filter)
+}
+
+// ConsumeCodeChallenge handles to obtain and delete an unexpired passwordless login by
+// its email and code in database, only from the device that requested it and while
+// less than maxAttempts wrong codes were recorded.
+func (s *PasswordlessService) ConsumeCodeChallenge(ctx context.Context, email string, codeHash string, nonceHash string, maxAttempts int) (*domain.PasswordlessChallenge, error) {
+	filter := bson.M{
+		"email":     email,
+		"codeHash":  codeHash,
+		"nonceHash": nonceHash,
+		"attempts":  bson.M{"$lt": maxAttempts},
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	return s.consume(ctx, filter)
+}
+
+// RecordFailedCode handles to count a wrong code of the passwordless login of the device in database.
+func (s *PasswordlessService) RecordFailedCode(ctx context.Context, email string, nonceHash string) error {
+	filter := bson.M{
+		"email":     email,
+		"nonceHash": nonceHash,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	update := bson.M{"$inc": bson.M{"attempts": 1}}
+
+	return s.challengeCollection.FindOneAndUpdate(ctx, filter, update).Err()
+}
+
+func (s *PasswordlessService) consume(ctx context.Context, filter bson.M) (*domain.PasswordlessChallenge, error) {
+	var challenge domain.PasswordlessChallenge
+
+	err := s.challengeCollection.FindOneAndDelete(ctx, filter).Decode(&challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
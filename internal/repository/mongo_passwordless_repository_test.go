@@ -0,0 +1,83 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var passwordlessDoc = bson.M{
+	"_id":    "tokenhash",
+	"userId": "12345",
+	"email":  "cristian@gmail.com",
+}
+
+func TestPasswordlessRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store and consume passwordless logins when methods are called",
+		},
+		{
+			name:    "should throw an error when passwordless logins database fails",
+			isError: true,
+			err:     errors.New("passwordless error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			passwordlessService := repository.NewPasswordlessRepository(mockCollection)
+			ctx := context.Background()
+
+			mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(challenge *domain.PasswordlessChallenge) bool {
+				return !challenge.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			mockCollection.On("FindOneAndDelete", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["_id"] == "tokenhash" && filter["nonceHash"] == "noncehash"
+			})).Return(mongo.NewSingleResultFromDocument(passwordlessDoc, test.err, nil)).Once()
+
+			mockCollection.On("FindOneAndDelete", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["codeHash"] == "codehash" && filter["nonceHash"] == "noncehash" &&
+					filter["attempts"].(bson.M)["$lt"] == 5
+			})).Return(mongo.NewSingleResultFromDocument(passwordlessDoc, test.err, nil)).Once()
+
+			mockCollection.On("FindOneAndUpdate", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["email"] == "cristian@gmail.com" && filter["nonceHash"] == "noncehash"
+			}), bson.M{"$inc": bson.M{"attempts": 1}}).Return(mongo.NewSingleResultFromDocument(passwordlessDoc, test.err, nil)).Once()
+
+			err := passwordlessService.CreateChallenge(ctx, &domain.PasswordlessChallenge{
+				ID:        "tokenhash",
+				UserID:    "12345",
+				ExpiresAt: time.Now().Add(time.Minute),
+			})
+			linkChallenge, linkErr := passwordlessService.ConsumeLinkChallenge(ctx, "tokenhash", "noncehash")
+			codeChallenge, codeErr := passwordlessService.ConsumeCodeChallenge(ctx, "cristian@gmail.com", "codehash", "noncehash", 5)
+			failedErr := passwordlessService.RecordFailedCode(ctx, "cristian@gmail.com", "noncehash")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, linkErr)
+				assert.Error(t, codeErr)
+				assert.Error(t, failedErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, linkErr)
+				assert.NoError(t, codeErr)
+				assert.NoError(t, failedErr)
+				assert.Equal(t, "12345", linkChallenge.UserID)
+				assert.Equal(t, "cristian@gmail.com", codeChallenge.Email)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RateLimitService struct of request counters in Mongo collection.
+type RateLimitService struct {
+	rateLimitCollection IMongoCollectionInterface
+}
+
+// NewRateLimitRepository join to Mongo rate limits collection.
+func NewRateLimitRepository(collection IMongoCollectionInterface) *RateLimitService {
+	return &RateLimitService{
+		rateLimitCollection: collection,
+	}
+}
+
+// Hit handles to count a request of the key in database. Counters use fixed windows,
+// the first request after a window expired starts a new one with count one.
+func (s *RateLimitService) Hit(ctx context.Context, key string, window time.Duration) (*domain.RateLimit, error) {
+	var rateLimit domain.RateLimit
+
+	now := time.Now()
+	active := bson.M{"$gt": bson.A{"$expiresAt", now}}
+
+	update := bson.A{bson.M{"$set": bson.M{
+		"count":     bson.M{"$cond": bson.A{active, bson.M{"$add": bson.A{"$count", 1}}, 1}},
+		"expiresAt": bson.M{"$cond": bson.A{active, "$expiresAt", now.Add(window)}},
+	}}}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	err := s.rateLimitCollection.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, opts).Decode(&rateLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rateLimit, nil
+}
@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestRateLimitHit(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should count request of the key when method is called",
+		},
+		{
+			name:    "should throw an error when rate limits database fails",
+			isError: true,
+			err:     errors.New("rate limit error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			rateLimitService := repository.NewRateLimitRepository(mockCollection)
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(bson.M{"_id": "key", "count": 3, "expiresAt": time.Now()}, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": "key"}, mock.MatchedBy(func(update bson.A) bool {
+				set := update[0].(bson.M)["$set"].(bson.M)
+				return set["count"] != nil && set["expiresAt"] != nil
+			}), mock.MatchedBy(func(opts *options.FindOneAndUpdateOptions) bool {
+				return *opts.Upsert && *opts.ReturnDocument == options.After
+			})).Return(singleResult).Once()
+
+			rateLimit, err := rateLimitService.Hit(ctx, "key", time.Minute)
+
+			if test.isError {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, 3, rateLimit.Count)
+			}
+		})
+	}
+}
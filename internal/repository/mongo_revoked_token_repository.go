@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// RevokedTokenService struct of the revocation list in Mongo collection.
+type RevokedTokenService struct {
+	revokedCollection IMongoCollectionInterface
+}
+
+// NewRevokedTokenRepository join to Mongo revoked tokens collection.
+func NewRevokedTokenRepository(collection IMongoCollectionInterface) *RevokedTokenService {
+	return &RevokedTokenService{
+		revokedCollection: collection,
+	}
+}
+
+// RevokeToken handles to add the hash of a token to the revocation list in database, the entry is
+// removed when the token expires. Revoking a token twice keeps the first entry.
+func (s *RevokedTokenService) RevokeToken(ctx context.Context, tokenHash string, expiresAt time.Time) error {
+	update := bson.M{"$setOnInsert": bson.M{
+		"expiresAt": expiresAt,
+		"revokedAt": time.Now(),
+	}}
+
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	return s.revokedCollection.FindOneAndUpdate(ctx, bson.M{"_id": tokenHash}, update, opts).Err()
+}
+
+// IsRevoked handles to check whether the hash of a token is in the revocation list in database.
+func (s *RevokedTokenService) IsRevoked(ctx context.Context, tokenHash string) (bool, error) {
+	var revoked domain.RevokedToken
+
+	err := s.revokedCollection.FindOne(ctx, bson.M{"_id": tokenHash}).Decode(&revoked)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
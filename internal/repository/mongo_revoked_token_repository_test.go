@@ -0,0 +1,67 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestRevokedTokenRepository(t *testing.T) {
+	testCases := []struct {
+		valuesTestCases
+		revoked bool
+	}{
+		{
+			valuesTestCases: valuesTestCases{name: "should revoke token and find it in the revocation list"},
+			revoked:         true,
+		},
+		{
+			valuesTestCases: valuesTestCases{name: "should not find token that is not in the revocation list", err: mongo.ErrNoDocuments},
+		},
+		{
+			valuesTestCases: valuesTestCases{name: "should throw an error when revocation list database fails", isError: true, err: errors.New("revoked error")},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			revokedService := repository.NewRevokedTokenRepository(mockCollection)
+			ctx := context.Background()
+			expiresAt := time.Now().Add(time.Hour)
+
+			var revokeErr error
+			if test.isError {
+				revokeErr = test.err
+			}
+
+			upsertResult := mongo.NewSingleResultFromDocument(bson.M{"_id": "tokenhash", "expiresAt": expiresAt}, revokeErr, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": "tokenhash"}, mock.MatchedBy(func(update bson.M) bool {
+				return update["$setOnInsert"].(bson.M)["expiresAt"] == expiresAt
+			}), mock.Anything).Return(upsertResult).Once()
+
+			findResult := mongo.NewSingleResultFromDocument(bson.M{"_id": "tokenhash", "expiresAt": expiresAt}, test.err, nil)
+			mockCollection.On("FindOne", ctx, bson.M{"_id": "tokenhash"}).Return(findResult).Once()
+
+			err := revokedService.RevokeToken(ctx, "tokenhash", expiresAt)
+			revoked, findErr := revokedService.IsRevoked(ctx, "tokenhash")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, findErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, findErr)
+				assert.Equal(t, test.revoked, revoked)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SessionService struct of sessions in Mongo collection.
+type SessionService struct {
+	sessionCollection IMongoCollectionInterface
+}
+
+// NewSessionRepository join to Mongo sessions collection.
+func NewSessionRepository(collection IMongoCollectionInterface) *SessionService {
+	return &SessionService{
+		sessionCollection: collection,
+	}
+}
+
+// CreateSession handles to store a session in database, the token hash must be unique.
+func (s *SessionService) CreateSession(ctx context.Context, session *domain.Session) error {
+	now := time.Now()
+	session.CreatedAt = now
+	session.LastSeenAt = now
+
+	_, err := s.sessionCollection.InsertOne(ctx, session)
+
+	return err
+}
+
+// TouchSession handles to obtain an unexpired session by the hash of its token in
+// database and records the time it was last seen.
+func (s *SessionService) TouchSession(ctx context.Context, tokenHash string) (*domain.Session, error) {
+	var session domain.Session
+
+	now := time.Now()
+
+	filter := bson.M{
+		"tokenHash": tokenHash,
+		"expiresAt": bson.M{"$gt": now},
+	}
+
+	update := bson.M{"$set": bson.M{"lastSeenAt": now}}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	err := s.sessionCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// ListSessions handles to obtain the unexpired sessions of a user in database, the last seen first.
+func (s *SessionService) ListSessions(ctx context.Context, userID string) ([]domain.Session, error) {
+	filter := bson.M{
+		"userId":    userID,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "lastSeenAt", Value: -1}})
+
+	cursor, err := s.sessionCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sessions := []domain.Session{}
+	if err := cursor.All(ctx, &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// DeleteSession handles to revoke a session of a user in database, it fails when the session does not exist.
+func (s *SessionService) DeleteSession(ctx context.Context, userID string, id string) error {
+	filter := bson.M{
+		"_id":    id,
+		"userId": userID,
+	}
+
+	return s.sessionCollection.FindOneAndDelete(ctx, filter).Err()
+}
+
+// DeleteUserSessions handles to revoke all the sessions of a user in database and returns how many were revoked.
+func (s *SessionService) DeleteUserSessions(ctx context.Context, userID string) (int64, error) {
+	result, err := s.sessionCollection.DeleteMany(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return 0, err
+	}
+
+	return result.DeletedCount, nil
+}
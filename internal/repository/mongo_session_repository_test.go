@@ -0,0 +1,146 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var sessionDoc = bson.M{
+	"_id":       "sid",
+	"tokenHash": "tokenhash",
+	"userId":    "12345",
+	"device":    "Firefox on Linux",
+}
+
+func TestCreateAndTouchSession(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store and touch session when method is called",
+		},
+		{
+			name:    "should throw an error when session database fails",
+			isError: true,
+			err:     errors.New("session error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			sessionService := repository.NewSessionRepository(mockCollection)
+			ctx := context.Background()
+
+			mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(session *domain.Session) bool {
+				return !session.CreatedAt.IsZero() && session.LastSeenAt.Equal(session.CreatedAt)
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			singleResult := mongo.NewSingleResultFromDocument(sessionDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["tokenHash"] == "tokenhash" && filter["expiresAt"] != nil
+			}), mock.MatchedBy(func(update bson.M) bool {
+				return update["$set"].(bson.M)["lastSeenAt"] != nil
+			}), mock.Anything).Return(singleResult).Once()
+
+			err := sessionService.CreateSession(ctx, &domain.Session{
+				ID:        "sid",
+				TokenHash: "tokenhash",
+				UserID:    "12345",
+				ExpiresAt: time.Now().Add(time.Hour),
+			})
+			session, touchErr := sessionService.TouchSession(ctx, "tokenhash")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, touchErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, touchErr)
+				assert.Equal(t, "12345", session.UserID)
+				assert.Equal(t, "Firefox on Linux", session.Device)
+			}
+		})
+	}
+}
+
+func TestListSessions(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should list sessions of user when method is called",
+		},
+		{
+			name:    "should throw an error when listing sessions fails",
+			isError: true,
+			err:     errors.New("session error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			sessionService := repository.NewSessionRepository(mockCollection)
+			ctx := context.Background()
+
+			cursor, _ := mongo.NewCursorFromDocuments([]interface{}{sessionDoc}, nil, nil)
+			mockCollection.On("Find", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["userId"] == "12345" && filter["expiresAt"] != nil
+			}), mock.Anything).Return(cursor, test.err).Once()
+
+			sessions, err := sessionService.ListSessions(ctx, "12345")
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, sessions, 1)
+				assert.Equal(t, "sid", sessions[0].ID)
+			}
+		})
+	}
+}
+
+func TestDeleteSessions(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should revoke sessions of user when method is called",
+		},
+		{
+			name:    "should throw an error when session does not exist",
+			isError: true,
+			err:     mongo.ErrNoDocuments,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			sessionService := repository.NewSessionRepository(mockCollection)
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(sessionDoc, test.err, nil)
+			mockCollection.On("FindOneAndDelete", ctx, bson.M{"_id": "sid", "userId": "12345"}).Return(singleResult).Once()
+			mockCollection.On("DeleteMany", ctx, bson.M{"userId": "12345"}).Return(&mongo.DeleteResult{DeletedCount: 2}, test.err).Once()
+
+			err := sessionService.DeleteSession(ctx, "12345", "sid")
+			count, deleteErr := sessionService.DeleteUserSessions(ctx, "12345")
+
+			if test.isError {
+				assert.ErrorIs(t, err, test.err)
+				assert.Error(t, deleteErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, deleteErr)
+				assert.Equal(t, int64(2), count)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// SigningKeyService struct of ID token signing keys in Mongo collection.
+type SigningKeyService struct {
+	keyCollection IMongoCollectionInterface
+}
+
+// NewSigningKeyRepository join to Mongo signing keys collection.
+func NewSigningKeyRepository(collection IMongoCollectionInterface) *SigningKeyService {
+	return &SigningKeyService{
+		keyCollection: collection,
+	}
+}
+
+// CreateKey handles to store a signing key in database.
+func (s *SigningKeyService) CreateKey(ctx context.Context, key *domain.SigningKey) error {
+	_, err := s.keyCollection.InsertOne(ctx, key)
+
+	return err
+}
+
+// ListKeys handles to obtain the unexpired signing keys in database, the newest first.
+func (s *SigningKeyService) ListKeys(ctx context.Context) ([]domain.SigningKey, error) {
+	filter := bson.M{"expiresAt": bson.M{"$gt": time.Now()}}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+
+	cursor, err := s.keyCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []domain.SigningKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,57 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func TestSigningKeyRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store and list signing keys when method is called",
+		},
+		{
+			name:    "should throw an error when signing keys database fails",
+			isError: true,
+			err:     errors.New("signing key error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			keyService := repository.NewSigningKeyRepository(mockCollection)
+			ctx := context.Background()
+
+			mockCollection.On("InsertOne", ctx, mock.Anything).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			cursor, _ := mongo.NewCursorFromDocuments([]interface{}{bson.M{"_id": "kid", "algorithm": "RS256"}}, nil, nil)
+			mockCollection.On("Find", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["expiresAt"] != nil
+			}), mock.Anything).Return(cursor, test.err).Once()
+
+			err := keyService.CreateKey(ctx, &domain.SigningKey{ID: "kid", ExpiresAt: time.Now().Add(time.Hour)})
+			keys, listErr := keyService.ListKeys(ctx)
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, listErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, listErr)
+				assert.Len(t, keys, 1)
+				assert.Equal(t, "kid", keys[0].ID)
+			}
+		})
+	}
+}
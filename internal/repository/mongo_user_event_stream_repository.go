@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// userEventFields are the fields of a user carried by its domain events, updates of other
+// fields such as the password or the MFA settings are not streamed.
+var userEventFields = []string{"name", "email", "userName", "enabled", "emailVerified"}
+
+// UserEventStreamService struct of change stream of users in Mongo collection.
+type UserEventStreamService struct {
+	userCollection IMongoCollectionInterface
+}
+
+// NewUserEventStreamRepository join to Mongo users collection.
+func NewUserEventStreamRepository(collection IMongoCollectionInterface) *UserEventStreamService {
+	return &UserEventStreamService{
+		userCollection: collection,
+	}
+}
+
+// userChange struct of a change of a user read from a change stream.
+type userChange struct {
+	OperationType     string       `bson:"operationType"`
+	FullDocument      *domain.User `bson:"fullDocument"`
+	WallTime          time.Time    `bson:"wallTime"`
+	UpdateDescription struct {
+		UpdatedFields bson.M `bson:"updatedFields"`
+	} `bson:"updateDescription"`
+}
+
+// WatchUserEvents handles to stream the creations, updates and deletions of users in database
+// with a change stream, it starts after the change with the resume token resumeAfter when it is
+// set. The channel is closed when ctx is done or the change stream fails.
+func (s *UserEventStreamService) WatchUserEvents(ctx context.Context, resumeAfter string) (<-chan domain.UserEventChange, error) {
+	updated := bson.A{}
+	for _, field := range userEventFields {
+		updated = append(updated, bson.M{"updateDescription.updatedFields." + field: bson.M{"$exists": true}})
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{"$or": bson.A{
+		bson.M{"operationType": "insert"},
+		bson.M{"operationType": "update", "$or": updated},
+	}}}}}
+
+	opts := options.ChangeStream().SetFullDocument(options.UpdateLookup)
+	if resumeAfter != "" {
+		opts.SetResumeAfter(bson.M{"_data": resumeAfter})
+	}
+
+	stream, err := s.userCollection.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(chan domain.UserEventChange)
+	go func() {
+		defer close(changes)
+		defer stream.Close(context.Background())
+
+		for stream.Next(ctx) {
+			var change userChange
+			if err := stream.Decode(&change); err != nil || change.FullDocument == nil {
+				continue
+			}
+
+			token, _ := stream.ResumeToken().Lookup("_data").StringValueOK()
+
+			select {
+			case changes <- domain.UserEventChange{ResumeToken: token, Event: change.event()}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return changes, nil
+}
+
+// event returns the domain event of the change, disabling a user is its deletion.
+func (c *userChange) event() *domain.OutboxEvent {
+	var event *domain.OutboxEvent
+	switch {
+	case c.OperationType == "insert":
+		event = NewUserEvent(domain.EventUserCreated, c.FullDocument)
+	case c.UpdateDescription.UpdatedFields["enabled"] == false:
+		event = NewUserEvent(domain.EventUserDeleted, &domain.User{ID: c.FullDocument.ID})
+	default:
+		event = NewUserEvent(domain.EventUserUpdated, c.FullDocument)
+	}
+
+	if !c.WallTime.IsZero() {
+		event.OccurredAt = c.WallTime
+	}
+
+	return event
+}
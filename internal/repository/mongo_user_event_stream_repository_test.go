@@ -0,0 +1,52 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestWatchUserEvents(t *testing.T) {
+	testCases := []struct {
+		name        string
+		resumeAfter string
+	}{
+		{
+			name: "should throw an error when change stream can not be opened",
+		},
+		{
+			name:        "should throw an error when change stream can not be resumed",
+			resumeAfter: "8263F0A1B2000000012B",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			streamService := repository.NewUserEventStreamRepository(mockCollection)
+			ctx := context.Background()
+
+			mockCollection.On("Watch", ctx, mock.MatchedBy(func(pipeline mongo.Pipeline) bool {
+				return len(pipeline) == 1 && pipeline[0][0].Key == "$match"
+			}), mock.MatchedBy(func(opts *options.ChangeStreamOptions) bool {
+				if test.resumeAfter == "" {
+					return opts.ResumeAfter == nil
+				}
+				return assert.ObjectsAreEqual(bson.M{"_data": test.resumeAfter}, opts.ResumeAfter)
+			})).Return(nil, errors.New("change stream error")).Once()
+
+			changes, err := streamService.WatchUserEvents(ctx, test.resumeAfter)
+			assert.Error(t, err)
+			assert.Nil(t, changes)
+			mockCollection.AssertExpectations(t)
+		})
+	}
+}
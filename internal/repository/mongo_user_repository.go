@@ -2,7 +2,6 @@ package repository
 
 import (
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
@@ -17,13 +16,20 @@ type IMongoCollectionInterface interface {
 	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
 	InsertMany(ctx context.Context, documents []interface{}, opts ...*options.InsertManyOptions) (*mongo.InsertManyResult, error)
 	FindOne(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (*mongo.Cursor, error)
 	FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult
+	FindOneAndDelete(ctx context.Context, filter interface{}, opts ...*options.FindOneAndDeleteOptions) *mongo.SingleResult
+	DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	Watch(ctx context.Context, pipeline interface{}, opts ...*options.ChangeStreamOptions) (*mongo.ChangeStream, error)
 }
 
 // UserService struct of user in Mongo collection.
 type UserService struct {
-	userCollection IMongoCollectionInterface
-	hashPassword   func(string) (string, error)
+	userCollection      IMongoCollectionInterface
+	hashPassword        func(string) (string, error)
+	passwordHistorySize int
+	outboxCollection    IMongoCollectionInterface
+	transactions        TransactionRunner
 }
 
 // NewUserRepository join to Mongo collection.
@@ -34,6 +40,20 @@ func NewUserRepository(collection IMongoCollectionInterface, hashFunc func(strin
 	}
 }
 
+// WithPasswordHistory keeps the last size password hashes of each user, zero disables the history.
+func (s *UserService) WithPasswordHistory(size int) *UserService {
+	s.passwordHistorySize = size
+	return s
+}
+
+// WithOutbox writes a domain event into the outbox collection in the same transaction as each
+// creation, update and deletion of a user.
+func (s *UserService) WithOutbox(outboxCollection IMongoCollectionInterface, transactions TransactionRunner) *UserService {
+	s.outboxCollection = outboxCollection
+	s.transactions = transactions
+	return s
+}
+
 // CreateUser handles to create user in database.
 func (s *UserService) CreateUser(ctx context.Context, user *domain.User) (string, error) {
 	now := time.Now()
@@ -42,13 +62,22 @@ func (s *UserService) CreateUser(ctx context.Context, user *domain.User) (string
 	user.UpdatedAt = now
 	user.DeletedAt = now
 	user.Enabled = true
+	user.EmailVerified = false
+	user.PendingEmail = ""
 	password, err := s.hashPassword(user.Password)
 	if err != nil {
 		return "", err
 	}
 	user.Password = password
+	user.PasswordHistory = s.passwordHistory(password)
 
-	_, err = s.userCollection.InsertOne(ctx, user)
+	err = s.inTransaction(ctx, func(ctx context.Context) error {
+		if _, err := s.userCollection.InsertOne(ctx, user); err != nil {
+			return err
+		}
+
+		return s.writeEvents(ctx, NewUserEvent(domain.EventUserCreated, user))
+	})
 
 	if err != nil {
 		return "", err
@@ -62,6 +91,7 @@ func (s *UserService) CreateUserBatch(ctx context.Context, users *[]domain.User)
 	now := time.Now()
 
 	var validUsers []interface{}
+	var events []*domain.OutboxEvent
 
 	for _, user := range *users {
 		user.ID = primitive.NewObjectID().Hex()
@@ -69,15 +99,27 @@ func (s *UserService) CreateUserBatch(ctx context.Context, users *[]domain.User)
 		user.UpdatedAt = now
 		user.DeletedAt = now
 		user.Enabled = true
+		user.EmailVerified = false
+		user.PendingEmail = ""
 		password, err := s.hashPassword(user.Password)
 		if err != nil {
 			return nil, err
 		}
 		user.Password = password
+		user.PasswordHistory = s.passwordHistory(password)
 		validUsers = append(validUsers, user)
+		events = append(events, NewUserEvent(domain.EventUserCreated, &user))
 	}
 
-	usersIDs, err := s.userCollection.InsertMany(ctx, validUsers)
+	var usersIDs *mongo.InsertManyResult
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		var err error
+		if usersIDs, err = s.userCollection.InsertMany(ctx, validUsers); err != nil {
+			return err
+		}
+
+		return s.writeEvents(ctx, events...)
+	})
 
 	if err != nil {
 		return nil, err
@@ -96,7 +138,6 @@ func (s *UserService) GetUserByID(ctx context.Context, id string) (*domain.User,
 	}
 
 	result := s.userCollection.FindOne(ctx, filter)
-	fmt.Println(&result)
 	err := result.Decode(&user)
 
 	if err != nil {
@@ -106,27 +147,32 @@ func (s *UserService) GetUserByID(ctx context.Context, id string) (*domain.User,
 	return &user, nil
 }
 
-// UpdateUser handles to obtain and update user by ID in database.
+// UpdateUser handles to obtain and update user by ID in database, the password is changed with UpdatePassword.
+// A new email is stored as pendingEmail and the current one is kept until the new address is verified.
 func (s *UserService) UpdateUser(ctx context.Context, id string, updateFields *domain.User) (*domain.User, error) {
-	password, err := s.hashPassword(updateFields.Password)
-	if err != nil {
-		return &domain.User{}, err
-	}
 	filter := bson.M{
 		"_id":     id,
 		"enabled": true,
 	}
-	update := bson.M{"$set": bson.M{
+	update := bson.A{bson.M{"$set": bson.M{
 		"updatedAt": time.Now(),
 		"name":      updateFields.Name,
-		"email":     updateFields.Email,
-		"password":  password,
 		"userName":  updateFields.UserName,
-	},
-	}
+		"pendingEmail": bson.M{"$cond": bson.A{
+			bson.M{"$eq": bson.A{"$email", updateFields.Email}},
+			"$$REMOVE",
+			updateFields.Email,
+		}},
+	}}}
 	var updatedUser domain.User
 	optionsUpdate := options.FindOneAndUpdate().SetReturnDocument(options.After)
-	err = s.userCollection.FindOneAndUpdate(ctx, filter, update, optionsUpdate).Decode(&updatedUser)
+	err := s.inTransaction(ctx, func(ctx context.Context) error {
+		if err := s.userCollection.FindOneAndUpdate(ctx, filter, update, optionsUpdate).Decode(&updatedUser); err != nil {
+			return err
+		}
+
+		return s.writeEvents(ctx, NewUserEvent(domain.EventUserUpdated, &updatedUser))
+	})
 
 	if err != nil {
 		return nil, err
@@ -135,6 +181,68 @@ func (s *UserService) UpdateUser(ctx context.Context, id string, updateFields *d
 	return &updatedUser, nil
 }
 
+// UpdatePassword handles to hash and replace the password of a user in database, keeping the password history.
+func (s *UserService) UpdatePassword(ctx context.Context, id string, password string) error {
+	hash, err := s.hashPassword(password)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{
+		"_id":     id,
+		"enabled": true,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"password":  hash,
+		"updatedAt": time.Now(),
+	}}
+
+	if s.passwordHistorySize > 0 {
+		update["$push"] = bson.M{"passwordHistory": bson.M{
+			"$each":  bson.A{hash},
+			"$slice": -s.passwordHistorySize,
+		}}
+	}
+
+	result := s.userCollection.FindOneAndUpdate(ctx, filter, update)
+	if result.Err() != nil {
+		return result.Err()
+	}
+
+	return nil
+}
+
+// VerifyEmail handles to mark email as verified, when email is the pending email of the user it replaces the current one.
+func (s *UserService) VerifyEmail(ctx context.Context, id string, email string) (*domain.User, error) {
+	filter := bson.M{
+		"_id":     id,
+		"enabled": true,
+		"$or": bson.A{
+			bson.M{"email": email, "pendingEmail": bson.M{"$exists": false}},
+			bson.M{"pendingEmail": email},
+		},
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"email":         email,
+			"emailVerified": true,
+			"updatedAt":     time.Now(),
+		},
+		"$unset": bson.M{"pendingEmail": ""},
+	}
+
+	var user domain.User
+	optionsUpdate := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := s.userCollection.FindOneAndUpdate(ctx, filter, update, optionsUpdate).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
 // DeleteUser handles to obtain and delete user by ID in database.
 func (s *UserService) DeleteUser(ctx context.Context, id string) error {
 	filter := bson.M{
@@ -142,16 +250,115 @@ func (s *UserService) DeleteUser(ctx context.Context, id string) error {
 		"enabled": true,
 	}
 
+	update := bson.M{
+		"$set": bson.M{
+			"enabled":   false,
+			"deletedAt": time.Now(),
+		},
+		"$unset": bson.M{"passwordHistory": ""},
+	}
+
+	return s.inTransaction(ctx, func(ctx context.Context) error {
+		result := s.userCollection.FindOneAndUpdate(ctx, filter, update)
+
+		if result.Err() != nil {
+			return result.Err()
+		}
+
+		return s.writeEvents(ctx, NewUserEvent(domain.EventUserDeleted, &domain.User{ID: id}))
+	})
+}
+
+// GetUserByLogin handles to obtain an enabled user by userName or email in database.
+func (s *UserService) GetUserByLogin(ctx context.Context, login string) (*domain.User, error) {
+	var user domain.User
+
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"email": login},
+			bson.M{"userName": login},
+		},
+		"enabled": true,
+	}
+
+	err := s.userCollection.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// EmailCollation compares emails ignoring case, the lookups by email use it with an index of the same collation.
+var EmailCollation = &options.Collation{Locale: "en", Strength: 2}
+
+// GetUserByEmail handles to obtain an enabled user by email in database, ignoring the case of the email.
+func (s *UserService) GetUserByEmail(ctx context.Context, email string) (*domain.User, error) {
+	var user domain.User
+
+	filter := bson.M{
+		"email":   email,
+		"enabled": true,
+	}
+
+	err := s.userCollection.FindOne(ctx, filter, options.FindOne().SetCollation(EmailCollation)).Decode(&user)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// UpdatePasswordHash handles to replace the stored password hash of a user in database.
+func (s *UserService) UpdatePasswordHash(ctx context.Context, id string, hash string) error {
+	filter := bson.M{
+		"_id":     id,
+		"enabled": true,
+	}
+
 	update := bson.M{"$set": bson.M{
-		"enabled":   false,
-		"deletedAt": time.Now(),
+		"password":  hash,
+		"updatedAt": time.Now(),
 	}}
 
 	result := s.userCollection.FindOneAndUpdate(ctx, filter, update)
-
 	if result.Err() != nil {
 		return result.Err()
 	}
 
 	return nil
 }
+
+func (s *UserService) passwordHistory(hash string) []string {
+	if s.passwordHistorySize == 0 {
+		return nil
+	}
+
+	return []string{hash}
+}
+
+// inTransaction runs fn in a transaction when the outbox is enabled, so the change of the user
+// and its events are written together.
+func (s *UserService) inTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	if s.transactions == nil {
+		return fn(ctx)
+	}
+
+	return s.transactions.WithTransaction(ctx, fn)
+}
+
+// writeEvents inserts domain events into the outbox when it is enabled.
+func (s *UserService) writeEvents(ctx context.Context, events ...*domain.OutboxEvent) error {
+	if s.outboxCollection == nil || len(events) == 0 {
+		return nil
+	}
+
+	documents := make([]interface{}, 0, len(events))
+	for _, event := range events {
+		documents = append(documents, event)
+	}
+
+	_, err := s.outboxCollection.InsertMany(ctx, documents)
+
+	return err
+}
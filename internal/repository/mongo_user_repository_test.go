@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/mock"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 var (
@@ -191,13 +192,6 @@ func TestUpdateUser(t *testing.T) {
 			isError: true,
 			err:     errors.New("delete user error"),
 		},
-		{
-			name:        "should throw an error when hash password fails",
-			id:          "123456",
-			body:        userRequest,
-			isError:     true,
-			errPassword: errorPassword,
-		},
 	}
 
 	for _, test := range testCases {
@@ -258,3 +252,249 @@ func TestDeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestGetUserByLogin(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should get user by login when method is called",
+			id:   "cristian",
+		},
+		{
+			name:    "should throw an error when get user by login in database fail",
+			id:      "cristian",
+			isError: true,
+			err:     errors.New("get user error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			userService := repository.NewUserRepository(mockCollection, func(s string) (string, error) {
+				return test.hashPassword, test.errPassword
+			})
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(userDoc, test.err, nil)
+			mockCollection.On("FindOne", ctx, mock.Anything).Return(singleResult, test.err).Once()
+
+			user, err := userService.GetUserByLogin(ctx, test.id)
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.Equal(t, "hashedpassword", user.Password)
+			}
+		})
+	}
+}
+
+func TestGetUserByEmail(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should get user by email ignoring case when method is called",
+			id:   "Cristian@Gmail.com",
+		},
+		{
+			name:    "should throw an error when get user by email in database fail",
+			id:      "cristian@gmail.com",
+			isError: true,
+			err:     errors.New("get user error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			userService := repository.NewUserRepository(mockCollection, func(s string) (string, error) {
+				return test.hashPassword, test.errPassword
+			})
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(userDoc, test.err, nil)
+			mockCollection.On("FindOne", ctx, bson.M{"email": test.id, "enabled": true}, mock.MatchedBy(func(opts *options.FindOneOptions) bool {
+				return opts.Collation == repository.EmailCollation
+			})).Return(singleResult).Once()
+
+			user, err := userService.GetUserByEmail(ctx, test.id)
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.Equal(t, "hashedpassword", user.Password)
+			}
+		})
+	}
+}
+
+func TestUpdatePasswordHash(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name:         "should update password hash when method is called",
+			id:           "123456",
+			hashPassword: "newHash",
+		},
+		{
+			name:    "should throw an error when update password hash database fail",
+			id:      "123456",
+			isError: true,
+			err:     errors.New("update password error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			userService := repository.NewUserRepository(mockCollection, func(s string) (string, error) {
+				return test.hashPassword, test.errPassword
+			})
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(userDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, mock.Anything, mock.Anything).Return(singleResult, test.err).Once()
+
+			err := userService.UpdatePasswordHash(ctx, test.id, test.hashPassword)
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPasswordHistory(t *testing.T) {
+	mockCollection := new(mocks.MongoCollectionInterface)
+	userService := repository.NewUserRepository(mockCollection, func(s string) (string, error) {
+		return "hashPassword", nil
+	}).WithPasswordHistory(3)
+	ctx := context.Background()
+
+	mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(user *domain.User) bool {
+		return assert.ObjectsAreEqual([]string{"hashPassword"}, user.PasswordHistory)
+	})).Return(&mongo.InsertOneResult{InsertedID: "12345"}, nil).Once()
+
+	_, err := userService.CreateUser(ctx, &domain.User{Password: "Test123*"})
+	assert.NoError(t, err)
+
+	mockCollection.On("FindOneAndUpdate", ctx, mock.Anything, mock.MatchedBy(func(update bson.M) bool {
+		return assert.ObjectsAreEqual(bson.M{"passwordHistory": bson.M{
+			"$each":  bson.A{"hashPassword"},
+			"$slice": -3,
+		}}, update["$push"])
+	})).Return(mongo.NewSingleResultFromDocument(userDoc, nil, nil)).Once()
+
+	err = userService.UpdatePassword(ctx, "12345", "Brand123*")
+	assert.NoError(t, err)
+
+	mockCollection.On("FindOneAndUpdate", ctx, mock.Anything, mock.MatchedBy(func(update bson.M) bool {
+		return assert.ObjectsAreEqual(bson.M{"passwordHistory": ""}, update["$unset"])
+	})).Return(mongo.NewSingleResultFromDocument(userDoc, nil, nil)).Once()
+
+	err = userService.DeleteUser(ctx, "12345")
+	assert.NoError(t, err)
+
+	mockCollection.AssertExpectations(t)
+}
+
+func TestUpdatePassword(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name:         "should hash and update password when method is called",
+			id:           "123456",
+			hashPassword: "hashPassword",
+		},
+		{
+			name:        "should throw an error when hash new password fails",
+			id:          "123456",
+			isError:     true,
+			errPassword: errorPassword,
+		},
+		{
+			name:         "should throw an error when update password database fail",
+			id:           "123456",
+			hashPassword: "hashPassword",
+			isError:      true,
+			err:          errors.New("update password error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			userService := repository.NewUserRepository(mockCollection, func(s string) (string, error) {
+				return test.hashPassword, test.errPassword
+			})
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(userDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, mock.Anything, mock.MatchedBy(func(update bson.M) bool {
+				return update["$set"].(bson.M)["password"] == test.hashPassword
+			})).Return(singleResult).Once()
+
+			err := userService.UpdatePassword(ctx, test.id, "Brand123*")
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestUpdateUserKeepsEmailPending(t *testing.T) {
+	mockCollection := new(mocks.MongoCollectionInterface)
+	userService := repository.NewUserRepository(mockCollection, nil)
+	ctx := context.Background()
+
+	mockCollection.On("FindOneAndUpdate", ctx, mock.Anything, mock.MatchedBy(func(update bson.A) bool {
+		set := update[0].(bson.M)["$set"].(bson.M)
+		_, changesEmail := set["email"]
+		return !changesEmail && set["pendingEmail"] != nil
+	}), mock.Anything).Return(mongo.NewSingleResultFromDocument(userDoc, nil, nil)).Once()
+
+	_, err := userService.UpdateUser(ctx, "12345", userRequest)
+
+	assert.NoError(t, err)
+	mockCollection.AssertExpectations(t)
+}
+
+func TestVerifyEmail(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should verify email and replace the current one when method is called",
+			id:   "12345",
+		},
+		{
+			name:    "should throw an error when email is not the current or pending email",
+			id:      "12345",
+			isError: true,
+			err:     mongo.ErrNoDocuments,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			userService := repository.NewUserRepository(mockCollection, nil)
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(userDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, mock.Anything, mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				return set["email"] == "new@gmail.com" && set["emailVerified"] == true
+			}), mock.Anything).Return(singleResult).Once()
+
+			user, err := userService.VerifyEmail(ctx, test.id, "new@gmail.com")
+
+			if test.isError {
+				assert.ErrorIs(t, err, test.err)
+			} else {
+				assert.NoError(t, err)
+				assert.NotEmpty(t, user)
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// WebAuthnService struct of passkeys in Mongo collection.
+type WebAuthnService struct {
+	credentialCollection IMongoCollectionInterface
+}
+
+// NewWebAuthnRepository join to Mongo WebAuthn credentials collection.
+func NewWebAuthnRepository(collection IMongoCollectionInterface) *WebAuthnService {
+	return &WebAuthnService{
+		credentialCollection: collection,
+	}
+}
+
+// CreateCredential handles to store a verified passkey in database, it fails when the credential ID is already registered.
+func (s *WebAuthnService) CreateCredential(ctx context.Context, credential *domain.WebAuthnCredential) error {
+	credential.CreatedAt = time.Now()
+
+	_, err := s.credentialCollection.InsertOne(ctx, credential)
+
+	return err
+}
+
+// GetCredential handles to obtain a passkey by its credential ID in database.
+func (s *WebAuthnService) GetCredential(ctx context.Context, id string) (*domain.WebAuthnCredential, error) {
+	var credential domain.WebAuthnCredential
+
+	err := s.credentialCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&credential)
+	if err != nil {
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+// ListCredentials handles to obtain the passkeys of a user in database.
+func (s *WebAuthnService) ListCredentials(ctx context.Context, userID string) ([]domain.WebAuthnCredential, error) {
+	cursor, err := s.credentialCollection.Find(ctx, bson.M{"userId": userID})
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := []domain.WebAuthnCredential{}
+	if err := cursor.All(ctx, &credentials); err != nil {
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+// UpdateSignCount handles to store the signature counter of a passkey after a login in database,
+// it only updates when the stored counter is still oldCount so concurrent logins can not reuse it.
+func (s *WebAuthnService) UpdateSignCount(ctx context.Context, id string, oldCount uint32, newCount uint32) error {
+	filter := bson.M{
+		"_id":       id,
+		"signCount": oldCount,
+	}
+
+	update := bson.M{"$set": bson.M{
+		"signCount":  newCount,
+		"lastUsedAt": time.Now(),
+	}}
+
+	return s.credentialCollection.FindOneAndUpdate(ctx, filter, update).Err()
+}
+
+// WebAuthnChallengeService struct of pending WebAuthn ceremonies in Mongo collection.
+type WebAuthnChallengeService struct {
+	challengeCollection IMongoCollectionInterface
+}
+
+// NewWebAuthnChallengeRepository join to Mongo WebAuthn challenges collection.
+func NewWebAuthnChallengeRepository(collection IMongoCollectionInterface) *WebAuthnChallengeService {
+	return &WebAuthnChallengeService{
+		challengeCollection: collection,
+	}
+}
+
+// CreateChallenge handles to store a WebAuthn challenge in database, the ID of the challenge must be its hash.
+func (s *WebAuthnChallengeService) CreateChallenge(ctx context.Context, challenge *domain.WebAuthnChallenge) error {
+	challenge.CreatedAt = time.Now()
+
+	_, err := s.challengeCollection.InsertOne(ctx, challenge)
+
+	return err
+}
+
+// ConsumeChallenge handles to obtain and delete an unexpired WebAuthn challenge in database.
+func (s *WebAuthnChallengeService) ConsumeChallenge(ctx context.Context, challengeHash string) (*domain.WebAuthnChallenge, error) {
+	var challenge domain.WebAuthnChallenge
+
+	filter := bson.M{
+		"_id":       challengeHash,
+		"expiresAt": bson.M{"$gt": time.Now()},
+	}
+
+	err := s.challengeCollection.FindOneAndDelete(ctx, filter).Decode(&challenge)
+	if err != nil {
+		return nil, err
+	}
+
+	return &challenge, nil
+}
@@ -0,0 +1,125 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var credentialDoc = bson.M{
+	"_id":       "credential",
+	"userId":    "12345",
+	"publicKey": []byte("cose-key"),
+	"signCount": int64(7),
+	"format":    "none",
+}
+
+func TestWebAuthnRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store, obtain, list and update passkeys when methods are called",
+		},
+		{
+			name:    "should throw an error when passkeys database fails",
+			isError: true,
+			err:     errors.New("webauthn error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			webAuthnService := repository.NewWebAuthnRepository(mockCollection)
+			ctx := context.Background()
+
+			mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(credential *domain.WebAuthnCredential) bool {
+				return !credential.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			mockCollection.On("FindOne", ctx, bson.M{"_id": "credential"}).
+				Return(mongo.NewSingleResultFromDocument(credentialDoc, test.err, nil)).Once()
+
+			cursor, err := mongo.NewCursorFromDocuments([]interface{}{credentialDoc}, nil, nil)
+			assert.NoError(t, err)
+			mockCollection.On("Find", ctx, bson.M{"userId": "12345"}).Return(cursor, test.err).Once()
+
+			mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": "credential", "signCount": uint32(7)}, mock.MatchedBy(func(update bson.M) bool {
+				return update["$set"].(bson.M)["signCount"] == uint32(8)
+			})).Return(mongo.NewSingleResultFromDocument(credentialDoc, test.err, nil)).Once()
+
+			createErr := webAuthnService.CreateCredential(ctx, &domain.WebAuthnCredential{ID: "credential", UserID: "12345"})
+			credential, getErr := webAuthnService.GetCredential(ctx, "credential")
+			credentials, listErr := webAuthnService.ListCredentials(ctx, "12345")
+			updateErr := webAuthnService.UpdateSignCount(ctx, "credential", 7, 8)
+
+			if test.isError {
+				assert.Error(t, createErr)
+				assert.Error(t, getErr)
+				assert.Error(t, listErr)
+				assert.Error(t, updateErr)
+			} else {
+				assert.NoError(t, createErr)
+				assert.NoError(t, getErr)
+				assert.NoError(t, listErr)
+				assert.NoError(t, updateErr)
+				assert.Equal(t, uint32(7), credential.SignCount)
+				assert.Equal(t, []byte("cose-key"), credential.PublicKey)
+				assert.Len(t, credentials, 1)
+				assert.Equal(t, "12345", credentials[0].UserID)
+			}
+		})
+	}
+}
+
+func TestWebAuthnChallengeRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store and consume WebAuthn challenge when method is called",
+		},
+		{
+			name:    "should throw an error when WebAuthn challenge database fails",
+			isError: true,
+			err:     errors.New("webauthn challenge error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			challengeService := repository.NewWebAuthnChallengeRepository(mockCollection)
+			ctx := context.Background()
+
+			mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(challenge *domain.WebAuthnChallenge) bool {
+				return !challenge.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			singleResult := mongo.NewSingleResultFromDocument(bson.M{"_id": "challengehash", "ceremony": "webauthn.get"}, test.err, nil)
+			mockCollection.On("FindOneAndDelete", ctx, mock.Anything).Return(singleResult).Once()
+
+			err := challengeService.CreateChallenge(ctx, &domain.WebAuthnChallenge{
+				ID:        "challengehash",
+				Ceremony:  "webauthn.get",
+				ExpiresAt: time.Now().Add(time.Minute),
+			})
+			challenge, consumeErr := challengeService.ConsumeChallenge(ctx, "challengehash")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, consumeErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, consumeErr)
+				assert.Equal(t, "webauthn.get", challenge.Ceremony)
+			}
+		})
+	}
+}
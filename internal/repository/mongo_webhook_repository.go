@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// WebhookSubscriptionService struct of webhook subscriptions in Mongo collection.
+type WebhookSubscriptionService struct {
+	subscriptionCollection IMongoCollectionInterface
+}
+
+// NewWebhookSubscriptionRepository join to Mongo webhook subscriptions collection.
+func NewWebhookSubscriptionRepository(collection IMongoCollectionInterface) *WebhookSubscriptionService {
+	return &WebhookSubscriptionService{
+		subscriptionCollection: collection,
+	}
+}
+
+// CreateSubscription handles to store a webhook subscription in database.
+func (s *WebhookSubscriptionService) CreateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error {
+	subscription.CreatedAt = time.Now()
+
+	_, err := s.subscriptionCollection.InsertOne(ctx, subscription)
+
+	return err
+}
+
+// GetSubscription handles to obtain a webhook subscription by id in database.
+func (s *WebhookSubscriptionService) GetSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	var subscription domain.WebhookSubscription
+
+	if err := s.subscriptionCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&subscription); err != nil {
+		return nil, err
+	}
+
+	return &subscription, nil
+}
+
+// ListSubscriptions handles to obtain the webhook subscriptions of a tenant in database, the
+// newest first. The subscriptions of every tenant are returned when tenant is empty.
+func (s *WebhookSubscriptionService) ListSubscriptions(ctx context.Context, tenant string) ([]domain.WebhookSubscription, error) {
+	filter := bson.M{}
+	if tenant != "" {
+		filter["tenant"] = tenant
+	}
+
+	return s.find(ctx, filter, options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}}))
+}
+
+// ListSubscriptionsForEvent handles to obtain the webhook subscriptions receiving events of
+// eventType in database, those subscribed to every event included.
+func (s *WebhookSubscriptionService) ListSubscriptionsForEvent(ctx context.Context, eventType string) ([]domain.WebhookSubscription, error) {
+	filter := bson.M{
+		"$or": bson.A{
+			bson.M{"events": eventType},
+			bson.M{"events": bson.M{"$size": 0}},
+		},
+	}
+
+	return s.find(ctx, filter)
+}
+
+// DeleteSubscription handles to remove a webhook subscription in database, it fails when the subscription does not exist.
+func (s *WebhookSubscriptionService) DeleteSubscription(ctx context.Context, id string) error {
+	return s.subscriptionCollection.FindOneAndDelete(ctx, bson.M{"_id": id}).Err()
+}
+
+func (s *WebhookSubscriptionService) find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) ([]domain.WebhookSubscription, error) {
+	cursor, err := s.subscriptionCollection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	subscriptions := []domain.WebhookSubscription{}
+	if err := cursor.All(ctx, &subscriptions); err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// maxWebhookAttemptHistory is the number of attempts kept in the history of a delivery.
+const maxWebhookAttemptHistory = 50
+
+// WebhookDeliveryService struct of webhook deliveries in Mongo collection.
+type WebhookDeliveryService struct {
+	deliveryCollection IMongoCollectionInterface
+}
+
+// NewWebhookDeliveryRepository join to Mongo webhook deliveries collection.
+func NewWebhookDeliveryRepository(collection IMongoCollectionInterface) *WebhookDeliveryService {
+	return &WebhookDeliveryService{
+		deliveryCollection: collection,
+	}
+}
+
+// CreateDelivery handles to store a pending delivery in database, an event is delivered
+// once to each subscription so the pair must be unique.
+func (s *WebhookDeliveryService) CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	delivery.CreatedAt = time.Now()
+
+	_, err := s.deliveryCollection.InsertOne(ctx, delivery)
+
+	return err
+}
+
+// ClaimDelivery handles to lock the oldest pending delivery that is due for lease in database
+// and counts the attempt. It fails with ErrNoDocuments when there is no delivery due.
+func (s *WebhookDeliveryService) ClaimDelivery(ctx context.Context, lease time.Duration) (*domain.WebhookDelivery, error) {
+	now := time.Now()
+
+	filter := bson.M{
+		"status":        domain.WebhookDeliveryPending,
+		"nextAttemptAt": bson.M{"$lte": now},
+	}
+
+	update := bson.M{
+		"$set": bson.M{"nextAttemptAt": now.Add(lease)},
+		"$inc": bson.M{"attempts": 1},
+	}
+
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "nextAttemptAt", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var delivery domain.WebhookDelivery
+	if err := s.deliveryCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery); err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// SaveAttempt handles to store the state of a delivery after an attempt in database, the attempt
+// is appended to the history of the delivery that keeps the latest ones.
+func (s *WebhookDeliveryService) SaveAttempt(ctx context.Context, delivery *domain.WebhookDelivery, attempt *domain.WebhookAttempt) error {
+	set := bson.M{
+		"status":         delivery.Status,
+		"nextAttemptAt":  delivery.NextAttemptAt,
+		"lastStatusCode": delivery.LastStatusCode,
+		"lastError":      delivery.LastError,
+	}
+	if delivery.DeliveredAt != nil {
+		set["deliveredAt"] = delivery.DeliveredAt
+	}
+
+	update := bson.M{
+		"$set": set,
+		"$push": bson.M{"history": bson.M{
+			"$each":  []*domain.WebhookAttempt{attempt},
+			"$slice": -maxWebhookAttemptHistory,
+		}},
+	}
+
+	return s.deliveryCollection.FindOneAndUpdate(ctx, bson.M{"_id": delivery.ID}, update).Err()
+}
+
+// ListDeliveries handles to obtain the deliveries of a subscription from newest to oldest in
+// database, optionally in a status. The deliveries start after the cursor, which is the ID of
+// the last delivery of the previous page.
+func (s *WebhookDeliveryService) ListDeliveries(ctx context.Context, subscriptionID string, status string, cursor string, limit int64) ([]domain.WebhookDelivery, error) {
+	filter := bson.M{"subscriptionId": subscriptionID}
+	if status != "" {
+		filter["status"] = status
+	}
+	if cursor != "" {
+		filter["_id"] = bson.M{"$lt": cursor}
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(limit)
+
+	result, err := s.deliveryCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := []domain.WebhookDelivery{}
+	if err := result.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Redeliver handles to schedule a delivery of a subscription to be attempted now in database,
+// with its attempts reset. It fails when the delivery does not exist.
+func (s *WebhookDeliveryService) Redeliver(ctx context.Context, subscriptionID string, id string) (*domain.WebhookDelivery, error) {
+	now := time.Now()
+
+	filter := bson.M{
+		"_id":            id,
+		"subscriptionId": subscriptionID,
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":        domain.WebhookDeliveryPending,
+			"attempts":      0,
+			"nextAttemptAt": now,
+		},
+		"$unset": bson.M{"deliveredAt": ""},
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var delivery domain.WebhookDelivery
+	if err := s.deliveryCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery); err != nil {
+		return nil, err
+	}
+
+	return &delivery, nil
+}
+
+// DeleteDeliveries handles to remove the deliveries of a subscription in database.
+func (s *WebhookDeliveryService) DeleteDeliveries(ctx context.Context, subscriptionID string) error {
+	_, err := s.deliveryCollection.DeleteMany(ctx, bson.M{"subscriptionId": subscriptionID})
+
+	return err
+}
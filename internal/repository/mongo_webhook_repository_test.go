@@ -0,0 +1,199 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var webhookDeliveryDoc = bson.M{
+	"_id":            "delivery-1",
+	"subscriptionId": "webhook-1",
+	"eventId":        "event1",
+	"eventType":      domain.EventUserCreated,
+	"status":         domain.WebhookDeliveryPending,
+	"attempts":       1,
+}
+
+func TestWebhookSubscriptionRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store, get, list and delete webhooks when method is called",
+		},
+		{
+			name:    "should throw an error when webhook database fails",
+			isError: true,
+			err:     errors.New("webhook error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			subscriptionService := repository.NewWebhookSubscriptionRepository(mockCollection)
+			ctx := context.Background()
+
+			webhookDoc := bson.M{"_id": "webhook-1", "tenant": "acme", "url": "https://acme.test/hooks", "events": bson.A{}}
+
+			mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(subscription *domain.WebhookSubscription) bool {
+				return !subscription.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			mockCollection.On("FindOne", ctx, bson.M{"_id": "webhook-1"}).Return(mongo.NewSingleResultFromDocument(webhookDoc, test.err, nil)).Once()
+
+			listCursor, _ := mongo.NewCursorFromDocuments([]interface{}{webhookDoc}, test.err, nil)
+			mockCollection.On("Find", ctx, bson.M{"tenant": "acme"}, mock.Anything).Return(listCursor, test.err).Once()
+
+			eventCursor, _ := mongo.NewCursorFromDocuments([]interface{}{webhookDoc}, test.err, nil)
+			mockCollection.On("Find", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return len(filter["$or"].(bson.A)) == 2
+			})).Return(eventCursor, test.err).Once()
+
+			mockCollection.On("FindOneAndDelete", ctx, bson.M{"_id": "webhook-1"}).Return(mongo.NewSingleResultFromDocument(webhookDoc, test.err, nil)).Once()
+
+			err := subscriptionService.CreateSubscription(ctx, &domain.WebhookSubscription{ID: "webhook-1"})
+			subscription, getErr := subscriptionService.GetSubscription(ctx, "webhook-1")
+			subscriptions, listErr := subscriptionService.ListSubscriptions(ctx, "acme")
+			subscribed, eventErr := subscriptionService.ListSubscriptionsForEvent(ctx, domain.EventUserCreated)
+			deleteErr := subscriptionService.DeleteSubscription(ctx, "webhook-1")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, getErr)
+				assert.Error(t, listErr)
+				assert.Error(t, eventErr)
+				assert.Error(t, deleteErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, getErr)
+				assert.NoError(t, listErr)
+				assert.NoError(t, eventErr)
+				assert.NoError(t, deleteErr)
+				assert.Equal(t, "acme", subscription.Tenant)
+				assert.Len(t, subscriptions, 1)
+				assert.Len(t, subscribed, 1)
+			}
+		})
+	}
+}
+
+func TestClaimDelivery(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should claim the pending delivery that is due when method is called",
+		},
+		{
+			name:    "should throw an error when there is no delivery due",
+			isError: true,
+			err:     mongo.ErrNoDocuments,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			deliveryService := repository.NewWebhookDeliveryRepository(mockCollection)
+			ctx := context.Background()
+
+			singleResult := mongo.NewSingleResultFromDocument(webhookDeliveryDoc, test.err, nil)
+			mockCollection.On("FindOneAndUpdate", ctx, mock.MatchedBy(func(filter bson.M) bool {
+				return filter["status"] == domain.WebhookDeliveryPending && filter["nextAttemptAt"] != nil
+			}), mock.MatchedBy(func(update bson.M) bool {
+				nextAttemptAt := update["$set"].(bson.M)["nextAttemptAt"].(time.Time)
+				return time.Until(nextAttemptAt) > 50*time.Second && update["$inc"] != nil
+			}), mock.Anything).Return(singleResult).Once()
+
+			delivery, err := deliveryService.ClaimDelivery(ctx, time.Minute)
+
+			if test.isError {
+				assert.ErrorIs(t, err, mongo.ErrNoDocuments)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "webhook-1", delivery.SubscriptionID)
+				assert.Equal(t, 1, delivery.Attempts)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliveryRepository(t *testing.T) {
+	testCases := []valuesTestCases{
+		{
+			name: "should store, save, list, redeliver and delete deliveries when method is called",
+		},
+		{
+			name:    "should throw an error when delivery database fails",
+			isError: true,
+			err:     errors.New("delivery error"),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCollection := new(mocks.MongoCollectionInterface)
+			deliveryService := repository.NewWebhookDeliveryRepository(mockCollection)
+			ctx := context.Background()
+			deliveredAt := time.Now()
+
+			mockCollection.On("InsertOne", ctx, mock.MatchedBy(func(delivery *domain.WebhookDelivery) bool {
+				return !delivery.CreatedAt.IsZero()
+			})).Return(&mongo.InsertOneResult{}, test.err).Once()
+
+			mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": "delivery-1"}, mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				history := update["$push"].(bson.M)["history"].(bson.M)
+				return set["status"] == domain.WebhookDeliverySucceeded && set["deliveredAt"] == &deliveredAt &&
+					history["$each"].([]*domain.WebhookAttempt)[0].StatusCode == 200 && history["$slice"] == -50
+			})).Return(mongo.NewSingleResultFromDocument(webhookDeliveryDoc, test.err, nil)).Once()
+
+			cursor, _ := mongo.NewCursorFromDocuments([]interface{}{webhookDeliveryDoc}, test.err, nil)
+			mockCollection.On("Find", ctx, bson.M{
+				"subscriptionId": "webhook-1",
+				"status":         domain.WebhookDeliveryDeadLetter,
+				"_id":            bson.M{"$lt": "delivery-9"},
+			}, mock.Anything).Return(cursor, test.err).Once()
+
+			mockCollection.On("FindOneAndUpdate", ctx, bson.M{"_id": "delivery-1", "subscriptionId": "webhook-1"}, mock.MatchedBy(func(update bson.M) bool {
+				set := update["$set"].(bson.M)
+				return set["status"] == domain.WebhookDeliveryPending && set["attempts"] == 0
+			}), mock.Anything).Return(mongo.NewSingleResultFromDocument(webhookDeliveryDoc, test.err, nil)).Once()
+
+			mockCollection.On("DeleteMany", ctx, bson.M{"subscriptionId": "webhook-1"}).Return(&mongo.DeleteResult{}, test.err).Once()
+
+			err := deliveryService.CreateDelivery(ctx, &domain.WebhookDelivery{ID: "delivery-1"})
+			saveErr := deliveryService.SaveAttempt(ctx, &domain.WebhookDelivery{
+				ID:          "delivery-1",
+				Status:      domain.WebhookDeliverySucceeded,
+				DeliveredAt: &deliveredAt,
+			}, &domain.WebhookAttempt{Attempt: 1, StatusCode: 200, AttemptedAt: deliveredAt})
+			deliveries, listErr := deliveryService.ListDeliveries(ctx, "webhook-1", domain.WebhookDeliveryDeadLetter, "delivery-9", 10)
+			delivery, redeliverErr := deliveryService.Redeliver(ctx, "webhook-1", "delivery-1")
+			deleteErr := deliveryService.DeleteDeliveries(ctx, "webhook-1")
+
+			if test.isError {
+				assert.Error(t, err)
+				assert.Error(t, saveErr)
+				assert.Error(t, listErr)
+				assert.Error(t, redeliverErr)
+				assert.Error(t, deleteErr)
+			} else {
+				assert.NoError(t, err)
+				assert.NoError(t, saveErr)
+				assert.NoError(t, listErr)
+				assert.NoError(t, redeliverErr)
+				assert.NoError(t, deleteErr)
+				assert.Len(t, deliveries, 1)
+				assert.Equal(t, "delivery-1", delivery.ID)
+			}
+		})
+	}
+}
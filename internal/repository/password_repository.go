@@ -1,11 +1,40 @@
 package repository
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Argon2 default parameters, see RFC 9106 section 4. The time is accepted between
+// Argon2MinTime and Argon2MaxTime passes.
+const (
+	Argon2DefaultTime    = 3
+	Argon2MinTime        = 1
+	Argon2MaxTime        = 10
+	Argon2DefaultMemory  = 64 * 1024
+	Argon2DefaultThreads = 4
+	argon2SaltLength     = 16
+	argon2KeyLength      = 32
+	argon2Prefix         = "$argon2id$"
+)
+
+// ErrInvalidArgon2Hash is returned when a stored hash is not a valid argon2id PHC string.
+var ErrInvalidArgon2Hash = errors.New("invalid argon2id hash")
 
 // AppCrypto interface defines the methods for password hashing and comparison.
 type AppCrypto interface {
 	GenerateFromPassword(password []byte, cost int) ([]byte, error)
 	CompareHashAndPassword(hashedPassword []byte, password []byte) error
+	Identify(hashedPassword []byte) bool
+	NeedsRehash(hashedPassword []byte, cost int) bool
 }
 
 // BcryptCrypto struct implements the AppCrypto interface using bcrypt.
@@ -20,3 +49,126 @@ func (BcryptCrypto) GenerateFromPassword(password []byte, cost int) ([]byte, err
 func (BcryptCrypto) CompareHashAndPassword(hashedPassword []byte, password []byte) error {
 	return bcrypt.CompareHashAndPassword(hashedPassword, password)
 }
+
+// Identify reports whether the hash was generated by bcrypt.
+func (BcryptCrypto) Identify(hashedPassword []byte) bool {
+	return bytes.HasPrefix(hashedPassword, []byte("$2a$")) ||
+		bytes.HasPrefix(hashedPassword, []byte("$2b$")) ||
+		bytes.HasPrefix(hashedPassword, []byte("$2y$"))
+}
+
+// NeedsRehash reports whether the bcrypt hash was generated with a different cost.
+func (BcryptCrypto) NeedsRehash(hashedPassword []byte, cost int) bool {
+	hashCost, err := bcrypt.Cost(hashedPassword)
+
+	return err != nil || hashCost != cost
+}
+
+// Argon2Crypto struct implements the AppCrypto interface using argon2id.
+// Hashes are encoded in the PHC string format, the cost is the number of passes (t).
+type Argon2Crypto struct {
+	Memory  uint32
+	Threads uint8
+}
+
+// NewArgon2Crypto creates an argon2id implementation with the default memory and threads.
+func NewArgon2Crypto() Argon2Crypto {
+	return Argon2Crypto{
+		Memory:  Argon2DefaultMemory,
+		Threads: Argon2DefaultThreads,
+	}
+}
+
+type argon2Params struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+// GenerateFromPassword hashes the password with argon2id and a random salt.
+func (a Argon2Crypto) GenerateFromPassword(password []byte, cost int) ([]byte, error) {
+	if cost < 1 {
+		return nil, fmt.Errorf("invalid argon2id cost %d", cost)
+	}
+
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	key := argon2.IDKey(password, salt, uint32(cost), a.Memory, a.Threads, argon2KeyLength)
+
+	return []byte(fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix,
+		argon2.Version,
+		a.Memory,
+		cost,
+		a.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)), nil
+}
+
+// CompareHashAndPassword compares an argon2id PHC hash with a plain password.
+func (Argon2Crypto) CompareHashAndPassword(hashedPassword []byte, password []byte) error {
+	params, err := decodeArgon2Hash(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	key := argon2.IDKey(password, params.salt, params.time, params.memory, params.threads, uint32(len(params.key)))
+
+	if subtle.ConstantTimeCompare(key, params.key) != 1 {
+		return bcrypt.ErrMismatchedHashAndPassword
+	}
+
+	return nil
+}
+
+// Identify reports whether the hash was generated by argon2id.
+func (Argon2Crypto) Identify(hashedPassword []byte) bool {
+	return bytes.HasPrefix(hashedPassword, []byte(argon2Prefix))
+}
+
+// NeedsRehash reports whether the argon2id hash was generated with different parameters.
+func (a Argon2Crypto) NeedsRehash(hashedPassword []byte, cost int) bool {
+	params, err := decodeArgon2Hash(hashedPassword)
+	if err != nil {
+		return true
+	}
+
+	return params.time != uint32(cost) ||
+		params.memory != a.Memory ||
+		params.threads != a.Threads ||
+		len(params.key) != argon2KeyLength
+}
+
+func decodeArgon2Hash(hashedPassword []byte) (*argon2Params, error) {
+	parts := strings.Split(string(hashedPassword), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	params := &argon2Params{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil || len(params.key) == 0 {
+		return nil, ErrInvalidArgon2Hash
+	}
+
+	return params, nil
+}
@@ -0,0 +1,77 @@
+package repository_test
+
+import (
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type valuesCryptoTestCases struct {
+	name        string
+	crypto      repository.AppCrypto
+	cost        int
+	rehashCost  int
+	needsRehash bool
+}
+
+func TestAppCrypto(t *testing.T) {
+	testCases := []valuesCryptoTestCases{
+		{
+			name:       "should hash and compare password with bcrypt",
+			crypto:     repository.BcryptCrypto{},
+			cost:       bcrypt.MinCost,
+			rehashCost: bcrypt.MinCost,
+		},
+		{
+			name:        "should ask rehash when bcrypt cost changes",
+			crypto:      repository.BcryptCrypto{},
+			cost:        bcrypt.MinCost,
+			rehashCost:  bcrypt.DefaultCost,
+			needsRehash: true,
+		},
+		{
+			name:       "should hash and compare password with argon2id",
+			crypto:     repository.Argon2Crypto{Memory: 1024, Threads: 1},
+			cost:       1,
+			rehashCost: 1,
+		},
+		{
+			name:        "should ask rehash when argon2id cost changes",
+			crypto:      repository.Argon2Crypto{Memory: 1024, Threads: 1},
+			cost:        1,
+			rehashCost:  2,
+			needsRehash: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			hash, err := test.crypto.GenerateFromPassword([]byte("Test123*"), test.cost)
+			assert.NoError(t, err)
+
+			assert.True(t, test.crypto.Identify(hash))
+			assert.NoError(t, test.crypto.CompareHashAndPassword(hash, []byte("Test123*")))
+			assert.Error(t, test.crypto.CompareHashAndPassword(hash, []byte("Other123*")))
+			assert.Equal(t, test.needsRehash, test.crypto.NeedsRehash(hash, test.rehashCost))
+		})
+	}
+}
+
+func TestArgon2CryptoPHCFormat(t *testing.T) {
+	crypto := repository.Argon2Crypto{Memory: 1024, Threads: 1}
+
+	hash, err := crypto.GenerateFromPassword([]byte("Test123*"), 2)
+	assert.NoError(t, err)
+	assert.Regexp(t, `^\$argon2id\$v=19\$m=1024,t=2,p=1\$[A-Za-z0-9+/]+\$[A-Za-z0-9+/]+$`, string(hash))
+
+	assert.False(t, repository.BcryptCrypto{}.Identify(hash))
+	assert.True(t, repository.Argon2Crypto{Memory: 2048, Threads: 1}.NeedsRehash(hash, 2))
+
+	err = crypto.CompareHashAndPassword([]byte("$argon2id$v=19$m=1024"), []byte("Test123*"))
+	assert.ErrorIs(t, err, repository.ErrInvalidArgon2Hash)
+
+	_, err = crypto.GenerateFromPassword([]byte("Test123*"), 0)
+	assert.Error(t, err)
+}
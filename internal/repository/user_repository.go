@@ -2,8 +2,10 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
 )
 
 // UserRepository interface of user in BD.
@@ -13,4 +15,172 @@ type UserRepository interface {
 	GetUserByID(ctx context.Context, id string) (*domain.User, error)
 	UpdateUser(ctx context.Context, id string, updateFields *domain.User) (*domain.User, error)
 	DeleteUser(ctx context.Context, id string) error
+	GetUserByLogin(ctx context.Context, login string) (*domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*domain.User, error)
+	UpdatePasswordHash(ctx context.Context, id string, hash string) error
+	UpdatePassword(ctx context.Context, id string, password string) error
+	VerifyEmail(ctx context.Context, id string, email string) (*domain.User, error)
+}
+
+// AuditRepository interface of audit log in BD.
+type AuditRepository interface {
+	RecordEvent(ctx context.Context, event *domain.AuditEvent) error
+	ListEvents(ctx context.Context, targetID string, cursor string, limit int64) ([]domain.AuditEvent, error)
+	LastEvent(ctx context.Context) (*domain.AuditEvent, error)
+	WalkChain(ctx context.Context, visit func(entry bson.Raw) bool) error
+}
+
+// OutboxRepository interface of outbox of domain events in BD.
+type OutboxRepository interface {
+	ClaimEvent(ctx context.Context, lease time.Duration) (*domain.OutboxEvent, error)
+	MarkPublished(ctx context.Context, id string, retention time.Duration) error
+	ReleaseEvent(ctx context.Context, id string, retryAt time.Time) error
+}
+
+// UserEventStreamRepository interface of change stream of users in BD.
+type UserEventStreamRepository interface {
+	WatchUserEvents(ctx context.Context, resumeAfter string) (<-chan domain.UserEventChange, error)
+}
+
+// WebhookSubscriptionRepository interface of webhook subscriptions in BD.
+type WebhookSubscriptionRepository interface {
+	CreateSubscription(ctx context.Context, subscription *domain.WebhookSubscription) error
+	GetSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context, tenant string) ([]domain.WebhookSubscription, error)
+	ListSubscriptionsForEvent(ctx context.Context, eventType string) ([]domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+}
+
+// WebhookDeliveryRepository interface of deliveries of events to webhooks in BD.
+type WebhookDeliveryRepository interface {
+	CreateDelivery(ctx context.Context, delivery *domain.WebhookDelivery) error
+	ClaimDelivery(ctx context.Context, lease time.Duration) (*domain.WebhookDelivery, error)
+	SaveAttempt(ctx context.Context, delivery *domain.WebhookDelivery, attempt *domain.WebhookAttempt) error
+	ListDeliveries(ctx context.Context, subscriptionID string, status string, cursor string, limit int64) ([]domain.WebhookDelivery, error)
+	Redeliver(ctx context.Context, subscriptionID string, id string) (*domain.WebhookDelivery, error)
+	DeleteDeliveries(ctx context.Context, subscriptionID string) error
+}
+
+// AuditCheckpointRepository interface of signed audit log checkpoints in BD.
+type AuditCheckpointRepository interface {
+	CreateCheckpoint(ctx context.Context, checkpoint *domain.AuditCheckpoint) error
+	LastCheckpoint(ctx context.Context) (*domain.AuditCheckpoint, error)
+	ListCheckpoints(ctx context.Context) ([]domain.AuditCheckpoint, error)
+}
+
+// PasswordResetRepository interface of password reset tokens in BD.
+type PasswordResetRepository interface {
+	CreateResetToken(ctx context.Context, token *domain.PasswordResetToken) error
+	ConsumeResetToken(ctx context.Context, tokenHash string) (*domain.PasswordResetToken, error)
+}
+
+// EmailVerificationRepository interface of email verification tokens in BD.
+type EmailVerificationRepository interface {
+	CreateVerificationToken(ctx context.Context, token *domain.EmailVerificationToken) error
+	ConsumeVerificationToken(ctx context.Context, tokenHash string) (*domain.EmailVerificationToken, error)
+}
+
+// LoginAttemptRepository interface of failed logins in BD.
+type LoginAttemptRepository interface {
+	GetLoginAttempt(ctx context.Context, key string) (*domain.LoginAttempt, error)
+	RecordFailedLogin(ctx context.Context, key string, expiresAt time.Time) (*domain.LoginAttempt, error)
+	LockLogin(ctx context.Context, key string, lockedUntil time.Time, expiresAt time.Time) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+}
+
+// MFARepository interface of multi-factor authentication settings of users in BD.
+type MFARepository interface {
+	SetPendingTOTPSecret(ctx context.Context, id string, secret string) error
+	EnableTOTP(ctx context.Context, id string, secret string, recoveryCodes []string, step int64) error
+	UseTOTPStep(ctx context.Context, id string, step int64) error
+	UseRecoveryCode(ctx context.Context, id string, codeHash string) error
+}
+
+// MFAChallengeRepository interface of pending multi-factor logins in BD.
+type MFAChallengeRepository interface {
+	CreateChallenge(ctx context.Context, challenge *domain.MFAChallenge) error
+	AttemptChallenge(ctx context.Context, tokenHash string, maxAttempts int) (*domain.MFAChallenge, error)
+	ConsumeChallenge(ctx context.Context, tokenHash string) (*domain.MFAChallenge, error)
+}
+
+// WebAuthnRepository interface of passkeys of users in BD.
+type WebAuthnRepository interface {
+	CreateCredential(ctx context.Context, credential *domain.WebAuthnCredential) error
+	GetCredential(ctx context.Context, id string) (*domain.WebAuthnCredential, error)
+	ListCredentials(ctx context.Context, userID string) ([]domain.WebAuthnCredential, error)
+	UpdateSignCount(ctx context.Context, id string, oldCount uint32, newCount uint32) error
+}
+
+// WebAuthnChallengeRepository interface of pending WebAuthn ceremonies in BD.
+type WebAuthnChallengeRepository interface {
+	CreateChallenge(ctx context.Context, challenge *domain.WebAuthnChallenge) error
+	ConsumeChallenge(ctx context.Context, challengeHash string) (*domain.WebAuthnChallenge, error)
+}
+
+// PasswordlessRepository interface of logins by emailed link or code in BD.
+type PasswordlessRepository interface {
+	CreateChallenge(ctx context.Context, challenge *domain.PasswordlessChallenge) error
+	ConsumeLinkChallenge(ctx context.Context, tokenHash string, nonceHash string) (*domain.PasswordlessChallenge, error)
+	ConsumeCodeChallenge(ctx context.Context, email string, codeHash string, nonceHash string, maxAttempts int) (*domain.PasswordlessChallenge, error)
+	RecordFailedCode(ctx context.Context, email string, nonceHash string) error
+}
+
+// RateLimitRepository interface of request counters in BD.
+type RateLimitRepository interface {
+	Hit(ctx context.Context, key string, window time.Duration) (*domain.RateLimit, error)
+}
+
+// SessionRepository interface of sessions of users in BD.
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session *domain.Session) error
+	TouchSession(ctx context.Context, tokenHash string) (*domain.Session, error)
+	ListSessions(ctx context.Context, userID string) ([]domain.Session, error)
+	DeleteSession(ctx context.Context, userID string, id string) error
+	DeleteUserSessions(ctx context.Context, userID string) (int64, error)
+}
+
+// OAuthClientRepository interface of applications registered in the authorization server in BD.
+type OAuthClientRepository interface {
+	CreateClient(ctx context.Context, client *domain.OAuthClient) error
+	GetClient(ctx context.Context, id string) (*domain.OAuthClient, error)
+}
+
+// OAuthConsentRepository interface of scopes granted by users to OAuth clients in BD.
+type OAuthConsentRepository interface {
+	GetConsent(ctx context.Context, userID string, clientID string) (*domain.OAuthConsent, error)
+	GrantConsent(ctx context.Context, userID string, clientID string, scopes []string) error
+}
+
+// OAuthCodeRepository interface of pending OAuth authorization codes in BD.
+type OAuthCodeRepository interface {
+	CreateCode(ctx context.Context, code *domain.OAuthAuthorizationCode) error
+	ConsumeCode(ctx context.Context, codeHash string) (*domain.OAuthAuthorizationCode, error)
+}
+
+// OAuthTokenRepository interface of OAuth access tokens in BD.
+type OAuthTokenRepository interface {
+	CreateToken(ctx context.Context, token *domain.OAuthAccessToken) error
+	GetToken(ctx context.Context, tokenHash string) (*domain.OAuthAccessToken, error)
+	DeleteUserTokens(ctx context.Context, userID string) (int64, error)
+}
+
+// APIKeyRepository interface of personal API keys in BD.
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *domain.APIKey) error
+	TouchAPIKey(ctx context.Context, keyHash string) (*domain.APIKey, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error)
+	DeleteAPIKey(ctx context.Context, userID string, id string) error
+	DeleteUserAPIKeys(ctx context.Context, userID string) (int64, error)
+}
+
+// RevokedTokenRepository interface of the revocation list of tokens in BD.
+type RevokedTokenRepository interface {
+	RevokeToken(ctx context.Context, tokenHash string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, tokenHash string) (bool, error)
+}
+
+// SigningKeyRepository interface of the keys signing ID tokens in BD.
+type SigningKeyRepository interface {
+	CreateKey(ctx context.Context, key *domain.SigningKey) error
+	ListKeys(ctx context.Context) ([]domain.SigningKey, error)
 }
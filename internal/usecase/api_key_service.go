@@ -0,0 +1,122 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// apiKeyPrefix starts every API key so leaked keys are easy to recognize by secret scanners.
+const apiKeyPrefix = "cnm_"
+
+// apiKeyPrefixLength is how many characters of the key are stored to identify it in listings.
+const apiKeyPrefixLength = len(apiKeyPrefix) + 8
+
+// Errors returned by API keys.
+var (
+	ErrInvalidAPIKey        = errors.New(constants.ErrInvalidAPIKey)
+	ErrInvalidAPIKeyRequest = errors.New(constants.ErrInvalidAPIKeyRequest)
+	ErrAPIKeyNotFound       = errors.New(constants.ErrAPIKeyNotFound)
+)
+
+// APIKeyService handles the personal API keys users create for service automation.
+type APIKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+}
+
+// NewAPIKeyService obtain new API key service.
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository) *APIKeyService {
+	return &APIKeyService{
+		apiKeyRepo: apiKeyRepo,
+	}
+}
+
+// CreateAPIKey creates an API key of the user and returns the key, only its hash is stored
+// so it can not be shown again.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, userID string, request *domain.APIKeyRequest) (*domain.APIKey, string, error) {
+	for _, permission := range request.Permissions {
+		if !slices.Contains(domain.APIKeyPermissions, permission) {
+			return nil, "", fmt.Errorf("%w: unknown permission %v", ErrInvalidAPIKeyRequest, permission)
+		}
+	}
+
+	if request.ExpiresAt != nil && !request.ExpiresAt.After(time.Now()) {
+		return nil, "", fmt.Errorf("%w: expiresAt must be in the future", ErrInvalidAPIKeyRequest)
+	}
+
+	id, _, err := utils.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, _, err := utils.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	secret := apiKeyPrefix + token
+
+	key := &domain.APIKey{
+		ID:          id,
+		UserID:      userID,
+		Name:        request.Name,
+		Prefix:      secret[:apiKeyPrefixLength],
+		KeyHash:     utils.HashToken(secret),
+		Permissions: slices.Compact(slices.Sorted(slices.Values(request.Permissions))),
+		ExpiresAt:   request.ExpiresAt,
+	}
+
+	if err := s.apiKeyRepo.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	return key, secret, nil
+}
+
+// ValidateAPIKey returns the API key and records its use, revoked and expired keys return ErrInvalidAPIKey.
+func (s *APIKeyService) ValidateAPIKey(ctx context.Context, secret string) (*domain.APIKey, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := s.apiKeyRepo.TouchAPIKey(ctx, utils.HashToken(secret))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// ListAPIKeys returns the API keys of the user, only their prefix is shown.
+func (s *APIKeyService) ListAPIKeys(ctx context.Context, userID string) ([]domain.APIKey, error) {
+	return s.apiKeyRepo.ListAPIKeys(ctx, userID)
+}
+
+// RevokeAPIKey deletes an API key of the user.
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, userID string, id string) error {
+	err := s.apiKeyRepo.DeleteAPIKey(ctx, userID, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrAPIKeyNotFound
+	}
+
+	return err
+}
+
+// RevokeUserAPIKeys deletes all the API keys of the user.
+func (s *APIKeyService) RevokeUserAPIKeys(ctx context.Context, userID string) error {
+	_, err := s.apiKeyRepo.DeleteUserAPIKeys(ctx, userID)
+
+	return err
+}
@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// AuditChainService handles the signed checkpoints of the audit log hash chain and its
+// verification. A checkpoint signs the newest entry, so entries removed from the end of the
+// chain are detected too.
+type AuditChainService struct {
+	auditRepo      repository.AuditRepository
+	checkpointRepo repository.AuditCheckpointRepository
+	signingKey     ed25519.PrivateKey
+}
+
+// NewAuditChainService obtain new audit chain service, signingKey is only needed to create checkpoints.
+func NewAuditChainService(auditRepo repository.AuditRepository, checkpointRepo repository.AuditCheckpointRepository, signingKey ed25519.PrivateKey) *AuditChainService {
+	return &AuditChainService{
+		auditRepo:      auditRepo,
+		checkpointRepo: checkpointRepo,
+		signingKey:     signingKey,
+	}
+}
+
+// Run creates a checkpoint every interval until ctx is done, errors are logged and retried on the next tick.
+func (s *AuditChainService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Checkpoint(ctx); err != nil {
+				log.Printf("%v: %v", constants.ErrCheckpointAudit, err)
+			}
+		}
+	}
+}
+
+// Checkpoint signs the newest entry of the audit log when it is not signed yet. Instances
+// signing the same entry at the same time only store one checkpoint.
+func (s *AuditChainService) Checkpoint(ctx context.Context) error {
+	head, err := s.auditRepo.LastEvent(ctx)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	}
+
+	last, err := s.checkpointRepo.LastCheckpoint(ctx)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	if last != nil && last.Sequence >= head.Sequence {
+		return nil
+	}
+
+	err = s.checkpointRepo.CreateCheckpoint(ctx, &domain.AuditCheckpoint{
+		ID:        primitive.NewObjectID().Hex(),
+		Sequence:  head.Sequence,
+		Hash:      head.Hash,
+		KeyID:     utils.AuditKeyID(s.signingKey.Public().(ed25519.PublicKey)),
+		Signature: ed25519.Sign(s.signingKey, utils.AuditCheckpointMessage(head.Sequence, head.Hash)),
+		CreatedAt: time.Now(),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Verify walks the audit log chain in sequence order and reports the first broken link, the
+// checkpoints are verified with publicKey.
+func (s *AuditChainService) Verify(ctx context.Context, publicKey ed25519.PublicKey) (*domain.AuditVerification, error) {
+	checkpoints, err := s.checkpointRepo.ListCheckpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	verifier := utils.NewAuditChainVerifier(publicKey, checkpoints)
+	if err := s.auditRepo.WalkChain(ctx, verifier.Next); err != nil {
+		return nil, err
+	}
+
+	return verifier.Result(), nil
+}
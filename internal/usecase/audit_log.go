@@ -0,0 +1,60 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+)
+
+// Default size of a page of the audit log.
+const defaultAuditPageSize = 20
+
+type auditInfoKey struct{}
+
+// WithAuditInfo returns a context that carries the actor, request ID and IP recorded
+// with the audit events of the changes made by the request.
+func WithAuditInfo(ctx context.Context, info domain.AuditInfo) context.Context {
+	return context.WithValue(ctx, auditInfoKey{}, info)
+}
+
+// AuditInfoFrom returns the audit info of the request carried by the context.
+func AuditInfoFrom(ctx context.Context) (domain.AuditInfo, bool) {
+	info, found := ctx.Value(auditInfoKey{}).(domain.AuditInfo)
+
+	return info, found
+}
+
+// withAuditInfo completes the event with the audit info of the request, the actor of the
+// event is kept when it is already set.
+func withAuditInfo(ctx context.Context, event *domain.AuditEvent) *domain.AuditEvent {
+	info, _ := AuditInfoFrom(ctx)
+	if event.ActorID == "" {
+		event.ActorID = info.ActorID
+	}
+	event.RequestID = info.RequestID
+	event.IP = info.IP
+
+	return event
+}
+
+// ListAuditEvents returns a page of the audit log of a user from newest to oldest and
+// the cursor of the next page, which is empty on the last page.
+func (s *UserService) ListAuditEvents(ctx context.Context, id string, query *domain.AuditQuery) ([]domain.AuditEvent, string, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultAuditPageSize
+	}
+
+	events, err := s.auditRepo.ListEvents(ctx, id, query.Cursor, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if int64(len(events)) <= limit {
+		return events, "", nil
+	}
+
+	events = events[:limit]
+
+	return events, events[limit-1].ID, nil
+}
@@ -0,0 +1,80 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func auditEvents(ids ...string) []domain.AuditEvent {
+	events := []domain.AuditEvent{}
+	for _, id := range ids {
+		events = append(events, domain.AuditEvent{ID: id, TargetID: "12345"})
+	}
+
+	return events
+}
+
+func TestListAuditEvents(t *testing.T) {
+	t.Run("should return the cursor of the next page when there are more events", func(t *testing.T) {
+		auditRepo := new(mocks.AuditRepository)
+		userService := usecase.NewUserService(new(mocks.UserRepository), auditRepo, nil)
+
+		auditRepo.On("ListEvents", mock.Anything, "12345", "event9", int64(3)).Return(auditEvents("event8", "event7", "event6"), nil)
+
+		events, cursor, err := userService.ListAuditEvents(context.Background(), "12345", &domain.AuditQuery{Limit: 2, Cursor: "event9"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, auditEvents("event8", "event7"), events)
+		assert.Equal(t, "event7", cursor)
+	})
+
+	t.Run("should return no cursor on the last page", func(t *testing.T) {
+		auditRepo := new(mocks.AuditRepository)
+		userService := usecase.NewUserService(new(mocks.UserRepository), auditRepo, nil)
+
+		auditRepo.On("ListEvents", mock.Anything, "12345", "event7", int64(3)).Return(auditEvents("event6", "event5"), nil)
+
+		events, cursor, err := userService.ListAuditEvents(context.Background(), "12345", &domain.AuditQuery{Limit: 2, Cursor: "event7"})
+
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Empty(t, cursor)
+	})
+
+	t.Run("should use the default page size without limit", func(t *testing.T) {
+		auditRepo := new(mocks.AuditRepository)
+		userService := usecase.NewUserService(new(mocks.UserRepository), auditRepo, nil)
+
+		auditRepo.On("ListEvents", mock.Anything, "12345", "", int64(21)).Return(auditEvents("event1"), nil)
+
+		_, cursor, err := userService.ListAuditEvents(context.Background(), "12345", &domain.AuditQuery{})
+
+		assert.NoError(t, err)
+		assert.Empty(t, cursor)
+		auditRepo.AssertExpectations(t)
+	})
+}
+
+func TestAuditInfo(t *testing.T) {
+	t.Run("should record the actor, request and IP of the request with the events", func(t *testing.T) {
+		userRepo := new(mocks.UserRepository)
+		auditRepo := new(mocks.AuditRepository)
+		userService := usecase.NewUserService(userRepo, auditRepo, nil)
+		ctx := usecase.WithAuditInfo(context.Background(), domain.AuditInfo{ActorID: "support-1", RequestID: "request-1", IP: "203.0.113.7"})
+
+		userRepo.On("DeleteUser", mock.Anything, "12345").Return(nil)
+		auditRepo.On("RecordEvent", mock.Anything, mock.MatchedBy(func(event *domain.AuditEvent) bool {
+			return event.Action == domain.AuditActionUserDeleted && event.ActorID == "support-1" &&
+				event.RequestID == "request-1" && event.IP == "203.0.113.7"
+		})).Return(nil)
+
+		assert.NoError(t, userService.DeleteUser(ctx, "12345"))
+		auditRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidCredentials is returned when login or password does not match.
+var ErrInvalidCredentials = errors.New(constants.ErrInvalidCredentials)
+
+// VerifyPasswordFunc compares a password with his hash and returns a new hash when the stored one is outdated.
+type VerifyPasswordFunc func(password, hash string) (bool, string, error)
+
+// AuthService handles the authentication of users.
+type AuthService struct {
+	userRepo       repository.UserRepository
+	verifyPassword VerifyPasswordFunc
+	lockout        *loginLockout
+	mfa            *MFAService
+	webAuthn       *WebAuthnService
+	passwordless   *passwordless
+	sessions       *SessionService
+}
+
+// NewAuthService obtain new auth service.
+func NewAuthService(userRepo repository.UserRepository, verifyPassword VerifyPasswordFunc) *AuthService {
+	return &AuthService{
+		userRepo:       userRepo,
+		verifyPassword: verifyPassword,
+	}
+}
+
+// WithSessions starts a server side session on each completed login.
+func (s *AuthService) WithSessions(sessions *SessionService) *AuthService {
+	s.sessions = sessions
+	return s
+}
+
+// WithMFA requires a second step for users with multi-factor authentication enabled.
+func (s *AuthService) WithMFA(mfa *MFAService) *AuthService {
+	s.mfa = mfa
+	return s
+}
+
+// WithWebAuthn allows users to login with a passkey instead of a password.
+func (s *AuthService) WithWebAuthn(webAuthn *WebAuthnService) *AuthService {
+	s.webAuthn = webAuthn
+	return s
+}
+
+// Login verifies the credentials of a user sent from client, passwords stored with an
+// outdated algorithm or cost are transparently rehashed. When lockout is enabled failed
+// logins are counted per account and source IP and a LockoutError is returned while locked.
+// Users with multi-factor authentication receive a MFA token to complete the login with VerifyMFA.
+func (s *AuthService) Login(ctx context.Context, credentials *domain.Credentials, client domain.ClientInfo) (*domain.LoginResult, error) {
+	ipKey := ipLockoutKey(client.IP)
+	if err := s.lockout.checkLocked(ctx, ipKey); err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByLogin(ctx, credentials.Login)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			s.recordFailedLogin(ctx, ipKey, "")
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	userKey := userLockoutKey(user.ID)
+	if err := s.lockout.checkLocked(ctx, userKey); err != nil {
+		return nil, err
+	}
+
+	ok, newHash, err := s.verifyPassword(credentials.Password, user.Password)
+	if !ok {
+		s.recordFailedLogin(ctx, ipKey, userKey)
+		return nil, ErrInvalidCredentials
+	}
+
+	if err != nil {
+		log.Printf("%v: %v", constants.ErrRehashPassword, err)
+	} else if newHash != "" {
+		if err := s.userRepo.UpdatePasswordHash(ctx, user.ID, newHash); err != nil {
+			log.Printf("%v: %v", constants.ErrRehashPassword, err)
+		}
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
+
+// VerifyMFA completes a login with the MFA token returned by Login and a TOTP or
+// recovery code, wrong codes are counted as failed logins.
+func (s *AuthService) VerifyMFA(ctx context.Context, request *domain.MFALoginRequest, client domain.ClientInfo) (*domain.LoginResult, error) {
+	if s.mfa == nil {
+		return nil, ErrInvalidMFAToken
+	}
+
+	ipKey := ipLockoutKey(client.IP)
+	if err := s.lockout.checkLocked(ctx, ipKey); err != nil {
+		return nil, err
+	}
+
+	user, err := s.mfa.VerifyChallenge(ctx, request)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrInvalidMFACode):
+			s.recordFailedLogin(ctx, ipKey, userLockoutKey(user.ID))
+		case errors.Is(err, ErrInvalidMFAToken):
+			s.recordFailedLogin(ctx, ipKey, "")
+		}
+		return nil, err
+	}
+
+	userKey := userLockoutKey(user.ID)
+	if err := s.lockout.checkLocked(ctx, userKey); err != nil {
+		return nil, err
+	}
+
+	s.lockout.reset(ctx, userKey)
+
+	return s.startSession(ctx, user, client)
+}
+
+// LoginWebAuthn completes a login with the assertion of a passkey started with
+// WebAuthnService.BeginLogin. Passkeys are phishing resistant and bound to a device,
+// so no MFA step is required, failed assertions are counted as failed logins.
+func (s *AuthService) LoginWebAuthn(ctx context.Context, response *domain.WebAuthnAssertionResponse, client domain.ClientInfo) (*domain.LoginResult, error) {
+	if s.webAuthn == nil {
+		return nil, ErrInvalidWebAuthnResponse
+	}
+
+	ipKey := ipLockoutKey(client.IP)
+	if err := s.lockout.checkLocked(ctx, ipKey); err != nil {
+		return nil, err
+	}
+
+	user, err := s.webAuthn.FinishLogin(ctx, response)
+	if err != nil {
+		switch {
+		case user != nil:
+			s.recordFailedLogin(ctx, ipKey, userLockoutKey(user.ID))
+		case errors.Is(err, ErrInvalidWebAuthnResponse), errors.Is(err, ErrInvalidWebAuthnChallenge):
+			s.recordFailedLogin(ctx, ipKey, "")
+		}
+		return nil, err
+	}
+
+	userKey := userLockoutKey(user.ID)
+	if err := s.lockout.checkLocked(ctx, userKey); err != nil {
+		return nil, err
+	}
+
+	s.lockout.reset(ctx, userKey)
+
+	return s.startSession(ctx, user, client)
+}
+
+// completeLogin returns the user of a verified first login step, users with multi-factor
+// authentication receive a MFA token instead and keep their failed logins until VerifyMFA.
+func (s *AuthService) completeLogin(ctx context.Context, user *domain.User, client domain.ClientInfo) (*domain.LoginResult, error) {
+	if s.mfa != nil && mfaRequired(user) {
+		token, err := s.mfa.CreateChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &domain.LoginResult{MFAToken: token}, nil
+	}
+
+	s.lockout.reset(ctx, userLockoutKey(user.ID))
+
+	return s.startSession(ctx, user, client)
+}
+
+// startSession returns the user of a completed login with a new session when sessions are enabled.
+func (s *AuthService) startSession(ctx context.Context, user *domain.User, client domain.ClientInfo) (*domain.LoginResult, error) {
+	if s.sessions == nil {
+		return &domain.LoginResult{User: user}, nil
+	}
+
+	session, token, err := s.sessions.CreateSession(ctx, user.ID, client)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.LoginResult{User: user, Session: session, SessionToken: token}, nil
+}
+
+func (s *AuthService) recordFailedLogin(ctx context.Context, ipKey, userKey string) {
+	if s.lockout == nil {
+		return
+	}
+
+	s.lockout.recordFailure(ctx, ipKey, s.lockout.policy.IPThreshold)
+	if userKey != "" {
+		s.lockout.recordFailure(ctx, userKey, s.lockout.policy.Threshold)
+	}
+}
@@ -0,0 +1,146 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Errors returned when verifying an email.
+var (
+	ErrEmailAlreadyVerified     = errors.New(constants.ErrEmailAlreadyVerified)
+	ErrInvalidVerificationToken = errors.New(constants.ErrInvalidVerificationToken)
+)
+
+type emailVerification struct {
+	verificationRepo repository.EmailVerificationRepository
+	notifier         adapters.Notifier
+	verificationURL  string
+	ttl              time.Duration
+}
+
+// WithEmailVerification enables email verification, verification links are built from
+// verificationURL adding the token as query parameter and expire after ttl.
+func (s *UserService) WithEmailVerification(verificationRepo repository.EmailVerificationRepository, notifier adapters.Notifier, verificationURL string, ttl time.Duration) *UserService {
+	s.emailVerification = &emailVerification{
+		verificationRepo: verificationRepo,
+		notifier:         notifier,
+		verificationURL:  verificationURL,
+		ttl:              ttl,
+	}
+	return s
+}
+
+// SendEmailVerification sends a verification link to the pending email of the user,
+// or to the current one when it was not verified yet.
+func (s *UserService) SendEmailVerification(ctx context.Context, id string) error {
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	email := user.PendingEmail
+	if email == "" {
+		if user.EmailVerified {
+			return ErrEmailAlreadyVerified
+		}
+		email = user.Email
+	}
+
+	return s.sendEmailVerification(ctx, user.ID, email)
+}
+
+// VerifyEmail consumes the verification token and marks its email as verified,
+// a pending email replaces the current email of the user.
+func (s *UserService) VerifyEmail(ctx context.Context, token string) (*domain.User, error) {
+	verificationToken, err := s.emailVerification.verificationRepo.ConsumeVerificationToken(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.VerifyEmail(ctx, verificationToken.UserID, verificationToken.Email)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidVerificationToken
+		}
+		return nil, err
+	}
+
+	s.recordEvent(ctx, &domain.AuditEvent{
+		Action:   domain.AuditActionEmailVerified,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+	})
+
+	return user, nil
+}
+
+// requestEmailVerification sends a verification link after a signup or email change,
+// failures are logged because the user can request a new link.
+func (s *UserService) requestEmailVerification(ctx context.Context, id string, email string) {
+	if s.emailVerification == nil {
+		return
+	}
+
+	if err := s.sendEmailVerification(ctx, id, email); err != nil {
+		log.Printf("%v: %v", constants.ErrSendEmailVerification, err)
+	}
+}
+
+// notifyEmailChange tells the current email of a user that a change to pendingEmail was
+// requested, failures are logged because the change is already stored.
+func (s *UserService) notifyEmailChange(ctx context.Context, email string, pendingEmail string) {
+	if s.emailVerification == nil {
+		return
+	}
+
+	err := s.emailVerification.notifier.Send(ctx, &domain.Message{
+		To:      email,
+		Subject: constants.EmailChangeSubject,
+		Body:    fmt.Sprintf(constants.EmailChangeBody, pendingEmail),
+	})
+	if err != nil {
+		log.Printf("%v: %v", constants.ErrSendEmailChangeNotice, err)
+	}
+}
+
+func (s *UserService) sendEmailVerification(ctx context.Context, id string, email string) error {
+	token, hash, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.emailVerification.verificationRepo.CreateVerificationToken(ctx, &domain.EmailVerificationToken{
+		ID:        hash,
+		UserID:    id,
+		Email:     email,
+		ExpiresAt: time.Now().Add(s.emailVerification.ttl),
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := tokenLink(s.emailVerification.verificationURL, token)
+	if err != nil {
+		return err
+	}
+
+	return s.emailVerification.notifier.Send(ctx, &domain.Message{
+		To:      email,
+		Subject: constants.EmailVerificationSubject,
+		Body:    fmt.Sprintf(constants.EmailVerificationBody, s.emailVerification.ttl, link),
+		Secrets: []string{token},
+	})
+}
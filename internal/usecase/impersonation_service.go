@@ -0,0 +1,112 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+)
+
+// Errors returned by impersonation.
+var (
+	ErrImpersonationDisabled  = errors.New(constants.ErrImpersonationDisabled)
+	ErrImpersonationForbidden = errors.New(constants.ErrImpersonationForbidden)
+)
+
+type impersonation struct {
+	userRepo  repository.UserRepository
+	auditRepo repository.AuditRepository
+	ttl       time.Duration
+	adminIDs  []string
+}
+
+// WithImpersonation enables the support staff in adminIDs to sign in as other users, the
+// sessions expire ttl after they are issued and their start and end are recorded in the audit log.
+func (s *SessionService) WithImpersonation(userRepo repository.UserRepository, auditRepo repository.AuditRepository, ttl time.Duration, adminIDs []string) *SessionService {
+	s.impersonation = &impersonation{
+		userRepo:  userRepo,
+		auditRepo: auditRepo,
+		ttl:       ttl,
+		adminIDs:  adminIDs,
+	}
+	return s
+}
+
+// Impersonate starts a session of the user acted by the staff member of the actor session and
+// returns the token that authenticates it. An impersonation session can not impersonate again.
+func (s *SessionService) Impersonate(ctx context.Context, actor *domain.Session, userID string, client domain.ClientInfo) (*domain.Session, string, error) {
+	if s.impersonation == nil {
+		return nil, "", ErrImpersonationDisabled
+	}
+
+	if actor.ActorID != "" || actor.UserID == userID || !slices.Contains(s.impersonation.adminIDs, actor.UserID) {
+		return nil, "", ErrImpersonationForbidden
+	}
+
+	if _, err := s.impersonation.userRepo.GetUserByID(ctx, userID); err != nil {
+		return nil, "", err
+	}
+
+	id, _, err := utils.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, hash, err := utils.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	session := &domain.Session{
+		ID:        id,
+		TokenHash: hash,
+		UserID:    userID,
+		ActorID:   actor.UserID,
+		Device:    utils.DeviceName(client.UserAgent),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		ExpiresAt: time.Now().Add(s.impersonation.ttl),
+	}
+
+	if err := s.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, "", err
+	}
+
+	// The start must be audited, the session is revoked when it can not be recorded.
+	err = s.impersonation.auditRepo.RecordEvent(ctx, withAuditInfo(ctx, &domain.AuditEvent{
+		Action:   domain.AuditActionImpersonationStarted,
+		ActorID:  actor.UserID,
+		TargetID: userID,
+	}))
+	if err != nil {
+		if revokeErr := s.sessionRepo.DeleteSession(ctx, userID, id); revokeErr != nil {
+			log.Printf("%v: %v", constants.ErrFailedToRevokeSession, revokeErr)
+		}
+		return nil, "", err
+	}
+
+	return session, token, nil
+}
+
+// endImpersonation records the end of an impersonation session, failures are logged because
+// the session is already revoked.
+func (s *SessionService) endImpersonation(ctx context.Context, session *domain.Session) {
+	if s.impersonation == nil || session.ActorID == "" {
+		return
+	}
+
+	err := s.impersonation.auditRepo.RecordEvent(ctx, withAuditInfo(ctx, &domain.AuditEvent{
+		Action:   domain.AuditActionImpersonationEnded,
+		ActorID:  session.ActorID,
+		TargetID: session.UserID,
+	}))
+	if err != nil {
+		log.Printf("%v: %v", constants.ErrRecordAuditEvent, err)
+	}
+}
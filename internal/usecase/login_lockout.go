@@ -0,0 +1,143 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrLoginLocked is matched by LockoutError with errors.Is.
+var ErrLoginLocked = errors.New(constants.ErrLoginLocked)
+
+// LockoutError is returned when an account or source IP is locked after too many failed logins.
+type LockoutError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockoutError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", constants.ErrLoginLocked, e.RetryAfter)
+}
+
+// Is reports ErrLoginLocked as the same error.
+func (e *LockoutError) Is(target error) bool {
+	return target == ErrLoginLocked
+}
+
+// LockoutPolicy configures when failed logins lock an account or source IP. Each
+// consecutive lock doubles BaseDuration up to MaxDuration, failures and lockouts
+// are forgotten Window after the last failure or lock.
+type LockoutPolicy struct {
+	Threshold    int
+	IPThreshold  int
+	BaseDuration time.Duration
+	MaxDuration  time.Duration
+	Window       time.Duration
+}
+
+type loginLockout struct {
+	attemptRepo repository.LoginAttemptRepository
+	policy      LockoutPolicy
+}
+
+// WithLockout enables locking accounts and source IPs after repeated failed logins.
+func (s *AuthService) WithLockout(attemptRepo repository.LoginAttemptRepository, policy LockoutPolicy) *AuthService {
+	s.lockout = &loginLockout{
+		attemptRepo: attemptRepo,
+		policy:      policy,
+	}
+	return s
+}
+
+// UnlockUser removes the lock and failed logins of a user.
+func (s *AuthService) UnlockUser(ctx context.Context, id string) error {
+	if _, err := s.userRepo.GetUserByID(ctx, id); err != nil {
+		return err
+	}
+
+	if s.lockout == nil {
+		return nil
+	}
+
+	return s.lockout.attemptRepo.ResetLoginAttempts(ctx, userLockoutKey(id))
+}
+
+// checkLocked returns a LockoutError when the source is locked.
+func (l *loginLockout) checkLocked(ctx context.Context, key string) error {
+	if l == nil {
+		return nil
+	}
+
+	attempt, err := l.attemptRepo.GetLoginAttempt(ctx, key)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	}
+
+	if retryAfter := time.Until(attempt.LockedUntil); retryAfter > 0 {
+		return &LockoutError{RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// recordFailure counts a failed login of the source and locks it when the threshold is
+// reached, errors are logged so the login still answers with invalid credentials.
+func (l *loginLockout) recordFailure(ctx context.Context, key string, threshold int) {
+	if l == nil || threshold <= 0 {
+		return
+	}
+
+	now := time.Now()
+
+	attempt, err := l.attemptRepo.RecordFailedLogin(ctx, key, now.Add(l.policy.Window))
+	if err != nil {
+		log.Printf("%v: %v", constants.ErrRecordFailedLogin, err)
+		return
+	}
+
+	if attempt.Failures < threshold {
+		return
+	}
+
+	lockedUntil := now.Add(l.policy.lockDuration(attempt.Lockouts))
+	if err := l.attemptRepo.LockLogin(ctx, key, lockedUntil, lockedUntil.Add(l.policy.Window)); err != nil {
+		log.Printf("%v: %v", constants.ErrRecordFailedLogin, err)
+	}
+}
+
+// reset clears the failed logins of the source after a successful login.
+func (l *loginLockout) reset(ctx context.Context, key string) {
+	if l == nil {
+		return
+	}
+
+	if err := l.attemptRepo.ResetLoginAttempts(ctx, key); err != nil {
+		log.Printf("%v: %v", constants.ErrRecordFailedLogin, err)
+	}
+}
+
+// lockDuration doubles BaseDuration for each previous lock up to MaxDuration.
+func (p LockoutPolicy) lockDuration(lockouts int) time.Duration {
+	duration := p.BaseDuration
+	for i := 0; i < lockouts && duration < p.MaxDuration; i++ {
+		duration *= 2
+	}
+
+	return min(duration, p.MaxDuration)
+}
+
+func userLockoutKey(id string) string {
+	return "user:" + id
+}
+
+func ipLockoutKey(ip string) string {
+	return "ip:" + ip
+}
@@ -0,0 +1,135 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var lockoutPolicy = usecase.LockoutPolicy{
+	Threshold:    3,
+	IPThreshold:  10,
+	BaseDuration: time.Minute,
+	MaxDuration:  time.Hour,
+	Window:       15 * time.Minute,
+}
+
+var lockoutClient = domain.ClientInfo{IP: "203.0.113.7"}
+
+// lockoutLogin returns an auth service for the user 12345 that only accepts the password "right".
+func lockoutLogin() (*mocks.LoginAttemptRepository, *usecase.AuthService) {
+	userRepo := new(mocks.UserRepository)
+	attemptRepo := new(mocks.LoginAttemptRepository)
+
+	userRepo.On("GetUserByLogin", mock.Anything, "cristian").Return(&domain.User{ID: "12345", Password: "right"}, nil)
+
+	authService := usecase.NewAuthService(userRepo, func(password, hash string) (bool, string, error) {
+		return password == hash, "", nil
+	}).WithLockout(attemptRepo, lockoutPolicy)
+
+	return attemptRepo, authService
+}
+
+// lockedFor matches a lock that ends duration after now.
+func lockedFor(duration time.Duration) interface{} {
+	return mock.MatchedBy(func(lockedUntil time.Time) bool {
+		return time.Until(lockedUntil) > duration-time.Second && time.Until(lockedUntil) <= duration
+	})
+}
+
+func TestLoginLockout(t *testing.T) {
+	credentials := &domain.Credentials{Login: "cristian", Password: "wrong"}
+
+	t.Run("should count the failure without locking below the threshold", func(t *testing.T) {
+		attemptRepo, authService := lockoutLogin()
+
+		attemptRepo.On("GetLoginAttempt", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+		attemptRepo.On("RecordFailedLogin", mock.Anything, "ip:203.0.113.7", mock.Anything).Return(&domain.LoginAttempt{Failures: 1}, nil)
+		attemptRepo.On("RecordFailedLogin", mock.Anything, "user:12345", mock.Anything).Return(&domain.LoginAttempt{Failures: 2}, nil)
+
+		_, err := authService.Login(context.Background(), credentials, lockoutClient)
+
+		assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+		attemptRepo.AssertNotCalled(t, "LockLogin", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should lock the user for the base duration when the threshold is reached", func(t *testing.T) {
+		attemptRepo, authService := lockoutLogin()
+
+		attemptRepo.On("GetLoginAttempt", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+		attemptRepo.On("RecordFailedLogin", mock.Anything, "ip:203.0.113.7", mock.Anything).Return(&domain.LoginAttempt{Failures: 3}, nil)
+		attemptRepo.On("RecordFailedLogin", mock.Anything, "user:12345", mock.Anything).Return(&domain.LoginAttempt{Failures: 3}, nil)
+		attemptRepo.On("LockLogin", mock.Anything, "user:12345", lockedFor(time.Minute), mock.Anything).Return(nil)
+
+		_, err := authService.Login(context.Background(), credentials, lockoutClient)
+
+		assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+		attemptRepo.AssertExpectations(t)
+		attemptRepo.AssertNotCalled(t, "LockLogin", mock.Anything, "ip:203.0.113.7", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should lock the source IP at its own threshold", func(t *testing.T) {
+		attemptRepo, authService := lockoutLogin()
+
+		attemptRepo.On("GetLoginAttempt", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+		attemptRepo.On("RecordFailedLogin", mock.Anything, "ip:203.0.113.7", mock.Anything).Return(&domain.LoginAttempt{Failures: 10}, nil)
+		attemptRepo.On("RecordFailedLogin", mock.Anything, "user:12345", mock.Anything).Return(&domain.LoginAttempt{Failures: 1}, nil)
+		attemptRepo.On("LockLogin", mock.Anything, "ip:203.0.113.7", lockedFor(time.Minute), mock.Anything).Return(nil)
+
+		_, err := authService.Login(context.Background(), credentials, lockoutClient)
+
+		assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+		attemptRepo.AssertExpectations(t)
+	})
+
+	t.Run("should double the lock for each previous lock up to the maximum", func(t *testing.T) {
+		for lockouts, duration := range map[int]time.Duration{1: 2 * time.Minute, 3: 8 * time.Minute, 10: time.Hour} {
+			attemptRepo, authService := lockoutLogin()
+
+			attemptRepo.On("GetLoginAttempt", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+			attemptRepo.On("RecordFailedLogin", mock.Anything, "ip:203.0.113.7", mock.Anything).Return(&domain.LoginAttempt{Failures: 1}, nil)
+			attemptRepo.On("RecordFailedLogin", mock.Anything, "user:12345", mock.Anything).Return(&domain.LoginAttempt{Failures: 3, Lockouts: lockouts}, nil)
+			attemptRepo.On("LockLogin", mock.Anything, "user:12345", lockedFor(duration), mock.Anything).Return(nil)
+
+			_, err := authService.Login(context.Background(), credentials, lockoutClient)
+
+			assert.ErrorIs(t, err, usecase.ErrInvalidCredentials)
+			attemptRepo.AssertExpectations(t)
+		}
+	})
+
+	t.Run("should reject even the right password while the user is locked", func(t *testing.T) {
+		attemptRepo, authService := lockoutLogin()
+
+		attemptRepo.On("GetLoginAttempt", mock.Anything, "ip:203.0.113.7").Return(nil, mongo.ErrNoDocuments)
+		attemptRepo.On("GetLoginAttempt", mock.Anything, "user:12345").Return(&domain.LoginAttempt{Failures: 3, LockedUntil: time.Now().Add(time.Minute)}, nil)
+
+		_, err := authService.Login(context.Background(), &domain.Credentials{Login: "cristian", Password: "right"}, lockoutClient)
+
+		var lockoutErr *usecase.LockoutError
+		assert.ErrorAs(t, err, &lockoutErr)
+		assert.InDelta(t, time.Minute, lockoutErr.RetryAfter, float64(time.Second))
+		attemptRepo.AssertNotCalled(t, "RecordFailedLogin", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should forget the failures of the user after a successful login", func(t *testing.T) {
+		attemptRepo, authService := lockoutLogin()
+
+		attemptRepo.On("GetLoginAttempt", mock.Anything, "ip:203.0.113.7").Return(nil, mongo.ErrNoDocuments)
+		attemptRepo.On("GetLoginAttempt", mock.Anything, "user:12345").Return(&domain.LoginAttempt{Failures: 2, LockedUntil: time.Now().Add(-time.Minute)}, nil)
+		attemptRepo.On("ResetLoginAttempts", mock.Anything, "user:12345").Return(nil)
+
+		result, err := authService.Login(context.Background(), &domain.Credentials{Login: "cristian", Password: "right"}, lockoutClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "12345", result.User.ID)
+		attemptRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,279 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// recoveryCodeCount is the number of recovery codes generated when TOTP is enabled.
+	recoveryCodeCount = 10
+	// mfaMaxCodeAttempts is the number of codes that can be tried with a MFA challenge.
+	mfaMaxCodeAttempts = 5
+)
+
+// Errors returned by multi-factor authentication.
+var (
+	ErrMFANotConfigured  = errors.New(constants.ErrMFANotConfigured)
+	ErrMFAAlreadyEnabled = errors.New(constants.ErrMFAAlreadyEnabled)
+	ErrMFANotEnrolled    = errors.New(constants.ErrMFANotEnrolled)
+	ErrInvalidMFACode    = errors.New(constants.ErrInvalidMFACode)
+	ErrInvalidMFAToken   = errors.New(constants.ErrInvalidMFAToken)
+	ErrMFAReauthRequired = errors.New(constants.ErrMFAReauthRequired)
+)
+
+// MFAService handles TOTP enrollment and the second step of logins.
+type MFAService struct {
+	userRepo      repository.UserRepository
+	mfaRepo       repository.MFARepository
+	challengeRepo repository.MFAChallengeRepository
+	secretBox     *utils.SecretBox
+	issuer        string
+	challengeTTL  time.Duration
+	checkPassword CheckPasswordFunc
+	reauthWindow  time.Duration
+}
+
+// NewMFAService obtain new MFA service, without secretBox the enrollment is disabled
+// because TOTP secrets can not be stored unencrypted.
+func NewMFAService(userRepo repository.UserRepository, mfaRepo repository.MFARepository, challengeRepo repository.MFAChallengeRepository, secretBox *utils.SecretBox, issuer string, challengeTTL time.Duration) *MFAService {
+	return &MFAService{
+		userRepo:      userRepo,
+		mfaRepo:       mfaRepo,
+		challengeRepo: challengeRepo,
+		secretBox:     secretBox,
+		issuer:        issuer,
+		challengeTTL:  challengeTTL,
+	}
+}
+
+// WithReauthentication allows replacing a pending enrollment with the current password checked by
+// checkPassword or within window of the login of the session.
+func (s *MFAService) WithReauthentication(checkPassword CheckPasswordFunc, window time.Duration) *MFAService {
+	s.checkPassword = checkPassword
+	s.reauthWindow = window
+	return s
+}
+
+// EnrollTOTP generates a new TOTP secret for the user, it is stored encrypted and
+// pending until ConfirmTOTP receives a valid code. A pending enrollment is only replaced
+// with the current password or when the user logged in at loggedInAt within the
+// reauthentication window.
+func (s *MFAService) EnrollTOTP(ctx context.Context, id string, request *domain.EnrollMFARequest, loggedInAt time.Time) (*domain.TOTPEnrollment, error) {
+	if s.secretBox == nil {
+		return nil, ErrMFANotConfigured
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if mfaRequired(user) {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	if user.MFA != nil && user.MFA.PendingSecret != "" && !s.reauthenticated(user, request.CurrentPassword, loggedInAt) {
+		return nil, ErrMFAReauthRequired
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.secretBox.Encrypt(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaRepo.SetPendingTOTPSecret(ctx, id, encrypted); err != nil {
+		return nil, err
+	}
+
+	return &domain.TOTPEnrollment{
+		Secret:     secret,
+		OTPAuthURI: utils.TOTPURI(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// TOTPQRCode returns the otpauth URI of the pending enrollment as a QR code PNG image.
+func (s *MFAService) TOTPQRCode(ctx context.Context, id string) ([]byte, error) {
+	user, secret, err := s.pendingSecret(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.QRCodePNG(utils.TOTPURI(s.issuer, user.Email, secret))
+}
+
+// ConfirmTOTP enables TOTP when code matches the pending secret and returns the
+// recovery codes, which are only stored hashed and can not be shown again.
+func (s *MFAService) ConfirmTOTP(ctx context.Context, id string, code string) ([]string, error) {
+	user, secret, err := s.pendingSecret(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mfaRepo.EnableTOTP(ctx, id, user.MFA.PendingSecret, hashes, step)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrMFANotEnrolled
+		}
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// reauthenticated reports whether password is the current password of the user or the user
+// logged in at loggedInAt within the reauthentication window.
+func (s *MFAService) reauthenticated(user *domain.User, password string, loggedInAt time.Time) bool {
+	if s.reauthWindow > 0 && time.Since(loggedInAt) < s.reauthWindow {
+		return true
+	}
+
+	return s.checkPassword != nil && password != "" && s.checkPassword(password, user.Password)
+}
+
+// mfaRequired reports whether the user must complete a MFA challenge to login.
+func mfaRequired(user *domain.User) bool {
+	return user.MFA != nil && user.MFA.Enabled
+}
+
+// CreateChallenge issues the token used to complete the login of the user with a MFA code.
+func (s *MFAService) CreateChallenge(ctx context.Context, userID string) (string, error) {
+	token, hash, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.challengeRepo.CreateChallenge(ctx, &domain.MFAChallenge{
+		ID:        hash,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(s.challengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// VerifyChallenge checks the TOTP or recovery code and consumes the challenge once a
+// code is valid. Each code tried counts as an attempt and the challenge is invalid after
+// a few, the user is also returned with ErrInvalidMFACode so the failure can be counted.
+func (s *MFAService) VerifyChallenge(ctx context.Context, request *domain.MFALoginRequest) (*domain.User, error) {
+	tokenHash := utils.HashToken(request.MFAToken)
+
+	challenge, err := s.challengeRepo.AttemptChallenge(ctx, tokenHash, mfaMaxCodeAttempts)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	if !mfaRequired(user) {
+		return nil, ErrInvalidMFAToken
+	}
+
+	if err := s.verifyCode(ctx, user, request.Code); err != nil {
+		return user, err
+	}
+
+	if _, err := s.challengeRepo.ConsumeChallenge(ctx, tokenHash); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidMFAToken
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// verifyCode accepts a TOTP code not used before or an unused recovery code.
+func (s *MFAService) verifyCode(ctx context.Context, user *domain.User, code string) error {
+	var err error
+
+	if len(code) == utils.TOTPDigits {
+		err = s.useTOTPCode(ctx, user, code)
+	} else {
+		err = s.mfaRepo.UseRecoveryCode(ctx, user.ID, utils.HashRecoveryCode(code))
+	}
+
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrInvalidMFACode
+	}
+
+	return err
+}
+
+func (s *MFAService) useTOTPCode(ctx context.Context, user *domain.User, code string) error {
+	if s.secretBox == nil {
+		return ErrMFANotConfigured
+	}
+
+	secret, err := s.secretBox.Decrypt(user.MFA.Secret)
+	if err != nil {
+		return err
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return ErrInvalidMFACode
+	}
+
+	return s.mfaRepo.UseTOTPStep(ctx, user.ID, step)
+}
+
+func (s *MFAService) pendingSecret(ctx context.Context, id string) (*domain.User, string, error) {
+	if s.secretBox == nil {
+		return nil, "", ErrMFANotConfigured
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if mfaRequired(user) {
+		return nil, "", ErrMFAAlreadyEnabled
+	}
+
+	if user.MFA == nil || user.MFA.PendingSecret == "" {
+		return nil, "", ErrMFANotEnrolled
+	}
+
+	secret, err := s.secretBox.Decrypt(user.MFA.PendingSecret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return user, secret, nil
+}
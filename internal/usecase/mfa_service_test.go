@@ -0,0 +1,97 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const totpSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+func mfaLogin(t *testing.T) (*mocks.UserRepository, *mocks.MFARepository, *mocks.MFAChallengeRepository, *usecase.MFAService) {
+	userRepo := new(mocks.UserRepository)
+	mfaRepo := new(mocks.MFARepository)
+	challengeRepo := new(mocks.MFAChallengeRepository)
+
+	secretBox, err := utils.NewSecretBox("v1", map[string][]byte{"v1": []byte("mfa-secret")})
+	assert.NoError(t, err)
+
+	secret, err := secretBox.Encrypt(totpSecret)
+	assert.NoError(t, err)
+
+	userRepo.On("GetUserByID", mock.Anything, "12345").
+		Return(&domain.User{ID: "12345", MFA: &domain.MFASettings{Enabled: true, Secret: secret}}, nil)
+	mfaRepo.On("UseTOTPStep", mock.Anything, "12345", mock.Anything).Return(nil)
+
+	return userRepo, mfaRepo, challengeRepo, usecase.NewMFAService(userRepo, mfaRepo, challengeRepo, secretBox, "cnm", time.Minute)
+}
+
+func TestMFAVerifyChallenge(t *testing.T) {
+	tokenHash := utils.HashToken("mfa-token")
+
+	t.Run("should keep the challenge for another attempt when the code is wrong", func(t *testing.T) {
+		_, _, challengeRepo, mfaService := mfaLogin(t)
+
+		challengeRepo.On("AttemptChallenge", mock.Anything, tokenHash, 5).Return(&domain.MFAChallenge{UserID: "12345", Attempts: 1}, nil)
+
+		user, err := mfaService.VerifyChallenge(context.Background(), &domain.MFALoginRequest{MFAToken: "mfa-token", Code: "000000"})
+
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFACode)
+		assert.Equal(t, "12345", user.ID)
+		challengeRepo.AssertNotCalled(t, "ConsumeChallenge", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should consume the challenge once the code is valid", func(t *testing.T) {
+		_, _, challengeRepo, mfaService := mfaLogin(t)
+
+		code, err := utils.TOTPCode(totpSecret, utils.TOTPStep(time.Now()))
+		assert.NoError(t, err)
+
+		challengeRepo.On("AttemptChallenge", mock.Anything, tokenHash, 5).Return(&domain.MFAChallenge{UserID: "12345", Attempts: 3}, nil)
+		challengeRepo.On("ConsumeChallenge", mock.Anything, tokenHash).Return(&domain.MFAChallenge{UserID: "12345", Attempts: 3}, nil)
+
+		user, err := mfaService.VerifyChallenge(context.Background(), &domain.MFALoginRequest{MFAToken: "mfa-token", Code: code})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "12345", user.ID)
+		challengeRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject any code once the challenge is out of attempts", func(t *testing.T) {
+		userRepo, _, challengeRepo, mfaService := mfaLogin(t)
+
+		code, err := utils.TOTPCode(totpSecret, utils.TOTPStep(time.Now()))
+		assert.NoError(t, err)
+
+		challengeRepo.On("AttemptChallenge", mock.Anything, tokenHash, 5).Return(nil, mongo.ErrNoDocuments)
+
+		user, err := mfaService.VerifyChallenge(context.Background(), &domain.MFALoginRequest{MFAToken: "mfa-token", Code: code})
+
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFAToken)
+		assert.Nil(t, user)
+		userRepo.AssertNotCalled(t, "GetUserByID", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject a valid code when other login consumed the challenge", func(t *testing.T) {
+		_, _, challengeRepo, mfaService := mfaLogin(t)
+
+		code, err := utils.TOTPCode(totpSecret, utils.TOTPStep(time.Now()))
+		assert.NoError(t, err)
+
+		challengeRepo.On("AttemptChallenge", mock.Anything, tokenHash, 5).Return(&domain.MFAChallenge{UserID: "12345", Attempts: 2}, nil)
+		challengeRepo.On("ConsumeChallenge", mock.Anything, tokenHash).Return(nil, mongo.ErrNoDocuments)
+
+		user, err := mfaService.VerifyChallenge(context.Background(), &domain.MFALoginRequest{MFAToken: "mfa-token", Code: code})
+
+		assert.ErrorIs(t, err, usecase.ErrInvalidMFAToken)
+		assert.Nil(t, user)
+	})
+}
@@ -0,0 +1,159 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ValidateAccessToken returns the access token when it is not expired nor in the revocation list.
+func (s *OAuthService) ValidateAccessToken(ctx context.Context, accessToken string) (*domain.OAuthAccessToken, error) {
+	hash := utils.HashToken(accessToken)
+
+	token, err := s.tokenRepo.GetToken(ctx, hash)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+
+	if err := s.checkRevoked(ctx, hash); err != nil {
+		return nil, err
+	}
+
+	return token, nil
+}
+
+// Introspect returns the state of an access token to a resource server authenticated as a
+// confidential client, tokens that are invalid, expired or revoked are not active. ID tokens
+// are never active because they do not grant access to resources.
+func (s *OAuthService) Introspect(ctx context.Context, request *domain.OAuthTokenActionRequest) (*domain.OAuthIntrospection, error) {
+	client, err := s.authenticateClient(ctx, request.ClientID, request.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	if client.Public {
+		return nil, &OAuthError{Code: OAuthErrInvalidClient, Description: "public clients can not introspect tokens"}
+	}
+
+	introspection, err := s.introspect(ctx, request.Token)
+	if errors.Is(err, ErrInvalidAccessToken) {
+		return &domain.OAuthIntrospection{Active: false}, nil
+	}
+
+	return introspection, err
+}
+
+// Revoke adds an access token or ID token issued to the client to the revocation list. As
+// required by RFC 7009 invalid tokens and tokens of other clients are ignored without error.
+func (s *OAuthService) Revoke(ctx context.Context, request *domain.OAuthTokenActionRequest) error {
+	client, err := s.authenticateClient(ctx, request.ClientID, request.ClientSecret)
+	if err != nil {
+		return err
+	}
+
+	hash := utils.HashToken(request.Token)
+
+	if isJWT(request.Token) {
+		claims, err := s.verifyIDToken(request.Token)
+		if err != nil || claims.Audience != client.ID {
+			return nil
+		}
+
+		return s.revokedRepo.RevokeToken(ctx, hash, time.Unix(claims.ExpiresAt, 0))
+	}
+
+	token, err := s.tokenRepo.GetToken(ctx, hash)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	}
+
+	if token.ClientID != client.ID {
+		return nil
+	}
+
+	return s.revokedRepo.RevokeToken(ctx, hash, token.ExpiresAt)
+}
+
+// RevokeUserTokens deletes all the access tokens issued for the user.
+func (s *OAuthService) RevokeUserTokens(ctx context.Context, userID string) error {
+	_, err := s.tokenRepo.DeleteUserTokens(ctx, userID)
+
+	return err
+}
+
+// introspect returns the claims of an active access token, ErrInvalidAccessToken when it is
+// not active or is an ID token.
+func (s *OAuthService) introspect(ctx context.Context, token string) (*domain.OAuthIntrospection, error) {
+	if isJWT(token) {
+		return nil, ErrInvalidAccessToken
+	}
+
+	accessToken, err := s.ValidateAccessToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	introspection := &domain.OAuthIntrospection{
+		Active:    true,
+		Scope:     strings.Join(accessToken.Scopes, " "),
+		ClientID:  accessToken.ClientID,
+		TokenType: oauthTokenType,
+		ExpiresAt: accessToken.ExpiresAt.Unix(),
+		IssuedAt:  accessToken.CreatedAt.Unix(),
+		Subject:   accessToken.UserID,
+	}
+	if s.openID != nil {
+		introspection.Issuer = s.openID.config.Issuer
+	}
+
+	return introspection, nil
+}
+
+// verifyIDToken returns the claims of an ID token signed by a published key of this issuer
+// that is not expired, ErrInvalidAccessToken otherwise.
+func (s *OAuthService) verifyIDToken(token string) (*domain.IDTokenClaims, error) {
+	if s.openID == nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	var claims domain.IDTokenClaims
+	if err := utils.VerifyJWT(token, s.openID.signingKeys.PublicKey, &claims); err != nil {
+		return nil, ErrInvalidAccessToken
+	}
+
+	if claims.Issuer != s.openID.config.Issuer || time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidAccessToken
+	}
+
+	return &claims, nil
+}
+
+// checkRevoked returns ErrInvalidAccessToken when the hash of the token is in the revocation list.
+func (s *OAuthService) checkRevoked(ctx context.Context, tokenHash string) error {
+	revoked, err := s.revokedRepo.IsRevoked(ctx, tokenHash)
+	if err != nil {
+		return err
+	}
+
+	if revoked {
+		return ErrInvalidAccessToken
+	}
+
+	return nil
+}
+
+// isJWT reports whether the token has the three parts of a JWT, opaque tokens have no dots.
+func isJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
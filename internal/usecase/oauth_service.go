@@ -0,0 +1,453 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Error codes of the OAuth 2.0 authorization server defined by RFC 6749.
+const (
+	OAuthErrInvalidRequest          = "invalid_request"
+	OAuthErrInvalidClient           = "invalid_client"
+	OAuthErrInvalidGrant            = "invalid_grant"
+	OAuthErrUnauthorizedClient      = "unauthorized_client"
+	OAuthErrUnsupportedGrantType    = "unsupported_grant_type"
+	OAuthErrUnsupportedResponseType = "unsupported_response_type"
+	OAuthErrInvalidScope            = "invalid_scope"
+	OAuthErrAccessDenied            = "access_denied"
+)
+
+// oauthTokenType is the type of the access tokens, they are sent as bearer tokens.
+const oauthTokenType = "Bearer"
+
+// Errors returned by the authorization server, the authorization request can not redirect
+// back to the client when its client ID or redirect URI are not valid.
+var (
+	ErrOAuth               = errors.New(constants.ErrOAuthRequest)
+	ErrOAuthClientNotFound = errors.New(constants.ErrOAuthClientNotFound)
+	ErrInvalidRedirectURI  = errors.New(constants.ErrInvalidRedirectURI)
+	ErrInvalidOAuthClient  = errors.New(constants.ErrInvalidOAuthClient)
+)
+
+// OAuthError is an error reported to OAuth clients with its RFC 6749 code.
+type OAuthError struct {
+	Code        string
+	Description string
+}
+
+func (e *OAuthError) Error() string {
+	return fmt.Sprintf("%v: %v", e.Code, e.Description)
+}
+
+// Is reports ErrOAuth as the same error.
+func (e *OAuthError) Is(target error) bool {
+	return target == ErrOAuth
+}
+
+// OAuthAuthorization is the outcome of an authorization request, either the redirect back to
+// the client with the code or the error, or the consent the user must give first.
+type OAuthAuthorization struct {
+	RedirectURI string
+	Consent     *domain.OAuthConsentPrompt
+}
+
+// OAuthService handles the authorization code grant with PKCE and the client credentials grant.
+type OAuthService struct {
+	userRepo       repository.UserRepository
+	clientRepo     repository.OAuthClientRepository
+	consentRepo    repository.OAuthConsentRepository
+	codeRepo       repository.OAuthCodeRepository
+	tokenRepo      repository.OAuthTokenRepository
+	revokedRepo    repository.RevokedTokenRepository
+	codeTTL        time.Duration
+	accessTokenTTL time.Duration
+	openID         *openID
+}
+
+// NewOAuthService obtain new OAuth service, authorization codes expire codeTTL after they are
+// issued and access tokens accessTokenTTL after the exchange.
+func NewOAuthService(userRepo repository.UserRepository, clientRepo repository.OAuthClientRepository, consentRepo repository.OAuthConsentRepository, codeRepo repository.OAuthCodeRepository, tokenRepo repository.OAuthTokenRepository, revokedRepo repository.RevokedTokenRepository, codeTTL time.Duration, accessTokenTTL time.Duration) *OAuthService {
+	return &OAuthService{
+		userRepo:       userRepo,
+		clientRepo:     clientRepo,
+		consentRepo:    consentRepo,
+		codeRepo:       codeRepo,
+		tokenRepo:      tokenRepo,
+		revokedRepo:    revokedRepo,
+		codeTTL:        codeTTL,
+		accessTokenTTL: accessTokenTTL,
+	}
+}
+
+// RegisterClient stores a new client and returns the secret of confidential clients, only
+// its hash is stored so it can not be shown again.
+func (s *OAuthService) RegisterClient(ctx context.Context, request *domain.OAuthClientRequest) (*domain.OAuthClient, string, error) {
+	grantTypes := slices.Compact(slices.Sorted(slices.Values(request.GrantTypes)))
+
+	usesCode := slices.Contains(grantTypes, domain.OAuthGrantAuthorizationCode)
+	if usesCode && len(request.RedirectURIs) == 0 {
+		return nil, "", fmt.Errorf("%w: %v requires redirect URIs", ErrInvalidOAuthClient, domain.OAuthGrantAuthorizationCode)
+	}
+
+	if request.Public && slices.Contains(grantTypes, domain.OAuthGrantClientCredentials) {
+		return nil, "", fmt.Errorf("%w: public clients can not use %v", ErrInvalidOAuthClient, domain.OAuthGrantClientCredentials)
+	}
+
+	for _, redirectURI := range request.RedirectURIs {
+		if !utils.ValidRedirectURI(redirectURI) {
+			return nil, "", fmt.Errorf("%w: %v", ErrInvalidRedirectURI, redirectURI)
+		}
+	}
+
+	id, _, err := utils.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	client := &domain.OAuthClient{
+		ID:           id,
+		Name:         request.Name,
+		Public:       request.Public,
+		RedirectURIs: request.RedirectURIs,
+		GrantTypes:   grantTypes,
+		Scopes:       utils.ParseScope(strings.Join(request.Scopes, " ")),
+	}
+
+	var secret string
+	if !client.Public {
+		secret, client.SecretHash, err = utils.GenerateToken()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := s.clientRepo.CreateClient(ctx, client); err != nil {
+		return nil, "", err
+	}
+
+	return client, secret, nil
+}
+
+// Authorize handles an authorization request of the user, the code is issued right away when
+// the user already consented to the requested scopes, otherwise the consent prompt is returned.
+func (s *OAuthService) Authorize(ctx context.Context, userID string, request *domain.OAuthAuthorizeRequest) (*OAuthAuthorization, error) {
+	client, redirectURI, err := s.authorizationClient(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := validateAuthorizeRequest(client, request)
+	if err != nil {
+		return errorRedirect(redirectURI, request.State, err)
+	}
+
+	consent, err := s.consentRepo.GetConsent(ctx, userID, client.ID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, err
+	}
+
+	if consent != nil && containsAll(consent.Scopes, scopes) {
+		return s.issueCode(ctx, userID, client, redirectURI, scopes, request)
+	}
+
+	return &OAuthAuthorization{
+		Consent: &domain.OAuthConsentPrompt{
+			ClientID:   client.ID,
+			ClientName: client.Name,
+			Scopes:     scopes,
+		},
+	}, nil
+}
+
+// Consent handles the decision of the user on an authorization request, an approval is
+// remembered so the user is not asked again for the same scopes.
+func (s *OAuthService) Consent(ctx context.Context, userID string, request *domain.OAuthConsentRequest) (*OAuthAuthorization, error) {
+	client, redirectURI, err := s.authorizationClient(ctx, &request.OAuthAuthorizeRequest)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes, err := validateAuthorizeRequest(client, &request.OAuthAuthorizeRequest)
+	if err != nil {
+		return errorRedirect(redirectURI, request.State, err)
+	}
+
+	if !request.Approve {
+		return errorRedirect(redirectURI, request.State, &OAuthError{Code: OAuthErrAccessDenied, Description: "the user denied the request"})
+	}
+
+	if err := s.consentRepo.GrantConsent(ctx, userID, client.ID, scopes); err != nil {
+		return nil, err
+	}
+
+	return s.issueCode(ctx, userID, client, redirectURI, scopes, &request.OAuthAuthorizeRequest)
+}
+
+// Token handles a request of the token endpoint, errors reported to the client are OAuthError.
+func (s *OAuthService) Token(ctx context.Context, request *domain.OAuthTokenRequest) (*domain.OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(ctx, request.ClientID, request.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	switch request.GrantType {
+	case domain.OAuthGrantAuthorizationCode, domain.OAuthGrantClientCredentials:
+	default:
+		return nil, &OAuthError{Code: OAuthErrUnsupportedGrantType, Description: "grant_type is not supported"}
+	}
+
+	if !slices.Contains(client.GrantTypes, request.GrantType) {
+		return nil, &OAuthError{Code: OAuthErrUnauthorizedClient, Description: "the client is not allowed to use this grant_type"}
+	}
+
+	if request.GrantType == domain.OAuthGrantClientCredentials {
+		scopes, err := requestedScopes(client, request.Scope)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.issueAccessToken(ctx, client.ID, "", scopes)
+	}
+
+	return s.exchangeCode(ctx, client, request)
+}
+
+// exchangeCode redeems an authorization code, the code is consumed before it is verified so
+// a wrong code verifier can not be retried.
+func (s *OAuthService) exchangeCode(ctx context.Context, client *domain.OAuthClient, request *domain.OAuthTokenRequest) (*domain.OAuthTokenResponse, error) {
+	if request.Code == "" || request.CodeVerifier == "" {
+		return nil, &OAuthError{Code: OAuthErrInvalidRequest, Description: "code and code_verifier are required"}
+	}
+
+	code, err := s.codeRepo.ConsumeCode(ctx, utils.HashToken(request.Code))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &OAuthError{Code: OAuthErrInvalidGrant, Description: "code is invalid or expired"}
+		}
+		return nil, err
+	}
+
+	if code.ClientID != client.ID || code.RedirectURI != request.RedirectURI {
+		return nil, &OAuthError{Code: OAuthErrInvalidGrant, Description: "code was issued to another client or redirect_uri"}
+	}
+
+	if !utils.VerifyPKCE(request.CodeVerifier, code.CodeChallenge) {
+		return nil, &OAuthError{Code: OAuthErrInvalidGrant, Description: "code_verifier does not match the code_challenge"}
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, code.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, &OAuthError{Code: OAuthErrInvalidGrant, Description: "the user of the code no longer exists"}
+		}
+		return nil, err
+	}
+
+	response, err := s.issueAccessToken(ctx, client.ID, code.UserID, code.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	response.IDToken, err = s.idToken(user, code, response.AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// authenticateClient identifies the client of a token request, public clients only send
+// their client ID and confidential clients must send the secret that was issued to them.
+func (s *OAuthService) authenticateClient(ctx context.Context, clientID string, secret string) (*domain.OAuthClient, error) {
+	invalidClient := &OAuthError{Code: OAuthErrInvalidClient, Description: "client authentication failed"}
+
+	if clientID == "" {
+		return nil, invalidClient
+	}
+
+	client, err := s.clientRepo.GetClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, invalidClient
+		}
+		return nil, err
+	}
+
+	if client.Public {
+		if secret != "" {
+			return nil, invalidClient
+		}
+		return client, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, invalidClient
+	}
+
+	return client, nil
+}
+
+// authorizationClient returns the client of an authorization request and the redirect URI to
+// answer it, which must be registered for the client. It can be omitted when only one is registered.
+func (s *OAuthService) authorizationClient(ctx context.Context, request *domain.OAuthAuthorizeRequest) (*domain.OAuthClient, string, error) {
+	client, err := s.clientRepo.GetClient(ctx, request.ClientID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, "", ErrOAuthClientNotFound
+		}
+		return nil, "", err
+	}
+
+	if request.RedirectURI == "" {
+		if len(client.RedirectURIs) != 1 {
+			return nil, "", ErrInvalidRedirectURI
+		}
+		return client, client.RedirectURIs[0], nil
+	}
+
+	if !slices.Contains(client.RedirectURIs, request.RedirectURI) {
+		return nil, "", ErrInvalidRedirectURI
+	}
+
+	return client, request.RedirectURI, nil
+}
+
+// issueCode stores a new authorization code and returns the redirect that delivers it to the client.
+func (s *OAuthService) issueCode(ctx context.Context, userID string, client *domain.OAuthClient, redirectURI string, scopes []string, request *domain.OAuthAuthorizeRequest) (*OAuthAuthorization, error) {
+	code, hash, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.codeRepo.CreateCode(ctx, &domain.OAuthAuthorizationCode{
+		ID:            hash,
+		ClientID:      client.ID,
+		UserID:        userID,
+		RedirectURI:   request.RedirectURI,
+		Scopes:        scopes,
+		CodeChallenge: request.CodeChallenge,
+		Nonce:         request.Nonce,
+		ExpiresAt:     time.Now().Add(s.codeTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthAuthorization{
+		RedirectURI: withQuery(redirectURI, map[string]string{"code": code, "state": request.State}),
+	}, nil
+}
+
+// issueAccessToken stores a new access token and returns the response of the token endpoint.
+func (s *OAuthService) issueAccessToken(ctx context.Context, clientID string, userID string, scopes []string) (*domain.OAuthTokenResponse, error) {
+	token, hash, err := utils.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.tokenRepo.CreateToken(ctx, &domain.OAuthAccessToken{
+		ID:        hash,
+		ClientID:  clientID,
+		UserID:    userID,
+		Scopes:    scopes,
+		ExpiresAt: time.Now().Add(s.accessTokenTTL),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   oauthTokenType,
+		ExpiresIn:   int64(s.accessTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// validateAuthorizeRequest checks the parameters of an authorization request that are reported
+// to the client by redirect and returns the requested scopes. PKCE with S256 is required for all clients.
+func validateAuthorizeRequest(client *domain.OAuthClient, request *domain.OAuthAuthorizeRequest) ([]string, error) {
+	if request.ResponseType != "code" {
+		return nil, &OAuthError{Code: OAuthErrUnsupportedResponseType, Description: "response_type must be code"}
+	}
+
+	if !slices.Contains(client.GrantTypes, domain.OAuthGrantAuthorizationCode) {
+		return nil, &OAuthError{Code: OAuthErrUnauthorizedClient, Description: "the client is not allowed to use the authorization code grant"}
+	}
+
+	if request.CodeChallengeMethod != utils.PKCEMethodS256 || !utils.ValidPKCEChallenge(request.CodeChallenge) {
+		return nil, &OAuthError{Code: OAuthErrInvalidRequest, Description: "code_challenge with code_challenge_method S256 is required"}
+	}
+
+	return requestedScopes(client, request.Scope)
+}
+
+// requestedScopes returns the scopes of a request, all the scopes of the client when it is empty.
+func requestedScopes(client *domain.OAuthClient, scope string) ([]string, error) {
+	scopes := utils.ParseScope(scope)
+	if len(scopes) == 0 {
+		return client.Scopes, nil
+	}
+
+	if !containsAll(client.Scopes, scopes) {
+		return nil, &OAuthError{Code: OAuthErrInvalidScope, Description: "the client is not allowed to request this scope"}
+	}
+
+	return scopes, nil
+}
+
+// errorRedirect returns the redirect that reports err to the client, other errors than OAuthError are returned.
+func errorRedirect(redirectURI string, state string, err error) (*OAuthAuthorization, error) {
+	var oauthErr *OAuthError
+	if !errors.As(err, &oauthErr) {
+		return nil, err
+	}
+
+	return &OAuthAuthorization{
+		RedirectURI: withQuery(redirectURI, map[string]string{
+			"error":             oauthErr.Code,
+			"error_description": oauthErr.Description,
+			"state":             state,
+		}),
+	}, nil
+}
+
+// withQuery adds the non empty params to the query of a registered redirect URI, keeping its own query.
+func withQuery(redirectURI string, params map[string]string) string {
+	parsed, err := url.Parse(redirectURI)
+	if err != nil {
+		return redirectURI
+	}
+
+	query := parsed.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String()
+}
+
+func containsAll(values []string, wanted []string) bool {
+	for _, value := range wanted {
+		if !slices.Contains(values, value) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Scopes of OpenID Connect, openid requests an ID token and the others select its claims.
+const (
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// Errors returned by the userinfo endpoint.
+var (
+	ErrInvalidAccessToken = errors.New(constants.ErrInvalidAccessToken)
+	ErrInsufficientScope  = errors.New(constants.ErrInsufficientScope)
+)
+
+// OpenIDConfig configures OpenID Connect, Issuer is the base URL of the endpoints and
+// AuthorizationURL the consent page that forwards authorization requests.
+type OpenIDConfig struct {
+	Issuer           string
+	AuthorizationURL string
+	IDTokenTTL       time.Duration
+}
+
+type openID struct {
+	signingKeys *SigningKeyService
+	config      OpenIDConfig
+}
+
+// WithOpenID enables ID tokens on the authorization code grant when the openid scope is granted,
+// the discovery document and the userinfo endpoint.
+func (s *OAuthService) WithOpenID(signingKeys *SigningKeyService, config OpenIDConfig) *OAuthService {
+	s.openID = &openID{
+		signingKeys: signingKeys,
+		config:      config,
+	}
+	return s
+}
+
+// Discovery returns the OpenID Provider metadata.
+func (s *OAuthService) Discovery() (*domain.OIDCDiscovery, error) {
+	if s.openID == nil {
+		return nil, ErrOpenIDNotConfigured
+	}
+
+	issuer := s.openID.config.Issuer
+
+	return &domain.OIDCDiscovery{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             s.openID.config.AuthorizationURL,
+		TokenEndpoint:                     issuer + "/oauth/token",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		RevocationEndpoint:                issuer + "/oauth/revoke",
+		UserInfoEndpoint:                  issuer + "/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{domain.OAuthGrantAuthorizationCode, domain.OAuthGrantClientCredentials},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{utils.JWTAlgRS256},
+		ScopesSupported:                   []string{ScopeOpenID, ScopeProfile, ScopeEmail},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "nonce", "name", "preferred_username", "updated_at", "email", "email_verified"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{utils.PKCEMethodS256},
+	}, nil
+}
+
+// JWKS returns the public keys that verify the ID tokens.
+func (s *OAuthService) JWKS() (*domain.JWKS, error) {
+	if s.openID == nil {
+		return nil, ErrOpenIDNotConfigured
+	}
+
+	return s.openID.signingKeys.JWKS(), nil
+}
+
+// UserInfo returns the claims of the user of a validated access token granted the openid scope.
+func (s *OAuthService) UserInfo(ctx context.Context, token *domain.OAuthAccessToken) (*domain.UserInfo, error) {
+	if s.openID == nil {
+		return nil, ErrOpenIDNotConfigured
+	}
+
+	if token.UserID == "" || !slices.Contains(token.Scopes, ScopeOpenID) {
+		return nil, ErrInsufficientScope
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidAccessToken
+		}
+		return nil, err
+	}
+
+	return userInfo(user, token.Scopes), nil
+}
+
+// idToken returns the ID token of a redeemed authorization code, it is empty when OpenID
+// Connect is not enabled or the openid scope was not granted.
+func (s *OAuthService) idToken(user *domain.User, code *domain.OAuthAuthorizationCode, accessToken string) (string, error) {
+	if s.openID == nil || !slices.Contains(code.Scopes, ScopeOpenID) {
+		return "", nil
+	}
+
+	now := time.Now()
+
+	return s.openID.signingKeys.Sign(domain.IDTokenClaims{
+		UserInfo:        *userInfo(user, code.Scopes),
+		Issuer:          s.openID.config.Issuer,
+		Audience:        code.ClientID,
+		ExpiresAt:       now.Add(s.openID.config.IDTokenTTL).Unix(),
+		IssuedAt:        now.Unix(),
+		Nonce:           code.Nonce,
+		AccessTokenHash: utils.AccessTokenHash(accessToken),
+	})
+}
+
+// userInfo maps the user to the standard claims of the granted scopes.
+func userInfo(user *domain.User, scopes []string) *domain.UserInfo {
+	info := &domain.UserInfo{Subject: user.ID}
+
+	if slices.Contains(scopes, ScopeProfile) {
+		info.Name = user.Name
+		info.PreferredUsername = user.UserName
+		if !user.UpdatedAt.IsZero() {
+			info.UpdatedAt = user.UpdatedAt.Unix()
+		}
+	}
+
+	if slices.Contains(scopes, ScopeEmail) {
+		emailVerified := user.EmailVerified
+		info.Email = user.Email
+		info.EmailVerified = &emailVerified
+	}
+
+	return info
+}
@@ -0,0 +1,100 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Delays before an event that could not be published is claimed again, doubled on each attempt.
+const (
+	minOutboxRetryDelay = time.Second
+	maxOutboxRetryDelay = 5 * time.Minute
+)
+
+// OutboxRelay publishes the events of the outbox as CloudEvents through the event publisher. An
+// event is claimed for a lease, so relays of other instances skip it, and it is only marked as
+// published once the publisher accepts it. When the relay stops before marking it the event is
+// published again after the lease, so delivery is at least once.
+type OutboxRelay struct {
+	outboxRepo repository.OutboxRepository
+	publisher  adapters.EventPublisher
+	source     string
+	lease      time.Duration
+	retention  time.Duration
+}
+
+// NewOutboxRelay obtain new outbox relay, source identifies the service in the published
+// CloudEvents and published events are kept for retention.
+func NewOutboxRelay(outboxRepo repository.OutboxRepository, publisher adapters.EventPublisher, source string, lease time.Duration, retention time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		outboxRepo: outboxRepo,
+		publisher:  publisher,
+		source:     source,
+		lease:      lease,
+		retention:  retention,
+	}
+}
+
+// Run relays the pending events every interval until ctx is done, errors are logged and retried on the next tick.
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := r.Relay(ctx); err != nil {
+				log.Printf("%v: %v", constants.ErrRelayOutbox, err)
+			}
+		}
+	}
+}
+
+// Relay publishes the pending events in order until there are none left and returns how many
+// were published. An event the publisher rejects is released to be retried later with backoff
+// and ends the pass, the events after it wait so they are never published before it.
+func (r *OutboxRelay) Relay(ctx context.Context) (int, error) {
+	published := 0
+
+	for ctx.Err() == nil {
+		event, err := r.outboxRepo.ClaimEvent(ctx, r.lease)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return published, nil
+			}
+			return published, err
+		}
+
+		if err := r.publisher.Publish(ctx, utils.NewCloudEvent(event, r.source)); err != nil {
+			log.Printf("%v %v: %v", constants.ErrPublishEvent, event.ID, err)
+			return published, r.outboxRepo.ReleaseEvent(ctx, event.ID, time.Now().Add(outboxRetryDelay(event.Attempts)))
+		}
+
+		if err := r.outboxRepo.MarkPublished(ctx, event.ID, r.retention); err != nil {
+			return published, err
+		}
+		published++
+	}
+
+	return published, ctx.Err()
+}
+
+// outboxRetryDelay returns the delay before the next attempt to publish an event.
+func outboxRetryDelay(attempts int) time.Duration {
+	delay := minOutboxRetryDelay
+	for i := 1; i < attempts && delay < maxOutboxRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxOutboxRetryDelay)
+}
@@ -0,0 +1,127 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const outboxLease = 30 * time.Second
+
+// recordingPublisher keeps the IDs of the published events and rejects the ones in failures.
+type recordingPublisher struct {
+	published []string
+	failures  map[string]error
+}
+
+func (p *recordingPublisher) Publish(_ context.Context, event *domain.CloudEvent) error {
+	if err := p.failures[event.ID]; err != nil {
+		return err
+	}
+
+	p.published = append(p.published, event.ID)
+
+	return nil
+}
+
+func outboxEvent(id string, aggregateID string, eventType string, attempts int) *domain.OutboxEvent {
+	return &domain.OutboxEvent{
+		ID:          id,
+		Type:        eventType,
+		AggregateID: aggregateID,
+		Attempts:    attempts,
+	}
+}
+
+func TestOutboxRelay(t *testing.T) {
+	t.Run("should publish the pending events in order until there are none left", func(t *testing.T) {
+		outboxRepo := new(mocks.OutboxRepository)
+		publisher := &recordingPublisher{}
+		relay := usecase.NewOutboxRelay(outboxRepo, publisher, "/users", outboxLease, time.Hour)
+
+		outboxRepo.On("ClaimEvent", mock.Anything, outboxLease).Return(outboxEvent("event1", "12345", domain.EventUserCreated, 1), nil).Once()
+		outboxRepo.On("ClaimEvent", mock.Anything, outboxLease).Return(outboxEvent("event2", "12345", domain.EventUserUpdated, 1), nil).Once()
+		outboxRepo.On("ClaimEvent", mock.Anything, outboxLease).Return(nil, mongo.ErrNoDocuments).Once()
+		outboxRepo.On("MarkPublished", mock.Anything, mock.Anything, time.Hour).Return(nil)
+
+		published, err := relay.Relay(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, published)
+		assert.Equal(t, []string{"event1", "event2"}, publisher.published)
+		outboxRepo.AssertExpectations(t)
+	})
+
+	t.Run("should release a rejected event and stop so later events are not published before it", func(t *testing.T) {
+		outboxRepo := new(mocks.OutboxRepository)
+		publisher := &recordingPublisher{failures: map[string]error{"event1": errors.New("broker unavailable")}}
+		relay := usecase.NewOutboxRelay(outboxRepo, publisher, "/users", outboxLease, time.Hour)
+
+		outboxRepo.On("ClaimEvent", mock.Anything, outboxLease).Return(outboxEvent("event1", "12345", domain.EventUserCreated, 3), nil).Once()
+		outboxRepo.On("ReleaseEvent", mock.Anything, "event1", mock.MatchedBy(func(retryAt time.Time) bool {
+			delay := time.Until(retryAt)
+			return delay > 3*time.Second && delay <= 4*time.Second
+		})).Return(nil)
+
+		published, err := relay.Relay(context.Background())
+
+		assert.NoError(t, err)
+		assert.Zero(t, published)
+		assert.Empty(t, publisher.published)
+		outboxRepo.AssertNumberOfCalls(t, "ClaimEvent", 1)
+		outboxRepo.AssertNotCalled(t, "MarkPublished", mock.Anything, mock.Anything, mock.Anything)
+		outboxRepo.AssertExpectations(t)
+	})
+
+	t.Run("should cap the retry delay of an event rejected many times", func(t *testing.T) {
+		outboxRepo := new(mocks.OutboxRepository)
+		publisher := &recordingPublisher{failures: map[string]error{"event1": errors.New("broker unavailable")}}
+		relay := usecase.NewOutboxRelay(outboxRepo, publisher, "/users", outboxLease, time.Hour)
+
+		outboxRepo.On("ClaimEvent", mock.Anything, outboxLease).Return(outboxEvent("event1", "12345", domain.EventUserCreated, 50), nil).Once()
+		outboxRepo.On("ReleaseEvent", mock.Anything, "event1", mock.MatchedBy(func(retryAt time.Time) bool {
+			delay := time.Until(retryAt)
+			return delay > 4*time.Minute && delay <= 5*time.Minute
+		})).Return(nil)
+
+		_, err := relay.Relay(context.Background())
+
+		assert.NoError(t, err)
+		outboxRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return an error when claiming fails", func(t *testing.T) {
+		outboxRepo := new(mocks.OutboxRepository)
+		relay := usecase.NewOutboxRelay(outboxRepo, &recordingPublisher{}, "/users", outboxLease, time.Hour)
+
+		outboxRepo.On("ClaimEvent", mock.Anything, outboxLease).Return(nil, errors.New("outbox error"))
+
+		published, err := relay.Relay(context.Background())
+
+		assert.EqualError(t, err, "outbox error")
+		assert.Zero(t, published)
+	})
+
+	t.Run("should return an error when marking as published fails", func(t *testing.T) {
+		outboxRepo := new(mocks.OutboxRepository)
+		publisher := &recordingPublisher{}
+		relay := usecase.NewOutboxRelay(outboxRepo, publisher, "/users", outboxLease, time.Hour)
+
+		outboxRepo.On("ClaimEvent", mock.Anything, outboxLease).Return(outboxEvent("event1", "12345", domain.EventUserCreated, 1), nil).Once()
+		outboxRepo.On("MarkPublished", mock.Anything, "event1", time.Hour).Return(errors.New("outbox error"))
+
+		published, err := relay.Relay(context.Background())
+
+		assert.EqualError(t, err, "outbox error")
+		assert.Zero(t, published)
+		assert.Equal(t, []string{"event1"}, publisher.published)
+	})
+}
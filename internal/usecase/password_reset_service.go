@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrInvalidResetToken is returned when a reset token does not exist, expired or was already used.
+var ErrInvalidResetToken = errors.New(constants.ErrInvalidResetToken)
+
+// PasswordResetPolicy configures the forgot password flow. Reset links expire after TTL,
+// each email address can request AddressLimit links and each source IP IPLimit links per
+// Window, zero disables a limit.
+type PasswordResetPolicy struct {
+	TTL          time.Duration
+	AddressLimit int
+	IPLimit      int
+	Window       time.Duration
+}
+
+type passwordReset struct {
+	resetRepo     repository.PasswordResetRepository
+	rateLimitRepo repository.RateLimitRepository
+	notifier      adapters.Notifier
+	resetURL      string
+	policy        PasswordResetPolicy
+}
+
+// WithPasswordReset enables the forgot password flow, reset links are built from resetURL
+// adding the token as query parameter. The notifier should deliver in the background, like
+// adapters.AsyncNotifier, so the time of a response does not reveal which emails exist.
+func (s *UserService) WithPasswordReset(resetRepo repository.PasswordResetRepository, rateLimitRepo repository.RateLimitRepository, notifier adapters.Notifier, resetURL string, policy PasswordResetPolicy) *UserService {
+	s.passwordReset = &passwordReset{
+		resetRepo:     resetRepo,
+		rateLimitRepo: rateLimitRepo,
+		notifier:      notifier,
+		resetURL:      resetURL,
+		policy:        policy,
+	}
+	return s
+}
+
+// ForgotPassword sends a reset link to the user with the email, the email address and
+// sourceIP are rate limited and a RateLimitError is returned when they made too many requests.
+// Unknown emails and delivery failures are only logged so the response does not reveal which
+// emails exist.
+func (s *UserService) ForgotPassword(ctx context.Context, email string, sourceIP string) error {
+	policy := s.passwordReset.policy
+	email = normalizeEmail(email)
+
+	err := checkRateLimit(ctx, s.passwordReset.rateLimitRepo, "password_reset:ip:"+sourceIP, policy.IPLimit, policy.Window)
+	if err != nil {
+		return err
+	}
+
+	err = checkRateLimit(ctx, s.passwordReset.rateLimitRepo, "password_reset:email:"+email, policy.AddressLimit, policy.Window)
+	if err != nil {
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil
+		}
+		return err
+	}
+
+	token, hash, err := utils.GenerateToken()
+	if err != nil {
+		return err
+	}
+
+	err = s.passwordReset.resetRepo.CreateResetToken(ctx, &domain.PasswordResetToken{
+		ID:        hash,
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(policy.TTL),
+	})
+	if err != nil {
+		return err
+	}
+
+	link, err := tokenLink(s.passwordReset.resetURL, token)
+	if err != nil {
+		return err
+	}
+
+	message := &domain.Message{
+		To:      user.Email,
+		Subject: constants.PasswordResetSubject,
+		Body:    fmt.Sprintf(constants.PasswordResetBody, policy.TTL, link),
+		Secrets: []string{token},
+	}
+
+	if err := s.passwordReset.notifier.Send(ctx, message); err != nil {
+		log.Printf("%v: %v", constants.ErrSendNotification, err)
+	}
+
+	return nil
+}
+
+// ResetPassword consumes the reset token and replaces the password of its user,
+// the new password must satisfy the same rules as ChangePassword.
+func (s *UserService) ResetPassword(ctx context.Context, request *domain.ResetPasswordRequest) error {
+	token, err := s.passwordReset.resetRepo.ConsumeResetToken(ctx, utils.HashToken(request.Token))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, token.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	return s.setPassword(ctx, user, request.NewPassword, domain.AuditActionPasswordReset)
+}
+
+// tokenLink adds the token as query parameter of baseURL.
+func tokenLink(baseURL, token string) (string, error) {
+	link, err := url.Parse(baseURL)
+	if err != nil {
+		return "", err
+	}
+
+	query := link.Query()
+	query.Set("token", token)
+	link.RawQuery = query.Encode()
+
+	return link.String(), nil
+}
@@ -0,0 +1,234 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	passwordlessCodeDigits      = 6
+	passwordlessMaxCodeAttempts = 5
+)
+
+// ErrInvalidPasswordlessLogin is returned when a login link or code does not exist,
+// expired, was already used or is used from other device.
+var ErrInvalidPasswordlessLogin = errors.New(constants.ErrInvalidPasswordlessLogin)
+
+// PasswordlessPolicy configures logins by emailed link or code. Links and codes expire
+// after TTL, each email address can request AddressLimit logins and each source IP
+// IPLimit logins per Window, zero disables a limit.
+type PasswordlessPolicy struct {
+	TTL          time.Duration
+	AddressLimit int
+	IPLimit      int
+	Window       time.Duration
+}
+
+type passwordless struct {
+	passwordlessRepo repository.PasswordlessRepository
+	rateLimitRepo    repository.RateLimitRepository
+	notifier         adapters.Notifier
+	loginURL         string
+	policy           PasswordlessPolicy
+}
+
+// WithPasswordless enables logins by emailed link or code, links are built from loginURL
+// adding the token as query parameter. The notifier should deliver in the background, like
+// adapters.AsyncNotifier, so the time of a response does not reveal which emails exist.
+func (s *AuthService) WithPasswordless(passwordlessRepo repository.PasswordlessRepository, rateLimitRepo repository.RateLimitRepository, notifier adapters.Notifier, loginURL string, policy PasswordlessPolicy) *AuthService {
+	s.passwordless = &passwordless{
+		passwordlessRepo: passwordlessRepo,
+		rateLimitRepo:    rateLimitRepo,
+		notifier:         notifier,
+		loginURL:         loginURL,
+		policy:           policy,
+	}
+	return s
+}
+
+// RequestPasswordless sends a login link and code to the user with the email and returns
+// the nonce that binds them to the requesting device, it must be sent back to login.
+// Unknown emails receive a nonce too so the response does not reveal which emails exist.
+func (s *AuthService) RequestPasswordless(ctx context.Context, email string, sourceIP string) (string, error) {
+	if s.passwordless == nil {
+		return "", ErrInvalidPasswordlessLogin
+	}
+
+	policy := s.passwordless.policy
+	email = normalizeEmail(email)
+
+	err := checkRateLimit(ctx, s.passwordless.rateLimitRepo, "passwordless:ip:"+sourceIP, policy.IPLimit, policy.Window)
+	if err != nil {
+		return "", err
+	}
+
+	err = checkRateLimit(ctx, s.passwordless.rateLimitRepo, "passwordless:email:"+email, policy.AddressLimit, policy.Window)
+	if err != nil {
+		return "", err
+	}
+
+	nonce, nonceHash, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	user, err := s.userRepo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nonce, nil
+		}
+		return "", err
+	}
+
+	token, tokenHash, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	code, err := utils.GenerateCode(passwordlessCodeDigits)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.passwordless.passwordlessRepo.CreateChallenge(ctx, &domain.PasswordlessChallenge{
+		ID:        tokenHash,
+		UserID:    user.ID,
+		Email:     user.Email,
+		CodeHash:  utils.HashToken(code),
+		NonceHash: nonceHash,
+		ExpiresAt: time.Now().Add(policy.TTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	link, err := tokenLink(s.passwordless.loginURL, token)
+	if err != nil {
+		return "", err
+	}
+
+	message := &domain.Message{
+		To:      user.Email,
+		Subject: constants.PasswordlessSubject,
+		Body:    fmt.Sprintf(constants.PasswordlessBody, code, policy.TTL, link),
+		Secrets: []string{token, code},
+	}
+
+	if err := s.passwordless.notifier.Send(ctx, message); err != nil {
+		log.Printf("%v: %v", constants.ErrSendNotification, err)
+	}
+
+	return nonce, nil
+}
+
+// LoginPasswordless completes a login with the token of the emailed link or the email and
+// code, nonce must be the one returned by RequestPasswordless on the same device. Links and
+// codes are single use, wrong codes are counted as failed logins and invalidate the code after
+// a few attempts. Users with multi-factor authentication receive a MFA token as in Login.
+func (s *AuthService) LoginPasswordless(ctx context.Context, request *domain.PasswordlessLoginRequest, nonce string, client domain.ClientInfo) (*domain.LoginResult, error) {
+	if s.passwordless == nil || nonce == "" {
+		return nil, ErrInvalidPasswordlessLogin
+	}
+
+	ipKey := ipLockoutKey(client.IP)
+	if err := s.lockout.checkLocked(ctx, ipKey); err != nil {
+		return nil, err
+	}
+
+	var user *domain.User
+	var err error
+
+	if request.Token != "" {
+		user, err = s.consumePasswordlessLink(ctx, request.Token, nonce)
+	} else {
+		user, err = s.consumePasswordlessCode(ctx, request.Email, request.Code, nonce)
+	}
+
+	if err != nil {
+		switch {
+		case user != nil:
+			s.recordFailedLogin(ctx, ipKey, userLockoutKey(user.ID))
+		case errors.Is(err, ErrInvalidPasswordlessLogin):
+			s.recordFailedLogin(ctx, ipKey, "")
+		}
+		return nil, err
+	}
+
+	return s.completeLogin(ctx, user, client)
+}
+
+func (s *AuthService) consumePasswordlessLink(ctx context.Context, token string, nonce string) (*domain.User, error) {
+	challenge, err := s.passwordless.passwordlessRepo.ConsumeLinkChallenge(ctx, utils.HashToken(token), utils.HashToken(nonce))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidPasswordlessLogin
+		}
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, challenge.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidPasswordlessLogin
+		}
+		return nil, err
+	}
+
+	if user.Email != challenge.Email {
+		return nil, ErrInvalidPasswordlessLogin
+	}
+
+	if err := s.lockout.checkLocked(ctx, userLockoutKey(user.ID)); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// consumePasswordlessCode returns the user with ErrInvalidPasswordlessLogin when the
+// code is wrong so the failure can be counted.
+func (s *AuthService) consumePasswordlessCode(ctx context.Context, email string, code string, nonce string) (*domain.User, error) {
+	user, err := s.userRepo.GetUserByEmail(ctx, normalizeEmail(email))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidPasswordlessLogin
+		}
+		return nil, err
+	}
+
+	if err := s.lockout.checkLocked(ctx, userLockoutKey(user.ID)); err != nil {
+		return nil, err
+	}
+
+	nonceHash := utils.HashToken(nonce)
+
+	challenge, err := s.passwordless.passwordlessRepo.ConsumeCodeChallenge(ctx, user.Email, utils.HashToken(code), nonceHash, passwordlessMaxCodeAttempts)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+
+		err = s.passwordless.passwordlessRepo.RecordFailedCode(ctx, user.Email, nonceHash)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Printf("%v: %v", constants.ErrRecordFailedLogin, err)
+		}
+
+		return user, ErrInvalidPasswordlessLogin
+	}
+
+	if challenge.UserID != user.ID {
+		return nil, ErrInvalidPasswordlessLogin
+	}
+
+	return user, nil
+}
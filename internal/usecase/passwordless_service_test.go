@@ -0,0 +1,147 @@
+package usecase_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var passwordlessPolicy = usecase.PasswordlessPolicy{
+	TTL:          10 * time.Minute,
+	AddressLimit: 5,
+	IPLimit:      20,
+	Window:       15 * time.Minute,
+}
+
+type passwordlessRepos struct {
+	users        *mocks.UserRepository
+	passwordless *mocks.PasswordlessRepository
+	rateLimits   *mocks.RateLimitRepository
+	attempts     *mocks.LoginAttemptRepository
+}
+
+// passwordlessLogin returns an auth service with passwordless logins and lockout for the user 12345.
+func passwordlessLogin() (passwordlessRepos, *usecase.AuthService) {
+	repos := passwordlessRepos{
+		users:        new(mocks.UserRepository),
+		passwordless: new(mocks.PasswordlessRepository),
+		rateLimits:   new(mocks.RateLimitRepository),
+		attempts:     new(mocks.LoginAttemptRepository),
+	}
+
+	repos.users.On("GetUserByEmail", mock.Anything, "cristian@gmail.com").Return(&domain.User{ID: "12345", Email: "cristian@gmail.com"}, nil)
+	repos.attempts.On("GetLoginAttempt", mock.Anything, mock.Anything).Return(nil, mongo.ErrNoDocuments)
+	repos.attempts.On("RecordFailedLogin", mock.Anything, mock.Anything, mock.Anything).Return(&domain.LoginAttempt{Failures: 1}, nil)
+	repos.attempts.On("ResetLoginAttempts", mock.Anything, "user:12345").Return(nil)
+
+	authService := usecase.NewAuthService(repos.users, nil).
+		WithLockout(repos.attempts, lockoutPolicy).
+		WithPasswordless(repos.passwordless, repos.rateLimits, adapters.NewLogNotifier(io.Discard), "https://example.com/login", passwordlessPolicy)
+
+	return repos, authService
+}
+
+func TestLoginPasswordlessCode(t *testing.T) {
+	nonceHash := utils.HashToken("nonce")
+	codeHash := utils.HashToken("123456")
+	request := &domain.PasswordlessLoginRequest{Email: "Cristian@Gmail.com", Code: "123456"}
+
+	t.Run("should count a wrong code on the challenge and as a failed login of the user", func(t *testing.T) {
+		repos, authService := passwordlessLogin()
+
+		repos.passwordless.On("ConsumeCodeChallenge", mock.Anything, "cristian@gmail.com", codeHash, nonceHash, 5).Return(nil, mongo.ErrNoDocuments)
+		repos.passwordless.On("RecordFailedCode", mock.Anything, "cristian@gmail.com", nonceHash).Return(nil)
+
+		_, err := authService.LoginPasswordless(context.Background(), request, "nonce", lockoutClient)
+
+		assert.ErrorIs(t, err, usecase.ErrInvalidPasswordlessLogin)
+		repos.passwordless.AssertExpectations(t)
+		repos.attempts.AssertCalled(t, "RecordFailedLogin", mock.Anything, "user:12345", mock.Anything)
+		repos.attempts.AssertCalled(t, "RecordFailedLogin", mock.Anything, "ip:203.0.113.7", mock.Anything)
+	})
+
+	t.Run("should login with a valid code while the challenge has attempts left", func(t *testing.T) {
+		repos, authService := passwordlessLogin()
+
+		repos.passwordless.On("ConsumeCodeChallenge", mock.Anything, "cristian@gmail.com", codeHash, nonceHash, 5).
+			Return(&domain.PasswordlessChallenge{UserID: "12345", Email: "cristian@gmail.com", Attempts: 4}, nil)
+
+		result, err := authService.LoginPasswordless(context.Background(), request, "nonce", lockoutClient)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "12345", result.User.ID)
+		repos.passwordless.AssertNotCalled(t, "RecordFailedCode", mock.Anything, mock.Anything, mock.Anything)
+		repos.attempts.AssertNotCalled(t, "RecordFailedLogin", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject a code of a challenge of other user", func(t *testing.T) {
+		repos, authService := passwordlessLogin()
+
+		repos.passwordless.On("ConsumeCodeChallenge", mock.Anything, "cristian@gmail.com", codeHash, nonceHash, 5).
+			Return(&domain.PasswordlessChallenge{UserID: "67890", Email: "cristian@gmail.com"}, nil)
+
+		_, err := authService.LoginPasswordless(context.Background(), request, "nonce", lockoutClient)
+
+		assert.ErrorIs(t, err, usecase.ErrInvalidPasswordlessLogin)
+	})
+
+	t.Run("should not check the code while the user is locked", func(t *testing.T) {
+		repos := passwordlessRepos{
+			users:        new(mocks.UserRepository),
+			passwordless: new(mocks.PasswordlessRepository),
+			attempts:     new(mocks.LoginAttemptRepository),
+		}
+		authService := usecase.NewAuthService(repos.users, nil).
+			WithLockout(repos.attempts, lockoutPolicy).
+			WithPasswordless(repos.passwordless, new(mocks.RateLimitRepository), adapters.NewLogNotifier(io.Discard), "https://example.com/login", passwordlessPolicy)
+
+		repos.users.On("GetUserByEmail", mock.Anything, "cristian@gmail.com").Return(&domain.User{ID: "12345", Email: "cristian@gmail.com"}, nil)
+		repos.attempts.On("GetLoginAttempt", mock.Anything, "ip:203.0.113.7").Return(nil, mongo.ErrNoDocuments)
+		repos.attempts.On("GetLoginAttempt", mock.Anything, "user:12345").Return(&domain.LoginAttempt{LockedUntil: time.Now().Add(time.Minute)}, nil)
+
+		_, err := authService.LoginPasswordless(context.Background(), request, "nonce", lockoutClient)
+
+		assert.ErrorIs(t, err, usecase.ErrLoginLocked)
+		repos.passwordless.AssertNotCalled(t, "ConsumeCodeChallenge", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRequestPasswordlessLimits(t *testing.T) {
+	t.Run("should limit the requests of an address whatever the case of the email", func(t *testing.T) {
+		repos, authService := passwordlessLogin()
+
+		repos.rateLimits.On("Hit", mock.Anything, "passwordless:ip:203.0.113.7", passwordlessPolicy.Window).
+			Return(&domain.RateLimit{Count: 1, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+		repos.rateLimits.On("Hit", mock.Anything, "passwordless:email:cristian@gmail.com", passwordlessPolicy.Window).
+			Return(&domain.RateLimit{Count: 6, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+
+		_, err := authService.RequestPasswordless(context.Background(), " CRISTIAN@gmail.com", "203.0.113.7")
+
+		var rateLimitErr *usecase.RateLimitError
+		assert.ErrorAs(t, err, &rateLimitErr)
+		assert.InDelta(t, time.Minute, rateLimitErr.RetryAfter, float64(time.Second))
+		repos.users.AssertNotCalled(t, "GetUserByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should limit the requests of a source IP before counting the address", func(t *testing.T) {
+		repos, authService := passwordlessLogin()
+
+		repos.rateLimits.On("Hit", mock.Anything, "passwordless:ip:203.0.113.7", passwordlessPolicy.Window).
+			Return(&domain.RateLimit{Count: 21, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+
+		_, err := authService.RequestPasswordless(context.Background(), "cristian@gmail.com", "203.0.113.7")
+
+		assert.ErrorIs(t, err, usecase.ErrRateLimited)
+		repos.rateLimits.AssertNumberOfCalls(t, "Hit", 1)
+	})
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+)
+
+// ErrRateLimited is matched by RateLimitError with errors.Is.
+var ErrRateLimited = errors.New(constants.ErrRateLimited)
+
+// RateLimitError is returned when a key made too many requests in the current window.
+type RateLimitError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v, retry after %v", constants.ErrRateLimited, e.RetryAfter)
+}
+
+// Is reports ErrRateLimited as the same error.
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// checkRateLimit counts a request of key and returns a RateLimitError when more than
+// limit requests were made in the window, a limit of zero disables the check.
+func checkRateLimit(ctx context.Context, rateLimitRepo repository.RateLimitRepository, key string, limit int, window time.Duration) error {
+	if limit <= 0 {
+		return nil
+	}
+
+	rateLimit, err := rateLimitRepo.Hit(ctx, key, window)
+	if err != nil {
+		return err
+	}
+
+	if rateLimit.Count > limit {
+		return &RateLimitError{RetryAfter: time.Until(rateLimit.ExpiresAt)}
+	}
+
+	return nil
+}
+
+// normalizeEmail returns the email as the lookups of users by email compare it, so the limits
+// of an address can not be skipped changing its case.
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
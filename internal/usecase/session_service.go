@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// Errors returned by sessions.
+var (
+	ErrInvalidSession  = errors.New(constants.ErrInvalidSession)
+	ErrSessionNotFound = errors.New(constants.ErrSessionNotFound)
+)
+
+// SessionService handles the server side sessions created on login.
+type SessionService struct {
+	sessionRepo   repository.SessionRepository
+	ttl           time.Duration
+	adminIDs      []string
+	impersonation *impersonation
+}
+
+// NewSessionService obtain new session service, sessions expire ttl after the login.
+func NewSessionService(sessionRepo repository.SessionRepository, ttl time.Duration) *SessionService {
+	return &SessionService{
+		sessionRepo: sessionRepo,
+		ttl:         ttl,
+	}
+}
+
+// WithAdmins sets the users in adminIDs as administrators, allowed to call the admin endpoints.
+func (s *SessionService) WithAdmins(adminIDs []string) *SessionService {
+	s.adminIDs = adminIDs
+	return s
+}
+
+// IsAdmin reports whether the user is an administrator.
+func (s *SessionService) IsAdmin(userID string) bool {
+	return slices.Contains(s.adminIDs, userID)
+}
+
+// CreateSession starts a session of the user on the client and returns the token that authenticates it.
+func (s *SessionService) CreateSession(ctx context.Context, userID string, client domain.ClientInfo) (*domain.Session, string, error) {
+	id, _, err := utils.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	token, hash, err := utils.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+
+	session := &domain.Session{
+		ID:        id,
+		TokenHash: hash,
+		UserID:    userID,
+		Device:    utils.DeviceName(client.UserAgent),
+		UserAgent: client.UserAgent,
+		IP:        client.IP,
+		ExpiresAt: time.Now().Add(s.ttl),
+	}
+
+	if err := s.sessionRepo.CreateSession(ctx, session); err != nil {
+		return nil, "", err
+	}
+
+	return session, token, nil
+}
+
+// ValidateSession returns the session of the token, revoked and expired sessions return ErrInvalidSession.
+func (s *SessionService) ValidateSession(ctx context.Context, token string) (*domain.Session, error) {
+	if token == "" {
+		return nil, ErrInvalidSession
+	}
+
+	session, err := s.sessionRepo.TouchSession(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidSession
+		}
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// ListSessions returns the active sessions of the user, currentID marks the session making the request.
+func (s *SessionService) ListSessions(ctx context.Context, userID string, currentID string) ([]domain.Session, error) {
+	sessions, err := s.sessionRepo.ListSessions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession signs out a session of the user.
+func (s *SessionService) RevokeSession(ctx context.Context, userID string, id string) error {
+	err := s.sessionRepo.DeleteSession(ctx, userID, id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return ErrSessionNotFound
+	}
+
+	return err
+}
+
+// Logout signs out the session making the request, the end of an impersonation is recorded in the audit log.
+func (s *SessionService) Logout(ctx context.Context, session *domain.Session) error {
+	err := s.RevokeSession(ctx, session.UserID, session.ID)
+	if err != nil && !errors.Is(err, ErrSessionNotFound) {
+		return err
+	}
+
+	s.endImpersonation(ctx, session)
+
+	return nil
+}
+
+// RevokeUserSessions signs out the user everywhere.
+func (s *SessionService) RevokeUserSessions(ctx context.Context, userID string) error {
+	_, err := s.sessionRepo.DeleteUserSessions(ctx, userID)
+
+	return err
+}
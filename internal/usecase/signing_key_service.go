@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+)
+
+// signingKeyBits is the size of the RSA keys signing ID tokens.
+const signingKeyBits = 2048
+
+// ErrOpenIDNotConfigured is returned when there is no key to sign ID tokens.
+var ErrOpenIDNotConfigured = errors.New(constants.ErrOpenIDNotConfigured)
+
+type signingKey struct {
+	id         string
+	privateKey *rsa.PrivateKey
+	createdAt  time.Time
+}
+
+// SigningKeyService handles the keys signing ID tokens. A new key is created every rotation
+// interval and each key is published for two intervals, so the JWKS has the current and the
+// previous key and tokens signed just before a rotation can still be verified.
+type SigningKeyService struct {
+	keyRepo          repository.SigningKeyRepository
+	secretBox        *utils.SecretBox
+	rotationInterval time.Duration
+
+	mu   sync.RWMutex
+	keys []signingKey
+}
+
+// NewSigningKeyService obtain new signing key service, the private keys are encrypted with secretBox.
+func NewSigningKeyService(keyRepo repository.SigningKeyRepository, secretBox *utils.SecretBox, rotationInterval time.Duration) *SigningKeyService {
+	return &SigningKeyService{
+		keyRepo:          keyRepo,
+		secretBox:        secretBox,
+		rotationInterval: rotationInterval,
+	}
+}
+
+// Run refreshes the keys every interval until ctx is done, errors are logged and retried on the next tick.
+func (s *SigningKeyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil {
+				log.Printf("%v: %v", constants.ErrRotateSigningKeys, err)
+			}
+		}
+	}
+}
+
+// Refresh loads the published keys and creates a new key when the newest one is older than
+// the rotation interval. Instances rotating at the same time only add an extra published key.
+func (s *SigningKeyService) Refresh(ctx context.Context) error {
+	stored, err := s.keyRepo.ListKeys(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]signingKey, 0, len(stored)+1)
+	for _, key := range stored {
+		decoded, err := s.decodeKey(key)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, decoded)
+	}
+
+	if len(keys) == 0 || time.Since(keys[0].createdAt) >= s.rotationInterval {
+		key, err := s.createKey(ctx)
+		if err != nil {
+			return err
+		}
+		keys = append([]signingKey{key}, keys...)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Sign returns the claims signed with the current key.
+func (s *SigningKeyService) Sign(claims interface{}) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.keys) == 0 {
+		return "", ErrOpenIDNotConfigured
+	}
+
+	return utils.SignJWT(claims, s.keys[0].id, s.keys[0].privateKey)
+}
+
+// JWKS returns the public keys that verify the ID tokens, the current key first.
+func (s *SigningKeyService) JWKS() *domain.JWKS {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jwks := &domain.JWKS{Keys: make([]domain.JWK, 0, len(s.keys))}
+	for _, key := range s.keys {
+		jwks.Keys = append(jwks.Keys, utils.RSAPublicJWK(key.id, &key.privateKey.PublicKey))
+	}
+
+	return jwks
+}
+
+// PublicKey returns the published public key with the key ID, it verifies the tokens signed with it.
+func (s *SigningKeyService) PublicKey(kid string) (*rsa.PublicKey, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, key := range s.keys {
+		if key.id == kid {
+			return &key.privateKey.PublicKey, true
+		}
+	}
+
+	return nil, false
+}
+
+// createKey generates and stores a new signing key published for two rotation intervals.
+func (s *SigningKeyService) createKey(ctx context.Context) (signingKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, signingKeyBits)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	encrypted, err := s.secretBox.Encrypt(base64.StdEncoding.EncodeToString(der))
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	id, _, err := utils.GenerateToken()
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	now := time.Now()
+
+	err = s.keyRepo.CreateKey(ctx, &domain.SigningKey{
+		ID:         id,
+		Algorithm:  utils.JWTAlgRS256,
+		PrivateKey: encrypted,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(2 * s.rotationInterval),
+	})
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	return signingKey{id: id, privateKey: privateKey, createdAt: now}, nil
+}
+
+// decodeKey decrypts a stored signing key.
+func (s *SigningKeyService) decodeKey(key domain.SigningKey) (signingKey, error) {
+	encoded, err := s.secretBox.Decrypt(key.PrivateKey)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	der, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return signingKey{}, err
+	}
+
+	privateKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return signingKey{}, fmt.Errorf("%v: signing key %v is not RSA", constants.ErrRotateSigningKeys, key.ID)
+	}
+
+	return signingKey{id: key.ID, privateKey: privateKey, createdAt: key.CreatedAt}, nil
+}
@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ErrEventHistoryLost is returned when a stream can not be resumed after the last event of a subscriber.
+var ErrEventHistoryLost = errors.New(constants.ErrEventHistoryLost)
+
+// userEventBufferSize is how many notifications wait for a subscriber of the broadcaster before
+// it is disconnected, it resumes the stream with the ID of the last notification it received.
+const userEventBufferSize = 64
+
+// UserEventStream streams the creations, updates and deletions of users as CloudEvents. A
+// subscriber resumes the stream after the notification with lastEventID, it fails with
+// ErrEventHistoryLost when the notification is no longer available. The channel is closed
+// when ctx is done or the stream ends.
+type UserEventStream interface {
+	Subscribe(ctx context.Context, lastEventID string) (<-chan *domain.CloudEventNotification, error)
+}
+
+// ChangeStreamUserEvents streams the changes of users from the change streams of Mongo. The ID of
+// a notification is the resume token of the change stream, so it resumes in any instance.
+type ChangeStreamUserEvents struct {
+	streamRepo repository.UserEventStreamRepository
+	source     string
+}
+
+// NewChangeStreamUserEvents creates a stream publishing CloudEvents from source.
+func NewChangeStreamUserEvents(streamRepo repository.UserEventStreamRepository, source string) *ChangeStreamUserEvents {
+	return &ChangeStreamUserEvents{
+		streamRepo: streamRepo,
+		source:     source,
+	}
+}
+
+// Subscribe opens a change stream of the users, Mongo rejects resume tokens that are invalid or
+// no longer in the oplog.
+func (s *ChangeStreamUserEvents) Subscribe(ctx context.Context, lastEventID string) (<-chan *domain.CloudEventNotification, error) {
+	changes, err := s.streamRepo.WatchUserEvents(ctx, lastEventID)
+	if err != nil {
+		var serverErr mongo.ServerError
+		if lastEventID != "" && errors.As(err, &serverErr) {
+			return nil, ErrEventHistoryLost
+		}
+		return nil, err
+	}
+
+	notifications := make(chan *domain.CloudEventNotification)
+	go func() {
+		defer close(notifications)
+
+		for change := range changes {
+			notification := &domain.CloudEventNotification{
+				ID:    change.ResumeToken,
+				Event: utils.NewCloudEvent(change.Event, s.source),
+			}
+
+			select {
+			case notifications <- notification:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return notifications, nil
+}
+
+// UserEventBroadcaster streams the changes of users published in process, it keeps the last
+// notifications so subscribers can resume. It is used when Mongo has no change streams, so an
+// instance only streams the changes it makes.
+type UserEventBroadcaster struct {
+	mu          sync.Mutex
+	history     []*domain.CloudEventNotification
+	historySize int
+	subscribers map[chan *domain.CloudEventNotification]struct{}
+}
+
+// NewUserEventBroadcaster creates a broadcaster keeping the last historySize notifications.
+func NewUserEventBroadcaster(historySize int) *UserEventBroadcaster {
+	return &UserEventBroadcaster{
+		historySize: historySize,
+		subscribers: make(map[chan *domain.CloudEventNotification]struct{}),
+	}
+}
+
+// Publish sends the event to the subscribers with its ID as the ID of the notification, a
+// subscriber not keeping up is disconnected.
+func (b *UserEventBroadcaster) Publish(_ context.Context, event *domain.CloudEvent) error {
+	notification := &domain.CloudEventNotification{ID: event.ID, Event: event}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.history = append(b.history, notification)
+	if len(b.history) > b.historySize {
+		b.history = slices.Delete(b.history, 0, len(b.history)-b.historySize)
+	}
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- notification:
+		default:
+			delete(b.subscribers, subscriber)
+			close(subscriber)
+		}
+	}
+
+	return nil
+}
+
+// Subscribe sends the notifications kept after lastEventID and then the published ones.
+func (b *UserEventBroadcaster) Subscribe(ctx context.Context, lastEventID string) (<-chan *domain.CloudEventNotification, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var backlog []*domain.CloudEventNotification
+	if lastEventID != "" {
+		i := slices.IndexFunc(b.history, func(notification *domain.CloudEventNotification) bool {
+			return notification.ID == lastEventID
+		})
+		if i < 0 {
+			return nil, ErrEventHistoryLost
+		}
+		backlog = b.history[i+1:]
+	}
+
+	subscriber := make(chan *domain.CloudEventNotification, userEventBufferSize+len(backlog))
+	for _, notification := range backlog {
+		subscriber <- notification
+	}
+	b.subscribers[subscriber] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		b.unsubscribe(subscriber)
+	}()
+
+	return subscriber, nil
+}
+
+// unsubscribe stops sending notifications to subscriber when it is still subscribed.
+func (b *UserEventBroadcaster) unsubscribe(subscriber chan *domain.CloudEventNotification) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, found := b.subscribers[subscriber]; found {
+		delete(b.subscribers, subscriber)
+		close(subscriber)
+	}
+}
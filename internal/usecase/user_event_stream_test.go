@@ -0,0 +1,97 @@
+package usecase_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/stretchr/testify/assert"
+)
+
+func publishUserEvents(t *testing.T, broadcaster *usecase.UserEventBroadcaster, ids ...string) {
+	for _, id := range ids {
+		assert.NoError(t, broadcaster.Publish(context.Background(), &domain.CloudEvent{ID: id, Type: domain.CloudEventUserUpdated}))
+	}
+}
+
+// receivedIDs returns the IDs of the notifications waiting in the channel and whether it is still open.
+func receivedIDs(notifications <-chan *domain.CloudEventNotification) ([]string, bool) {
+	ids := []string{}
+	for {
+		select {
+		case notification, open := <-notifications:
+			if !open {
+				return ids, false
+			}
+			ids = append(ids, notification.ID)
+		default:
+			return ids, true
+		}
+	}
+}
+
+func TestUserEventBroadcaster(t *testing.T) {
+	t.Run("should send the published events to the subscribers", func(t *testing.T) {
+		broadcaster := usecase.NewUserEventBroadcaster(10)
+
+		notifications, err := broadcaster.Subscribe(context.Background(), "")
+		assert.NoError(t, err)
+
+		publishUserEvents(t, broadcaster, "event1", "event2")
+
+		ids, open := receivedIDs(notifications)
+		assert.Equal(t, []string{"event1", "event2"}, ids)
+		assert.True(t, open)
+	})
+
+	t.Run("should resume after the last event received by the subscriber", func(t *testing.T) {
+		broadcaster := usecase.NewUserEventBroadcaster(10)
+		publishUserEvents(t, broadcaster, "event1", "event2", "event3")
+
+		notifications, err := broadcaster.Subscribe(context.Background(), "event1")
+		assert.NoError(t, err)
+
+		publishUserEvents(t, broadcaster, "event4")
+
+		ids, _ := receivedIDs(notifications)
+		assert.Equal(t, []string{"event2", "event3", "event4"}, ids)
+	})
+
+	t.Run("should not resume after an event no longer kept", func(t *testing.T) {
+		broadcaster := usecase.NewUserEventBroadcaster(2)
+		publishUserEvents(t, broadcaster, "event1", "event2", "event3")
+
+		_, err := broadcaster.Subscribe(context.Background(), "event1")
+
+		assert.ErrorIs(t, err, usecase.ErrEventHistoryLost)
+	})
+
+	t.Run("should disconnect a subscriber that does not keep up", func(t *testing.T) {
+		broadcaster := usecase.NewUserEventBroadcaster(100)
+
+		notifications, err := broadcaster.Subscribe(context.Background(), "")
+		assert.NoError(t, err)
+
+		for i := 0; i < 65; i++ {
+			publishUserEvents(t, broadcaster, "event")
+		}
+
+		ids, open := receivedIDs(notifications)
+		assert.Len(t, ids, 64)
+		assert.False(t, open)
+	})
+
+	t.Run("should close the stream when the subscriber is done", func(t *testing.T) {
+		broadcaster := usecase.NewUserEventBroadcaster(10)
+		ctx, cancel := context.WithCancel(context.Background())
+
+		notifications, err := broadcaster.Subscribe(ctx, "")
+		assert.NoError(t, err)
+
+		cancel()
+
+		_, open := <-notifications
+		assert.False(t, open)
+	})
+}
@@ -2,31 +2,172 @@ package usecase
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
 	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
 	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
 )
 
+// Errors returned when changing the password.
+var (
+	ErrPasswordReused         = errors.New(constants.ErrPasswordReused)
+	ErrInvalidCurrentPassword = errors.New(constants.ErrInvalidCurrentPassword)
+)
+
+// CheckPasswordFunc compares a password with his hash.
+type CheckPasswordFunc func(password, hash string) bool
+
+// SessionRevoker revokes the sessions and refresh tokens of a user.
+type SessionRevoker interface {
+	RevokeUserSessions(ctx context.Context, userID string) error
+}
+
+// APIKeyRevoker revokes the API keys of a user.
+type APIKeyRevoker interface {
+	RevokeUserAPIKeys(ctx context.Context, userID string) error
+}
+
+// OAuthTokenRevoker revokes the OAuth access tokens issued for a user.
+type OAuthTokenRevoker interface {
+	RevokeUserTokens(ctx context.Context, userID string) error
+}
+
 // UserService handles to obtain user repository.
 type UserService struct {
-	userRepo repository.UserRepository
+	userRepo          repository.UserRepository
+	auditRepo         repository.AuditRepository
+	checkPassword     CheckPasswordFunc
+	sessionRevoker    SessionRevoker
+	apiKeyRevoker     APIKeyRevoker
+	oauthTokenRevoker OAuthTokenRevoker
+	lockout           *loginLockout
+	eventPublisher    adapters.EventPublisher
+	eventSource       string
+	passwordReset     *passwordReset
+	emailVerification *emailVerification
 }
 
 // NewUserService obtain new user service.
-func NewUserService(userRepo repository.UserRepository) *UserService {
+func NewUserService(userRepo repository.UserRepository, auditRepo repository.AuditRepository, checkPassword CheckPasswordFunc) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:      userRepo,
+		auditRepo:     auditRepo,
+		checkPassword: checkPassword,
+	}
+}
+
+// WithSessionRevoker sets the revoker used to sign out a user after a password change or deletion.
+func (s *UserService) WithSessionRevoker(sessionRevoker SessionRevoker) *UserService {
+	s.sessionRevoker = sessionRevoker
+	return s
+}
+
+// WithAPIKeyRevoker sets the revoker used to delete the API keys of a deleted user.
+func (s *UserService) WithAPIKeyRevoker(apiKeyRevoker APIKeyRevoker) *UserService {
+	s.apiKeyRevoker = apiKeyRevoker
+	return s
+}
+
+// WithOAuthTokenRevoker sets the revoker used to revoke the OAuth access tokens of a user after a
+// password change or deletion.
+func (s *UserService) WithOAuthTokenRevoker(oauthTokenRevoker OAuthTokenRevoker) *UserService {
+	s.oauthTokenRevoker = oauthTokenRevoker
+	return s
+}
+
+// WithLockout counts wrong current passwords as failed logins of the user, sharing the
+// attempts and policy of the login lockout.
+func (s *UserService) WithLockout(attemptRepo repository.LoginAttemptRepository, policy LockoutPolicy) *UserService {
+	s.lockout = &loginLockout{
+		attemptRepo: attemptRepo,
+		policy:      policy,
 	}
+	return s
+}
+
+// WithEventPublisher sets the publisher notified in process of the creations, updates and deletions
+// of users as CloudEvents from source, it streams them when Mongo has no change streams.
+func (s *UserService) WithEventPublisher(eventPublisher adapters.EventPublisher, source string) *UserService {
+	s.eventPublisher = eventPublisher
+	s.eventSource = source
+	return s
 }
 
-// CreateUser interface for create user.
+// CreateUser interface for create user, a verification link is sent to the email of the user.
 func (s *UserService) CreateUser(ctx context.Context, user *domain.User) (string, error) {
-	return s.userRepo.CreateUser(ctx, user)
+	id, err := s.userRepo.CreateUser(ctx, user)
+	if err != nil {
+		return "", err
+	}
+
+	s.recordEvent(ctx, &domain.AuditEvent{
+		Action:   domain.AuditActionUserCreated,
+		TargetID: id,
+		Changes:  utils.AuditChanges(nil, user),
+	})
+
+	created := *user
+	created.ID = id
+	s.publishEvent(ctx, domain.EventUserCreated, &created)
+
+	s.requestEmailVerification(ctx, id, user.Email)
+
+	return id, nil
 }
 
-// CreateUserBatch interface for create users.
+// CreateUserBatch interface for create users, an audit event is recorded for each imported user.
+// The passwords are checked against the password policy before any user is created and a
+// *utils.PasswordPolicyError lists the violations of every row.
 func (s *UserService) CreateUserBatch(ctx context.Context, user *[]domain.User) ([]interface{}, error) {
-	return s.userRepo.CreateUserBatch(ctx, user)
+	if err := validateBatchPasswords(*user); err != nil {
+		return nil, err
+	}
+
+	ids, err := s.userRepo.CreateUserBatch(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range min(len(ids), len(*user)) {
+		targetID, _ := ids[i].(string)
+		s.recordEvent(ctx, &domain.AuditEvent{
+			Action:   domain.AuditActionUserImported,
+			TargetID: targetID,
+			Changes:  utils.AuditChanges(nil, &(*user)[i]),
+		})
+
+		imported := (*user)[i]
+		imported.ID = targetID
+		s.publishEvent(ctx, domain.EventUserCreated, &imported)
+	}
+
+	return ids, nil
+}
+
+// validateBatchPasswords applies the password policy to the imported users, the violations
+// are prefixed with the row of the user in the file.
+func validateBatchPasswords(users []domain.User) error {
+	var violations []string
+
+	for i, user := range users {
+		var policyErr *utils.PasswordPolicyError
+		if errors.As(utils.ValidatePassword(user.Password, user.UserName, user.Email), &policyErr) {
+			for _, violation := range policyErr.Violations {
+				violations = append(violations, fmt.Sprintf(constants.RulePasswordRow, i+1, violation))
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return &utils.PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
 }
 
 // GetUserByID interface for get user by ID.
@@ -34,12 +175,151 @@ func (s *UserService) GetUserByID(ctx context.Context, id string) (*domain.User,
 	return s.userRepo.GetUserByID(ctx, id)
 }
 
-// UpdateUser interface for update user by ID.
+// UpdateUser interface for update user by ID, a new email stays pending and a verification link is sent to it,
+// the current email is notified of the change.
 func (s *UserService) UpdateUser(ctx context.Context, id string, updateFields *domain.User) (*domain.User, error) {
-	return s.userRepo.UpdateUser(ctx, id, updateFields)
+	before, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.UpdateUser(ctx, id, updateFields)
+	if err != nil {
+		return nil, err
+	}
+
+	if changes := utils.AuditChanges(before, user); len(changes) > 0 {
+		s.recordEvent(ctx, &domain.AuditEvent{
+			Action:   domain.AuditActionUserUpdated,
+			TargetID: id,
+			Changes:  changes,
+		})
+		s.publishEvent(ctx, domain.EventUserUpdated, user)
+	}
+
+	if user.PendingEmail != "" && user.PendingEmail == updateFields.Email {
+		s.requestEmailVerification(ctx, id, user.PendingEmail)
+		s.notifyEmailChange(ctx, before.Email, user.PendingEmail)
+	}
+
+	return user, nil
+}
+
+// ChangePassword verifies the current password, applies the password policy and
+// history, stores the new password and revokes the sessions of the user. When lockout
+// is enabled a wrong current password counts as a failed login of the user and a
+// LockoutError is returned while the user is locked.
+func (s *UserService) ChangePassword(ctx context.Context, id string, request *domain.ChangePasswordRequest) error {
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	userKey := userLockoutKey(user.ID)
+	if err := s.lockout.checkLocked(ctx, userKey); err != nil {
+		return err
+	}
+
+	if !s.checkPassword(request.CurrentPassword, user.Password) {
+		if s.lockout != nil {
+			s.lockout.recordFailure(ctx, userKey, s.lockout.policy.Threshold)
+		}
+		return ErrInvalidCurrentPassword
+	}
+
+	s.lockout.reset(ctx, userKey)
+
+	return s.setPassword(ctx, user, request.NewPassword, domain.AuditActionPasswordChanged)
+}
+
+// setPassword applies the password policy and history, stores the new password,
+// revokes the sessions and OAuth access tokens of the user and records the change with action.
+func (s *UserService) setPassword(ctx context.Context, user *domain.User, password string, action string) error {
+	if err := utils.ValidatePassword(password, user.UserName, user.Email); err != nil {
+		return err
+	}
+
+	for _, hash := range append([]string{user.Password}, user.PasswordHistory...) {
+		if s.checkPassword(password, hash) {
+			return ErrPasswordReused
+		}
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, user.ID, password); err != nil {
+		return err
+	}
+
+	if err := s.revokeUserAccess(ctx, user.ID); err != nil {
+		return err
+	}
+
+	s.recordEvent(ctx, &domain.AuditEvent{
+		Action:   action,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Changes:  []domain.AuditChange{{Field: "password", Before: utils.AuditRedacted, After: utils.AuditRedacted}},
+	})
+
+	return nil
 }
 
-// DeleteUser interface for delete user by ID.
+// DeleteUser interface for delete user by ID, the user is disabled and his sessions, OAuth access
+// tokens and API keys are revoked.
 func (s *UserService) DeleteUser(ctx context.Context, id string) error {
-	return s.userRepo.DeleteUser(ctx, id)
+	if err := s.userRepo.DeleteUser(ctx, id); err != nil {
+		return err
+	}
+
+	s.recordEvent(ctx, &domain.AuditEvent{
+		Action:   domain.AuditActionUserDeleted,
+		TargetID: id,
+		Changes:  []domain.AuditChange{{Field: "enabled", Before: true, After: false}},
+	})
+	s.publishEvent(ctx, domain.EventUserDeleted, &domain.User{ID: id})
+
+	if err := s.revokeUserAccess(ctx, id); err != nil {
+		return err
+	}
+
+	if s.apiKeyRevoker != nil {
+		return s.apiKeyRevoker.RevokeUserAPIKeys(ctx, id)
+	}
+
+	return nil
+}
+
+// revokeUserAccess signs out the user everywhere and revokes the OAuth access tokens issued for the user.
+func (s *UserService) revokeUserAccess(ctx context.Context, userID string) error {
+	if s.sessionRevoker != nil {
+		if err := s.sessionRevoker.RevokeUserSessions(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	if s.oauthTokenRevoker != nil {
+		return s.oauthTokenRevoker.RevokeUserTokens(ctx, userID)
+	}
+
+	return nil
+}
+
+// recordEvent appends an event with the audit info of the request to the audit log, failures
+// are logged because the change is already stored.
+func (s *UserService) recordEvent(ctx context.Context, event *domain.AuditEvent) {
+	if err := s.auditRepo.RecordEvent(ctx, withAuditInfo(ctx, event)); err != nil {
+		log.Printf("%v: %v", constants.ErrRecordAuditEvent, err)
+	}
+}
+
+// publishEvent notifies the publisher of an event of eventType for the user, failures are logged
+// because the change is already stored.
+func (s *UserService) publishEvent(ctx context.Context, eventType string, user *domain.User) {
+	if s.eventPublisher == nil {
+		return
+	}
+
+	event := utils.NewCloudEvent(repository.NewUserEvent(eventType, user), s.eventSource)
+	if err := s.eventPublisher.Publish(ctx, event); err != nil {
+		log.Printf("%v %v: %v", constants.ErrPublishEvent, event.ID, err)
+	}
 }
@@ -0,0 +1,331 @@
+package usecase
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// webAuthnCredentialType is the only credential type defined by WebAuthn.
+const webAuthnCredentialType = "public-key"
+
+// Errors returned by passkey registration and login.
+var (
+	ErrInvalidWebAuthnResponse  = utils.ErrInvalidWebAuthnResponse
+	ErrInvalidWebAuthnChallenge = errors.New(constants.ErrInvalidWebAuthnChallenge)
+	ErrWebAuthnCredentialExists = errors.New(constants.ErrWebAuthnCredentialExists)
+	ErrWebAuthnSignCount        = errors.New(constants.ErrWebAuthnSignCount)
+)
+
+// webAuthnAlgorithms are the COSE algorithms offered for new passkeys in order of preference.
+var webAuthnAlgorithms = []int64{utils.COSEAlgES256, utils.COSEAlgEdDSA, utils.COSEAlgRS256}
+
+// WebAuthnService handles the registration of passkeys and the login with them.
+type WebAuthnService struct {
+	userRepo       repository.UserRepository
+	credentialRepo repository.WebAuthnRepository
+	challengeRepo  repository.WebAuthnChallengeRepository
+	config         utils.WebAuthnConfig
+	rpName         string
+	attestation    string
+	challengeTTL   time.Duration
+}
+
+// NewWebAuthnService obtain new WebAuthn service, attestation is the conveyance
+// preference sent to authenticators, none or direct.
+func NewWebAuthnService(userRepo repository.UserRepository, credentialRepo repository.WebAuthnRepository, challengeRepo repository.WebAuthnChallengeRepository, config utils.WebAuthnConfig, rpName string, attestation string, challengeTTL time.Duration) *WebAuthnService {
+	return &WebAuthnService{
+		userRepo:       userRepo,
+		credentialRepo: credentialRepo,
+		challengeRepo:  challengeRepo,
+		config:         config,
+		rpName:         rpName,
+		attestation:    attestation,
+		challengeTTL:   challengeTTL,
+	}
+}
+
+// BeginRegistration returns the options to create a passkey for the user, the
+// passkeys already registered are excluded so an authenticator is not registered twice.
+func (s *WebAuthnService) BeginRegistration(ctx context.Context, id string) (*domain.WebAuthnCreationOptions, error) {
+	user, err := s.userRepo.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := s.credentialRepo.ListCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.createChallenge(ctx, user.ID, utils.WebAuthnCeremonyCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	parameters := make([]domain.WebAuthnCredentialParameter, 0, len(webAuthnAlgorithms))
+	for _, algorithm := range webAuthnAlgorithms {
+		parameters = append(parameters, domain.WebAuthnCredentialParameter{Type: webAuthnCredentialType, Algorithm: algorithm})
+	}
+
+	return &domain.WebAuthnCreationOptions{
+		Challenge:    challenge,
+		RelyingParty: domain.WebAuthnRelyingParty{ID: s.config.RPID, Name: s.rpName},
+		User: domain.WebAuthnUser{
+			ID:          base64.RawURLEncoding.EncodeToString([]byte(user.ID)),
+			Name:        user.UserName,
+			DisplayName: user.Name,
+		},
+		CredentialParameters: parameters,
+		Timeout:              s.challengeTTL.Milliseconds(),
+		ExcludeCredentials:   credentialDescriptors(credentials),
+		AuthenticatorSelection: domain.WebAuthnAuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: s.userVerification(),
+		},
+		Attestation: s.attestation,
+	}, nil
+}
+
+// FinishRegistration verifies the attestation of a new passkey created with the
+// options of BeginRegistration and stores it for the user, the challenge must have
+// been issued to the same user.
+func (s *WebAuthnService) FinishRegistration(ctx context.Context, id string, response *domain.WebAuthnRegistrationResponse) (*domain.WebAuthnCredential, error) {
+	clientDataJSON, err := decodeWebAuthnField(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	attestationObject, err := decodeWebAuthnField(response.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	clientData, err := s.config.ParseClientData(clientDataJSON, utils.WebAuthnCeremonyCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.consumeChallenge(ctx, clientData.Challenge, utils.WebAuthnCeremonyCreate)
+	if err != nil {
+		return nil, err
+	}
+
+	if challenge.UserID != id {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+
+	attested, err := s.config.VerifyAttestation(attestationObject, clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	credential := &domain.WebAuthnCredential{
+		ID:        base64.RawURLEncoding.EncodeToString(attested.ID),
+		UserID:    id,
+		PublicKey: attested.PublicKey,
+		Algorithm: attested.Algorithm,
+		SignCount: attested.SignCount,
+		Format:    attested.Format,
+		AAGUID:    attested.AAGUID,
+	}
+
+	if credential.ID != response.ID {
+		return nil, ErrInvalidWebAuthnResponse
+	}
+
+	if err := s.credentialRepo.CreateCredential(ctx, credential); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, ErrWebAuthnCredentialExists
+		}
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+// BeginLogin returns the options to login with a passkey. With login only the passkeys
+// of that user are allowed, an unknown login gets the same options as a login without
+// it so the existence of users is not revealed.
+func (s *WebAuthnService) BeginLogin(ctx context.Context, login string) (*domain.WebAuthnRequestOptions, error) {
+	var userID string
+	var allowed []domain.WebAuthnCredentialDescriptor
+
+	if login != "" {
+		user, err := s.userRepo.GetUserByLogin(ctx, login)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, err
+		}
+
+		if user != nil {
+			credentials, err := s.credentialRepo.ListCredentials(ctx, user.ID)
+			if err != nil {
+				return nil, err
+			}
+
+			userID = user.ID
+			allowed = credentialDescriptors(credentials)
+		}
+	}
+
+	challenge, err := s.createChallenge(ctx, userID, utils.WebAuthnCeremonyGet)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.WebAuthnRequestOptions{
+		Challenge:        challenge,
+		Timeout:          s.challengeTTL.Milliseconds(),
+		RPID:             s.config.RPID,
+		AllowCredentials: allowed,
+		UserVerification: s.userVerification(),
+	}, nil
+}
+
+// FinishLogin verifies the assertion of a passkey and returns its user. The signature
+// counter must increase when the authenticator implements it, otherwise the passkey may
+// be cloned and ErrWebAuthnSignCount is returned with the user so the failure can be counted.
+func (s *WebAuthnService) FinishLogin(ctx context.Context, response *domain.WebAuthnAssertionResponse) (*domain.User, error) {
+	clientDataJSON, err := decodeWebAuthnField(response.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	authenticatorData, err := decodeWebAuthnField(response.Response.AuthenticatorData)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := decodeWebAuthnField(response.Response.Signature)
+	if err != nil {
+		return nil, err
+	}
+
+	userHandle, err := decodeWebAuthnField(response.Response.UserHandle)
+	if err != nil {
+		return nil, err
+	}
+
+	clientData, err := s.config.ParseClientData(clientDataJSON, utils.WebAuthnCeremonyGet)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.consumeChallenge(ctx, clientData.Challenge, utils.WebAuthnCeremonyGet)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.credentialRepo.GetCredential(ctx, response.ID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidWebAuthnResponse
+		}
+		return nil, err
+	}
+
+	if challenge.UserID != "" && challenge.UserID != credential.UserID {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+
+	if len(userHandle) > 0 && string(userHandle) != credential.UserID {
+		return nil, ErrInvalidWebAuthnResponse
+	}
+
+	user, err := s.userRepo.GetUserByID(ctx, credential.UserID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidWebAuthnResponse
+		}
+		return nil, err
+	}
+
+	signCount, err := s.config.VerifyAssertion(credential.PublicKey, authenticatorData, clientDataJSON, signature)
+	if err != nil {
+		return user, err
+	}
+
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		return user, ErrWebAuthnSignCount
+	}
+
+	err = s.credentialRepo.UpdateSignCount(ctx, credential.ID, credential.SignCount, signCount)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return user, ErrWebAuthnSignCount
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *WebAuthnService) createChallenge(ctx context.Context, userID string, ceremony string) (string, error) {
+	challenge, hash, err := utils.GenerateToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.challengeRepo.CreateChallenge(ctx, &domain.WebAuthnChallenge{
+		ID:        hash,
+		UserID:    userID,
+		Ceremony:  ceremony,
+		ExpiresAt: time.Now().Add(s.challengeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// consumeChallenge deletes the challenge signed by the authenticator, it can only be used once.
+func (s *WebAuthnService) consumeChallenge(ctx context.Context, challenge string, ceremony string) (*domain.WebAuthnChallenge, error) {
+	stored, err := s.challengeRepo.ConsumeChallenge(ctx, utils.HashToken(challenge))
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrInvalidWebAuthnChallenge
+		}
+		return nil, err
+	}
+
+	if stored.Ceremony != ceremony {
+		return nil, ErrInvalidWebAuthnChallenge
+	}
+
+	return stored, nil
+}
+
+func (s *WebAuthnService) userVerification() string {
+	if s.config.RequireUserVerification {
+		return "required"
+	}
+
+	return "preferred"
+}
+
+func credentialDescriptors(credentials []domain.WebAuthnCredential) []domain.WebAuthnCredentialDescriptor {
+	descriptors := make([]domain.WebAuthnCredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptors = append(descriptors, domain.WebAuthnCredentialDescriptor{Type: webAuthnCredentialType, ID: credential.ID})
+	}
+
+	return descriptors
+}
+
+// decodeWebAuthnField decodes a base64url value of a WebAuthn response.
+func decodeWebAuthnField(value string) ([]byte, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidWebAuthnResponse
+	}
+
+	return decoded, nil
+}
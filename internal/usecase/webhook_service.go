@@ -0,0 +1,355 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/adapters"
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// webhookSecretPrefix starts every webhook secret so leaked secrets are easy to recognize by secret scanners.
+const webhookSecretPrefix = "whsec_"
+
+// Default size of a page of the delivery log of a webhook.
+const defaultDeliveryPageSize = 20
+
+// Delays before a failed delivery is attempted again, doubled on each attempt.
+const (
+	minWebhookRetryDelay = 30 * time.Second
+	maxWebhookRetryDelay = 6 * time.Hour
+)
+
+// Errors returned by webhooks.
+var (
+	ErrWebhooksNotConfigured = errors.New(constants.ErrWebhooksNotConfigured)
+	ErrInvalidWebhook        = errors.New(constants.ErrInvalidWebhook)
+	ErrWebhookNotFound       = errors.New(constants.ErrWebhookNotFound)
+	ErrDeliveryNotFound      = errors.New(constants.ErrDeliveryNotFound)
+)
+
+// WebhookService handles the webhooks tenants register to receive user events. It publishes
+// the events of the outbox as a delivery for each subscribed webhook, and the deliveries are
+// sent signed with the secret of the webhook and retried with backoff until they are moved to
+// the dead letter state after maxAttempts.
+type WebhookService struct {
+	subscriptionRepo repository.WebhookSubscriptionRepository
+	deliveryRepo     repository.WebhookDeliveryRepository
+	sender           adapters.WebhookSender
+	secretBox        *utils.SecretBox
+	maxAttempts      int
+	lease            time.Duration
+}
+
+// NewWebhookService obtain new webhook service, the secrets are encrypted with secretBox and
+// without it webhooks are disabled. A delivery is claimed for lease while it is sent.
+func NewWebhookService(subscriptionRepo repository.WebhookSubscriptionRepository, deliveryRepo repository.WebhookDeliveryRepository, sender adapters.WebhookSender, secretBox *utils.SecretBox, maxAttempts int, lease time.Duration) *WebhookService {
+	return &WebhookService{
+		subscriptionRepo: subscriptionRepo,
+		deliveryRepo:     deliveryRepo,
+		sender:           sender,
+		secretBox:        secretBox,
+		maxAttempts:      maxAttempts,
+		lease:            lease,
+	}
+}
+
+// Enabled reports whether webhooks are configured.
+func (s *WebhookService) Enabled() bool {
+	return s.secretBox != nil
+}
+
+// CreateWebhook registers a webhook of a tenant and returns the secret signing its deliveries,
+// which is shown only once. The URL must be https and resolve only to public addresses.
+func (s *WebhookService) CreateWebhook(ctx context.Context, tenant string, request *domain.WebhookSubscriptionRequest) (*domain.WebhookSubscription, string, error) {
+	if s.secretBox == nil {
+		return nil, "", ErrWebhooksNotConfigured
+	}
+
+	if err := s.sender.ValidateURL(ctx, request.URL); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidWebhook, err)
+	}
+
+	events := []string{}
+	for _, event := range slices.Compact(slices.Sorted(slices.Values(request.Events))) {
+		if !slices.Contains(domain.WebhookEvents, event) {
+			return nil, "", fmt.Errorf("%w: unknown event %v", ErrInvalidWebhook, event)
+		}
+		events = append(events, event)
+	}
+
+	token, _, err := utils.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+	secret := webhookSecretPrefix + token
+
+	encrypted, err := s.secretBox.Encrypt(secret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	mode := request.Mode
+	if mode == "" {
+		mode = domain.CloudEventsStructured
+	}
+
+	subscription := &domain.WebhookSubscription{
+		ID:     primitive.NewObjectID().Hex(),
+		Tenant: tenant,
+		URL:    request.URL,
+		Events: events,
+		Mode:   mode,
+		Secret: encrypted,
+	}
+
+	if err := s.subscriptionRepo.CreateSubscription(ctx, subscription); err != nil {
+		return nil, "", err
+	}
+
+	return subscription, secret, nil
+}
+
+// ListWebhooks returns the webhooks of a tenant.
+func (s *WebhookService) ListWebhooks(ctx context.Context, tenant string) ([]domain.WebhookSubscription, error) {
+	if s.secretBox == nil {
+		return nil, ErrWebhooksNotConfigured
+	}
+
+	return s.subscriptionRepo.ListSubscriptions(ctx, tenant)
+}
+
+// DeleteWebhook removes a webhook of a tenant and its delivery log, pending deliveries are not sent.
+func (s *WebhookService) DeleteWebhook(ctx context.Context, tenant string, id string) error {
+	if s.secretBox == nil {
+		return ErrWebhooksNotConfigured
+	}
+
+	if _, err := s.tenantSubscription(ctx, tenant, id); err != nil {
+		return err
+	}
+
+	if err := s.subscriptionRepo.DeleteSubscription(ctx, id); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return ErrWebhookNotFound
+		}
+		return err
+	}
+
+	return s.deliveryRepo.DeleteDeliveries(ctx, id)
+}
+
+// ListDeliveries returns a page of the delivery log of a webhook of a tenant from newest to oldest
+// and the cursor of the next page, which is empty on the last page.
+func (s *WebhookService) ListDeliveries(ctx context.Context, tenant string, id string, query *domain.WebhookDeliveryQuery) ([]domain.WebhookDelivery, string, error) {
+	if s.secretBox == nil {
+		return nil, "", ErrWebhooksNotConfigured
+	}
+
+	if _, err := s.tenantSubscription(ctx, tenant, id); err != nil {
+		return nil, "", err
+	}
+
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultDeliveryPageSize
+	}
+
+	deliveries, err := s.deliveryRepo.ListDeliveries(ctx, id, query.Status, query.Cursor, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if int64(len(deliveries)) <= limit {
+		return deliveries, "", nil
+	}
+
+	deliveries = deliveries[:limit]
+
+	return deliveries, deliveries[limit-1].ID, nil
+}
+
+// Redeliver schedules a delivery of a webhook of a tenant to be sent again now with its attempts
+// reset, deliveries in the dead letter state are sent again once the endpoint is fixed.
+func (s *WebhookService) Redeliver(ctx context.Context, tenant string, id string, deliveryID string) (*domain.WebhookDelivery, error) {
+	if s.secretBox == nil {
+		return nil, ErrWebhooksNotConfigured
+	}
+
+	if _, err := s.tenantSubscription(ctx, tenant, id); err != nil {
+		return nil, err
+	}
+
+	delivery, err := s.deliveryRepo.Redeliver(ctx, id, deliveryID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// tenantSubscription returns the webhook id of tenant, the webhooks of other tenants are not found.
+func (s *WebhookService) tenantSubscription(ctx context.Context, tenant string, id string) (*domain.WebhookSubscription, error) {
+	subscription, err := s.subscriptionRepo.GetSubscription(ctx, id)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+
+	if subscription.Tenant != tenant {
+		return nil, ErrWebhookNotFound
+	}
+
+	return subscription, nil
+}
+
+// Publish creates a pending delivery of the CloudEvent for each webhook subscribed to its type,
+// it is an event publisher of the outbox relay. An event published again is not delivered twice.
+func (s *WebhookService) Publish(ctx context.Context, event *domain.CloudEvent) error {
+	subscriptions, err := s.subscriptionRepo.ListSubscriptionsForEvent(ctx, event.Type)
+	if err != nil {
+		return err
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		err := s.deliveryRepo.CreateDelivery(ctx, &domain.WebhookDelivery{
+			ID:             primitive.NewObjectID().Hex(),
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        string(payload),
+			Status:         domain.WebhookDeliveryPending,
+			NextAttemptAt:  time.Now(),
+		})
+		if err != nil && !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run sends the due deliveries every interval until ctx is done, errors are logged and retried on the next tick.
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.Dispatch(ctx); err != nil {
+				log.Printf("%v: %v", constants.ErrDeliverWebhooks, err)
+			}
+		}
+	}
+}
+
+// Dispatch sends the due deliveries until there are none left and returns how many were accepted
+// by their endpoints. A delivery is claimed for the lease, so dispatchers of other instances skip it.
+func (s *WebhookService) Dispatch(ctx context.Context) (int, error) {
+	delivered := 0
+
+	for ctx.Err() == nil {
+		delivery, err := s.deliveryRepo.ClaimDelivery(ctx, s.lease)
+		if err != nil {
+			if errors.Is(err, mongo.ErrNoDocuments) {
+				return delivered, nil
+			}
+			return delivered, err
+		}
+
+		if err := s.send(ctx, delivery); err != nil {
+			return delivered, err
+		}
+
+		if delivery.Status == domain.WebhookDeliverySucceeded {
+			delivered++
+		}
+	}
+
+	return delivered, ctx.Err()
+}
+
+// send attempts a delivery and stores the result. A failed delivery is retried with backoff and
+// moved to the dead letter state after maxAttempts, or at once when its webhook was deleted.
+func (s *WebhookService) send(ctx context.Context, delivery *domain.WebhookDelivery) error {
+	subscription, err := s.subscriptionRepo.GetSubscription(ctx, delivery.SubscriptionID)
+	if err != nil {
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return err
+		}
+		delivery.Status = domain.WebhookDeliveryDeadLetter
+
+		return s.saveAttempt(ctx, delivery, 0, constants.ErrWebhookNotFound)
+	}
+
+	secret, err := s.secretBox.Decrypt(subscription.Secret)
+	if err != nil {
+		return err
+	}
+
+	statusCode, err := s.sender.Send(ctx, subscription, secret, delivery)
+	now := time.Now()
+
+	switch {
+	case err == nil:
+		delivery.Status = domain.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+
+		return s.saveAttempt(ctx, delivery, statusCode, "")
+	case delivery.Attempts >= s.maxAttempts:
+		delivery.Status = domain.WebhookDeliveryDeadLetter
+	default:
+		delivery.NextAttemptAt = now.Add(webhookRetryDelay(delivery.Attempts))
+	}
+
+	return s.saveAttempt(ctx, delivery, statusCode, err.Error())
+}
+
+// saveAttempt stores the delivery with the result of its last attempt appended to its history.
+func (s *WebhookService) saveAttempt(ctx context.Context, delivery *domain.WebhookDelivery, statusCode int, message string) error {
+	attempt := domain.WebhookAttempt{
+		Attempt:     delivery.Attempts,
+		StatusCode:  statusCode,
+		Error:       message,
+		AttemptedAt: time.Now(),
+	}
+
+	delivery.LastStatusCode = statusCode
+	delivery.LastError = message
+	delivery.History = append(delivery.History, attempt)
+
+	return s.deliveryRepo.SaveAttempt(ctx, delivery, &attempt)
+}
+
+// webhookRetryDelay returns the delay before the next attempt of a delivery.
+func webhookRetryDelay(attempts int) time.Duration {
+	delay := minWebhookRetryDelay
+	for i := 1; i < attempts && delay < maxWebhookRetryDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, maxWebhookRetryDelay)
+}
@@ -0,0 +1,134 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/usecase"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const webhookMaxAttempts = 5
+
+// statusWebhookSender answers every delivery with statusCode and err.
+type statusWebhookSender struct {
+	statusCode int
+	err        error
+}
+
+func (s *statusWebhookSender) ValidateURL(context.Context, string) error {
+	return nil
+}
+
+func (s *statusWebhookSender) Send(context.Context, *domain.WebhookSubscription, string, *domain.WebhookDelivery) (int, error) {
+	return s.statusCode, s.err
+}
+
+// dispatchDelivery dispatches delivery once with sender and returns it after the attempt was saved.
+func dispatchDelivery(t *testing.T, delivery *domain.WebhookDelivery, sender *statusWebhookSender, errSubscription error, maxAttempts int) *domain.WebhookDelivery {
+	subscriptionRepo := new(mocks.WebhookSubscriptionRepository)
+	deliveryRepo := new(mocks.WebhookDeliveryRepository)
+
+	secretBox, err := utils.NewSecretBox("k1", map[string][]byte{"k1": []byte("webhook-encryption-key")})
+	assert.NoError(t, err)
+
+	secret, err := secretBox.Encrypt("whsec_secret")
+	assert.NoError(t, err)
+
+	subscriptionRepo.On("GetSubscription", mock.Anything, "webhook-1").Return(&domain.WebhookSubscription{ID: "webhook-1", Secret: secret}, errSubscription)
+	deliveryRepo.On("ClaimDelivery", mock.Anything, time.Minute).Return(delivery, nil).Once()
+	deliveryRepo.On("ClaimDelivery", mock.Anything, time.Minute).Return(nil, mongo.ErrNoDocuments).Once()
+	deliveryRepo.On("SaveAttempt", mock.Anything, delivery, mock.Anything).Return(nil).Once()
+
+	webhookService := usecase.NewWebhookService(subscriptionRepo, deliveryRepo, sender, secretBox, maxAttempts, time.Minute)
+
+	_, err = webhookService.Dispatch(context.Background())
+	assert.NoError(t, err)
+	deliveryRepo.AssertExpectations(t)
+
+	return delivery
+}
+
+func pendingDelivery(attempts int) *domain.WebhookDelivery {
+	return &domain.WebhookDelivery{
+		ID:             "delivery-1",
+		SubscriptionID: "webhook-1",
+		Status:         domain.WebhookDeliveryPending,
+		Attempts:       attempts,
+	}
+}
+
+func TestWebhookDispatch(t *testing.T) {
+	rejected := &statusWebhookSender{statusCode: http.StatusServiceUnavailable, err: errors.New("unexpected status 503")}
+
+	t.Run("should mark the delivery as succeeded when the endpoint accepts it", func(t *testing.T) {
+		delivery := dispatchDelivery(t, pendingDelivery(1), &statusWebhookSender{statusCode: http.StatusNoContent}, nil, webhookMaxAttempts)
+
+		assert.Equal(t, domain.WebhookDeliverySucceeded, delivery.Status)
+		assert.NotNil(t, delivery.DeliveredAt)
+		assert.Empty(t, delivery.LastError)
+		assert.Equal(t, []int{http.StatusNoContent}, historyStatusCodes(delivery))
+	})
+
+	t.Run("should double the delay before each retry", func(t *testing.T) {
+		for attempts, delay := range map[int]time.Duration{1: 30 * time.Second, 2: time.Minute, 4: 4 * time.Minute} {
+			before := time.Now()
+			delivery := dispatchDelivery(t, pendingDelivery(attempts), rejected, nil, webhookMaxAttempts)
+
+			assert.Equal(t, domain.WebhookDeliveryPending, delivery.Status)
+			assert.WithinDuration(t, before.Add(delay), delivery.NextAttemptAt, time.Second)
+		}
+	})
+
+	t.Run("should cap the delay before a retry", func(t *testing.T) {
+		before := time.Now()
+		delivery := dispatchDelivery(t, pendingDelivery(30), rejected, nil, 50)
+
+		assert.Equal(t, domain.WebhookDeliveryPending, delivery.Status)
+		assert.WithinDuration(t, before.Add(6*time.Hour), delivery.NextAttemptAt, time.Second)
+	})
+
+	t.Run("should move the delivery to dead letter after the last attempt", func(t *testing.T) {
+		delivery := dispatchDelivery(t, pendingDelivery(webhookMaxAttempts), rejected, nil, webhookMaxAttempts)
+
+		assert.Equal(t, domain.WebhookDeliveryDeadLetter, delivery.Status)
+		assert.Equal(t, http.StatusServiceUnavailable, delivery.LastStatusCode)
+		assert.Equal(t, "unexpected status 503", delivery.LastError)
+	})
+
+	t.Run("should move the delivery to dead letter when the webhook was deleted", func(t *testing.T) {
+		delivery := dispatchDelivery(t, pendingDelivery(1), rejected, mongo.ErrNoDocuments, webhookMaxAttempts)
+
+		assert.Equal(t, domain.WebhookDeliveryDeadLetter, delivery.Status)
+		assert.Equal(t, constants.ErrWebhookNotFound, delivery.LastError)
+	})
+
+	t.Run("should append each attempt to the history of the delivery", func(t *testing.T) {
+		delivery := pendingDelivery(2)
+		delivery.History = []domain.WebhookAttempt{{Attempt: 1, StatusCode: http.StatusBadGateway, Error: "unexpected status 502"}}
+
+		dispatchDelivery(t, delivery, rejected, nil, webhookMaxAttempts)
+
+		assert.Equal(t, []int{http.StatusBadGateway, http.StatusServiceUnavailable}, historyStatusCodes(delivery))
+		assert.Equal(t, 2, delivery.History[1].Attempt)
+		assert.Equal(t, "unexpected status 503", delivery.History[1].Error)
+	})
+}
+
+func historyStatusCodes(delivery *domain.WebhookDelivery) []int {
+	statusCodes := []int{}
+	for _, attempt := range delivery.History {
+		statusCodes = append(statusCodes, attempt.StatusCode)
+	}
+
+	return statusCodes
+}
@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+)
+
+// AuditRedacted replaces the value of a secret field in the audit log.
+const AuditRedacted = "[REDACTED]"
+
+// AuditChanges returns the fields that differ between before and after, named as in BD.
+// A nil before or after is a created or deleted record, its fields are compared as zero
+// values. Fields tagged audit:"redact" are recorded with AuditRedacted and fields tagged
+// audit:"-" are skipped.
+func AuditChanges[T any](before, after *T) []domain.AuditChange {
+	recordType := reflect.TypeFor[T]()
+	if recordType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var changes []domain.AuditChange
+	for i := range recordType.NumField() {
+		field := recordType.Field(i)
+		tag := field.Tag.Get("audit")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+
+		beforeValue, afterValue := auditValue(before, i), auditValue(after, i)
+		if reflect.DeepEqual(beforeValue.Interface(), afterValue.Interface()) {
+			continue
+		}
+
+		change := domain.AuditChange{Field: auditFieldName(field)}
+		if tag == "redact" {
+			change.Before = redactAuditValue(beforeValue)
+			change.After = redactAuditValue(afterValue)
+		} else {
+			if before != nil {
+				change.Before = beforeValue.Interface()
+			}
+			if after != nil {
+				change.After = afterValue.Interface()
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	return changes
+}
+
+// auditValue returns the field i of record, or its zero value when the record is nil.
+func auditValue[T any](record *T, i int) reflect.Value {
+	if record == nil {
+		return reflect.Zero(reflect.TypeFor[T]().Field(i).Type)
+	}
+
+	return reflect.ValueOf(record).Elem().Field(i)
+}
+
+// redactAuditValue hides a secret value, an empty secret is recorded as nil.
+func redactAuditValue(value reflect.Value) interface{} {
+	if value.IsZero() {
+		return nil
+	}
+
+	return AuditRedacted
+}
+
+// auditFieldName returns the name of the field in BD.
+func auditFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("bson"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+
+	return name
+}
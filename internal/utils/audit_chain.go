@@ -0,0 +1,189 @@
+package utils
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AuditCheckpointMessage returns the message signed by a checkpoint of the audit log.
+func AuditCheckpointMessage(sequence int64, hash string) []byte {
+	return []byte("audit-checkpoint:" + strconv.FormatInt(sequence, 10) + ":" + hash)
+}
+
+// AuditKeyID returns the id of the key that signs checkpoints, the first bytes of the hash of the public key.
+func AuditKeyID(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// LoadAuditSigningKey reads the Ed25519 private key that signs checkpoints from a PKCS #8 PEM file,
+// as created by openssl genpkey -algorithm ed25519.
+func LoadAuditSigningKey(path string) (ed25519.PrivateKey, error) {
+	block, err := readAuditKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", constants.ErrInvalidAuditKey, err)
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf(constants.ErrInvalidAuditKey)
+	}
+
+	return privateKey, nil
+}
+
+// LoadAuditVerificationKey reads the Ed25519 public key that verifies checkpoints from a PEM file,
+// the private key file is also accepted.
+func LoadAuditVerificationKey(path string) (ed25519.PublicKey, error) {
+	block, err := readAuditKeyFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type == "PRIVATE KEY" {
+		privateKey, err := LoadAuditSigningKey(path)
+		if err != nil {
+			return nil, err
+		}
+
+		return privateKey.Public().(ed25519.PublicKey), nil
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", constants.ErrInvalidAuditKey, err)
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf(constants.ErrInvalidAuditKey)
+	}
+
+	return publicKey, nil
+}
+
+// readAuditKeyFile returns the first PEM block of a key file.
+func readAuditKeyFile(path string) (*pem.Block, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", constants.ErrReadAuditKeyFile, err)
+	}
+
+	block, _ := pem.Decode(content)
+	if block == nil {
+		return nil, fmt.Errorf(constants.ErrInvalidAuditKey)
+	}
+
+	return block, nil
+}
+
+// AuditChainVerifier checks the entries of the audit log in sequence order: each entry must
+// follow the previous one, carry its hash and match its own hash and the signed checkpoints.
+type AuditChainVerifier struct {
+	publicKey    ed25519.PublicKey
+	checkpoints  map[int64]domain.AuditCheckpoint
+	lastSigned   int64
+	previousHash string
+	result       domain.AuditVerification
+}
+
+// NewAuditChainVerifier obtain new verifier of the audit log, checkpoints are verified with publicKey.
+func NewAuditChainVerifier(publicKey ed25519.PublicKey, checkpoints []domain.AuditCheckpoint) *AuditChainVerifier {
+	verifier := &AuditChainVerifier{
+		publicKey:   publicKey,
+		checkpoints: make(map[int64]domain.AuditCheckpoint, len(checkpoints)),
+		result: domain.AuditVerification{
+			Valid:       true,
+			Checkpoints: int64(len(checkpoints)),
+		},
+	}
+
+	for _, checkpoint := range checkpoints {
+		verifier.checkpoints[checkpoint.Sequence] = checkpoint
+		verifier.lastSigned = max(verifier.lastSigned, checkpoint.Sequence)
+	}
+
+	return verifier
+}
+
+// Next checks the next entry of the audit log, it returns false once a broken link is found.
+func (v *AuditChainVerifier) Next(entry bson.Raw) bool {
+	if !v.result.Valid {
+		return false
+	}
+
+	var event domain.AuditEvent
+	if err := bson.Unmarshal(entry, &event); err != nil {
+		return v.broken(v.result.Entries+1, "", err.Error())
+	}
+
+	if expected := v.result.Entries + 1; event.Sequence != expected {
+		return v.broken(expected, event.ID, fmt.Sprintf(constants.AuditBrokenSequence, expected))
+	}
+
+	if event.PrevHash != v.previousHash {
+		return v.broken(event.Sequence, event.ID, fmt.Sprintf(constants.AuditBrokenPrevHash, event.Sequence-1))
+	}
+
+	hash, err := repository.HashAuditEntry(entry)
+	if err != nil {
+		return v.broken(event.Sequence, event.ID, err.Error())
+	}
+	if hash != event.Hash {
+		return v.broken(event.Sequence, event.ID, constants.AuditBrokenHash)
+	}
+
+	if checkpoint, found := v.checkpoints[event.Sequence]; found {
+		if !ed25519.Verify(v.publicKey, AuditCheckpointMessage(checkpoint.Sequence, checkpoint.Hash), checkpoint.Signature) {
+			return v.broken(event.Sequence, event.ID, constants.AuditBadSignature)
+		}
+		if checkpoint.Hash != hash {
+			return v.broken(event.Sequence, event.ID, constants.AuditBrokenCheckpoint)
+		}
+	}
+
+	v.previousHash = hash
+	v.result.Entries = event.Sequence
+
+	return true
+}
+
+// Result returns the verification of the entries checked, the newest entries are missing when
+// a checkpoint signs a sequence after the last entry.
+func (v *AuditChainVerifier) Result() *domain.AuditVerification {
+	result := v.result
+	if result.Valid && v.lastSigned > result.Entries {
+		result.Valid = false
+		result.BrokenSequence = result.Entries + 1
+		result.Reason = fmt.Sprintf(constants.AuditMissingEntries, v.lastSigned)
+	}
+
+	return &result
+}
+
+// broken records the first broken link of the audit log.
+func (v *AuditChainVerifier) broken(sequence int64, eventID string, reason string) bool {
+	v.result.Valid = false
+	v.result.BrokenSequence = sequence
+	v.result.BrokenEventID = eventID
+	v.result.Reason = reason
+
+	return false
+}
@@ -0,0 +1,204 @@
+package utils_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// auditChain builds a valid chain of entries as they are stored by the audit repository.
+func auditChain(t *testing.T, size int) ([]*domain.AuditEvent, []bson.Raw) {
+	events := make([]*domain.AuditEvent, 0, size)
+	entries := make([]bson.Raw, 0, size)
+	prevHash := ""
+
+	for i := 1; i <= size; i++ {
+		event := &domain.AuditEvent{
+			ID:       fmt.Sprintf("event%d", i),
+			Sequence: int64(i),
+			PrevHash: prevHash,
+			Action:   domain.AuditActionUserUpdated,
+			TargetID: "12345",
+		}
+		entry, err := bson.Marshal(event)
+		assert.NoError(t, err)
+		event.Hash, err = repository.HashAuditEntry(entry)
+		assert.NoError(t, err)
+
+		events = append(events, event)
+		entries = append(entries, marshalAuditEvent(t, event))
+		prevHash = event.Hash
+	}
+
+	return events, entries
+}
+
+func marshalAuditEvent(t *testing.T, event *domain.AuditEvent) bson.Raw {
+	entry, err := bson.Marshal(event)
+	assert.NoError(t, err)
+
+	return entry
+}
+
+func signCheckpoint(privateKey ed25519.PrivateKey, event *domain.AuditEvent) domain.AuditCheckpoint {
+	return domain.AuditCheckpoint{
+		Sequence:  event.Sequence,
+		Hash:      event.Hash,
+		Signature: ed25519.Sign(privateKey, utils.AuditCheckpointMessage(event.Sequence, event.Hash)),
+	}
+}
+
+func TestAuditChainVerifier(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	testCases := []struct {
+		name     string
+		tamper   func(events []*domain.AuditEvent, entries []bson.Raw) ([]bson.Raw, []domain.AuditCheckpoint)
+		valid    bool
+		entries  int64
+		sequence int64
+		reason   string
+	}{
+		{
+			name: "should verify an untouched chain and its checkpoints",
+			tamper: func(events []*domain.AuditEvent, entries []bson.Raw) ([]bson.Raw, []domain.AuditCheckpoint) {
+				return entries, []domain.AuditCheckpoint{signCheckpoint(privateKey, events[1]), signCheckpoint(privateKey, events[3])}
+			},
+			valid:   true,
+			entries: 4,
+		},
+		{
+			name: "should report an entry whose content was edited",
+			tamper: func(events []*domain.AuditEvent, entries []bson.Raw) ([]bson.Raw, []domain.AuditCheckpoint) {
+				events[2].TargetID = "other"
+				entries[2] = marshalAuditEvent(t, events[2])
+				return entries, nil
+			},
+			entries:  2,
+			sequence: 3,
+			reason:   constants.AuditBrokenHash,
+		},
+		{
+			name: "should report an entry whose hash was recomputed after an edit",
+			tamper: func(events []*domain.AuditEvent, entries []bson.Raw) ([]bson.Raw, []domain.AuditCheckpoint) {
+				events[1].TargetID = "other"
+				events[1].Hash = ""
+				events[1].Hash, _ = repository.HashAuditEntry(marshalAuditEvent(t, events[1]))
+				entries[1] = marshalAuditEvent(t, events[1])
+				return entries, nil
+			},
+			entries:  2,
+			sequence: 3,
+			reason:   fmt.Sprintf(constants.AuditBrokenPrevHash, 2),
+		},
+		{
+			name: "should report a removed entry",
+			tamper: func(events []*domain.AuditEvent, entries []bson.Raw) ([]bson.Raw, []domain.AuditCheckpoint) {
+				return append(entries[:1], entries[2:]...), nil
+			},
+			entries:  1,
+			sequence: 2,
+			reason:   fmt.Sprintf(constants.AuditBrokenSequence, 2),
+		},
+		{
+			name: "should report entries removed from the end of a signed chain",
+			tamper: func(events []*domain.AuditEvent, entries []bson.Raw) ([]bson.Raw, []domain.AuditCheckpoint) {
+				return entries[:2], []domain.AuditCheckpoint{signCheckpoint(privateKey, events[3])}
+			},
+			entries:  2,
+			sequence: 3,
+			reason:   fmt.Sprintf(constants.AuditMissingEntries, 4),
+		},
+		{
+			name: "should report a checkpoint signed by other key",
+			tamper: func(events []*domain.AuditEvent, entries []bson.Raw) ([]bson.Raw, []domain.AuditCheckpoint) {
+				return entries, []domain.AuditCheckpoint{signCheckpoint(otherKey, events[1])}
+			},
+			entries:  1,
+			sequence: 2,
+			reason:   constants.AuditBadSignature,
+		},
+		{
+			name: "should report a chain rewritten after a checkpoint",
+			tamper: func(events []*domain.AuditEvent, entries []bson.Raw) ([]bson.Raw, []domain.AuditCheckpoint) {
+				checkpoint := signCheckpoint(privateKey, events[0])
+				checkpoint.Hash = "rewritten"
+				checkpoint.Signature = ed25519.Sign(privateKey, utils.AuditCheckpointMessage(1, "rewritten"))
+				return entries, []domain.AuditCheckpoint{checkpoint}
+			},
+			entries:  0,
+			sequence: 1,
+			reason:   constants.AuditBrokenCheckpoint,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			events, entries := auditChain(t, 4)
+			entries, checkpoints := test.tamper(events, entries)
+
+			verifier := utils.NewAuditChainVerifier(publicKey, checkpoints)
+			for _, entry := range entries {
+				if !verifier.Next(entry) {
+					break
+				}
+			}
+			result := verifier.Result()
+
+			assert.Equal(t, test.valid, result.Valid)
+			assert.Equal(t, test.entries, result.Entries)
+			assert.Equal(t, test.sequence, result.BrokenSequence)
+			assert.Equal(t, test.reason, result.Reason)
+		})
+	}
+}
+
+func TestLoadAuditKeys(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	dir := t.TempDir()
+
+	privateDER, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	privatePath := filepath.Join(dir, "audit.pem")
+	assert.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600))
+
+	publicDER, _ := x509.MarshalPKIXPublicKey(publicKey)
+	publicPath := filepath.Join(dir, "audit.pub.pem")
+	assert.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600))
+
+	invalidPath := filepath.Join(dir, "invalid.pem")
+	assert.NoError(t, os.WriteFile(invalidPath, []byte("not a key"), 0600))
+
+	loadedPrivate, err := utils.LoadAuditSigningKey(privatePath)
+	assert.NoError(t, err)
+	assert.Equal(t, privateKey, loadedPrivate)
+
+	for _, path := range []string{publicPath, privatePath} {
+		loadedPublic, err := utils.LoadAuditVerificationKey(path)
+		assert.NoError(t, err)
+		assert.Equal(t, publicKey, loadedPublic)
+	}
+
+	_, err = utils.LoadAuditSigningKey(publicPath)
+	assert.Error(t, err)
+
+	_, err = utils.LoadAuditVerificationKey(invalidPath)
+	assert.Error(t, err)
+
+	_, err = utils.LoadAuditSigningKey(filepath.Join(dir, "missing.pem"))
+	assert.Error(t, err)
+
+	assert.Len(t, utils.AuditKeyID(publicKey), 16)
+}
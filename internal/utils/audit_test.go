@@ -0,0 +1,66 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditChanges(t *testing.T) {
+	stored := &domain.User{
+		ID:       "12345",
+		Name:     "Carlos",
+		Email:    "carlos@example.com",
+		Enabled:  true,
+		Password: "hash",
+		UserName: "cnmoreno",
+	}
+	updated := *stored
+	updated.Name = "Carlos Moreno"
+	updated.PendingEmail = "new@example.com"
+	updated.Password = "new-hash"
+
+	testCases := []struct {
+		name    string
+		before  *domain.User
+		after   *domain.User
+		changes []domain.AuditChange
+	}{
+		{
+			name:   "should record the set fields and redact secrets when the user is created",
+			before: nil,
+			after:  stored,
+			changes: []domain.AuditChange{
+				{Field: "name", After: "Carlos"},
+				{Field: "email", After: "carlos@example.com"},
+				{Field: "enabled", After: true},
+				{Field: "password", After: utils.AuditRedacted},
+				{Field: "userName", After: "cnmoreno"},
+			},
+		},
+		{
+			name:   "should record only the changed fields when the user is updated",
+			before: stored,
+			after:  &updated,
+			changes: []domain.AuditChange{
+				{Field: "name", Before: "Carlos", After: "Carlos Moreno"},
+				{Field: "password", Before: utils.AuditRedacted, After: utils.AuditRedacted},
+				{Field: "pendingEmail", Before: "", After: "new@example.com"},
+			},
+		},
+		{
+			name:    "should record nothing when the user does not change",
+			before:  stored,
+			after:   stored,
+			changes: nil,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.changes, utils.AuditChanges(test.before, test.after))
+		})
+	}
+}
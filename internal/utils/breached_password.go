@@ -0,0 +1,227 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"expvar"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+)
+
+// sha1HexLength is the length of the hashes in the HIBP corpus, which is published as SHA-1.
+const sha1HexLength = 40
+
+// BreachedPasswordRejections counts the passwords rejected because they appear in a breach corpus.
+var BreachedPasswordRejections = expvar.NewInt("breached_password_rejections")
+
+// BreachedPasswordChecker reports whether a password appears in a breach corpus.
+type BreachedPasswordChecker interface {
+	Contains(password string) (bool, error)
+}
+
+// SortedHashFile checks passwords with a binary search over a local HIBP
+// SHA-1 hash list sorted by hash, each line has the form HASH:COUNT.
+type SortedHashFile struct {
+	file *os.File
+	size int64
+}
+
+// OpenSortedHashFile opens a SHA-1 hash list downloaded in the HIBP format.
+func OpenSortedHashFile(path string) (*SortedHashFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", constants.ErrOpenBreachedPasswords, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("%v: %w", constants.ErrOpenBreachedPasswords, err)
+	}
+
+	return &SortedHashFile{
+		file: file,
+		size: info.Size(),
+	}, nil
+}
+
+// Contains reports whether the SHA-1 hash of the password is in the file.
+func (f *SortedHashFile) Contains(password string) (bool, error) {
+	target := passwordSHA1(password)
+
+	low, high := int64(0), f.size
+	for low < high {
+		middle := low + (high-low)/2
+
+		start, line, err := f.lineAt(middle)
+		if err != nil {
+			return false, err
+		}
+
+		if start >= high || line == "" {
+			high = middle
+			continue
+		}
+
+		switch strings.Compare(hashOf(line), target) {
+		case 0:
+			return true, nil
+		case -1:
+			low = start + int64(len(line)) + 1
+		default:
+			high = middle
+		}
+	}
+
+	return false, nil
+}
+
+// Close releases the hash list file.
+func (f *SortedHashFile) Close() error {
+	return f.file.Close()
+}
+
+// lineAt returns the first line starting at or after position.
+func (f *SortedHashFile) lineAt(position int64) (int64, string, error) {
+	start := position
+	if position > 0 {
+		start = position - 1
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(f.file, start, f.size-start))
+
+	if position > 0 {
+		skipped, err := reader.ReadString('\n')
+		if err == io.EOF {
+			return f.size, "", nil
+		}
+		if err != nil {
+			return 0, "", err
+		}
+		start += int64(len(skipped))
+	}
+
+	line, err := reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return 0, "", err
+	}
+
+	return start, strings.TrimSuffix(line, "\n"), nil
+}
+
+// BloomFilter checks passwords against an in-memory Bloom filter built from a
+// HIBP hash list, it may report false positives with the configured rate.
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes uint64
+}
+
+// NewBloomFilter creates an empty filter sized for entries with the false positive rate.
+func NewBloomFilter(entries uint64, falsePositiveRate float64) *BloomFilter {
+	entries = max(entries, 1)
+
+	size := uint64(math.Ceil(-float64(entries) * math.Log(falsePositiveRate) / (math.Ln2 * math.Ln2)))
+	size = max(size, 64)
+	hashes := uint64(math.Max(1, math.Round(float64(size)/float64(entries)*math.Ln2)))
+
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// BuildBloomFilter reads a HIBP hash list twice, first to size the filter and then to fill it.
+func BuildBloomFilter(path string, falsePositiveRate float64) (*BloomFilter, error) {
+	entries := uint64(0)
+	if err := scanHashList(path, func([]byte) { entries++ }); err != nil {
+		return nil, err
+	}
+
+	filter := NewBloomFilter(entries, falsePositiveRate)
+	if err := scanHashList(path, filter.add); err != nil {
+		return nil, err
+	}
+
+	return filter, nil
+}
+
+// Contains reports whether the SHA-1 hash of the password was probably added to the filter.
+func (b *BloomFilter) Contains(password string) (bool, error) {
+	digest := sha1.Sum([]byte(password))
+
+	for _, index := range b.indexes(digest[:]) {
+		if b.bits[index/64]&(1<<(index%64)) == 0 {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func (b *BloomFilter) add(digest []byte) {
+	for _, index := range b.indexes(digest) {
+		b.bits[index/64] |= 1 << (index % 64)
+	}
+}
+
+// indexes derives the bit positions with double hashing over the SHA-1 digest.
+func (b *BloomFilter) indexes(digest []byte) []uint64 {
+	first := binary.BigEndian.Uint64(digest[0:8])
+	second := binary.BigEndian.Uint64(digest[8:16]) | 1
+
+	indexes := make([]uint64, b.hashes)
+	for i := range indexes {
+		indexes[i] = (first + uint64(i)*second) % b.size
+	}
+
+	return indexes
+}
+
+func scanHashList(path string, add func(digest []byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%v: %w", constants.ErrOpenBreachedPasswords, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) < sha1HexLength {
+			continue
+		}
+
+		digest := make([]byte, sha1.Size)
+		if _, err := hex.Decode(digest, line[:sha1HexLength]); err != nil {
+			return fmt.Errorf("%v: %w", constants.ErrInvalidBreachedPasswords, err)
+		}
+
+		add(digest)
+	}
+
+	return scanner.Err()
+}
+
+func passwordSHA1(password string) string {
+	digest := sha1.Sum([]byte(password))
+
+	return strings.ToUpper(hex.EncodeToString(digest[:]))
+}
+
+func hashOf(line string) string {
+	hash, _, _ := strings.Cut(strings.TrimSpace(line), ":")
+
+	return strings.ToUpper(hash)
+}
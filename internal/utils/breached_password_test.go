@@ -0,0 +1,90 @@
+package utils_test
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+var breachedPasswords = []string{"Password1!", "Qwerty123*", "Welcome2024$"}
+
+func writeHashList(t *testing.T) string {
+	t.Helper()
+
+	var lines []string
+	for i := 0; i < 500; i++ {
+		lines = append(lines, hashLine(fmt.Sprintf("filler-%d", i), i))
+	}
+	for i, password := range breachedPasswords {
+		lines = append(lines, hashLine(password, i+1))
+	}
+	sort.Strings(lines)
+
+	path := filepath.Join(t.TempDir(), "pwned-passwords-sha1-ordered-by-hash.txt")
+	err := os.WriteFile(path, []byte(strings.Join(lines, "\r\n")+"\r\n"), 0600)
+	assert.NoError(t, err)
+
+	return path
+}
+
+func hashLine(password string, count int) string {
+	digest := sha1.Sum([]byte(password))
+
+	return fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(digest[:])), count)
+}
+
+func TestBreachedPasswordCheckers(t *testing.T) {
+	path := writeHashList(t)
+
+	hashFile, err := utils.OpenSortedHashFile(path)
+	assert.NoError(t, err)
+	defer hashFile.Close()
+
+	filter, err := utils.BuildBloomFilter(path, 0.0001)
+	assert.NoError(t, err)
+
+	for name, checker := range map[string]utils.BreachedPasswordChecker{"file": hashFile, "bloom": filter} {
+		t.Run(name, func(t *testing.T) {
+			for _, password := range append(breachedPasswords, "filler-0", "filler-499") {
+				breached, err := checker.Contains(password)
+				assert.NoError(t, err)
+				assert.True(t, breached, password)
+			}
+
+			for _, password := range []string{"Test123*", "filler-500", ""} {
+				breached, err := checker.Contains(password)
+				assert.NoError(t, err)
+				assert.False(t, breached, password)
+			}
+		})
+	}
+}
+
+func TestValidatePasswordBreached(t *testing.T) {
+	hashFile, err := utils.OpenSortedHashFile(writeHashList(t))
+	assert.NoError(t, err)
+	defer hashFile.Close()
+
+	utils.SetBreachedPasswords(hashFile)
+	defer utils.SetBreachedPasswords(nil)
+
+	rejections := utils.BreachedPasswordRejections.Value()
+
+	assert.NoError(t, utils.ValidatePassword("Test123*"))
+
+	var policyErr *utils.PasswordPolicyError
+	assert.ErrorAs(t, utils.ValidatePassword("Password1!"), &policyErr)
+	assert.Equal(t, []string{"must not appear in a known data breach"}, policyErr.Violations)
+	assert.Equal(t, rejections+1, utils.BreachedPasswordRejections.Value())
+
+	assert.Error(t, utils.PasswordViolations("Password1!"))
+	assert.Equal(t, rejections+1, utils.BreachedPasswordRejections.Value())
+}
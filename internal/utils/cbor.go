@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+)
+
+// cborMaxDepth limits the nesting of decoded CBOR items, WebAuthn structures only use a few levels.
+const cborMaxDepth = 16
+
+// decodeCBOR decodes the first CBOR item of data and returns the remaining bytes. It
+// supports the subset used by WebAuthn: integers, byte and text strings, arrays, maps,
+// booleans and null. Map keys are int64 or string, integers are returned as int64.
+func decodeCBOR(data []byte) (interface{}, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, fmt.Errorf("%v: nesting too deep", constants.ErrInvalidCBOR)
+	}
+
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("%v: unexpected end of data", constants.ErrInvalidCBOR)
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, data[1:], nil
+		case 21:
+			return true, data[1:], nil
+		case 22:
+			return nil, data[1:], nil
+		default:
+			return nil, nil, fmt.Errorf("%v: unsupported simple value %d", constants.ErrInvalidCBOR, info)
+		}
+	}
+
+	argument, rest, err := cborArgument(info, data[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0:
+		if argument > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("%v: integer overflow", constants.ErrInvalidCBOR)
+		}
+		return int64(argument), rest, nil
+	case 1:
+		if argument > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("%v: integer overflow", constants.ErrInvalidCBOR)
+		}
+		return -1 - int64(argument), rest, nil
+	case 2, 3:
+		if argument > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%v: string longer than data", constants.ErrInvalidCBOR)
+		}
+		value := rest[:argument]
+		if major == 3 {
+			return string(value), rest[argument:], nil
+		}
+		return append([]byte(nil), value...), rest[argument:], nil
+	case 4:
+		if argument > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%v: array longer than data", constants.ErrInvalidCBOR)
+		}
+		items := make([]interface{}, 0, argument)
+		for i := uint64(0); i < argument; i++ {
+			var item interface{}
+			item, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, rest, nil
+	case 5:
+		if argument > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%v: map longer than data", constants.ErrInvalidCBOR)
+		}
+		items := make(map[interface{}]interface{}, argument)
+		for i := uint64(0); i < argument; i++ {
+			var key, value interface{}
+			key, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("%v: unsupported map key", constants.ErrInvalidCBOR)
+			}
+			if _, duplicated := items[key]; duplicated {
+				return nil, nil, fmt.Errorf("%v: duplicated map key", constants.ErrInvalidCBOR)
+			}
+			value, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items[key] = value
+		}
+		return items, rest, nil
+	default:
+		return nil, nil, fmt.Errorf("%v: unsupported major type %d", constants.ErrInvalidCBOR, major)
+	}
+}
+
+// cborArgument reads the argument of an item header, indefinite lengths are not supported.
+func cborArgument(info byte, data []byte) (uint64, []byte, error) {
+	size := 0
+
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24:
+		size = 1
+	case info == 25:
+		size = 2
+	case info == 26:
+		size = 4
+	case info == 27:
+		size = 8
+	default:
+		return 0, nil, fmt.Errorf("%v: unsupported length", constants.ErrInvalidCBOR)
+	}
+
+	if len(data) < size {
+		return 0, nil, fmt.Errorf("%v: unexpected end of data", constants.ErrInvalidCBOR)
+	}
+
+	var argument uint64
+	switch size {
+	case 1:
+		argument = uint64(data[0])
+	case 2:
+		argument = uint64(binary.BigEndian.Uint16(data))
+	case 4:
+		argument = uint64(binary.BigEndian.Uint32(data))
+	case 8:
+		argument = binary.BigEndian.Uint64(data)
+	}
+
+	return argument, data[size:], nil
+}
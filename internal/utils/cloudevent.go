@@ -0,0 +1,23 @@
+package utils
+
+import "github.com/CNMoreno/cnm-proyect-go/internal/domain"
+
+// NewCloudEvent wraps a domain event of the outbox in a CloudEvent from source, the event ID is
+// kept so consumers can skip duplicates. Unknown types are published unchanged.
+func NewCloudEvent(event *domain.OutboxEvent, source string) *domain.CloudEvent {
+	eventType, found := domain.CloudEventTypes[event.Type]
+	if !found {
+		eventType = event.Type
+	}
+
+	return &domain.CloudEvent{
+		SpecVersion:     domain.CloudEventsSpecVersion,
+		ID:              event.ID,
+		Source:          source,
+		Type:            eventType,
+		Subject:         event.AggregateID,
+		Time:            event.OccurredAt.UTC(),
+		DataContentType: "application/json",
+		Data:            event.Data,
+	}
+}
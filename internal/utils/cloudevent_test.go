@@ -0,0 +1,65 @@
+package utils_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewCloudEvent(t *testing.T) {
+	occurredAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.FixedZone("COT", -5*3600))
+
+	testCases := []struct {
+		name      string
+		eventType string
+		want      string
+	}{
+		{
+			name:      "should map created event to the CloudEvent type",
+			eventType: domain.EventUserCreated,
+			want:      "com.cnmoreno.user.created",
+		},
+		{
+			name:      "should map updated event to the CloudEvent type",
+			eventType: domain.EventUserUpdated,
+			want:      "com.cnmoreno.user.updated",
+		},
+		{
+			name:      "should map deleted event to the CloudEvent type",
+			eventType: domain.EventUserDeleted,
+			want:      "com.cnmoreno.user.deleted",
+		},
+		{
+			name:      "should keep unknown event type",
+			eventType: "UserPromoted",
+			want:      "UserPromoted",
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			event := &domain.OutboxEvent{
+				ID:          "event1",
+				Type:        test.eventType,
+				AggregateID: "12345",
+				Data:        domain.UserEventData{ID: "12345", Email: "cristian@gmail.com"},
+				OccurredAt:  occurredAt,
+			}
+
+			cloudEvent := utils.NewCloudEvent(event, "/cnm-proyect-go/users")
+
+			assert.Equal(t, "1.0", cloudEvent.SpecVersion)
+			assert.Equal(t, "event1", cloudEvent.ID)
+			assert.Equal(t, "/cnm-proyect-go/users", cloudEvent.Source)
+			assert.Equal(t, test.want, cloudEvent.Type)
+			assert.Equal(t, "12345", cloudEvent.Subject)
+			assert.Equal(t, time.UTC, cloudEvent.Time.Location())
+			assert.True(t, occurredAt.Equal(cloudEvent.Time))
+			assert.Equal(t, "application/json", cloudEvent.DataContentType)
+			assert.Equal(t, event.Data, cloudEvent.Data)
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/CNMoreno/cnm-proyect-go/internal/domain"
+)
+
+// JWTAlgRS256 is the algorithm of the signed tokens, OpenID Connect requires every provider to support it.
+const JWTAlgRS256 = "RS256"
+
+// ErrInvalidJWT is returned when a token is malformed or its signature is not valid.
+var ErrInvalidJWT = errors.New(constants.ErrInvalidJWT)
+
+// JWTHeader struct of the header of a signed token, KeyID selects the key of the JWKS that verifies it.
+type JWTHeader struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// SignJWT returns the claims signed with RS256 as a compact JWT, kid identifies the key.
+func SignJWT(claims interface{}, kid string, key *rsa.PrivateKey) (string, error) {
+	header, err := json.Marshal(JWTHeader{Algorithm: JWTAlgRS256, Type: "JWT", KeyID: kid})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := sha256.Sum256([]byte(signingInput))
+
+	signature, err := rsa.SignPKCS1v15(nil, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyJWT checks the RS256 signature of a compact JWT with the key returned by keyFunc for its
+// key ID and decodes the payload into claims. Expiration and audience are checked by the caller.
+func VerifyJWT(token string, keyFunc func(kid string) (*rsa.PublicKey, bool), claims interface{}) error {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("%w: token must have three parts", ErrInvalidJWT)
+	}
+
+	var header JWTHeader
+	if err := decodeJWTPart(parts[0], &header); err != nil {
+		return err
+	}
+
+	if header.Algorithm != JWTAlgRS256 {
+		return fmt.Errorf("%w: unsupported algorithm %v", ErrInvalidJWT, header.Algorithm)
+	}
+
+	key, ok := keyFunc(header.KeyID)
+	if !ok {
+		return fmt.Errorf("%w: unknown key %v", ErrInvalidJWT, header.KeyID)
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJWT, err)
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJWT, err)
+	}
+
+	return decodeJWTPart(parts[1], claims)
+}
+
+// AccessTokenHash returns the at_hash claim of an ID token, the base64url encoded left half of
+// the SHA-256 hash of the access token.
+func AccessTokenHash(accessToken string) string {
+	sum := sha256.Sum256([]byte(accessToken))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:len(sum)/2])
+}
+
+// RSAPublicJWK returns the JSON Web Key that publishes an RS256 verification key.
+func RSAPublicJWK(kid string, key *rsa.PublicKey) domain.JWK {
+	return domain.JWK{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: JWTAlgRS256,
+		KeyID:     kid,
+		Modulus:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// ParseRSAPublicJWK returns the RSA key published in a JSON Web Key.
+func ParseRSAPublicJWK(jwk domain.JWK) (*rsa.PublicKey, error) {
+	if jwk.KeyType != "RSA" {
+		return nil, fmt.Errorf("%w: key type must be RSA", ErrInvalidJWT)
+	}
+
+	modulus, err := base64.RawURLEncoding.DecodeString(jwk.Modulus)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJWT, err)
+	}
+
+	exponent, err := base64.RawURLEncoding.DecodeString(jwk.Exponent)
+	if err != nil || len(exponent) > 4 {
+		return nil, fmt.Errorf("%w: invalid exponent", ErrInvalidJWT)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}, nil
+}
+
+func decodeJWTPart(part string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJWT, err)
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidJWT, err)
+	}
+
+	return nil
+}
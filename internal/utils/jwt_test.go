@@ -0,0 +1,53 @@
+package utils_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"strings"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClaims struct {
+	Subject string `json:"sub"`
+}
+
+func TestSignAndVerifyJWT(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+
+	jwk := utils.RSAPublicJWK("kid-1", &key.PublicKey)
+	publicKey, err := utils.ParseRSAPublicJWK(jwk)
+	assert.NoError(t, err)
+	assert.True(t, key.PublicKey.Equal(publicKey))
+
+	keyFunc := func(kid string) (*rsa.PublicKey, bool) {
+		return publicKey, kid == "kid-1"
+	}
+
+	token, err := utils.SignJWT(testClaims{Subject: "12345"}, "kid-1", key)
+	assert.NoError(t, err)
+
+	var claims testClaims
+	assert.NoError(t, utils.VerifyJWT(token, keyFunc, &claims))
+	assert.Equal(t, "12345", claims.Subject)
+
+	parts := strings.Split(token, ".")
+	forged, err := utils.SignJWT(testClaims{Subject: "67890"}, "kid-1", key)
+	assert.NoError(t, err)
+	tampered := parts[0] + "." + strings.Split(forged, ".")[1] + "." + parts[2]
+	assert.ErrorIs(t, utils.VerifyJWT(tampered, keyFunc, &claims), utils.ErrInvalidJWT)
+
+	otherKey, err := utils.SignJWT(testClaims{Subject: "12345"}, "kid-2", key)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, utils.VerifyJWT(otherKey, keyFunc, &claims), utils.ErrInvalidJWT)
+
+	assert.ErrorIs(t, utils.VerifyJWT("not-a-token", keyFunc, &claims), utils.ErrInvalidJWT)
+}
+
+func TestAccessTokenHash(t *testing.T) {
+	// Example of OpenID Connect Core 1.0 appendix A.3.
+	assert.Equal(t, "77QmUPtjPfzWtF2AnpK9RQ", utils.AccessTokenHash("jHkWEdUXMU1BwAsC4vtUsZwnNvTIxEl0z9K3vx5KF0Y"))
+}
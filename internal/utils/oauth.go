@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"net"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// PKCEMethodS256 is the only code challenge method accepted, with plain anyone observing
+// the authorization request could redeem the code.
+const PKCEMethodS256 = "S256"
+
+var (
+	pkceVerifierPattern  = regexp.MustCompile(`^[A-Za-z0-9._~-]{43,128}$`)
+	pkceChallengePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{43}$`)
+)
+
+// ValidPKCEVerifier reports whether verifier has the length and characters required by RFC 7636.
+func ValidPKCEVerifier(verifier string) bool {
+	return pkceVerifierPattern.MatchString(verifier)
+}
+
+// ValidPKCEChallenge reports whether challenge is a base64url encoded SHA-256 hash.
+func ValidPKCEChallenge(challenge string) bool {
+	return pkceChallengePattern.MatchString(challenge)
+}
+
+// PKCEChallenge returns the S256 code challenge of verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// VerifyPKCE reports whether verifier matches the S256 challenge of the authorization request.
+func VerifyPKCE(verifier, challenge string) bool {
+	if !ValidPKCEVerifier(verifier) {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(PKCEChallenge(verifier)), []byte(challenge)) == 1
+}
+
+// ValidRedirectURI reports whether uri can be registered as redirect URI of an OAuth client.
+// It must be absolute without fragment, http is only allowed on loopback hosts and native apps
+// use private-use schemes in reverse domain notation like com.example.app:/callback.
+func ValidRedirectURI(uri string) bool {
+	parsed, err := url.Parse(uri)
+	if err != nil || parsed.Fragment != "" || strings.Contains(uri, "#") {
+		return false
+	}
+
+	switch parsed.Scheme {
+	case "https":
+		return parsed.Host != ""
+	case "http":
+		return isLoopbackHost(parsed.Hostname())
+	case "":
+		return false
+	default:
+		return strings.Contains(parsed.Scheme, ".")
+	}
+}
+
+// ParseScope splits a space delimited OAuth scope, repeated values are removed.
+func ParseScope(scope string) []string {
+	scopes := []string{}
+	seen := map[string]bool{}
+
+	for _, value := range strings.Fields(scope) {
+		if !seen[value] {
+			seen[value] = true
+			scopes = append(scopes, value)
+		}
+	}
+
+	return scopes
+}
+
+func isLoopbackHost(host string) bool {
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,50 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyPKCE(t *testing.T) {
+	// Example of RFC 7636 appendix B.
+	verifier := "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	challenge := "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+
+	assert.Equal(t, challenge, utils.PKCEChallenge(verifier))
+	assert.True(t, utils.ValidPKCEChallenge(challenge))
+	assert.True(t, utils.VerifyPKCE(verifier, challenge))
+	assert.False(t, utils.VerifyPKCE(verifier+"a", challenge))
+	assert.False(t, utils.VerifyPKCE("short", utils.PKCEChallenge("short")))
+	assert.False(t, utils.VerifyPKCE(strings.Repeat("a", 129), utils.PKCEChallenge(strings.Repeat("a", 129))))
+	assert.False(t, utils.ValidPKCEChallenge(verifier+"="))
+}
+
+func TestValidRedirectURI(t *testing.T) {
+	testCases := map[string]bool{
+		"https://app.example.com/callback":      true,
+		"https://app.example.com/callback?a=1":  true,
+		"http://localhost:3000/callback":        true,
+		"http://127.0.0.1:3000/callback":        true,
+		"http://[::1]/callback":                 true,
+		"com.example.app:/callback":             true,
+		"http://app.example.com/callback":       false,
+		"https://app.example.com/callback#page": false,
+		"/callback":                             false,
+		"javascript:alert(1)":                   false,
+		"https:///callback":                     false,
+	}
+
+	for uri, valid := range testCases {
+		t.Run(uri, func(t *testing.T) {
+			assert.Equal(t, valid, utils.ValidRedirectURI(uri))
+		})
+	}
+}
+
+func TestParseScope(t *testing.T) {
+	assert.Equal(t, []string{"openid", "profile"}, utils.ParseScope(" openid  profile openid "))
+	assert.Empty(t, utils.ParseScope(""))
+}
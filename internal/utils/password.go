@@ -1,38 +1,161 @@
 package utils
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
 	"log"
-	"regexp"
+	"strings"
 
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
 	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
 	"github.com/gin-gonic/gin/binding"
 	"github.com/go-playground/validator/v10"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const pepperPrefix = "$pepper$id="
+
+// DefaultAppCrypto hashes passwords with the current algorithm and verifies
+// hashes produced by any of the legacy algorithms.
 type DefaultAppCrypto struct {
-	crypto repository.AppCrypto
+	crypto   repository.AppCrypto
+	cost     int
+	legacy   []repository.AppCrypto
+	pepperID string
+	peppers  map[string][]byte
 }
 
-func NewHashPassword(crypto repository.AppCrypto) *DefaultAppCrypto {
+// NewHashPassword creates a password hasher with bcrypt's default cost.
+// Legacy algorithms are only used to verify hashes stored before a migration.
+func NewHashPassword(crypto repository.AppCrypto, legacy ...repository.AppCrypto) *DefaultAppCrypto {
 	return &DefaultAppCrypto{
 		crypto: crypto,
+		cost:   bcrypt.DefaultCost,
+		legacy: legacy,
 	}
 }
 
-// HashPassword generate a hash for password with bcrypt.
+// WithCost sets the cost used to generate new hashes.
+func (a *DefaultAppCrypto) WithCost(cost int) *DefaultAppCrypto {
+	a.cost = cost
+	return a
+}
+
+// WithPepper enables HMAC peppering with the pepper identified by id, the other
+// peppers are only used to verify hashes generated before a rotation.
+func (a *DefaultAppCrypto) WithPepper(id string, peppers map[string][]byte) *DefaultAppCrypto {
+	a.pepperID = id
+	a.peppers = peppers
+	return a
+}
+
+// HashPassword generate a hash for password with the current algorithm,
+// when a pepper is configured its key identifier is stored with the hash.
 func (a DefaultAppCrypto) HashPassword(password string) (string, error) {
-	bytes, err := a.crypto.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	return string(bytes), err
+	peppered, err := a.pepper(a.pepperID, password)
+	if err != nil {
+		return "", err
+	}
+
+	bytes, err := a.crypto.GenerateFromPassword(peppered, a.cost)
+	if err != nil || a.pepperID == "" {
+		return string(bytes), err
+	}
+
+	return pepperPrefix + a.pepperID + string(bytes), nil
 }
 
 // CheckPasswordHash compare plane password with his hash.
 func (a DefaultAppCrypto) CheckPasswordHash(password, hash string) bool {
-	err := a.crypto.CompareHashAndPassword([]byte(hash), []byte(password))
+	pepperID, hash := splitPepperID(hash)
+
+	peppered, err := a.pepper(pepperID, password)
+	if err != nil {
+		return false
+	}
+
+	err = a.cryptoFor(hash).CompareHashAndPassword([]byte(hash), peppered)
 
 	return err == nil
 }
 
+// NeedsRehash reports whether the hash was generated with an outdated algorithm, cost or pepper.
+func (a DefaultAppCrypto) NeedsRehash(hash string) bool {
+	pepperID, hash := splitPepperID(hash)
+	if pepperID != a.pepperID {
+		return true
+	}
+
+	if !a.crypto.Identify([]byte(hash)) {
+		return true
+	}
+
+	return a.crypto.NeedsRehash([]byte(hash), a.cost)
+}
+
+// VerifyPassword compare plane password with his hash, when the hash is outdated
+// it returns a new hash generated with the current algorithm and cost.
+func (a DefaultAppCrypto) VerifyPassword(password, hash string) (bool, string, error) {
+	if !a.CheckPasswordHash(password, hash) {
+		return false, "", nil
+	}
+
+	if !a.NeedsRehash(hash) {
+		return true, "", nil
+	}
+
+	newHash, err := a.HashPassword(password)
+	if err != nil {
+		return true, "", err
+	}
+
+	return true, newHash, nil
+}
+
+func (a DefaultAppCrypto) pepper(id, password string) ([]byte, error) {
+	if id == "" {
+		return []byte(password), nil
+	}
+
+	key, ok := a.peppers[id]
+	if !ok {
+		return nil, fmt.Errorf("%v: %v", constants.ErrUnknownPepper, id)
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(password))
+
+	return []byte(base64.RawStdEncoding.EncodeToString(mac.Sum(nil))), nil
+}
+
+// splitPepperID separates the pepper key identifier from a hash like $pepper$id=v1$2a$10$...
+func splitPepperID(hash string) (string, string) {
+	if !strings.HasPrefix(hash, pepperPrefix) {
+		return "", hash
+	}
+
+	rest := strings.TrimPrefix(hash, pepperPrefix)
+
+	index := strings.Index(rest, "$")
+	if index < 0 {
+		return "", hash
+	}
+
+	return rest[:index], rest[index:]
+}
+
+func (a DefaultAppCrypto) cryptoFor(hash string) repository.AppCrypto {
+	for _, crypto := range a.legacy {
+		if crypto.Identify([]byte(hash)) {
+			return crypto
+		}
+	}
+
+	return a.crypto
+}
+
 // RegisterCustomValidators handles to register validation.
 func RegisterCustomValidators(validate *validator.Validate) {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
@@ -41,14 +164,3 @@ func RegisterCustomValidators(validate *validator.Validate) {
 		}
 	}
 }
-
-func passwordValidator(f1 validator.FieldLevel) bool {
-	password := f1.Field().String()
-
-	hasLowerCase := regexp.MustCompile(`[a-z]`).MatchString(password)
-	hasUpperCase := regexp.MustCompile(`[A-Z]`).MatchString(password)
-	hasNumber := regexp.MustCompile(`\d`).MatchString(password)
-	hasSpecialCharacter := regexp.MustCompile(`[@$!%*?&]`).MatchString(password)
-
-	return hasLowerCase && hasUpperCase && hasNumber && hasSpecialCharacter
-}
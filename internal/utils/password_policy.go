@@ -0,0 +1,248 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+	"github.com/go-playground/validator/v10"
+)
+
+// Password policy defaults, MaxLength is counted in bytes to match the bcrypt input limit.
+const (
+	DefaultPasswordMinLength   = 8
+	DefaultPasswordMaxLength   = 72
+	DefaultPasswordMaxRepeated = 3
+	DefaultPasswordSymbols     = "!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~"
+	minIdentityLength          = 3
+)
+
+// PasswordPolicy rules applied to new passwords, MinLength counts characters and MaxLength
+// counts bytes, zero MaxLength or MaxRepeated disables the rule.
+type PasswordPolicy struct {
+	MinLength        int
+	MaxLength        int
+	RequireLower     bool
+	RequireUpper     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	Symbols          string
+	DisallowIdentity bool
+	MaxRepeated      int
+}
+
+// PasswordPolicyError lists the rules of the policy that a password does not satisfy.
+type PasswordPolicyError struct {
+	Violations []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return fmt.Sprintf("%v: %v", constants.ErrPasswordPolicy, strings.Join(e.Violations, ", "))
+}
+
+var (
+	passwordPolicy    = DefaultPasswordPolicy()
+	breachedPasswords BreachedPasswordChecker
+)
+
+// DefaultPasswordPolicy returns the policy used when no configuration is provided.
+func DefaultPasswordPolicy() PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:        DefaultPasswordMinLength,
+		MaxLength:        DefaultPasswordMaxLength,
+		RequireLower:     true,
+		RequireUpper:     true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		Symbols:          DefaultPasswordSymbols,
+		DisallowIdentity: true,
+		MaxRepeated:      DefaultPasswordMaxRepeated,
+	}
+}
+
+// SetPasswordPolicy replaces the policy used by the password validator.
+func SetPasswordPolicy(policy PasswordPolicy) {
+	passwordPolicy = policy
+}
+
+// SetBreachedPasswords sets the breach corpus used to reject known passwords, nil disables the check.
+func SetBreachedPasswords(checker BreachedPasswordChecker) {
+	breachedPasswords = checker
+}
+
+// ValidatePassword checks the password against the configured policy and breach corpus,
+// identities are the userName and email of the owner which can not be part of the password.
+func ValidatePassword(password string, identities ...string) error {
+	breached, err := checkPassword(password, identities)
+	if breached {
+		BreachedPasswordRejections.Add(1)
+	}
+
+	return err
+}
+
+// PasswordViolations returns the same error as ValidatePassword without updating
+// the metrics, it is used to explain a password rejected during binding.
+func PasswordViolations(password string, identities ...string) error {
+	_, err := checkPassword(password, identities)
+
+	return err
+}
+
+func checkPassword(password string, identities []string) (bool, error) {
+	err := passwordPolicy.Validate(password, identities...)
+
+	if breachedPasswords == nil {
+		return false, err
+	}
+
+	breached, checkErr := breachedPasswords.Contains(password)
+	if checkErr != nil {
+		log.Printf("%v: %v", constants.ErrCheckBreachedPasswords, checkErr)
+		return false, err
+	}
+
+	if !breached {
+		return false, err
+	}
+
+	var policyErr *PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		policyErr = &PasswordPolicyError{}
+	}
+	policyErr.Violations = append(policyErr.Violations, constants.RulePasswordBreached)
+
+	return true, policyErr
+}
+
+// Validate checks the password against the policy and returns a *PasswordPolicyError with every failed rule.
+func (p PasswordPolicy) Validate(password string, identities ...string) error {
+	var violations []string
+
+	if len([]rune(password)) < p.MinLength {
+		violations = append(violations, fmt.Sprintf(constants.RulePasswordMinLength, p.MinLength))
+	}
+
+	if p.MaxLength > 0 && len(password) > p.MaxLength {
+		violations = append(violations, fmt.Sprintf(constants.RulePasswordMaxLength, p.MaxLength))
+	}
+
+	violations = append(violations, p.characterViolations(password)...)
+
+	if p.MaxRepeated > 0 && maxRepeated(password) > p.MaxRepeated {
+		violations = append(violations, fmt.Sprintf(constants.RulePasswordMaxRepeated, p.MaxRepeated))
+	}
+
+	if p.DisallowIdentity && containsIdentity(password, identities) {
+		violations = append(violations, constants.RulePasswordIdentity)
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+
+	return nil
+}
+
+func (p PasswordPolicy) characterViolations(password string) []string {
+	var hasLower, hasUpper, hasDigit, hasSymbol, hasInvalid bool
+
+	for _, char := range password {
+		switch {
+		case unicode.IsLower(char):
+			hasLower = true
+		case unicode.IsUpper(char):
+			hasUpper = true
+		case unicode.IsDigit(char):
+			hasDigit = true
+		case strings.ContainsRune(p.Symbols, char):
+			hasSymbol = true
+		case !unicode.IsLetter(char):
+			hasInvalid = true
+		}
+	}
+
+	var violations []string
+
+	if p.RequireLower && !hasLower {
+		violations = append(violations, constants.RulePasswordLower)
+	}
+
+	if p.RequireUpper && !hasUpper {
+		violations = append(violations, constants.RulePasswordUpper)
+	}
+
+	if p.RequireDigit && !hasDigit {
+		violations = append(violations, constants.RulePasswordDigit)
+	}
+
+	if p.RequireSymbol && !hasSymbol {
+		violations = append(violations, fmt.Sprintf(constants.RulePasswordSymbol, p.Symbols))
+	}
+
+	if hasInvalid {
+		violations = append(violations, fmt.Sprintf(constants.RulePasswordAllowedSymbols, p.Symbols))
+	}
+
+	return violations
+}
+
+func maxRepeated(password string) int {
+	longest, current := 0, 0
+
+	var previous rune
+	for i, char := range []rune(password) {
+		if i > 0 && char == previous {
+			current++
+		} else {
+			current = 1
+		}
+
+		previous = char
+		longest = max(longest, current)
+	}
+
+	return longest
+}
+
+func containsIdentity(password string, identities []string) bool {
+	lowerPassword := strings.ToLower(password)
+
+	for _, identity := range identities {
+		identity = strings.ToLower(identity)
+
+		candidates := []string{identity}
+		if local, _, found := strings.Cut(identity, "@"); found {
+			candidates = append(candidates, local)
+		}
+
+		for _, candidate := range candidates {
+			if len(candidate) >= minIdentityLength && strings.Contains(lowerPassword, candidate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// passwordValidator applies the password policy in struct validation, userName
+// and email fields of the same struct are used as identities.
+func passwordValidator(fl validator.FieldLevel) bool {
+	var identities []string
+
+	parent := reflect.Indirect(fl.Parent())
+	if parent.Kind() == reflect.Struct {
+		for _, name := range []string{"UserName", "Email"} {
+			if field := parent.FieldByName(name); field.IsValid() && field.Kind() == reflect.String {
+				identities = append(identities, field.String())
+			}
+		}
+	}
+
+	return ValidatePassword(fl.Field().String(), identities...) == nil
+}
@@ -0,0 +1,88 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type valuesPolicyTestCases struct {
+	name       string
+	policy     utils.PasswordPolicy
+	password   string
+	identities []string
+	violations []string
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	lengthOnly := utils.PasswordPolicy{MinLength: 4, MaxLength: 6, Symbols: "#"}
+
+	testCases := []valuesPolicyTestCases{
+		{
+			name:       "should accept password when satisfies default policy",
+			policy:     utils.DefaultPasswordPolicy(),
+			password:   "Test123*",
+			identities: []string{"cristian", "cristian@gmail.com"},
+		},
+		{
+			name:     "should list every character class missing",
+			policy:   utils.DefaultPasswordPolicy(),
+			password: "        ",
+			violations: []string{
+				"must contain a lowercase letter",
+				"must contain an uppercase letter",
+				"must contain a number",
+				"must contain one of the symbols " + utils.DefaultPasswordSymbols,
+				"must only contain letters, numbers and the symbols " + utils.DefaultPasswordSymbols,
+				"must not repeat the same character more than 3 times in a row",
+			},
+		},
+		{
+			name:       "should reject password containing userName or email",
+			policy:     utils.DefaultPasswordPolicy(),
+			password:   "Cristian123*",
+			identities: []string{"other", "cristian@gmail.com"},
+			violations: []string{"must not contain the userName or email"},
+		},
+		{
+			name:       "should reject password shorter than min length",
+			policy:     lengthOnly,
+			password:   "abc",
+			violations: []string{"must be at least 4 characters long"},
+		},
+		{
+			name:       "should reject password longer than max length",
+			policy:     lengthOnly,
+			password:   "abcdefg",
+			violations: []string{"must be at most 6 bytes long"},
+		},
+		{
+			name:       "should count max length in bytes",
+			policy:     lengthOnly,
+			password:   "ñañaña",
+			violations: []string{"must be at most 6 bytes long"},
+		},
+		{
+			name:       "should reject symbols outside the allowed set",
+			policy:     lengthOnly,
+			password:   "ab#c*",
+			violations: []string{"must only contain letters, numbers and the symbols #"},
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			err := test.policy.Validate(test.password, test.identities...)
+
+			if test.violations == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var policyErr *utils.PasswordPolicyError
+			assert.ErrorAs(t, err, &policyErr)
+			assert.Equal(t, test.violations, policyErr.Violations)
+		})
+	}
+}
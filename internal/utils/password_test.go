@@ -2,12 +2,15 @@ package utils_test
 
 import (
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/CNMoreno/cnm-proyect-go/internal/repository"
 	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
 	mocks "github.com/CNMoreno/cnm-proyect-go/mocks/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type valuesTestCases struct {
@@ -89,3 +92,116 @@ func TestCheckPasswordHash(t *testing.T) {
 		})
 	}
 }
+
+type valuesVerifyTestCases struct {
+	name         string
+	hashPassword string
+	isLegacy     bool
+	needsRehash  bool
+	errCompare   error
+	errGenerate  error
+	match        bool
+	newHash      string
+	isError      bool
+}
+
+func TestVerifyPassword(t *testing.T) {
+	testCases := []valuesVerifyTestCases{
+		{
+			name:         "should verify password without rehash when hash is current",
+			hashPassword: "currentHash",
+			match:        true,
+		},
+		{
+			name:         "should rehash password when cost is outdated",
+			hashPassword: "outdatedHash",
+			needsRehash:  true,
+			match:        true,
+			newHash:      "newHash",
+		},
+		{
+			name:         "should rehash password when hash uses legacy algorithm",
+			hashPassword: "legacyHash",
+			isLegacy:     true,
+			match:        true,
+			newHash:      "newHash",
+		},
+		{
+			name:         "should return false when password is different",
+			hashPassword: "currentHash",
+			errCompare:   errors.New("check password Error"),
+		},
+		{
+			name:         "should return an error when rehash fails",
+			hashPassword: "legacyHash",
+			isLegacy:     true,
+			match:        true,
+			errGenerate:  errors.New("hashPassword Error"),
+			isError:      true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			mockCurrent := new(mocks.AppCrypto)
+			mockLegacy := new(mocks.AppCrypto)
+
+			compare := mockCurrent
+			if test.isLegacy {
+				compare = mockLegacy
+			}
+
+			mockLegacy.On("Identify", mock.Anything).Return(test.isLegacy)
+			mockCurrent.On("Identify", mock.Anything).Return(!test.isLegacy)
+			mockCurrent.On("NeedsRehash", mock.Anything, mock.Anything).Return(test.needsRehash)
+			compare.On("CompareHashAndPassword", mock.Anything, mock.Anything).Return(test.errCompare)
+			mockCurrent.On("GenerateFromPassword", mock.Anything, mock.Anything).Return([]byte("newHash"), test.errGenerate)
+
+			passwordUtils := utils.NewHashPassword(mockCurrent, mockLegacy)
+
+			match, newHash, err := passwordUtils.VerifyPassword("test", test.hashPassword)
+
+			assert.Equal(t, test.match, match)
+			assert.Equal(t, test.newHash, newHash)
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestPepperRotation(t *testing.T) {
+	crypto := repository.BcryptCrypto{}
+	peppers := map[string][]byte{
+		"v1": []byte("first-secret"),
+		"v2": []byte("second-secret"),
+	}
+
+	unpeppered, err := utils.NewHashPassword(crypto).WithCost(bcrypt.MinCost).HashPassword("Test123*")
+	assert.NoError(t, err)
+
+	oldHash, err := utils.NewHashPassword(crypto).WithCost(bcrypt.MinCost).WithPepper("v1", peppers).HashPassword("Test123*")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(oldHash, "$pepper$id=v1$2a$"))
+
+	passwordUtils := utils.NewHashPassword(crypto).WithCost(bcrypt.MinCost).WithPepper("v2", peppers)
+
+	assert.False(t, passwordUtils.CheckPasswordHash("Other123*", oldHash))
+
+	for _, hash := range []string{unpeppered, oldHash} {
+		match, newHash, err := passwordUtils.VerifyPassword("Test123*", hash)
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.True(t, strings.HasPrefix(newHash, "$pepper$id=v2$2a$"))
+
+		match, rehash, err := passwordUtils.VerifyPassword("Test123*", newHash)
+		assert.NoError(t, err)
+		assert.True(t, match)
+		assert.Empty(t, rehash)
+	}
+
+	withoutOldPepper := utils.NewHashPassword(crypto).WithPepper("v2", map[string][]byte{"v2": peppers["v2"]})
+	assert.False(t, withoutOldPepper.CheckPasswordHash("Test123*", oldHash))
+}
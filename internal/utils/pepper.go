@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+)
+
+// LoadPeppers reads the password peppers from a secret file with one id=secret per line.
+// It returns the peppers by id and the id of the last one, which is used for new hashes.
+func LoadPeppers(path string) (map[string][]byte, string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("%v: %w", constants.ErrReadPepperFile, err)
+	}
+
+	peppers := map[string][]byte{}
+	lastID := ""
+
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		id, secret, found := strings.Cut(line, "=")
+		if !found || id == "" || secret == "" || strings.Contains(id, "$") {
+			return nil, "", fmt.Errorf(constants.ErrInvalidPepperFile)
+		}
+
+		peppers[id] = []byte(secret)
+		lastID = id
+	}
+
+	if lastID == "" {
+		return nil, "", fmt.Errorf(constants.ErrInvalidPepperFile)
+	}
+
+	return peppers, lastID, nil
+}
@@ -0,0 +1,58 @@
+package utils_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type valuesPepperTestCases struct {
+	name      string
+	content   string
+	currentID string
+	isError   bool
+}
+
+func TestLoadPeppers(t *testing.T) {
+	testCases := []valuesPepperTestCases{
+		{
+			name:      "should load peppers and use the last one as current",
+			content:   "# rotated 2026-10\nv1=first-secret\n\nv2=second-secret\n",
+			currentID: "v2",
+		},
+		{
+			name:    "should throw an error when line is invalid",
+			content: "v1\n",
+			isError: true,
+		},
+		{
+			name:    "should throw an error when file has no peppers",
+			content: "# empty\n",
+			isError: true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "peppers")
+			err := os.WriteFile(path, []byte(test.content), 0600)
+			assert.NoError(t, err)
+
+			peppers, currentID, err := utils.LoadPeppers(path)
+
+			if test.isError {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, test.currentID, currentID)
+				assert.Equal(t, []byte("first-secret"), peppers["v1"])
+			}
+		})
+	}
+
+	_, _, err := utils.LoadPeppers(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
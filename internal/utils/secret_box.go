@@ -0,0 +1,89 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+)
+
+// SecretBox encrypts secrets stored in database with AES-256-GCM, the ciphertext
+// keeps the key identifier so keys can be rotated like password peppers.
+type SecretBox struct {
+	keyID string
+	keys  map[string]cipher.AEAD
+}
+
+// NewSecretBox creates a SecretBox encrypting with the key identified by keyID,
+// the AES keys are derived from the secrets with SHA-256.
+func NewSecretBox(keyID string, secrets map[string][]byte) (*SecretBox, error) {
+	if _, ok := secrets[keyID]; !ok {
+		return nil, fmt.Errorf("%v: %v", constants.ErrUnknownEncryptionKey, keyID)
+	}
+
+	keys := make(map[string]cipher.AEAD, len(secrets))
+	for id, secret := range secrets {
+		key := sha256.Sum256(secret)
+
+		block, err := aes.NewCipher(key[:])
+		if err != nil {
+			return nil, err
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+
+		keys[id] = aead
+	}
+
+	return &SecretBox{
+		keyID: keyID,
+		keys:  keys,
+	}, nil
+}
+
+// Encrypt returns the plaintext encrypted with the current key as id:base64(nonce|ciphertext).
+func (b *SecretBox) Encrypt(plaintext string) (string, error) {
+	aead := b.keys[b.keyID]
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(b.keyID))
+
+	return b.keyID + ":" + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of a value produced by Encrypt with any known key.
+func (b *SecretBox) Decrypt(value string) (string, error) {
+	keyID, encoded, found := strings.Cut(value, ":")
+	if !found {
+		return "", fmt.Errorf(constants.ErrDecryptSecret)
+	}
+
+	aead, ok := b.keys[keyID]
+	if !ok {
+		return "", fmt.Errorf("%v: %v", constants.ErrUnknownEncryptionKey, keyID)
+	}
+
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return "", fmt.Errorf(constants.ErrDecryptSecret)
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], []byte(keyID))
+	if err != nil {
+		return "", fmt.Errorf("%v: %w", constants.ErrDecryptSecret, err)
+	}
+
+	return string(plaintext), nil
+}
@@ -0,0 +1,47 @@
+package utils_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecretBox(t *testing.T) {
+	keys := map[string][]byte{
+		"v1": []byte("first-secret"),
+		"v2": []byte("second-secret"),
+	}
+
+	oldBox, err := utils.NewSecretBox("v1", keys)
+	assert.NoError(t, err)
+
+	box, err := utils.NewSecretBox("v2", keys)
+	assert.NoError(t, err)
+
+	encrypted, err := oldBox.Encrypt("JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, "v1:"))
+	assert.NotContains(t, encrypted, "JBSWY3DPEHPK3PXP")
+
+	plaintext, err := box.Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plaintext)
+
+	rotated, err := box.Encrypt("JBSWY3DPEHPK3PXP")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(rotated, "v2:"))
+
+	_, err = box.Decrypt("v1:" + strings.TrimPrefix(rotated, "v2:"))
+	assert.Error(t, err)
+
+	_, err = box.Decrypt("v3:" + strings.TrimPrefix(rotated, "v2:"))
+	assert.Error(t, err)
+
+	_, err = box.Decrypt("plaintext")
+	assert.Error(t, err)
+
+	_, err = utils.NewSecretBox("v3", keys)
+	assert.Error(t, err)
+}
@@ -0,0 +1,28 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerateToken(t *testing.T) {
+	token, hash, err := utils.GenerateToken()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, token)
+	assert.Equal(t, utils.HashToken(token), hash)
+	assert.NotContains(t, hash, token)
+
+	other, _, err := utils.GenerateToken()
+	assert.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestGenerateCode(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		code, err := utils.GenerateCode(6)
+		assert.NoError(t, err)
+		assert.Regexp(t, "^[0-9]{6}$", code)
+	}
+}
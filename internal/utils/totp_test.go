@@ -0,0 +1,91 @@
+package utils_test
+
+import (
+	"bytes"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret is the base32 encoding of the SHA-1 seed of RFC 6238 test vectors.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+type valuesTOTPTestCases struct {
+	name string
+	time int64
+	code string
+}
+
+func TestTOTPCode(t *testing.T) {
+	testCases := []valuesTOTPTestCases{
+		{name: "should match RFC 6238 vector at 59", time: 59, code: "287082"},
+		{name: "should match RFC 6238 vector at 1111111109", time: 1111111109, code: "081804"},
+		{name: "should match RFC 6238 vector at 1234567890", time: 1234567890, code: "005924"},
+		{name: "should match RFC 6238 vector at 2000000000", time: 2000000000, code: "279037"},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			code, err := utils.TOTPCode(rfcSecret, utils.TOTPStep(time.Unix(test.time, 0)))
+			assert.NoError(t, err)
+			assert.Equal(t, test.code, code)
+		})
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	step, ok := utils.ValidateTOTP(rfcSecret, "005924", now)
+	assert.True(t, ok)
+	assert.Equal(t, utils.TOTPStep(now), step)
+
+	previous, _ := utils.TOTPCode(rfcSecret, utils.TOTPStep(now)-1)
+	step, ok = utils.ValidateTOTP(rfcSecret, previous, now)
+	assert.True(t, ok)
+	assert.Equal(t, utils.TOTPStep(now)-1, step)
+
+	old, _ := utils.TOTPCode(rfcSecret, utils.TOTPStep(now)-2)
+	_, ok = utils.ValidateTOTP(rfcSecret, old, now)
+	assert.False(t, ok)
+
+	_, ok = utils.ValidateTOTP("not base32!", "005924", now)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	secret, err := utils.GenerateTOTPSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	uri, err := url.Parse(utils.TOTPURI("cnm", "cristian@gmail.com", secret))
+	assert.NoError(t, err)
+	assert.Equal(t, "otpauth", uri.Scheme)
+	assert.Equal(t, "totp", uri.Host)
+	assert.Equal(t, "/cnm:cristian@gmail.com", uri.Path)
+	assert.Equal(t, secret, uri.Query().Get("secret"))
+	assert.Equal(t, "cnm", uri.Query().Get("issuer"))
+
+	image, err := utils.QRCodePNG(uri.String())
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(image, []byte("\x89PNG\r\n\x1a\n")))
+}
+
+func TestGenerateRecoveryCodes(t *testing.T) {
+	codes, hashes, err := utils.GenerateRecoveryCodes(10)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+	assert.Len(t, hashes, 10)
+
+	format := regexp.MustCompile(`^[a-z2-7]{5}-[a-z2-7]{5}$`)
+	for i, code := range codes {
+		assert.Regexp(t, format, code)
+		assert.Equal(t, hashes[i], utils.HashRecoveryCode(code))
+	}
+
+	assert.Equal(t, utils.HashRecoveryCode("abcde-fghij"), utils.HashRecoveryCode(" ABCDEFGHIJ "))
+}
@@ -0,0 +1,59 @@
+package utils
+
+import "strings"
+
+type userAgentToken struct {
+	token string
+	name  string
+}
+
+// userAgentBrowsers are checked in order, most browsers also send the tokens of the ones they are based on.
+var userAgentBrowsers = []userAgentToken{
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"SamsungBrowser/", "Samsung Internet"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"CriOS/", "Chrome"},
+	{"Safari/", "Safari"},
+	{"curl/", "curl"},
+}
+
+// userAgentSystems are checked in order, iOS and Android also send the tokens of macOS and Linux.
+var userAgentSystems = []userAgentToken{
+	{"iPhone", "iOS"},
+	{"iPad", "iPadOS"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "macOS"},
+	{"CrOS", "ChromeOS"},
+	{"Linux", "Linux"},
+}
+
+// DeviceName describes the browser and operating system of a User-Agent header, like "Firefox on Linux".
+func DeviceName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browser := matchUserAgent(userAgent, userAgentBrowsers)
+	if browser == "" {
+		browser = "Unknown browser"
+	}
+
+	if system := matchUserAgent(userAgent, userAgentSystems); system != "" {
+		return browser + " on " + system
+	}
+
+	return browser
+}
+
+func matchUserAgent(userAgent string, tokens []userAgentToken) string {
+	for _, token := range tokens {
+		if strings.Contains(userAgent, token.token) {
+			return token.name
+		}
+	}
+
+	return ""
+}
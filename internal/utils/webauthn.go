@@ -0,0 +1,428 @@
+package utils
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/constants"
+)
+
+// COSE algorithms accepted for WebAuthn credentials.
+const (
+	COSEAlgES256 = -7
+	COSEAlgEdDSA = -8
+	COSEAlgRS256 = -257
+)
+
+// WebAuthn ceremony types of clientDataJSON.
+const (
+	WebAuthnCeremonyCreate = "webauthn.create"
+	WebAuthnCeremonyGet    = "webauthn.get"
+)
+
+const (
+	authDataMinLength              = 37
+	authDataFlagUserPresent        = 0x01
+	authDataFlagUserVerified       = 0x04
+	authDataFlagAttestedCredential = 0x40
+	packedAttestationOU            = "Authenticator Attestation"
+)
+
+// COSE key parameters of RFC 9052 and RFC 9053.
+const (
+	coseKeyType      = 1
+	coseKeyAlgorithm = 3
+	coseKeyCurve     = -1
+	coseKeyX         = -2
+	coseKeyY         = -3
+	coseKeyTypeOKP   = 1
+	coseKeyTypeEC2   = 2
+	coseKeyTypeRSA   = 3
+	coseCurveP256    = 1
+	coseCurveEd25519 = 6
+)
+
+// ErrInvalidWebAuthnResponse is returned when a WebAuthn response can not be verified.
+var ErrInvalidWebAuthnResponse = errors.New(constants.ErrInvalidWebAuthnResponse)
+
+// idFidoGenCeAAGUID is the certificate extension with the AAGUID of the authenticator model.
+var idFidoGenCeAAGUID = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+// WebAuthnConfig is the relying party configuration used to verify WebAuthn responses.
+type WebAuthnConfig struct {
+	RPID                    string
+	Origins                 []string
+	RequireUserVerification bool
+}
+
+// ClientData is the part of clientDataJSON checked by the relying party.
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// AttestedCredential is a credential verified in a registration ceremony.
+type AttestedCredential struct {
+	ID        []byte
+	PublicKey []byte
+	Algorithm int64
+	SignCount uint32
+	Format    string
+	AAGUID    []byte
+}
+
+type authenticatorData struct {
+	raw          []byte
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+// ParseClientData decodes clientDataJSON and checks the ceremony type and origin,
+// the challenge must be checked by the caller against the one it issued.
+func (c WebAuthnConfig) ParseClientData(clientDataJSON []byte, ceremony string) (*ClientData, error) {
+	var clientData ClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return nil, webAuthnError("client data is not valid JSON")
+	}
+
+	if clientData.Type != ceremony {
+		return nil, webAuthnError("unexpected ceremony type")
+	}
+
+	if !slices.Contains(c.Origins, clientData.Origin) || clientData.CrossOrigin {
+		return nil, webAuthnError("origin is not allowed")
+	}
+
+	if clientData.Challenge == "" {
+		return nil, webAuthnError("challenge is missing")
+	}
+
+	return &clientData, nil
+}
+
+// VerifyAttestation verifies the attestation object of a registration ceremony with
+// the formats none and packed. Packed certificate chains are checked for structure
+// and signatures but not against a trust store, so they are not proof of a model.
+func (c WebAuthnConfig) VerifyAttestation(attestationObject, clientDataJSON []byte) (*AttestedCredential, error) {
+	decoded, rest, err := decodeCBOR(attestationObject)
+	if err != nil || len(rest) > 0 {
+		return nil, webAuthnError("attestation object is not valid CBOR")
+	}
+
+	object, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, webAuthnError("attestation object is not a map")
+	}
+
+	format, _ := object["fmt"].(string)
+	rawAuthData, _ := object["authData"].([]byte)
+	statement, ok := object["attStmt"].(map[interface{}]interface{})
+	if !ok {
+		return nil, webAuthnError("attestation statement is missing")
+	}
+
+	authData, err := c.checkAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+
+	if authData.flags&authDataFlagAttestedCredential == 0 || len(authData.credentialID) == 0 {
+		return nil, webAuthnError("attested credential data is missing")
+	}
+
+	publicKey, algorithm, err := parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+
+	switch format {
+	case "none":
+		if len(statement) != 0 {
+			return nil, webAuthnError("none attestation statement must be empty")
+		}
+	case "packed":
+		err = verifyPackedAttestation(statement, authData, clientDataHash[:], publicKey, algorithm)
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, webAuthnError("unsupported attestation format " + format)
+	}
+
+	return &AttestedCredential{
+		ID:        authData.credentialID,
+		PublicKey: authData.publicKey,
+		Algorithm: algorithm,
+		SignCount: authData.signCount,
+		Format:    format,
+		AAGUID:    authData.aaguid,
+	}, nil
+}
+
+// VerifyAssertion verifies the signature of an authentication ceremony with the stored
+// COSE public key and returns the signature counter of the authenticator.
+func (c WebAuthnConfig) VerifyAssertion(publicKey, rawAuthData, clientDataJSON, signature []byte) (uint32, error) {
+	authData, err := c.checkAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+
+	key, algorithm, err := parseCOSEKey(publicKey)
+	if err != nil {
+		return 0, err
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signed := append(append([]byte(nil), authData.raw...), clientDataHash[:]...)
+
+	if err := verifySignature(key, algorithm, signed, signature); err != nil {
+		return 0, err
+	}
+
+	return authData.signCount, nil
+}
+
+// checkAuthenticatorData parses authenticator data and checks the relying party and user flags.
+func (c WebAuthnConfig) checkAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	authData, err := parseAuthenticatorData(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	rpIDHash := sha256.Sum256([]byte(c.RPID))
+	if !bytes.Equal(authData.rpIDHash, rpIDHash[:]) {
+		return nil, webAuthnError("relying party ID does not match")
+	}
+
+	if authData.flags&authDataFlagUserPresent == 0 {
+		return nil, webAuthnError("user was not present")
+	}
+
+	if c.RequireUserVerification && authData.flags&authDataFlagUserVerified == 0 {
+		return nil, webAuthnError("user was not verified")
+	}
+
+	return authData, nil
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < authDataMinLength {
+		return nil, webAuthnError("authenticator data is too short")
+	}
+
+	authData := &authenticatorData{
+		raw:       raw,
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+
+	if authData.flags&authDataFlagAttestedCredential == 0 {
+		return authData, nil
+	}
+
+	rest := raw[authDataMinLength:]
+	if len(rest) < 18 {
+		return nil, webAuthnError("attested credential data is too short")
+	}
+
+	authData.aaguid = rest[:16]
+	length := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+
+	if length == 0 || len(rest) < length {
+		return nil, webAuthnError("credential ID is not valid")
+	}
+
+	authData.credentialID = rest[:length]
+	rest = rest[length:]
+
+	_, extensions, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, webAuthnError("credential public key is not valid CBOR")
+	}
+
+	authData.publicKey = rest[:len(rest)-len(extensions)]
+
+	return authData, nil
+}
+
+// parseCOSEKey decodes a COSE public key with one of the accepted algorithms.
+func parseCOSEKey(data []byte) (crypto.PublicKey, int64, error) {
+	decoded, rest, err := decodeCBOR(data)
+	if err != nil || len(rest) > 0 {
+		return nil, 0, webAuthnError("public key is not valid CBOR")
+	}
+
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, webAuthnError("public key is not a map")
+	}
+
+	keyType, _ := key[int64(coseKeyType)].(int64)
+	algorithm, _ := key[int64(coseKeyAlgorithm)].(int64)
+
+	switch {
+	case keyType == coseKeyTypeEC2 && algorithm == COSEAlgES256:
+		curve, _ := key[int64(coseKeyCurve)].(int64)
+		x, _ := key[int64(coseKeyX)].([]byte)
+		y, _ := key[int64(coseKeyY)].([]byte)
+		if curve != coseCurveP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, webAuthnError("EC2 public key is not valid")
+		}
+
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if _, err := publicKey.ECDH(); err != nil {
+			return nil, 0, webAuthnError("EC2 public key is not on the curve")
+		}
+
+		return publicKey, algorithm, nil
+	case keyType == coseKeyTypeRSA && algorithm == COSEAlgRS256:
+		n, _ := key[int64(coseKeyCurve)].([]byte)
+		e, _ := key[int64(coseKeyX)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, webAuthnError("RSA public key is not valid")
+		}
+
+		exponent := new(big.Int).SetBytes(e)
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, algorithm, nil
+	case keyType == coseKeyTypeOKP && algorithm == COSEAlgEdDSA:
+		curve, _ := key[int64(coseKeyCurve)].(int64)
+		x, _ := key[int64(coseKeyX)].([]byte)
+		if curve != coseCurveEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, webAuthnError("OKP public key is not valid")
+		}
+
+		return ed25519.PublicKey(x), algorithm, nil
+	default:
+		return nil, 0, webAuthnError("unsupported public key algorithm")
+	}
+}
+
+func verifySignature(key crypto.PublicKey, algorithm int64, data, signature []byte) error {
+	valid := false
+
+	switch publicKey := key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = algorithm == COSEAlgES256 && ecdsa.VerifyASN1(publicKey, digest[:], signature)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		valid = algorithm == COSEAlgRS256 && rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) == nil
+	case ed25519.PublicKey:
+		valid = algorithm == COSEAlgEdDSA && ed25519.Verify(publicKey, data, signature)
+	}
+
+	if !valid {
+		return webAuthnError("signature is not valid")
+	}
+
+	return nil
+}
+
+// verifyPackedAttestation checks a packed statement, with x5c the signature is made
+// by the attestation certificate and without it by the credential itself.
+func verifyPackedAttestation(statement map[interface{}]interface{}, authData *authenticatorData, clientDataHash []byte, credentialKey crypto.PublicKey, credentialAlgorithm int64) error {
+	algorithm, _ := statement["alg"].(int64)
+	signature, _ := statement["sig"].([]byte)
+	if len(signature) == 0 {
+		return webAuthnError("packed attestation signature is missing")
+	}
+
+	signed := append(append([]byte(nil), authData.raw...), clientDataHash...)
+
+	chain, hasCertificates := statement["x5c"].([]interface{})
+	if !hasCertificates {
+		if algorithm != credentialAlgorithm {
+			return webAuthnError("self attestation algorithm does not match the credential")
+		}
+		return verifySignature(credentialKey, algorithm, signed, signature)
+	}
+
+	certificates := make([]*x509.Certificate, 0, len(chain))
+	for _, item := range chain {
+		der, ok := item.([]byte)
+		if !ok {
+			return webAuthnError("attestation certificate is not valid")
+		}
+
+		certificate, err := x509.ParseCertificate(der)
+		if err != nil {
+			return webAuthnError("attestation certificate is not valid")
+		}
+		certificates = append(certificates, certificate)
+	}
+
+	if len(certificates) == 0 {
+		return webAuthnError("attestation certificate is missing")
+	}
+
+	certificate := certificates[0]
+	if err := verifySignature(certificate.PublicKey, algorithm, signed, signature); err != nil {
+		return err
+	}
+
+	if err := checkPackedCertificate(certificate, authData.aaguid); err != nil {
+		return err
+	}
+
+	for i := 0; i+1 < len(certificates); i++ {
+		if err := certificates[i].CheckSignatureFrom(certificates[i+1]); err != nil {
+			return webAuthnError("attestation certificate chain is not valid")
+		}
+	}
+
+	return nil
+}
+
+// checkPackedCertificate applies the certificate requirements of the packed format.
+func checkPackedCertificate(certificate *x509.Certificate, aaguid []byte) error {
+	subject := certificate.Subject
+	if certificate.Version != 3 || len(subject.Country) == 0 || len(subject.Organization) == 0 ||
+		subject.CommonName == "" || !slices.Contains(subject.OrganizationalUnit, packedAttestationOU) {
+		return webAuthnError("attestation certificate subject is not valid")
+	}
+
+	if certificate.IsCA {
+		return webAuthnError("attestation certificate must not be a CA")
+	}
+
+	for _, extension := range certificate.Extensions {
+		if !extension.Id.Equal(idFidoGenCeAAGUID) {
+			continue
+		}
+
+		var value []byte
+		if _, err := asn1.Unmarshal(extension.Value, &value); err != nil || !bytes.Equal(value, aaguid) {
+			return webAuthnError("attestation certificate AAGUID does not match")
+		}
+	}
+
+	return nil
+}
+
+func webAuthnError(reason string) error {
+	return fmt.Errorf("%w: %v", ErrInvalidWebAuthnResponse, reason)
+}
@@ -0,0 +1,87 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+type valuesClientDataTestCases struct {
+	name       string
+	clientData string
+	ceremony   string
+	isError    bool
+}
+
+var webAuthnConfig = utils.WebAuthnConfig{RPID: "localhost", Origins: []string{"http://localhost:8080"}}
+
+func TestParseClientData(t *testing.T) {
+	testCases := []valuesClientDataTestCases{
+		{
+			name:       "should parse client data of an allowed origin",
+			clientData: `{"type":"webauthn.get","challenge":"abc","origin":"http://localhost:8080"}`,
+			ceremony:   utils.WebAuthnCeremonyGet,
+		},
+		{
+			name:       "should throw an error when ceremony type does not match",
+			clientData: `{"type":"webauthn.create","challenge":"abc","origin":"http://localhost:8080"}`,
+			ceremony:   utils.WebAuthnCeremonyGet,
+			isError:    true,
+		},
+		{
+			name:       "should throw an error when origin is not allowed",
+			clientData: `{"type":"webauthn.get","challenge":"abc","origin":"https://localhost.evil"}`,
+			ceremony:   utils.WebAuthnCeremonyGet,
+			isError:    true,
+		},
+		{
+			name:       "should throw an error when request is cross origin",
+			clientData: `{"type":"webauthn.get","challenge":"abc","origin":"http://localhost:8080","crossOrigin":true}`,
+			ceremony:   utils.WebAuthnCeremonyGet,
+			isError:    true,
+		},
+		{
+			name:       "should throw an error when challenge is missing",
+			clientData: `{"type":"webauthn.get","origin":"http://localhost:8080"}`,
+			ceremony:   utils.WebAuthnCeremonyGet,
+			isError:    true,
+		},
+		{
+			name:       "should throw an error when client data is not JSON",
+			clientData: `webauthn`,
+			ceremony:   utils.WebAuthnCeremonyGet,
+			isError:    true,
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			clientData, err := webAuthnConfig.ParseClientData([]byte(test.clientData), test.ceremony)
+
+			if test.isError {
+				assert.ErrorIs(t, err, utils.ErrInvalidWebAuthnResponse)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, "abc", clientData.Challenge)
+			}
+		})
+	}
+}
+
+func TestVerifyAttestationRejectsMalformedCBOR(t *testing.T) {
+	attestationObjects := [][]byte{
+		{},
+		{0xa1},
+		{0xbf, 0xff},
+		{0xa2, 0x01, 0x01, 0x01, 0x01},
+		{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff},
+		{0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x81, 0x00},
+		{0xa0, 0x00},
+	}
+
+	for _, attestationObject := range attestationObjects {
+		_, err := webAuthnConfig.VerifyAttestation(attestationObject, []byte("{}"))
+		assert.ErrorIs(t, err, utils.ErrInvalidWebAuthnResponse)
+	}
+}
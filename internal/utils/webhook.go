@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// webhookSignaturePrefix names the algorithm of a webhook signature.
+const webhookSignaturePrefix = "sha256="
+
+// SignWebhookPayload returns the HMAC-SHA256 signature of a webhook delivery sent at timestamp,
+// as sha256=<hex>. The timestamp is signed with the payload so a captured delivery can not be
+// replayed later.
+func SignWebhookPayload(secret string, timestamp int64, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(payload)
+
+	return webhookSignaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhookSignature reports whether signature signs the payload sent at timestamp with
+// secret and the timestamp is within tolerance of now, it is what receivers must check.
+func VerifyWebhookSignature(secret string, timestamp string, signature string, payload []byte, tolerance time.Duration) bool {
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+
+	if age := time.Since(time.Unix(unix, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+
+	return hmac.Equal([]byte(signature), []byte(SignWebhookPayload(secret, unix, payload)))
+}
@@ -0,0 +1,66 @@
+package utils_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/CNMoreno/cnm-proyect-go/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignWebhookPayload(t *testing.T) {
+	payload := []byte(`{"id":"event-1"}`)
+
+	signature := utils.SignWebhookPayload("secret", 1700000000, payload)
+	assert.Regexp(t, "^sha256=[0-9a-f]{64}$", signature)
+	assert.Equal(t, signature, utils.SignWebhookPayload("secret", 1700000000, payload))
+	assert.NotEqual(t, signature, utils.SignWebhookPayload("other", 1700000000, payload))
+	assert.NotEqual(t, signature, utils.SignWebhookPayload("secret", 1700000001, payload))
+	assert.NotEqual(t, signature, utils.SignWebhookPayload("secret", 1700000000, []byte(`{"id":"event-2"}`)))
+}
+
+func TestVerifyWebhookSignature(t *testing.T) {
+	payload := []byte(`{"id":"event-1"}`)
+	now := time.Now().Unix()
+
+	testCases := []struct {
+		name      string
+		timestamp string
+		signature string
+		valid     bool
+	}{
+		{
+			name:      "should accept signature of the payload",
+			timestamp: strconv.FormatInt(now, 10),
+			signature: utils.SignWebhookPayload("secret", now, payload),
+			valid:     true,
+		},
+		{
+			name:      "should reject signature with another secret",
+			timestamp: strconv.FormatInt(now, 10),
+			signature: utils.SignWebhookPayload("other", now, payload),
+		},
+		{
+			name:      "should reject signature of another timestamp",
+			timestamp: strconv.FormatInt(now, 10),
+			signature: utils.SignWebhookPayload("secret", now-1, payload),
+		},
+		{
+			name:      "should reject timestamp older than the tolerance",
+			timestamp: strconv.FormatInt(now-600, 10),
+			signature: utils.SignWebhookPayload("secret", now-600, payload),
+		},
+		{
+			name:      "should reject invalid timestamp",
+			timestamp: "yesterday",
+			signature: utils.SignWebhookPayload("secret", now, payload),
+		},
+	}
+
+	for _, test := range testCases {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.valid, utils.VerifyWebhookSignature("secret", test.timestamp, test.signature, payload, 5*time.Minute))
+		})
+	}
+}
@@ -57,6 +57,42 @@ func (_m *AppCrypto) GenerateFromPassword(password []byte, cost int) ([]byte, er
 	return r0, r1
 }
 
+// Identify provides a mock function with given fields: hashedPassword
+func (_m *AppCrypto) Identify(hashedPassword []byte) bool {
+	ret := _m.Called(hashedPassword)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Identify")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func([]byte) bool); ok {
+		r0 = rf(hashedPassword)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
+// NeedsRehash provides a mock function with given fields: hashedPassword, cost
+func (_m *AppCrypto) NeedsRehash(hashedPassword []byte, cost int) bool {
+	ret := _m.Called(hashedPassword, cost)
+
+	if len(ret) == 0 {
+		panic("no return value specified for NeedsRehash")
+	}
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func([]byte, int) bool); ok {
+		r0 = rf(hashedPassword, cost)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // NewAppCrypto creates a new instance of AppCrypto. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
 // The first argument is typically a *testing.T value.
 func NewAppCrypto(t interface {